@@ -0,0 +1,216 @@
+// Package bench builds reproducible synthetic repositories to measure the
+// merge engine, from `go test -bench` and `gravel debug bench`
+package bench
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"gravel/ort"
+
+	"github.com/go-git/go-billy/v6/memfs"
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/filemode"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/storage/memory"
+)
+
+// filesPerDir spreads the files over directories like real projects
+const filesPerDir = 100
+
+// signature dates every commit the same so that the hashes are reproducible
+var signature = object.Signature{Name: "gravel", Email: "bench@gravel", When: time.Unix(0, 0).UTC()}
+
+// Spec describes a synthetic merge: both sides change Changed files of the
+// base on different lines, and Conflicting more files on the same line
+type Spec struct {
+	Files       int `json:"files"`
+	Changed     int `json:"changed"`
+	Conflicting int `json:"conflicting"`
+	// Lines is the length of every file
+	Lines int `json:"lines"`
+}
+
+func (spec Spec) String() string {
+	return fmt.Sprintf("files=%d/changed=%d/conflicting=%d/lines=%d", spec.Files, spec.Changed, spec.Conflicting, spec.Lines)
+}
+
+// Validate checks the changed files exist in the base
+func (spec Spec) Validate() error {
+	if spec.Files < 1 || spec.Lines < 3 {
+		return errors.New("a benchmark needs at least a file of 3 lines")
+	}
+	if spec.Changed < 0 || spec.Conflicting < 0 || spec.Changed+spec.Conflicting > spec.Files {
+		return fmt.Errorf("cannot change %d and conflict %d of %d files", spec.Changed, spec.Conflicting, spec.Files)
+	}
+	return nil
+}
+
+// Specs are the standard benchmarks: a small template, a large one with and
+// without conflicts, and long files. They stay quick enough to run on every
+// change, bigger repositories are measured with gravel debug bench
+var Specs = []Spec{
+	{Files: 100, Changed: 10, Conflicting: 0, Lines: 50},
+	{Files: 1000, Changed: 100, Conflicting: 0, Lines: 50},
+	{Files: 1000, Changed: 100, Conflicting: 10, Lines: 50},
+	{Files: 100, Changed: 10, Conflicting: 0, Lines: 2000},
+}
+
+// Build creates an in-memory repository whose HEAD is our side of spec, and
+// returns the reference of their side
+func Build(spec Spec) (*git.Repository, plumbing.Reference, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, plumbing.Reference{}, err
+	}
+
+	r, err := git.Init(memory.NewStorage(), git.WithWorkTree(memfs.New()))
+	if err != nil {
+		return nil, plumbing.Reference{}, err
+	}
+
+	base, err := commitSide(r, spec, "", nil)
+	if err != nil {
+		return nil, plumbing.Reference{}, err
+	}
+	ours, err := commitSide(r, spec, "ours", &base)
+	if err != nil {
+		return nil, plumbing.Reference{}, err
+	}
+	theirs, err := commitSide(r, spec, "theirs", &base)
+	if err != nil {
+		return nil, plumbing.Reference{}, err
+	}
+
+	branch := plumbing.NewBranchReferenceName("main")
+	if err = r.Storer.SetReference(plumbing.NewHashReference(branch, ours)); err != nil {
+		return nil, plumbing.Reference{}, err
+	}
+	if err = r.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, branch)); err != nil {
+		return nil, plumbing.Reference{}, err
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		return nil, plumbing.Reference{}, err
+	}
+	if err = w.Reset(&git.ResetOptions{Commit: ours, Mode: git.HardReset}); err != nil {
+		return nil, plumbing.Reference{}, err
+	}
+	return r, *plumbing.NewHashReference(plumbing.NewBranchReferenceName("theirs"), theirs), nil
+}
+
+// commitSide stores the files of a side, the base when side is empty, and
+// commits them on parent
+func commitSide(r *git.Repository, spec Spec, side string, parent *plumbing.Hash) (plumbing.Hash, error) {
+	dirs := make(map[string][]object.TreeEntry)
+	for index := range spec.Files {
+		hash, err := storeBlob(r, fileContent(spec, index, side))
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		dir := fmt.Sprintf("dir%04d", index/filesPerDir)
+		dirs[dir] = append(dirs[dir], object.TreeEntry{
+			Name: fmt.Sprintf("file%06d.txt", index),
+			Mode: filemode.Regular,
+			Hash: hash,
+		})
+	}
+
+	root := &object.Tree{}
+	for index := range (spec.Files + filesPerDir - 1) / filesPerDir {
+		dir := fmt.Sprintf("dir%04d", index)
+		hash, err := storeTree(r, &object.Tree{Entries: dirs[dir]})
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		root.Entries = append(root.Entries, object.TreeEntry{Name: dir, Mode: filemode.Dir, Hash: hash})
+	}
+	tree, err := storeTree(r, root)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	commit := &object.Commit{
+		Author:    signature,
+		Committer: signature,
+		Message:   strings.TrimSpace("bench " + side),
+		TreeHash:  tree,
+	}
+	if parent != nil {
+		commit.ParentHashes = []plumbing.Hash{*parent}
+	}
+	encoded := r.Storer.NewEncodedObject()
+	if err = commit.Encode(encoded); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return r.Storer.SetEncodedObject(encoded)
+}
+
+// fileContent returns the file of a side: changed files get a line of the
+// side near their top for ours and bottom for theirs, conflicting files
+// get it in the middle for both
+func fileContent(spec Spec, index int, side string) string {
+	lines := make([]string, spec.Lines)
+	for line := range lines {
+		lines[line] = fmt.Sprintf("file %d line %d", index, line)
+	}
+
+	switch {
+	case side == "":
+	case index < spec.Changed && side == "ours":
+		lines[0] = side
+	case index < spec.Changed:
+		lines[len(lines)-1] = side
+	case index < spec.Changed+spec.Conflicting:
+		lines[len(lines)/2] = side
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func storeBlob(r *git.Repository, content string) (plumbing.Hash, error) {
+	encoded := r.Storer.NewEncodedObject()
+	encoded.SetType(plumbing.BlobObject)
+	writer, err := encoded.Writer()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if _, err = writer.Write([]byte(content)); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if err = writer.Close(); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return r.Storer.SetEncodedObject(encoded)
+}
+
+func storeTree(r *git.Repository, tree *object.Tree) (plumbing.Hash, error) {
+	encoded := r.Storer.NewEncodedObject()
+	if err := tree.Encode(encoded); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return r.Storer.SetEncodedObject(encoded)
+}
+
+// Run measures ort.Merge on spec, the repository is rebuilt out of the
+// timer for every iteration. Conflicts are expected when spec has some
+func Run(b *testing.B, spec Spec, opts ort.MergeOptions) {
+	b.ReportAllocs()
+	for range b.N {
+		b.StopTimer()
+		r, theirs, err := Build(spec)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+
+		_, err = ort.Merge(r, theirs, opts)
+		if err != nil && !(spec.Conflicting > 0 && errors.Is(err, ort.ErrMergeConflict)) {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(spec.Files)*float64(b.N)/b.Elapsed().Seconds(), "files/s")
+}
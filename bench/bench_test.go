@@ -0,0 +1,58 @@
+package bench
+
+import (
+	"testing"
+
+	"gravel/ort"
+
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+func BenchmarkMerge(b *testing.B) {
+	for _, spec := range Specs {
+		b.Run(spec.String(), func(b *testing.B) {
+			Run(b, spec, ort.MergeOptions{})
+		})
+	}
+}
+
+func BenchmarkMergeTrees(b *testing.B) {
+	for _, spec := range Specs {
+		b.Run(spec.String(), func(b *testing.B) {
+			r, theirs, err := Build(spec)
+			if err != nil {
+				b.Fatal(err)
+			}
+			head, err := r.Head()
+			if err != nil {
+				b.Fatal(err)
+			}
+			ourCommit, err := r.CommitObject(head.Hash())
+			if err != nil {
+				b.Fatal(err)
+			}
+			theirCommit, err := r.CommitObject(theirs.Hash())
+			if err != nil {
+				b.Fatal(err)
+			}
+			baseCommit, err := r.CommitObject(ourCommit.ParentHashes[0])
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			trees := make([]*object.Tree, 3)
+			for index, commit := range []*object.Commit{baseCommit, ourCommit, theirCommit} {
+				if trees[index], err = commit.Tree(); err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err = ort.MergeTrees(r.Storer, trees[0], trees[1], trees[2], ort.MergeOptions{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
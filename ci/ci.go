@@ -0,0 +1,64 @@
+package ci
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+)
+
+//go:embed templates/*.tmpl
+var templates embed.FS
+
+// Provider identifies a CI service a pipeline can be generated for
+type Provider string
+
+const (
+	// GitHub generates a GitHub Actions workflow
+	GitHub Provider = "github"
+	// GitLab generates a GitLab CI job
+	GitLab Provider = "gitlab"
+)
+
+// Providers lists the supported providers
+var Providers = []Provider{GitHub, GitLab}
+
+// Options holds the values substituted into the generated pipeline
+type Options struct {
+	Schedule string // Schedule is the cron expression triggering the update
+	Lock     string // Lock is the lockfile path named in the pull request
+	Branch   string // Branch receives the update and is proposed for review
+	Install  string // Install is the shell command installing gravel
+}
+
+// Generate writes the update pipeline of provider into w
+func Generate(w io.Writer, provider Provider, opts Options) error {
+	switch provider {
+	case GitHub, GitLab:
+	default:
+		return fmt.Errorf("unsupported ci provider %q", provider)
+	}
+
+	name := string(provider) + ".tmpl"
+	tmpl, err := template.New(name).Funcs(template.FuncMap{"quote": quote}).ParseFS(templates, "templates/"+name)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, opts)
+}
+
+// quote returns value as a double-quoted YAML scalar, so that characters
+// such as ':', '#' or a leading '*' are not read as YAML syntax. A JSON
+// string is a valid one
+func quote(value string) (string, error) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buffer.String(), "\n"), nil
+}
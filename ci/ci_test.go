@@ -0,0 +1,95 @@
+package ci
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestGenerate(t *testing.T) {
+	opts := Options{
+		Schedule: "0 6 * * 1",
+		Lock:     "gravel.lock",
+		Branch:   "gravel/update",
+		Install:  "make install",
+	}
+	for _, provider := range Providers {
+		t.Run(string(provider), func(t *testing.T) {
+			var out strings.Builder
+			if err := Generate(&out, provider, opts); err != nil {
+				t.Fatal(err)
+			}
+			pipeline := out.String()
+			for _, want := range []string{opts.Schedule, opts.Branch, opts.Install, "gravel update\n"} {
+				if !strings.Contains(pipeline, want) {
+					t.Errorf("pipeline lacks %q:\n%s", want, pipeline)
+				}
+			}
+			// update follows the lockfile of the app, it has no --lock flag
+			if strings.Contains(pipeline, "--lock") {
+				t.Errorf("pipeline passes --lock to update:\n%s", pipeline)
+			}
+		})
+	}
+}
+
+func TestGenerateUnsupported(t *testing.T) {
+	if err := Generate(new(strings.Builder), "jenkins", Options{}); err == nil {
+		t.Fatal("expected an error for an unsupported provider")
+	}
+}
+
+func TestGenerateQuotes(t *testing.T) {
+	opts := Options{
+		Schedule: "*/30 6 * * 1",
+		Lock:     "gravel.lock",
+		Branch:   "update: #1",
+		Install:  `echo "a: b" # c`,
+	}
+	for _, test := range []struct {
+		provider Provider
+		// paths locate the values of the options in the pipeline
+		paths map[string][]any
+	}{
+		{provider: GitHub, paths: map[string][]any{
+			opts.Schedule: {"on", "schedule", 0, "cron"},
+			opts.Install:  {"jobs", "update", "steps", 2, "run"},
+			opts.Branch:   {"jobs", "update", "steps", 4, "with", "branch"},
+		}},
+		{provider: GitLab, paths: map[string][]any{
+			opts.Install: {"gravel-update", "script", 0},
+			opts.Branch:  {"gravel-update", "variables", "GRAVEL_BRANCH"},
+		}},
+	} {
+		t.Run(string(test.provider), func(t *testing.T) {
+			var out strings.Builder
+			if err := Generate(&out, test.provider, opts); err != nil {
+				t.Fatal(err)
+			}
+			var pipeline any
+			if err := yaml.Unmarshal([]byte(out.String()), &pipeline); err != nil {
+				t.Fatalf("invalid YAML: %v\n%s", err, out.String())
+			}
+			for want, path := range test.paths {
+				value := pipeline
+				for _, key := range path {
+					switch key := key.(type) {
+					case string:
+						node, _ := value.(map[string]any)
+						value = node[key]
+					case int:
+						node, _ := value.([]any)
+						if key >= len(node) {
+							t.Fatalf("%v: no element %d", path, key)
+						}
+						value = node[key]
+					}
+				}
+				if value != want {
+					t.Errorf("%v = %#v, want %q", path, value, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package cmd
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"gravel/config"
+	"gravel/i18n"
+	"gravel/lock"
+	"gravel/manifest"
+	"gravel/ort"
+	"gravel/progress"
+
+	"github.com/go-git/go-git/v6"
+	gitconfig "github.com/go-git/go-git/v6/config"
+	"github.com/spf13/cobra"
+)
+
+// addCmd represents the add command
+var addCmd = &cobra.Command{
+	Use:   "add <plugin> [directory]",
+	Short: "Merge a plugin into an existing app",
+	Long: `
+Merges a plugin of the manifest into an app created by init, and records
+it in ` + lock.File + ` so that update follows it.
+
+A plugin repository, like github.com/org/plugin or a URL, may be given
+instead of a plugin name: it is merged on the branch or tag following an @,
+or the default branch of the remote.
+
+The manifest is the one the app was created from, unless --manifest is given.
+`,
+	Args: cobra.RangeArgs(1, 2),
+
+	RunE: RunAdd,
+
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(addCmd)
+	addCmd.Flags().StringP(ManifestFlag, string(ManifestFlag[0]), Manifest, "sets the manifest")
+	addCmd.Flags().
+		Bool(DryRunFlag, DryRun, "perform a trial run with no changes made to filesystem")
+	addCmd.Flags().Bool(VerboseFlag, Verbose, "runs in verbose mode")
+	addCmd.Flags().
+		String(ProgressFlag, Progress, "progress format (text, json), --verbose implies text")
+	addMergeFlags(addCmd)
+}
+
+func RunAdd(cmd *cobra.Command, args []string) error {
+	flags := cmd.Flags()
+
+	dryRun, err := flags.GetBool(DryRunFlag)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	repo, err := openRepository(cmd, args[1:])
+	if err != nil {
+		return err
+	}
+
+	store, err := openState(repo)
+	if err != nil {
+		return err
+	}
+
+	locked, err := lock.Load(store)
+	if err != nil {
+		return err
+	}
+
+	recorded, err := loadProject(store, locked)
+	if err != nil {
+		return err
+	}
+
+	installNetwork(cfg.Network, nil)
+
+	plugin, err := lookupPlugin(cmd, cfg, locked, args[0])
+	if err != nil {
+		return err
+	}
+	if err = plugin.Compatible(); err != nil {
+		return err
+	}
+	if err = checkRemote(cfg.Network, plugin.Remote.URL); err != nil {
+		return err
+	}
+
+	component := lock.Component{
+		Name:      plugin.Name,
+		Remote:    cmp.Or(plugin.Remote.Name, plugin.Name),
+		URL:       plugin.Remote.URL,
+		Ref:       plugin.Remote.Ref,
+		Paths:     plugin.Paths,
+		Conflicts: plugin.Conflicts,
+	}
+	// Refused before the remote is created
+	if slices.ContainsFunc(locked.Components(), func(existing lock.Component) bool {
+		return existing.Name == component.Name
+	}) {
+		return fmt.Errorf("%s: component %q is already locked", lock.File, component.Name)
+	}
+
+	opts, err := flagMergeOptions(cmd, cfg, dryRun)
+	if err != nil {
+		return err
+	}
+
+	reporter, err := newReporter(cmd, new(progress.Recorder))
+	if err != nil {
+		return err
+	}
+	pluginReporter := reporter.Scope("plugin:" + plugin.Name)
+
+	remote, err := repo.CreateRemote(&gitconfig.RemoteConfig{
+		Name: component.Remote,
+		URLs: []string{component.URL},
+	})
+	if err != nil {
+		return err
+	}
+	// A dry run leaves no remote behind
+	if dryRun {
+		defer func() { _ = repo.DeleteRemote(component.Remote) }()
+	}
+
+	err = remote.FetchContext(cmd.Context(), fetchOptions(cfg, plugin.Remote, component.Remote, pluginReporter.Scope("fetch").Writer()))
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return err
+	}
+
+	if component.Ref == "" {
+		component.Ref, err = defaultBranch(remote, remoteAuth(cfg, component.URL))
+		if err != nil {
+			return err
+		}
+	}
+
+	ref, err := remoteRef(repo, component.Remote, component.Ref)
+	if err != nil {
+		return err
+	}
+	component.Commit = ref.Hash().String()
+
+	strategies := conflictStrategies(plugin.Conflicts)
+
+	opts.Labels = ort.Labels{Theirs: plugin.Name}
+	opts.Provenance = ort.Provenance{
+		Patterns:  plugin.Provenance,
+		Component: plugin.Name,
+		Version:   fmt.Sprintf("%s@%s", component.Ref, ref.Hash().String()[:7]),
+	}
+	opts.ConflictStrategies = strategies
+	opts.PathSpecs = plugin.Paths
+	opts.Progress = pluginReporter.Scope("merge").Writer()
+	opts.Events = mergeEvents{reporter: pluginReporter.Scope("merge")}
+
+	stdout := cmd.OutOrStdout()
+	result, err := ort.MergeContext(cmd.Context(), repo, *ref, opts)
+	if dryRun {
+		reportDryMerge(stdout, plugin.Name, result)
+		return err
+	}
+	if err != nil && !errors.Is(err, ort.ErrMergeConflict) {
+		return err
+	}
+	conflicted := err
+
+	if err = markRemote(repo, component.Remote, component.Name); err != nil {
+		return err
+	}
+
+	// The state records the plugin whether or not it conflicted, a
+	// conflicted merge commits it once concluded, without its merge commit
+	if err = locked.Add(component); err != nil {
+		return err
+	}
+	if err = locked.Save(store); err != nil {
+		return err
+	}
+	recorded.Merged(plugin.Name, mergedCommit(result.Commit))
+	if err = recorded.Save(store); err != nil {
+		return err
+	}
+	if conflicted != nil {
+		if _, err = stageState(repo, store); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintln(stdout, i18n.Tf("%s conflicts in %s", plugin.Name, strings.Join(result.Conflicts, ", ")))
+		_, _ = fmt.Fprintln(stdout, i18n.T("Resolve the conflicts, then run gravel merge --continue"))
+		return conflicted
+	}
+
+	if err = commitState(repo, store, fmt.Sprintf("Add %s to %s", plugin.Name, lock.File)); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, i18n.Tf("Added %s at %s", plugin.Name, ref.Hash().String()[:7]))
+	return err
+}
+
+// lookupPlugin returns the plugin named arg in the manifest of the app, or
+// the plugin repository arg names
+func lookupPlugin(cmd *cobra.Command, cfg *config.Config, locked *lock.Lock, arg string) (*manifest.Base, error) {
+	// A plugin given as its repository: add takes no directory first, a
+	// shorthand cannot be mistaken for one
+	if manifest.IsTemplate(arg) || manifest.IsShorthand(arg) {
+		decoded, err := manifest.FromTemplate(arg)
+		if err != nil {
+			return nil, err
+		}
+		plugin := decoded.Base[0]
+		// Named after the repository, origin is the remote of the base
+		plugin.Remote.Name = plugin.Name
+		return &plugin, nil
+	}
+
+	raw, err := cmd.Flags().GetString(ManifestFlag)
+	if err != nil {
+		return nil, err
+	}
+	if !cmd.Flags().Changed(ManifestFlag) {
+		raw = cmp.Or(locked.Manifest, cfg.Manifest, raw)
+	}
+
+	decoded, err := loadManifest(raw, cfg.Network, nil)
+	if err != nil {
+		return nil, err
+	}
+	plugins, err := manifest.Lookup(decoded.Plugins, arg)
+	if err != nil {
+		return nil, err
+	}
+	return &plugins[0], nil
+}
+
+// markRemote marks the remote as added by gravel for component, so that
+// remotes sync maintains it
+func markRemote(repo *git.Repository, remote, component string) error {
+	cfg, err := repo.Config()
+	if err != nil {
+		return err
+	}
+	cfg.Raw.Section("remote").Subsection(remote).SetOption(remoteComponentKey, component)
+	return repo.SetConfig(cfg)
+}
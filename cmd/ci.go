@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gravel/ci"
+	"gravel/lock"
+	"gravel/version"
+
+	"github.com/spf13/cobra"
+)
+
+// ciCmd represents the ci command
+var ciCmd = &cobra.Command{
+	Use:   "ci",
+	Short: "Manage continuous integration pipelines",
+}
+
+// ciGenerateCmd represents the ci generate command
+var ciGenerateCmd = &cobra.Command{
+	Use:   "generate <github|gitlab>",
+	Short: "Generate a pipeline keeping the app up to date",
+	Long: `
+Emits a pipeline running "gravel update" on a schedule, which follows the
+lockfile of the app, and proposing the result as a pull request (GitHub) or merge request (GitLab).
+`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{string(ci.GitHub), string(ci.GitLab)},
+
+	RunE: RunCIGenerate,
+
+	SilenceUsage: true,
+}
+
+const (
+	ScheduleFlag = "schedule"
+	Schedule     = "0 6 * * 1"
+
+	LockFlag = "lock"
+	Lock     = lock.File
+
+	BranchFlag = "branch"
+	Branch     = "gravel/update"
+
+	InstallFlag = "install"
+
+	FileFlag = "file"
+	File     = ""
+)
+
+func init() {
+	rootCmd.AddCommand(ciCmd)
+	ciCmd.AddCommand(ciGenerateCmd)
+	ciGenerateCmd.Flags().String(ScheduleFlag, Schedule, "cron expression scheduling the update")
+	ciGenerateCmd.Flags().String(LockFlag, Lock, "lockfile named in the pull request")
+	ciGenerateCmd.Flags().String(BranchFlag, Branch, "branch receiving the update")
+	ciGenerateCmd.Flags().String(InstallFlag, install(version.Version), "shell command installing gravel")
+	ciGenerateCmd.Flags().
+		StringP(FileFlag, string(FileFlag[0]), File, "writes the pipeline to a file instead of stdout")
+}
+
+// install returns the shell command installing the release of gravel tagged
+// release, the default branch for the builds which are not a release. The
+// module is not go installable, its source is built into GOPATH/bin, which
+// setup-go and the golang images put on the PATH
+func install(release string) string {
+	if !version.Released(release) {
+		return `git clone --depth 1 https://github.com/gravel-dev-1/cli /tmp/gravel && go build -C /tmp/gravel -o "$(go env GOPATH)/bin/gravel" .`
+	}
+	return fmt.Sprintf(`git clone --depth 1 --branch %[1]s https://github.com/gravel-dev-1/cli /tmp/gravel && go build -C /tmp/gravel -ldflags "-X gravel/version.Version=%[1]s" -o "$(go env GOPATH)/bin/gravel" .`, release)
+}
+
+func RunCIGenerate(cmd *cobra.Command, args []string) error {
+	flags := cmd.Flags()
+
+	var opts ci.Options
+	var err error
+	for flag, value := range map[string]*string{
+		ScheduleFlag: &opts.Schedule,
+		LockFlag:     &opts.Lock,
+		BranchFlag:   &opts.Branch,
+		InstallFlag:  &opts.Install,
+	} {
+		*value, err = flags.GetString(flag)
+		if err != nil {
+			return err
+		}
+	}
+
+	file, err := flags.GetString(FileFlag)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = cmd.OutOrStdout()
+	if file != "" {
+		// Workflows usually live in nested directories such as .github/workflows
+		err = os.MkdirAll(filepath.Dir(file), 0o755)
+		if err != nil {
+			return err
+		}
+
+		dst, err := os.Create(file)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = dst.Close() }()
+		out = dst
+	}
+
+	err = ci.Generate(out, ci.Provider(args[0]), opts)
+	if err != nil {
+		return fmt.Errorf("ci generate: %w", err)
+	}
+	return nil
+}
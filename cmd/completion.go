@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+
+	"gravel/config"
+	"gravel/manifest"
+
+	"github.com/spf13/cobra"
+)
+
+// completionCmd represents the completion command
+var completionCmd = &cobra.Command{
+	Use:   "completion <bash|zsh|fish|powershell>",
+	Short: "Generate the completion script of a shell",
+	Long: `
+Prints the script completing the commands and flags of gravel in the shell,
+the names of the bases and plugins of the manifest included, e.g.:
+  bash        source <(gravel completion bash)
+  zsh         gravel completion zsh > "${fpath[1]}/_gravel"
+  fish        gravel completion fish > ~/.config/fish/completions/gravel.fish
+  powershell  gravel completion powershell | Out-String | Invoke-Expression
+`,
+	Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	ValidArgs: []string{"bash", "zsh", "fish", "powershell"},
+
+	RunE: RunCompletion,
+
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(completionCmd)
+}
+
+// completeBase completes --base with the bases of the manifest
+func completeBase(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	decoded, err := completionManifest(cmd)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	return completeEntries(decoded.Base, nil), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completePlugin completes --plugin with the plugins of the manifest not
+// selected yet
+func completePlugin(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	decoded, err := completionManifest(cmd)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	selected, _ := cmd.Flags().GetStringArray(PluginFlag)
+	return completeEntries(decoded.Plugins, selected), cobra.ShellCompDirectiveNoFileComp
+}
+
+func RunCompletion(cmd *cobra.Command, args []string) error {
+	stdout := cmd.OutOrStdout()
+	switch args[0] {
+	case "bash":
+		return rootCmd.GenBashCompletionV2(stdout, true)
+	case "zsh":
+		return rootCmd.GenZshCompletion(stdout)
+	case "fish":
+		return rootCmd.GenFishCompletion(stdout, true)
+	case "powershell":
+		return rootCmd.GenPowerShellCompletionWithDesc(stdout)
+	default:
+		return fmt.Errorf("unsupported shell %q", args[0])
+	}
+}
+
+// completionManifest loads the manifest init would, from --manifest, the
+// profile or the configuration
+func completionManifest(cmd *cobra.Command) (*manifest.Manifest, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	flags := cmd.Flags()
+	raw, err := flags.GetString(ManifestFlag)
+	if err != nil {
+		return nil, err
+	}
+	if !flags.Changed(ManifestFlag) {
+		if cfg.Manifest != "" {
+			raw = cfg.Manifest
+		}
+		profile, err := selectedProfile(cmd, cfg)
+		if err == nil && profile != nil && profile.Manifest != "" {
+			raw = profile.Manifest
+		}
+	}
+
+	installNetwork(cfg.Network, nil)
+	return loadManifest(raw, cfg.Network, nil)
+}
+
+// completeEntries returns the names of entries with their descriptions,
+// those already selected left out
+func completeEntries(entries []manifest.Base, selected []string) (names []string) {
+	for _, entry := range entries {
+		if slices.Contains(selected, entry.Name) {
+			continue
+		}
+		if entry.Description == "" {
+			names = append(names, entry.Name)
+			continue
+		}
+		names = append(names, cobra.CompletionWithDesc(entry.Name, entry.Description))
+	}
+	return
+}
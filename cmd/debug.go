@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"testing"
+	"text/tabwriter"
+
+	"gravel/bench"
+	"gravel/i18n"
+	"gravel/ort"
+
+	"github.com/spf13/cobra"
+)
+
+// debugCmd represents the debug command
+var debugCmd = &cobra.Command{
+	Use:   "debug",
+	Short: "Tools to troubleshoot and develop gravel",
+}
+
+// debugBenchCmd represents the debug bench command
+var debugBenchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Measure the merge engine on synthetic repositories",
+	Long: `
+Merges synthetic repositories built in memory, where both sides change
+--changed files of the base on different lines and --conflicting files on
+the same line, and reports the time and allocations per merge.
+
+Without any size flag, the standard benchmarks run, the same as
+` + "`go test -bench . ./bench`" + `. A JSON report of a previous run given as
+--baseline fails the command when a merge got slower by more than
+--tolerance percent.
+`,
+	Args: cobra.NoArgs,
+
+	RunE: RunDebugBench,
+
+	SilenceUsage: true,
+}
+
+const (
+	FilesFlag = "files"
+	Files     = 1000
+
+	ChangedFlag = "changed"
+	Changed     = 100
+
+	ConflictingFlag = "conflicting"
+	Conflicting     = 0
+
+	LinesFlag = "lines"
+	Lines     = 50
+
+	BaselineFlag = "baseline"
+	Baseline     = ""
+
+	ToleranceFlag = "tolerance"
+	Tolerance     = 20.0
+)
+
+// ErrBenchRegression is returned when a benchmark is slower than its baseline
+var ErrBenchRegression = errors.New("merge benchmark regressed")
+
+// BenchResult is the measure of a benchmark
+type BenchResult struct {
+	Spec        bench.Spec `json:"spec"`
+	NsPerOp     int64      `json:"nsPerOp"`
+	AllocsPerOp int64      `json:"allocsPerOp"`
+	BytesPerOp  int64      `json:"bytesPerOp"`
+	FilesPerSec float64    `json:"filesPerSecond"`
+}
+
+func init() {
+	rootCmd.AddCommand(debugCmd)
+	debugCmd.AddCommand(debugBenchCmd)
+
+	debugBenchCmd.Flags().
+		Int(FilesFlag, Files, "files of the base")
+	debugBenchCmd.Flags().
+		Int(ChangedFlag, Changed, "files both sides change without conflict")
+	debugBenchCmd.Flags().
+		Int(ConflictingFlag, Conflicting, "files both sides change on the same line")
+	debugBenchCmd.Flags().
+		Int(LinesFlag, Lines, "lines of every file")
+	debugBenchCmd.Flags().
+		StringP(OutputFlag, string(OutputFlag[0]), Output, "output format (text, json)")
+	debugBenchCmd.Flags().
+		String(BaselineFlag, Baseline, "JSON report of a previous run to detect regressions against")
+	debugBenchCmd.Flags().
+		Float64(ToleranceFlag, Tolerance, "percentage a merge may be slower than its baseline")
+}
+
+func RunDebugBench(cmd *cobra.Command, args []string) error {
+	flags := cmd.Flags()
+	output, err := flags.GetString(OutputFlag)
+	if err != nil {
+		return err
+	}
+	if output != "text" && output != "json" {
+		return fmt.Errorf("unsupported output format %q", output)
+	}
+	tolerance, err := flags.GetFloat64(ToleranceFlag)
+	if err != nil {
+		return err
+	}
+
+	var baseline []BenchResult
+	baselineFile, err := flags.GetString(BaselineFlag)
+	if err != nil {
+		return err
+	}
+	if baselineFile != "" {
+		var content []byte
+		if content, err = os.ReadFile(baselineFile); err != nil {
+			return err
+		}
+		if err = json.Unmarshal(content, &baseline); err != nil {
+			return fmt.Errorf("%s: %w", baselineFile, err)
+		}
+	}
+
+	specs := bench.Specs
+	if flags.Changed(FilesFlag) || flags.Changed(ChangedFlag) || flags.Changed(ConflictingFlag) || flags.Changed(LinesFlag) {
+		var spec bench.Spec
+		if spec.Files, err = flags.GetInt(FilesFlag); err != nil {
+			return err
+		}
+		if spec.Changed, err = flags.GetInt(ChangedFlag); err != nil {
+			return err
+		}
+		if spec.Conflicting, err = flags.GetInt(ConflictingFlag); err != nil {
+			return err
+		}
+		if spec.Lines, err = flags.GetInt(LinesFlag); err != nil {
+			return err
+		}
+		if err = spec.Validate(); err != nil {
+			return err
+		}
+		specs = []bench.Spec{spec}
+	}
+
+	results := make([]BenchResult, 0, len(specs))
+	for _, spec := range specs {
+		measure := testing.Benchmark(func(b *testing.B) {
+			bench.Run(b, spec, ort.MergeOptions{})
+		})
+		if measure.N == 0 {
+			return fmt.Errorf("benchmark %s failed", spec)
+		}
+		results = append(results, BenchResult{
+			Spec:        spec,
+			NsPerOp:     measure.NsPerOp(),
+			AllocsPerOp: measure.AllocsPerOp(),
+			BytesPerOp:  measure.AllocedBytesPerOp(),
+			FilesPerSec: measure.Extra["files/s"],
+		})
+	}
+
+	stdout := cmd.OutOrStdout()
+	switch output {
+	case "json":
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err = encoder.Encode(results); err != nil {
+			return err
+		}
+	case "text":
+		table := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(table, "BENCHMARK\tNS/OP\tALLOCS/OP\tB/OP\tFILES/S")
+		for _, result := range results {
+			_, _ = fmt.Fprintf(table, "%s\t%d\t%d\t%d\t%.0f\n",
+				result.Spec, result.NsPerOp, result.AllocsPerOp, result.BytesPerOp, result.FilesPerSec)
+		}
+		if err = table.Flush(); err != nil {
+			return err
+		}
+	}
+
+	// Regressions go to stderr, the report on stdout stays a valid baseline
+	var regressed bool
+	for _, result := range results {
+		index := slices.IndexFunc(baseline, func(previous BenchResult) bool { return previous.Spec == result.Spec })
+		if index < 0 {
+			continue
+		}
+		previous := baseline[index].NsPerOp
+		if float64(result.NsPerOp) > float64(previous)*(1+tolerance/100) {
+			regressed = true
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), i18n.Tf("%s: %d ns/op, %d ns/op in the baseline", result.Spec, result.NsPerOp, previous))
+		}
+	}
+	if regressed {
+		return ErrBenchRegression
+	}
+	return nil
+}
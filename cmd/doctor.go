@@ -0,0 +1,288 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"text/tabwriter"
+
+	"gravel/config"
+	"gravel/i18n"
+	"gravel/manifest"
+	"gravel/terminal"
+
+	"github.com/go-git/go-git/v6"
+	gitconfig "github.com/go-git/go-git/v6/config"
+	"github.com/go-git/go-git/v6/plumbing/transport"
+	"github.com/go-git/go-git/v6/storage/memory"
+	"github.com/spf13/cobra"
+)
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor [directory]",
+	Short: "Diagnose the environment gravel init runs in",
+	Long: `
+Checks what init needs and prints a finding for each check, with the
+command or setting fixing it:
+  git          git on the PATH, only needed by the hooks and the tools of the app
+  manifest     the manifest is reachable and valid
+  credentials  the remotes of the manifest accept the configured tokens
+  directory    the directory of the app, the current one by default, is writable
+  terminal     the selectors have a terminal, with its colors and glyphs
+
+Exits with an error when a check fails, warnings do not.
+`,
+	Args: cobra.MaximumNArgs(1),
+
+	RunE: RunDoctor,
+
+	SilenceUsage: true,
+}
+
+// ErrDoctorFailed is returned when a check of doctor fails
+var ErrDoctorFailed = errors.New("the environment is not ready for gravel init")
+
+// Statuses of the doctor findings
+const (
+	doctorOK      = "ok"
+	doctorWarning = "warning"
+	doctorError   = "error"
+)
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+	doctorCmd.Flags().StringP(ManifestFlag, string(ManifestFlag[0]), Manifest, "sets the manifest")
+	doctorCmd.Flags().
+		StringP(OutputFlag, string(OutputFlag[0]), Output, "output format (text, json, yaml)")
+}
+
+// finding is the outcome of a check of doctor, Hint tells how to fix it
+type finding struct {
+	Check  string `json:"check" yaml:"check"`
+	Status string `json:"status" yaml:"status"`
+	Detail string `json:"detail" yaml:"detail"`
+	Hint   string `json:"hint,omitempty" yaml:"hint,omitempty"`
+}
+
+func RunDoctor(cmd *cobra.Command, args []string) error {
+	flags := cmd.Flags()
+
+	output, err := flags.GetString(OutputFlag)
+	if err != nil {
+		return err
+	}
+	if err = checkOutput(output); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	raw, err := flags.GetString(ManifestFlag)
+	if err != nil {
+		return err
+	}
+	if !flags.Changed(ManifestFlag) && cfg.Manifest != "" {
+		raw = cfg.Manifest
+	}
+
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
+
+	installNetwork(cfg.Network, nil)
+
+	findings := []finding{checkGit()}
+	decoded, manifestFinding := checkManifest(cfg, raw)
+	findings = append(findings, manifestFinding)
+	if decoded != nil {
+		findings = append(findings, checkCredentials(cfg, decoded)...)
+	}
+	findings = append(findings, checkDirectory(dir), checkTerminal(cmd))
+
+	stdout := cmd.OutOrStdout()
+	if output != "text" {
+		err = encodeOutput(stdout, output, findings)
+	} else {
+		err = writeFindings(stdout, findings)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, finding := range findings {
+		if finding.Status == doctorError {
+			return ErrDoctorFailed
+		}
+	}
+	return nil
+}
+
+// checkGit looks for git on the PATH, gravel merges without it
+func checkGit() finding {
+	path, err := exec.LookPath("git")
+	if err != nil {
+		return finding{
+			Check:  "git",
+			Status: doctorWarning,
+			Detail: i18n.T("git is not on the PATH"),
+			Hint:   i18n.T("gravel does not need git, install it to commit in the app"),
+		}
+	}
+	return finding{Check: "git", Status: doctorOK, Detail: path}
+}
+
+// checkManifest loads the manifest raw names, the decoded manifest is nil
+// when it failed
+func checkManifest(cfg *config.Config, raw string) (*manifest.Manifest, finding) {
+	decoded, err := loadManifest(raw, cfg.Network, nil)
+	if err != nil {
+		return nil, finding{
+			Check:  "manifest",
+			Status: doctorError,
+			Detail: fmt.Sprintf("%s: %s", raw, err),
+			Hint:   i18n.T("check the network, or set another manifest with --manifest or gravel setup"),
+		}
+	}
+	return decoded, finding{
+		Check:  "manifest",
+		Status: doctorOK,
+		Detail: i18n.Tf("%s: %d bases, %d plugins", raw, len(decoded.Base), len(decoded.Plugins)),
+	}
+}
+
+// checkCredentials lists each remote of the manifest once, authenticated
+// like init fetches it
+func checkCredentials(cfg *config.Config, decoded *manifest.Manifest) (findings []finding) {
+	checked := make(map[string]bool)
+	for _, entry := range slices.Concat(decoded.Base, decoded.Plugins) {
+		remoteURL := entry.Remote.URL
+		if checked[remoteURL] {
+			continue
+		}
+		checked[remoteURL] = true
+
+		if err := checkRemote(cfg.Network, remoteURL); err != nil {
+			findings = append(findings, finding{
+				Check:  "credentials",
+				Status: doctorError,
+				Detail: err.Error(),
+				Hint:   i18n.T("allow the host in the network policy of the configuration"),
+			})
+			continue
+		}
+
+		// Listed from memory, nothing is written
+		remote := git.NewRemote(memory.NewStorage(), &gitconfig.RemoteConfig{
+			Name: entry.Name,
+			URLs: []string{remoteURL},
+		})
+		auth := remoteAuth(cfg, remoteURL)
+		_, err := remote.List(&git.ListOptions{Auth: auth})
+
+		host := remoteURL
+		if parsed, parseErr := url.Parse(remoteURL); parseErr == nil && parsed.Hostname() != "" {
+			host = parsed.Hostname()
+		}
+		switch {
+		case err == nil:
+			detail := i18n.Tf("%s is readable", remoteURL)
+			if auth != nil {
+				detail = i18n.Tf("%s is readable with the token of %s", remoteURL, host)
+			}
+			findings = append(findings, finding{Check: "credentials", Status: doctorOK, Detail: detail})
+		// Forges answer not found to private repositories without a token
+		case errors.Is(err, transport.ErrAuthenticationRequired),
+			errors.Is(err, transport.ErrAuthorizationFailed),
+			errors.Is(err, transport.ErrRepositoryNotFound):
+			hint := i18n.Tf("add a token for %s with gravel setup", host)
+			if auth != nil {
+				hint = i18n.Tf("the token of %s is refused, replace it with gravel setup", host)
+			}
+			findings = append(findings, finding{
+				Check:  "credentials",
+				Status: doctorError,
+				Detail: fmt.Sprintf("%s: %s", remoteURL, err),
+				Hint:   hint,
+			})
+		default:
+			findings = append(findings, finding{
+				Check:  "credentials",
+				Status: doctorWarning,
+				Detail: fmt.Sprintf("%s: %s", remoteURL, err),
+				Hint:   i18n.T("check the network, the remote could not be listed"),
+			})
+		}
+	}
+	return
+}
+
+// checkDirectory writes a file in dir, or in its closest existing parent
+// since init creates the missing directories
+func checkDirectory(dir string) finding {
+	existing, err := filepath.Abs(dir)
+	if err != nil {
+		return finding{Check: "directory", Status: doctorError, Detail: err.Error()}
+	}
+	for {
+		if _, err = os.Stat(existing); err == nil || filepath.Dir(existing) == existing {
+			break
+		}
+		existing = filepath.Dir(existing)
+	}
+
+	file, err := os.CreateTemp(existing, ".gravel-doctor-*")
+	if err != nil {
+		return finding{
+			Check:  "directory",
+			Status: doctorError,
+			Detail: err.Error(),
+			Hint:   i18n.T("choose another directory, or fix its permissions"),
+		}
+	}
+	_ = file.Close()
+	_ = os.Remove(file.Name())
+	return finding{Check: "directory", Status: doctorOK, Detail: i18n.Tf("%s is writable", existing)}
+}
+
+// checkTerminal reports the capabilities the selectors are rendered with
+func checkTerminal(cmd *cobra.Command) finding {
+	capabilities := terminal.Current()
+	glyphs := "ascii"
+	if capabilities.Unicode {
+		glyphs = "unicode"
+	}
+	detail := i18n.Tf("color %s, glyphs %s", capabilities.Color(), glyphs)
+
+	if !interactive(cmd) || unattended(cmd) {
+		return finding{
+			Check:  "terminal",
+			Status: doctorWarning,
+			Detail: i18n.T("no terminal, the selectors cannot be shown"),
+			Hint:   i18n.T("select the components with --base and --plugin, or --non-interactive"),
+		}
+	}
+	return finding{Check: "terminal", Status: doctorOK, Detail: detail}
+}
+
+// writeFindings prints the findings as a table followed by their hints
+func writeFindings(out io.Writer, findings []finding) error {
+	table := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(table, "CHECK\tSTATUS\tDETAIL")
+	for _, finding := range findings {
+		_, _ = fmt.Fprintf(table, "%s\t%s\t%s\n", finding.Check, i18n.T(finding.Status), finding.Detail)
+		if finding.Hint != "" {
+			_, _ = fmt.Fprintf(table, "\t\t%s\n", i18n.Tf("hint: %s", finding.Hint))
+		}
+	}
+	return table.Flush()
+}
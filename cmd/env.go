@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"gravel/components"
+	"gravel/config"
+	"gravel/lock"
+	"gravel/project"
+	"gravel/variables"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/go-git/go-git/v6"
+	"github.com/spf13/cobra"
+)
+
+// envCmd represents the env command
+var envCmd = &cobra.Command{
+	Use:   "env [directory]",
+	Short: "Print the template variables used for rendering",
+	Long: `
+Prints the effective template variables and where each value comes from,
+those render would use in the app of the directory, the current one by
+default.
+
+Origins are applied in order, later ones winning:
+  project      values recorded in the project state of the app
+  lockfile     values the app was last rendered with, recorded in its lockfile
+  config       variables section of the configuration file
+  profile      variables of the profile selected by --profile
+  environment  GRAVEL_VAR_<name> environment variables
+  flag         --set name=value
+`,
+	Args: cobra.MaximumNArgs(1),
+
+	RunE: RunEnv,
+
+	SilenceUsage: true,
+}
+
+const (
+	SetFlag = "set"
+
+	ProfileFlag = "profile"
+	Profile     = ""
+)
+
+func init() {
+	rootCmd.AddCommand(envCmd)
+	envCmd.Flags().StringArray(SetFlag, nil, "sets a variable (name=value), can be repeated")
+	envCmd.Flags().String(ProfileFlag, Profile, "applies the variables of a configured profile")
+	envCmd.Flags().
+		StringP(OutputFlag, string(OutputFlag[0]), Output, "output format (text, json)")
+}
+
+// selectedProfile returns the profile named by the --profile flag, nil when unset
+func selectedProfile(cmd *cobra.Command, cfg *config.Config) (*config.Profile, error) {
+	name, err := cmd.Flags().GetString(ProfileFlag)
+	if err != nil || name == "" {
+		return nil, err
+	}
+	return cfg.Profile(name)
+}
+
+// resolveVariables layers the variables of every origin over those recorded
+// by an app: recorded by its project state, then locked by its lockfile
+func resolveVariables(cmd *cobra.Command, cfg *config.Config, locked, recorded map[string]string) (*variables.Set, error) {
+	profile, err := selectedProfile(cmd, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	assignments, err := cmd.Flags().GetStringArray(SetFlag)
+	if err != nil {
+		return nil, err
+	}
+
+	flagValues, err := variables.ParseAssignments(assignments)
+	if err != nil {
+		return nil, err
+	}
+
+	set := variables.New()
+	set.Layer(variables.Project, recorded)
+	set.Layer(variables.Lockfile, locked)
+	set.Layer(variables.Config, cfg.Variables)
+	if profile != nil {
+		set.Layer(variables.Profile, profile.Variables)
+	}
+	set.Layer(variables.Environment, variables.FromEnviron(os.Environ()))
+	set.Layer(variables.Flag, flagValues)
+	return set, nil
+}
+
+// promptVariables asks for the declared variables values leave unset,
+// prefilled with their default
+func promptVariables(cmd *cobra.Command, namespace *variables.Namespace, values map[string]string) (map[string]string, error) {
+	missing := namespace.Missing(values)
+	if len(missing) == 0 {
+		return nil, nil
+	}
+
+	fields := make([]components.FormField, len(missing))
+	for index, declaration := range missing {
+		fields[index] = components.FormField{
+			Title:       namespace.Label(declaration),
+			Placeholder: declaration.Description,
+			Value:       declaration.Default,
+		}
+	}
+
+	form := components.NewForm(fields...)
+	program := tea.NewProgram(
+		form,
+		tea.WithInput(cmd.InOrStdin()),
+		tea.WithOutput(cmd.OutOrStdout()),
+		tea.WithContext(cmd.Context()),
+	)
+	if _, err := program.Run(); err != nil {
+		return nil, err
+	}
+	if form.Cancelled() {
+		return nil, context.Canceled
+	}
+
+	// Prompted values are set by qualified name, immune to ambiguity
+	prompted := make(map[string]string, len(missing))
+	for index, value := range form.Values() {
+		prompted[missing[index].Name] = value
+	}
+	return prompted, nil
+}
+
+// recordedVariables returns the variables of the lockfile and of the project
+// state of the app, nil for those it lacks
+func recordedVariables(repo *git.Repository) (locked, recorded map[string]string, err error) {
+	store, err := openState(repo)
+	if err != nil {
+		return
+	}
+
+	lockfile, err := lock.Load(store)
+	switch {
+	case err == nil:
+		locked = lockfile.Variables
+	case !errors.Is(err, lock.ErrNoLockfile):
+		return
+	}
+
+	app, err := project.Load(store)
+	switch {
+	case err == nil:
+		recorded = app.Variables
+	case !errors.Is(err, project.ErrNoProject):
+		return
+	}
+	return locked, recorded, nil
+}
+
+func RunEnv(cmd *cobra.Command, args []string) error {
+	output, err := cmd.Flags().GetString(OutputFlag)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	// Outside of an app, only the variables of the user are printed
+	var locked, recorded map[string]string
+	repo, err := openRepository(cmd, args)
+	switch {
+	case err == nil:
+		locked, recorded, err = recordedVariables(repo)
+		if err != nil {
+			return err
+		}
+	case !errors.Is(err, git.ErrRepositoryNotExists) || len(args) > 0:
+		return err
+	}
+
+	set, err := resolveVariables(cmd, cfg, locked, recorded)
+	if err != nil {
+		return err
+	}
+
+	stdout := cmd.OutOrStdout()
+	switch output {
+	case "json":
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(set.List())
+	case "text":
+		table := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(table, "NAME\tVALUE\tORIGIN")
+		for _, variable := range set.List() {
+			_, _ = fmt.Fprintf(table, "%s\t%s\t%s\n", variable.Name, variable.Value, variable.Origin)
+		}
+		return table.Flush()
+	default:
+		return fmt.Errorf("unsupported output format %q", output)
+	}
+}
@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gravel/components"
+	"gravel/i18n"
+	"gravel/manifest"
+	"gravel/ort"
+	"gravel/progress"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/transport"
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/cobra"
+)
+
+// Steps of the init wizard, named on the error page
+const (
+	stepManifest   = "Loading the manifest"
+	stepRepository = "Preparing the repository"
+	stepBase       = "Fetching the base"
+	stepPlugins    = "Merging the plugins"
+	stepVariables  = "Resolving the variables"
+	stepRender     = "Rendering the templates"
+	stepLicense    = "Writing the license"
+	stepWorkspace  = "Writing the editor workspace"
+	stepState      = "Writing the state of the app"
+	stepVerify     = "Verifying the checkout"
+)
+
+// classify names the kind of err and suggests the commands recovering from it
+func classify(err error, dir string) (class string, next []string) {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, ort.ErrMergeConflict):
+		return i18n.T("merge conflict"), []string{
+			"git -C " + dir + " status",
+			"gravel merge --continue " + dir,
+			"gravel init --continue " + dir,
+			"gravel merge --abort " + dir,
+		}
+	case errors.Is(err, ort.ErrMergeInProgress):
+		return i18n.T("merge in progress"), []string{
+			"gravel merge --continue " + dir,
+			"gravel init --continue " + dir,
+		}
+	case errors.Is(err, ort.ErrUnrelatedHistories):
+		return i18n.T("unrelated histories"), []string{"gravel init --allow-unrelated-histories"}
+	case errors.Is(err, ort.ErrLocalChanges):
+		return i18n.T("local changes"), []string{"git -C " + dir + " stash"}
+	case errors.Is(err, ort.ErrSecretsDetected):
+		return i18n.T("secrets detected"), []string{"gravel init --secrets warn"}
+	case errors.Is(err, manifest.ErrPinMismatch):
+		return i18n.T("pinned ref drifted"), []string{"gravel init --allow-drift"}
+	case errors.Is(err, transport.ErrAuthenticationRequired),
+		errors.Is(err, transport.ErrAuthorizationFailed):
+		return i18n.T("authentication"), []string{"gravel setup"}
+	case errors.Is(err, transport.ErrRepositoryNotFound),
+		errors.Is(err, plumbing.ErrReferenceNotFound):
+		return i18n.T("not found"), []string{"gravel init --verbose"}
+	case errors.As(err, &netErr):
+		return i18n.T("network"), []string{"gravel init --verbose", "gravel init --continue " + dir}
+	case errors.Is(err, ErrVerificationFailed):
+		return i18n.T("verification"), []string{"cd " + dir}
+	case errors.Is(err, context.Canceled):
+		return i18n.T("cancelled"), nil
+	case errors.Is(err, ErrInputRequired):
+		return i18n.T("input required"), []string{"gravel init --help"}
+	default:
+		return i18n.T("unexpected error"), []string{"gravel init --verbose"}
+	}
+}
+
+// reportFailure shows the error page of a failed step, or prints it as a
+// block without a terminal, and writes the collected logs. The returned
+// error is not printed again by cobra
+func reportFailure(cmd *cobra.Command, step, dir string, err error, recorder *progress.Recorder) error {
+	class, next := classify(err, dir)
+	report := components.ErrorReport{
+		Step:  i18n.T(step),
+		Class: class,
+		Err:   err,
+		Next:  next,
+	}
+
+	// Failing to write the logs must not hide the original error
+	if log, logErr := writeFailureLog(step, class, err, recorder); logErr == nil {
+		report.Log = log
+	}
+
+	cmd.SilenceErrors = true
+
+	if interactive(cmd) && !unattended(cmd) {
+		program := tea.NewProgram(
+			components.NewErrorPage(report),
+			tea.WithInput(cmd.InOrStdin()),
+			tea.WithOutput(cmd.OutOrStdout()),
+		)
+		if _, runErr := program.Run(); runErr == nil {
+			return err
+		}
+	}
+
+	_, _ = fmt.Fprint(cmd.ErrOrStderr(), report.String())
+	return err
+}
+
+// writeFailureLog writes the recorded progress events followed by the error
+// as JSON lines into a temporary file
+func writeFailureLog(step, class string, cause error, recorder *progress.Recorder) (string, error) {
+	path := filepath.Join(os.TempDir(), fmt.Sprintf("gravel-init-%d.log", time.Now().Unix()))
+
+	file, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = file.Close() }()
+
+	sink := progress.NewJSONSink(file)
+	for _, event := range recorder.Events() {
+		sink.Emit(event)
+	}
+
+	return path, json.NewEncoder(file).Encode(map[string]string{
+		"step":  step,
+		"class": class,
+		"error": cause.Error(),
+	})
+}
+
+// interactive tells whether both streams of cmd are terminals
+func interactive(cmd *cobra.Command) bool {
+	in, ok := cmd.InOrStdin().(*os.File)
+	if !ok {
+		return false
+	}
+	out, ok := cmd.OutOrStdout().(*os.File)
+	if !ok {
+		return false
+	}
+	return isatty.IsTerminal(in.Fd()) && isatty.IsTerminal(out.Fd())
+}
+
+// ErrInputRequired is returned when a prompt is needed but unattended
+// forbids it
+var ErrInputRequired = errors.New("an answer is required but prompts are disabled")
+
+// unattended reports whether the prompts are disabled, by --non-interactive
+// or because the standard input is a file that is not a terminal, like in
+// pipelines, where the selectors would wait forever. Embedders driving the
+// selectors through other readers are not unattended
+func unattended(cmd *cobra.Command) bool {
+	if disabled, _ := cmd.Flags().GetBool(NonInteractiveFlag); disabled {
+		return true
+	}
+	in, ok := cmd.InOrStdin().(*os.File)
+	return ok && !isatty.IsTerminal(in.Fd())
+}
+
+// inputRequired explains which flag answers the prompt unattended skipped
+func inputRequired(hint string) error {
+	return fmt.Errorf("%w: %s", ErrInputRequired, i18n.T(hint))
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"gravel/config"
+	"gravel/features"
+
+	"github.com/spf13/cobra"
+)
+
+// featuresCmd represents the features command
+var featuresCmd = &cobra.Command{
+	Use:   "features",
+	Short: "List experimental features and whether they are enabled",
+	Long: `
+Experimental features gate risky behaviors while they mature. Enable them
+in the features section of the configuration file or with the
+` + features.EnvFeatures + ` environment variable, e.g.
+
+  ` + features.EnvFeatures + `=octopus,prefix-merge gravel init
+`,
+	Args: cobra.NoArgs,
+
+	RunE: RunFeatures,
+
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(featuresCmd)
+}
+
+func RunFeatures(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	enabled, err := features.Load(cfg.Features)
+	if err != nil {
+		return err
+	}
+
+	table := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(table, "FEATURE\tENABLED")
+	for _, feature := range features.Features {
+		_, _ = fmt.Fprintf(table, "%s\t%t\n", feature, enabled.Enabled(feature))
+	}
+	return table.Flush()
+}
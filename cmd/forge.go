@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+
+	"gravel/config"
+	"gravel/forge"
+	"gravel/i18n"
+
+	"github.com/spf13/cobra"
+)
+
+// forgeCmd represents the forge command
+var forgeCmd = &cobra.Command{
+	Use:   "forge",
+	Short: "Manage the remote repository of the app on its forge",
+}
+
+// forgeApplyCmd represents the forge apply command
+var forgeApplyCmd = &cobra.Command{
+	Use:   "apply <remote>",
+	Short: "Apply the repository settings of the manifest to a remote repository",
+	Long: `
+Applies the repository section of the manifest (visibility, topics and
+branch protections) to the remote repository through the API of its forge,
+so that the repository of a scaffolded app complies with the policy of the
+catalog. The settings the manifest leaves out are not changed.
+
+Only GitHub is supported. The token configured for its host by gravel setup
+authenticates the requests and needs the administration permission.
+`,
+	Args: cobra.ExactArgs(1),
+
+	RunE: RunForgeApply,
+
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(forgeCmd)
+	forgeCmd.AddCommand(forgeApplyCmd)
+	forgeApplyCmd.Flags().StringP(ManifestFlag, string(ManifestFlag[0]), Manifest, "sets the manifest")
+}
+
+func RunForgeApply(cmd *cobra.Command, args []string) error {
+	flags := cmd.Flags()
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	raw, err := flags.GetString(ManifestFlag)
+	if err != nil {
+		return err
+	}
+	if !flags.Changed(ManifestFlag) && cfg.Manifest != "" {
+		raw = cfg.Manifest
+	}
+
+	decoded, err := loadManifest(raw, cfg.Network, nil)
+	if err != nil {
+		return err
+	}
+	if decoded.Repository == nil {
+		return fmt.Errorf("%s declares no repository settings", raw)
+	}
+
+	repository, err := forge.Parse(args[0])
+	if err != nil {
+		return err
+	}
+
+	client := http.DefaultClient
+	if cfg.Network != nil {
+		err = cfg.Network.Check(forge.GitHubAPI)
+		if err != nil {
+			return err
+		}
+		client = cfg.Network.HTTPClient()
+	}
+
+	provider, err := forge.For(repository, cfg.Tokens[repository.Host], client)
+	if err != nil {
+		return err
+	}
+
+	err = provider.Apply(cmd.Context(), repository, decoded.Repository)
+	if err != nil {
+		return fmt.Errorf("forge apply: %s: %w", repository, err)
+	}
+
+	_, _ = fmt.Fprintln(cmd.OutOrStdout(), i18n.Tf("Repository settings applied to %s", repository))
+	return nil
+}
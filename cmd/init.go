@@ -1,35 +1,83 @@
 package cmd
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"os"
+	"slices"
+	"strings"
+	"time"
 
 	"gravel/components"
+	"gravel/config"
+	"gravel/features"
+	"gravel/i18n"
+	"gravel/license"
+	"gravel/lock"
 	"gravel/manifest"
 	"gravel/ort"
+	"gravel/ort/diff3"
+	"gravel/progress"
+	"gravel/project"
+	"gravel/resume"
 	"gravel/source"
+	"gravel/state"
+	"gravel/variables"
+	"gravel/version"
+	"gravel/workspace"
 
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/go-git/go-billy/v6/memfs"
-	"github.com/go-git/go-billy/v6/osfs"
 	"github.com/go-git/go-git/v6"
-	"github.com/go-git/go-git/v6/config"
+	gitconfig "github.com/go-git/go-git/v6/config"
 	"github.com/go-git/go-git/v6/plumbing"
-	"github.com/go-git/go-git/v6/plumbing/cache"
-	"github.com/go-git/go-git/v6/storage"
-	"github.com/go-git/go-git/v6/storage/filesystem"
-	"github.com/go-git/go-git/v6/storage/memory"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/plumbing/transport"
+	"github.com/go-git/go-git/v6/plumbing/transport/http"
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v3"
+	"github.com/spf13/pflag"
 )
 
 // initCmd represents the init command
 var initCmd = &cobra.Command{
-	Use:   "init",
+	Use:   "init [template] [directory]",
 	Short: "Initialize a gravel App",
-	Long:  `Starts the cli process`,
+	Long: `
+Starts the cli process.
+
+A template repository may be given instead of a manifest, as the template
+argument or with --template: a URL or a shorthand like
+github.com/org/base-template, which the template argument only is when no
+directory exists at that path. It is used as the only base, on the branch
+following an @ or the default branch of the remote.
+
+Without a directory, the directory is asked for in a terminal and the
+current one is used otherwise.
+
+--base and --plugin select the components without the selectors, for
+scripts and pipelines without a terminal. --plugin alone merges the plugins
+into the base the selector picks, --base alone still offers the plugins.
+
+With --non-interactive, or when the standard input is not a terminal, the
+components missing from the flags are the defaults of the manifest, the
+variables keep their defaults and no license is written without --license.
+
+The chosen license is written to LICENSE and the templates can reference it
+as [[ license.spdx ]], [[ license.holder ]] and [[ license.year ]].
+
+--from-lock creates the app of a ` + lock.File + ` again, non-interactively: its
+base and plugins are merged at their locked commits, in the order of the
+lockfile, with the conflict rules of its manifest, and the templates are
+rendered with the variables of the lockfile.
+
+The plugins left to merge are recorded in the state of the app, so that an
+init stopped on a conflict or an error is not started over: once the
+conflict is resolved with gravel merge --continue, init --continue merges
+the plugins left into the directory, then completes the app. A replayed
+init is continued with the same --from-lock.
+`,
 
 	RunE: RunE,
 
@@ -45,107 +93,381 @@ const (
 
 	VerboseFlag = "verbose"
 	Verbose     = false
+
+	LicenseFlag = "license"
+	License     = ""
+
+	NoLicenseFlag = "no-license"
+	NoLicense     = false
+
+	AuthorFlag = "author"
+	Author     = ""
+
+	AllowDriftFlag = "allow-drift"
+	AllowDrift     = false
+
+	ProgressFlag = "progress"
+	Progress     = ""
+
+	StrategyOptionFlag = "strategy-option"
+
+	ConflictStyleFlag = "conflict-style"
+	ConflictStyle     = "merge"
+
+	UnionFlag = "union"
+	Union     = false
+
+	NoFastForwardFlag = "no-ff"
+	NoFastForward     = false
+
+	BackupFlag = "backup"
+	Backup     = false
+
+	AllowUnrelatedHistoriesFlag = "allow-unrelated-histories"
+	AllowUnrelatedHistories     = false
+
+	NoHooksFlag = "no-hooks"
+	NoHooks     = false
+
+	SignKeyFlag = "sign-key"
+	SignKey     = ""
+
+	ParentOrderFlag = "parent-order"
+	ParentOrder     = "ours"
+
+	PostCheckoutFlag = "post-checkout"
+	PostCheckout     = false
+
+	SecretsFlag = "secrets"
+	Secrets     = "warn"
+
+	NetworkReportFlag = "network-report"
+	NetworkReport     = ""
+
+	EmitWorkspaceFlag = "emit-workspace"
+	EmitWorkspace     = false
+
+	BaseFlag = "base"
+	Base     = ""
+
+	PluginFlag = "plugin"
+
+	FromLockFlag = "from-lock"
+	FromLock     = ""
+
+	TemplateFlag = "template"
+	Template     = ""
 )
 
+// initCommand names the init in the resume state
+const initCommand = "init"
+
 func init() {
 	rootCmd.AddCommand(initCmd)
 	initCmd.Flags().StringP(ManifestFlag, string(ManifestFlag[0]), Manifest, "sets the manifest")
 	initCmd.Flags().
 		Bool(DryRunFlag, DryRun, "perform a trial run with no changes made to filesystem")
 	initCmd.Flags().Bool(VerboseFlag, Verbose, "runs in verbose mode")
+	initCmd.Flags().
+		String(LicenseFlag, License, "SPDX identifier of the license to write, skips the license chooser")
+	initCmd.Flags().Bool(NoLicenseFlag, NoLicense, "skips writing a LICENSE file")
+	initCmd.Flags().
+		String(AuthorFlag, Author, "copyright holder written in the LICENSE (default: git user.name)")
+	initCmd.Flags().
+		Bool(AllowDriftFlag, AllowDrift, "warns instead of failing when a fetched ref does not match its pinned hash")
+	initCmd.Flags().
+		String(ProgressFlag, Progress, "progress format (text, json), --verbose implies text")
+	initCmd.Flags().
+		StringArrayP(StrategyOptionFlag, "X", nil, "merge option, can be repeated: ours or theirs resolves conflicting hunks, ignore-space-change or ignore-all-space resolves whitespace only changes")
+	initCmd.Flags().
+		String(ConflictStyleFlag, ConflictStyle, "conflict markers (merge, diff3, zdiff3), diff3 and zdiff3 also show the base lines")
+	initCmd.Flags().
+		Bool(UnionFlag, Union, "keeps the lines of both sides of conflicting hunks, like the union merge driver")
+	initCmd.Flags().
+		Bool(NoFastForwardFlag, NoFastForward, "creates a merge commit for every plugin, even when it could be fast-forwarded")
+	initCmd.Flags().
+		Bool(BackupFlag, Backup, "writes <file>.orig with the content before the merge of each conflicting file")
+	initCmd.Flags().
+		Bool(AllowUnrelatedHistoriesFlag, AllowUnrelatedHistories, "merges the plugins sharing no history with the base, from an empty base")
+	initCmd.Flags().
+		Bool(NoHooksFlag, NoHooks, "skips the pre-merge-commit, commit-msg and post-merge hooks of the app")
+	initCmd.Flags().
+		String(SignKeyFlag, SignKey, "signs the merge commits with the OpenPGP or SSH private key of the file, decrypted with GRAVEL_SIGN_PASSPHRASE")
+	initCmd.Flags().
+		String(ParentOrderFlag, ParentOrder, "first parent of the merge commits (ours, theirs), ours keeps the app history on --first-parent")
+	initCmd.Flags().
+		Bool(PostCheckoutFlag, PostCheckout, "runs the verify commands of the manifest in the scaffolded app")
+	initCmd.Flags().
+		StringP(OutputFlag, string(OutputFlag[0]), Output, "format of the summary of the components (text, json, yaml), json and yaml also list the commits and conflicts")
+	initCmd.Flags().
+		Bool(EmitWorkspaceFlag, EmitWorkspace, "writes the VS Code and JetBrains files contributed by the base and the plugins")
+	initCmd.Flags().
+		String(NetworkReportFlag, NetworkReport, "records the network endpoints contacted and writes them as JSON to the file, - prints them")
+	initCmd.Flags().
+		String(SecretsFlag, Secrets, "secrets found in the merged templates and plugins (ignore, warn, block), block stops before writing them")
+	initCmd.Flags().
+		String(ProfileFlag, Profile, "applies a configured profile, skipping the base and plugin selectors")
+	initCmd.Flags().StringArray(SetFlag, nil, "sets a template variable (name=value), can be repeated")
+	initCmd.Flags().String(BaseFlag, Base, "selects the base of the manifest, skipping the base selector")
+	initCmd.Flags().
+		StringArray(PluginFlag, nil, "selects a plugin of the manifest, skipping the plugin selector, can be repeated")
+	initCmd.MarkFlagsMutuallyExclusive(ProfileFlag, BaseFlag)
+	initCmd.MarkFlagsMutuallyExclusive(ProfileFlag, PluginFlag)
+	initCmd.Flags().
+		String(FromLockFlag, FromLock, "creates the app again from a lockfile, merging its commits in its order without prompting")
+	initCmd.MarkFlagsMutuallyExclusive(FromLockFlag, ProfileFlag)
+	initCmd.MarkFlagsMutuallyExclusive(FromLockFlag, BaseFlag)
+	initCmd.MarkFlagsMutuallyExclusive(FromLockFlag, PluginFlag)
+	initCmd.Flags().
+		String(TemplateFlag, Template, "uses the template repository (host/owner/repository or a URL) as the only base, instead of a manifest")
+	initCmd.Flags().
+		Bool(ContinueFlag, Continue, "merges the plugins left by an init stopped on conflicts or errors, then completes the app")
+	initCmd.MarkFlagsMutuallyExclusive(ContinueFlag, ProfileFlag)
+	initCmd.MarkFlagsMutuallyExclusive(ContinueFlag, BaseFlag)
+	initCmd.MarkFlagsMutuallyExclusive(ContinueFlag, PluginFlag)
+	initCmd.MarkFlagsMutuallyExclusive(ContinueFlag, DryRunFlag)
+	_ = initCmd.RegisterFlagCompletionFunc(BaseFlag, completeBase)
+	_ = initCmd.RegisterFlagCompletionFunc(PluginFlag, completePlugin)
+}
+
+// initRun tracks the step init is at, to report where it failed
+type initRun struct {
+	step     string
+	dir      string
+	recorder *progress.Recorder
+	// endpoints records the network endpoints contacted, nil without --network-report
+	endpoints *source.Recorder
 }
 
 func RunE(cmd *cobra.Command, args []string) error {
+	report, err := cmd.Flags().GetString(NetworkReportFlag)
+	if err != nil {
+		return err
+	}
+
+	run := &initRun{step: stepManifest, dir: ".", recorder: new(progress.Recorder)}
+	if report != "" {
+		run.endpoints = source.NewRecorder()
+	}
+
+	err = runInit(cmd, args, run)
+	// Written on failures too, a denied egress is what the report is for
+	if report != "" {
+		if reportErr := writeNetworkReport(cmd.OutOrStdout(), report, run.endpoints); reportErr != nil && err == nil {
+			err = reportErr
+		}
+	}
+	if err != nil {
+		return reportFailure(cmd, run.step, run.dir, err, run.recorder)
+	}
+	return nil
+}
+
+func runInit(cmd *cobra.Command, args []string, run *initRun) error {
 	flags := cmd.Flags()
 
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	var cont bool
+	if cont, err = flags.GetBool(ContinueFlag); err != nil {
+		return err
+	}
+	if cont {
+		return continueInit(cmd, cfg, args, run)
+	}
+
 	manifestFlag, err := flags.GetString(ManifestFlag)
 	if err != nil {
 		return err
 	}
+	var profile *config.Profile
+	profile, err = selectedProfile(cmd, cfg)
+	if err != nil {
+		return err
+	}
+
+	if !flags.Changed(ManifestFlag) && cfg.Manifest != "" {
+		manifestFlag = cfg.Manifest
+	}
+	if !flags.Changed(ManifestFlag) && profile != nil && profile.Manifest != "" {
+		manifestFlag = profile.Manifest
+	}
 
-	reader, err := source.Resolve(manifestFlag)
+	template, err := flags.GetString(TemplateFlag)
 	if err != nil {
 		return err
 	}
-	defer func() { _ = reader.Close() }()
+	if template == "" && len(args) > 0 && isTemplateArg(args[0]) {
+		template, args = args[0], args[1:]
+	}
 
-	decodedManifest := new(manifest.Manifest)
+	installNetwork(cfg.Network, run.endpoints)
 
-	err = yaml.NewDecoder(reader).Decode(decodedManifest)
+	var baseFlag string
+	baseFlag, err = flags.GetString(BaseFlag)
 	if err != nil {
 		return err
 	}
 
-	err = decodedManifest.Validate()
+	var pluginFlags []string
+	pluginFlags, err = flags.GetStringArray(PluginFlag)
 	if err != nil {
 		return err
 	}
 
-	var dryRun bool
-	dryRun, err = flags.GetBool(DryRunFlag)
+	var fromLock string
+	fromLock, err = flags.GetString(FromLockFlag)
 	if err != nil {
 		return err
 	}
 
-	var storer storage.Storer = memory.NewStorage()
-	worktree := memfs.New()
+	// The lockfile replaces the selectors and the prompts
+	var replayed *lock.Lock
+	if fromLock != "" {
+		if template != "" {
+			return errors.New(i18n.T("a template repository cannot be combined with --from-lock"))
+		}
+		if replayed, err = readLockfile(fromLock); err != nil {
+			return err
+		}
+		if !flags.Changed(ManifestFlag) {
+			manifestFlag = replayed.Manifest
+		}
+		if err = flags.Set(NonInteractiveFlag, "true"); err != nil {
+			return err
+		}
+	}
 
-	if !dryRun {
-		// Get current working directory
-		dir, err := os.Getwd()
-		if err != nil {
-			return fmt.Errorf("failed to get current directory: %w", err)
+	var decodedManifest *manifest.Manifest
+	if replayed != nil && manifestFlag == "" {
+		// The app of a template repository, its lockfile is all there is
+		decodedManifest = new(manifest.Manifest)
+	} else if template != "" {
+		if profile != nil || flags.Changed(ManifestFlag) {
+			return errors.New(i18n.T("a template repository cannot be combined with --manifest or --profile"))
 		}
+		if flags.Changed(BaseFlag) {
+			return errors.New(i18n.T("a template repository is the base, it cannot be combined with --base"))
+		}
+		decodedManifest, err = manifest.FromTemplate(template)
+	} else {
+		decodedManifest, err = loadManifest(manifestFlag, cfg.Network, run.endpoints)
+	}
+	if err != nil {
+		return err
+	}
+
+	var output string
+	output, err = flags.GetString(OutputFlag)
+	if err != nil {
+		return err
+	}
+	if err = checkOutput(output); err != nil {
+		return err
+	}
+
+	var dryRun bool
+	dryRun, err = flags.GetBool(DryRunFlag)
+	if err != nil {
+		return err
+	}
 
-		// Determine the target directory (use first arg if provided, else current dir)
-		targetDir := dir
-		if len(args) > 0 && args[0] != "" {
-			targetDir = args[0]
+	// Without a directory, ask for one instead of filling the current one
+	if len(args) == 0 && !dryRun {
+		var dir string
+		if dir, err = promptDirectory(cmd); err != nil || dir == "" {
+			return err
 		}
+		args = []string{dir}
+	}
 
-		worktree = osfs.New(targetDir)
-		dot, _ := worktree.Chroot(git.GitDirName)
-		storer = filesystem.NewStorage(dot, cache.NewObjectLRUDefault())
+	run.step = stepRepository
+	var store Storage
+	store, err = resolveStorage(cmd.Context(), dryRun, args)
+	if err != nil {
+		return err
 	}
+	run.dir = store.Worktree.Root()
 
 	var repo *git.Repository
-	repo, err = git.Init(storer, git.WithWorkTree(worktree))
+	repo, err = git.Init(store.Storer, git.WithWorkTree(store.Worktree))
 	if err != nil {
 		return err
 	}
 
+	// The app keeps its state where it was created, whatever the
+	// configuration of its later users
+	if cfg.State != "" {
+		if err = state.Select(repo, cfg.State); err != nil {
+			return err
+		}
+	}
+
 	stdin := cmd.InOrStdin()
 
 	stdout := cmd.OutOrStdout()
 
-	var verbose bool
-	verbose, err = flags.GetBool(VerboseFlag)
+	var reporter *progress.Reporter
+	reporter, err = newReporter(cmd, run.recorder)
 	if err != nil {
 		return err
 	}
-	progress := io.Discard
-	if verbose {
-		progress = stdout
-	}
 
-	baseSelector := components.NewBaseSelector(decodedManifest.Base...)
-	program := tea.NewProgram(
-		baseSelector,
-		tea.WithInput(stdin),
-		tea.WithOutput(stdout),
-		tea.WithContext(cmd.Context()),
-	)
-	if _, err = program.Run(); err != nil {
+	run.step = stepBase
+	var base *manifest.Base
+	if replayed != nil {
+		base = lockedEntry(decodedManifest.Base, replayed.Base)
+	} else if profile != nil || flags.Changed(BaseFlag) {
+		if profile != nil {
+			baseFlag = profile.Base
+		}
+		var bases []manifest.Base
+		bases, err = manifest.Lookup(decodedManifest.Base, baseFlag)
+		if err != nil {
+			return err
+		}
+		base = &bases[0]
+	} else if template != "" {
+		base = &decodedManifest.Base[0]
+	} else if unattended(cmd) {
+		base, err = decodedManifest.DefaultBase()
+		if errors.Is(err, manifest.ErrNoDefaultBase) {
+			return inputRequired("the manifest has no default base, choose one with --base")
+		}
+		if err != nil {
+			return err
+		}
+	} else {
+		baseSelector := components.NewBaseSelector(decodedManifest.Base...)
+		program := tea.NewProgram(
+			baseSelector,
+			tea.WithInput(stdin),
+			tea.WithOutput(stdout),
+			tea.WithContext(cmd.Context()),
+		)
+		if _, err = program.Run(); err != nil {
+			return err
+		}
+
+		base = baseSelector.Selected()
+		if base == nil {
+			return nil
+		}
+	}
+	if err = base.Compatible(); err != nil {
 		return err
 	}
 
-	base := baseSelector.Selected()
-	if base == nil {
-		return nil
+	if err = checkRemote(cfg.Network, base.Remote.URL); err != nil {
+		return err
 	}
+	run.endpoints.Record("base:"+base.Name, base.Remote.URL)
 
 	var origin *git.Remote
-	origin, err = repo.CreateRemote(&config.RemoteConfig{
+	origin, err = repo.CreateRemote(&gitconfig.RemoteConfig{
 		Name: "origin",
 		URLs: []string{base.Remote.URL},
 	})
@@ -153,50 +475,209 @@ func RunE(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	err = repo.Fetch(&git.FetchOptions{
-		RemoteName: origin.Config().Name,
-		Progress:   progress,
-	})
+	report := &MergeReport{Base: base.Name, Total: ComponentReport{Component: "total"}}
+	started := time.Now()
+
+	baseReporter := reporter.Scope("base:" + base.Name)
+	err = repo.FetchContext(cmd.Context(), fetchOptions(cfg, base.Remote, origin.Config().Name, baseReporter.Scope("fetch").Writer()))
+	if err != nil {
+		return err
+	}
+
+	if base.Remote.Ref == "" {
+		base.Remote.Ref, err = defaultBranch(origin, remoteAuth(cfg, base.Remote.URL))
+		if err != nil {
+			return err
+		}
+	}
+
+	// Get the remote reference
+	ref, err := remoteRef(repo, "origin", base.Remote.Ref)
 	if err != nil {
 		return err
 	}
+	if ref, err = lockedRef(repo, ref, replayed, base.Name); err != nil {
+		return err
+	}
 
-	var wt *git.Worktree
-	wt, err = repo.Worktree()
+	var allowDrift bool
+	allowDrift, err = flags.GetBool(AllowDriftFlag)
 	if err != nil {
 		return err
 	}
 
-	// Get the remote reference
-	ref, err := repo.Reference(plumbing.NewRemoteReferenceName("origin", base.Remote.Ref), true)
+	err = verifyPin(repo, base.Remote, ref.Hash(), allowDrift, stdout)
+	if err != nil {
+		return err
+	}
+
+	// The app has no commit yet, merging the base creates its branch at the
+	// base commit and checks it out
+	_, err = ort.MergeContext(cmd.Context(), repo, *ref, ort.MergeOptions{
+		Progress: baseReporter.Scope("merge").Writer(),
+		Events:   mergeEvents{reporter: baseReporter.Scope("merge")},
+	})
+	if err != nil {
+		return err
+	}
+	if err = report.add(repo, base.Name, plumbing.ZeroHash, ref.Hash(), nil, started); err != nil {
+		return err
+	}
+
+	// A template repository is its own manifest, there is none to record
+	locked := &lock.Lock{
+		Gravel: version.Version,
+		Base: lock.Component{
+			Name:   base.Name,
+			Remote: origin.Config().Name,
+			URL:    base.Remote.URL,
+			Ref:    base.Remote.Ref,
+			Commit: ref.Hash().String(),
+			// The base is merged into an empty app, its strategies matter to update
+			Conflicts: base.Conflicts,
+		},
+	}
+	if template == "" {
+		locked.Manifest = manifestFlag
+	}
+	recorded := &project.Project{Base: project.Component{Name: base.Name}}
+	recorded.Merged(base.Name, ref.Hash().String())
+
+	run.step = stepPlugins
+	var selectedPlugins []manifest.Base
+	// Unattended runs merge the default plugins, as if --plugin named them
+	defaultPlugins := replayed == nil && profile == nil && !flags.Changed(PluginFlag) && unattended(cmd)
+	if defaultPlugins {
+		for _, plugin := range decodedManifest.DefaultPlugins() {
+			pluginFlags = append(pluginFlags, plugin.Name)
+		}
+	}
+	if replayed != nil {
+		for _, component := range replayed.Plugins {
+			selectedPlugins = append(selectedPlugins, *lockedEntry(decodedManifest.Plugins, component))
+		}
+	} else if profile != nil || flags.Changed(PluginFlag) || defaultPlugins {
+		if profile != nil {
+			pluginFlags = profile.Plugins
+		}
+		selectedPlugins, err = manifest.Lookup(decodedManifest.Plugins, pluginFlags...)
+		if err != nil {
+			return err
+		}
+		// The selectors refuse incompatible entries, profiles and flags must too
+		for _, plugin := range selectedPlugins {
+			if err = plugin.Compatible(); err != nil {
+				return err
+			}
+		}
+	} else if len(decodedManifest.Plugins) > 0 {
+		pluginSelector := components.NewBaseMultiSelector(decodedManifest.Plugins...)
+		program := tea.NewProgram(
+			pluginSelector,
+			tea.WithInput(stdin),
+			tea.WithOutput(stdout),
+			tea.WithContext(cmd.Context()),
+		)
+
+		if _, err = program.Run(); err != nil {
+			return err
+		}
+		selectedPlugins = pluginSelector.Selected()
+	}
+
+	// Variables are resolved before merging, an ambiguous one fails early
+	run.step = stepVariables
+	namespace := variables.NewNamespace(manifest.Declarations(base, selectedPlugins), decodedManifest.Aliases)
+
+	// A replayed app is rendered with the variables of its lockfile
+	var replayedVariables map[string]string
+	if replayed != nil {
+		replayedVariables = replayed.Variables
+	}
+	var set *variables.Set
+	set, err = resolveVariables(cmd, cfg, replayedVariables, nil)
 	if err != nil {
 		return err
 	}
 
-	err = wt.Checkout(&git.CheckoutOptions{Branch: ref.Name()})
+	// Profiles run unattended, declared variables fall back to their default
+	if profile == nil && !unattended(cmd) {
+		var prompted map[string]string
+		prompted, err = promptVariables(cmd, namespace, set.Values())
+		if err != nil {
+			return err
+		}
+		set.Layer(variables.Prompt, prompted)
+	}
+
+	var values map[string]string
+	values, err = namespace.Resolve(set.Values())
 	if err != nil {
 		return err
 	}
+	recorded.Variables = values
 
-	pluginSelector := components.NewBaseMultiSelector(decodedManifest.Plugins...)
-	program = tea.NewProgram(
-		pluginSelector,
-		tea.WithInput(stdin),
-		tea.WithOutput(stdout),
-		tea.WithContext(cmd.Context()),
-	)
+	run.step = stepPlugins
+	var opts ort.MergeOptions
+	opts, err = initMergeOptions(cmd, cfg)
+	if err != nil {
+		return err
+	}
 
-	if _, err = program.Run(); err != nil {
+	var enabled features.Set
+	enabled, err = features.Load(cfg.Features)
+	if err != nil {
 		return err
 	}
+	octopus := enabled.Enabled(features.Octopus)
+
+	// With the octopus feature, plugins are merged together once all are fetched
+	var octopusRefs []plumbing.Reference
+	var octopusStrategies []ort.PathStrategy
+	var octopusRemotes []manifest.Remote
+
+	// Octopus merges report the plugins as one component
+	var octopusNames []string
+	octopusStarted := time.Now()
+
+	// The plugins left are kept in the state of the app as they are merged,
+	// init --continue merges them after a conflict or an error
+	var stateStore state.Store
+	var operation *resume.Operation
+	saveProgress := func(done ...string) error {
+		if operation == nil {
+			return nil
+		}
+		return saveInitProgress(stateStore, operation, locked, recorded, done...)
+	}
+	if !dryRun && len(selectedPlugins) > 0 {
+		if stateStore, err = openState(repo); err != nil {
+			return err
+		}
+		var names []string
+		for _, plugin := range selectedPlugins {
+			names = append(names, plugin.Name)
+		}
+		if operation, err = resume.Start(stateStore, initCommand, names); err != nil {
+			return err
+		}
+		if err = saveProgress(); err != nil {
+			return err
+		}
+	}
 
-	selectedPlugins := pluginSelector.Selected()
 	for index, plugin := range selectedPlugins {
+		started = time.Now()
 		if plugin.Remote.Name == "" {
 			plugin.Remote.Name = fmt.Sprintf("plugin-%d", index)
 		}
 
-		remote, err := repo.CreateRemote(&config.RemoteConfig{
+		if err = checkRemote(cfg.Network, plugin.Remote.URL); err != nil {
+			return err
+		}
+		run.endpoints.Record("plugin:"+plugin.Name, plugin.Remote.URL)
+
+		remote, err := repo.CreateRemote(&gitconfig.RemoteConfig{
 			Name: plugin.Remote.Name,
 			URLs: []string{plugin.Remote.URL},
 		})
@@ -204,33 +685,1071 @@ func RunE(cmd *cobra.Command, args []string) error {
 			return err
 		}
 
+		pluginReporter := reporter.Scope("plugin:" + plugin.Name)
+
 		// Fetch the remote
-		err = remote.Fetch(&git.FetchOptions{
-			RemoteName: plugin.Remote.Name,
-			Progress:   progress,
-		})
+		err = remote.FetchContext(cmd.Context(), fetchOptions(cfg, plugin.Remote, plugin.Remote.Name, pluginReporter.Scope("fetch").Writer()))
 		if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
 			return err
 		}
 
 		var pluginRef *plumbing.Reference
-		pluginRef, err = repo.Reference(
-			plumbing.NewRemoteReferenceName(remote.Config().Name, plugin.Remote.Ref),
-			true,
-		)
+		pluginRef, err = remoteRef(repo, remote.Config().Name, plugin.Remote.Ref)
 		if err != nil {
 			return err
 		}
+		if pluginRef, err = lockedRef(repo, pluginRef, replayed, plugin.Name); err != nil {
+			return err
+		}
 
-		// err = repo.Merge(*pluginRef, git.MergeOptions{}) // WIP
-		err = ort.Merge(repo, *pluginRef, ort.MergeOptions{
-			Progress: progress,
+		err = verifyPin(repo, plugin.Remote, pluginRef.Hash(), allowDrift, stdout)
+		if err != nil {
+			return err
+		}
+		locked.Plugins = append(locked.Plugins, lock.Component{
+			Name:      plugin.Name,
+			Remote:    plugin.Remote.Name,
+			URL:       plugin.Remote.URL,
+			Ref:       plugin.Remote.Ref,
+			Commit:    pluginRef.Hash().String(),
+			Paths:     plugin.Paths,
+			Conflicts: plugin.Conflicts,
 		})
+		report.Plugins = append(report.Plugins, plugin.Name)
+
+		strategies := conflictStrategies(plugin.Conflicts)
+
+		// Path-scoped plugins are merged on their own, an octopus merges every
+		// path. Replayed plugins are merged like the lockfile recorded
+		together := octopus && len(plugin.Paths) == 0
+		if replayed != nil {
+			together = slices.ContainsFunc(replayed.Plugins, func(component lock.Component) bool {
+				return component.Name == plugin.Name && component.Octopus
+			})
+		}
+		if together {
+			octopusRefs = append(octopusRefs, *pluginRef)
+			octopusNames = append(octopusNames, plugin.Name)
+			octopusStrategies = append(octopusStrategies, strategies...)
+			octopusRemotes = append(octopusRemotes, plugin.Remote)
+			locked.Plugins[len(locked.Plugins)-1].Octopus = true
+			if err = saveProgress(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err = saveProgress(); err != nil {
+			return err
+		}
+
+		var before *plumbing.Reference
+		before, err = repo.Head()
+		if err != nil {
+			return err
+		}
+
+		pluginOpts := opts
+		pluginOpts.Labels = ort.Labels{Theirs: plugin.Name}
+		pluginOpts.Provenance = ort.Provenance{
+			Patterns:  plugin.Provenance,
+			Component: plugin.Name,
+			Version:   fmt.Sprintf("%s@%s", plugin.Remote.Ref, pluginRef.Hash().String()[:7]),
+		}
+		pluginOpts.ConflictStrategies = strategies
+		pluginOpts.PathSpecs = plugin.Paths
+		pluginOpts.Progress = pluginReporter.Scope("merge").Writer()
+		pluginOpts.Events = mergeEvents{reporter: pluginReporter.Scope("merge")}
+		pluginOpts.Deepen, pluginOpts.MaxDepth = deepenOptions(cmd.Context(), cfg, repo, []manifest.Remote{plugin.Remote}, pluginReporter.Scope("deepen").Writer())
+
+		// err = repo.Merge(*pluginRef, git.MergeOptions{}) // WIP
+		var result *ort.MergeResult
+		result, err = ort.MergeContext(cmd.Context(), repo, *pluginRef, pluginOpts)
+		if dryRun {
+			reportDryMerge(stdout, plugin.Name, result)
+		}
+		if errors.Is(err, ort.ErrMergeConflict) {
+			_ = report.writeConflicted(stdout, output, repo, plugin.Name, before.Hash(), result.Conflicts, started)
+		}
 		if err != nil {
 			return err
 		}
+		if err = report.add(repo, plugin.Name, before.Hash(), result.Commit, result.Conflicts, started); err != nil {
+			return err
+		}
+		// A plugin HEAD already contained moves nothing, it has no merge
+		var merged string
+		if result.Commit != before.Hash() {
+			merged = mergedCommit(result.Commit)
+		}
+		recorded.Merged(plugin.Name, merged)
+		if err = saveProgress(plugin.Name); err != nil {
+			return err
+		}
 	}
 
-	return err
-	// return wt.Reset(&git.ResetOptions{Mode: git.SoftReset})
+	if len(octopusRefs) > 0 {
+		var before *plumbing.Reference
+		before, err = repo.Head()
+		if err != nil {
+			return err
+		}
+
+		octopusOpts := opts
+		octopusOpts.ConflictStrategies = octopusStrategies
+		octopusOpts.Progress = reporter.Scope("merge").Writer()
+		octopusOpts.Events = mergeEvents{reporter: reporter.Scope("merge")}
+		octopusOpts.Deepen, octopusOpts.MaxDepth = deepenOptions(cmd.Context(), cfg, repo, octopusRemotes, reporter.Scope("deepen").Writer())
+
+		var result *ort.MergeResult
+		result, err = ort.MergeManyContext(cmd.Context(), repo, octopusRefs, octopusOpts)
+		if dryRun {
+			reportDryMerge(stdout, i18n.T("plugins"), result)
+		}
+		if errors.Is(err, ort.ErrMergeConflict) {
+			_ = report.writeConflicted(stdout, output, repo, strings.Join(octopusNames, ", "), before.Hash(), result.Conflicts, octopusStarted)
+		}
+		if err != nil {
+			return err
+		}
+		err = report.add(repo, strings.Join(octopusNames, ", "), before.Hash(), result.Commit, result.Conflicts, octopusStarted)
+		if err != nil {
+			return err
+		}
+		// The plugins share the octopus merge
+		var merged string
+		if result.Commit != before.Hash() {
+			merged = mergedCommit(result.Commit)
+		}
+		for _, name := range octopusNames {
+			recorded.Merged(name, merged)
+		}
+		if err = saveProgress(octopusNames...); err != nil {
+			return err
+		}
+	}
+
+	return finishInit(cmd, cfg, repo, run, append([]manifest.Base{*base}, selectedPlugins...), locked, recorded, report, output, dryRun)
+}
+
+// finishInit completes the app once its components are merged: it renders
+// the templates, writes the license, the workspace and the state, prints
+// the report then verifies the checkout. entries are the base followed by
+// the plugins
+func finishInit(cmd *cobra.Command, cfg *config.Config, repo *git.Repository, run *initRun, entries []manifest.Base, locked *lock.Lock, recorded *project.Project, report *MergeReport, output string, dryRun bool) error {
+	flags := cmd.Flags()
+	stdout := cmd.OutOrStdout()
+
+	// The license is chosen first, the templates reference it
+	run.step = stepLicense
+	chosen, data, err := chooseLicense(cmd, cfg, repo)
+	if err != nil {
+		return err
+	}
+	if chosen != nil {
+		if recorded.Variables == nil {
+			recorded.Variables = make(map[string]string)
+		}
+		maps.Copy(recorded.Variables, chosen.Variables(data))
+	}
+	locked.Variables = recorded.Variables
+
+	run.step = stepRender
+	if _, _, err = renderTemplates(repo, recorded.Variables, true); err != nil {
+		return err
+	}
+
+	if chosen != nil {
+		run.step = stepLicense
+		if err = writeLicense(repo, *chosen, data); err != nil {
+			return err
+		}
+	}
+
+	var emitWorkspace bool
+	emitWorkspace, err = flags.GetBool(EmitWorkspaceFlag)
+	if err != nil {
+		return err
+	}
+	if emitWorkspace {
+		run.step = stepWorkspace
+		if err = writeWorkspace(repo, entries); err != nil {
+			return err
+		}
+	}
+
+	// Recorded once the app is complete, update, add and status follow it
+	run.step = stepState
+	if err = writeState(repo, locked, recorded); err != nil {
+		return err
+	}
+
+	if err = report.write(stdout, output); err != nil {
+		return err
+	}
+
+	var postCheckout bool
+	postCheckout, err = flags.GetBool(PostCheckoutFlag)
+	if err != nil {
+		return err
+	}
+	if !postCheckout {
+		return nil
+	}
+	if dryRun {
+		_, err = fmt.Fprintln(stdout, i18n.T("Skipping verification, nothing was written in dry run"))
+		return err
+	}
+
+	run.step = stepVerify
+	var commands []string
+	for _, entry := range entries {
+		commands = append(commands, entry.Verify...)
+	}
+	return verifyCheckout(cmd, run.dir, commands)
+	// return wt.Reset(&git.ResetOptions{Mode: git.SoftReset})
+}
+
+// isTemplateArg tells whether the first argument of init names a template
+// repository rather than the directory of the app: a URL, or a shorthand
+// no existing directory is named like
+func isTemplateArg(arg string) bool {
+	if manifest.IsTemplate(arg) {
+		return true
+	}
+	if !manifest.IsShorthand(arg) {
+		return false
+	}
+	_, err := os.Stat(arg)
+	return errors.Is(err, os.ErrNotExist)
+}
+
+// continueInit merges the plugins left by an init stopped on conflicts or
+// errors, recorded in the state of the app in args, then completes the app
+// like init does. A conflict concluded with gravel merge --continue is
+// recorded as the merge of its plugin
+func continueInit(cmd *cobra.Command, cfg *config.Config, args []string, run *initRun) error {
+	flags := cmd.Flags()
+
+	if flags.Changed(TemplateFlag) || len(args) > 0 && isTemplateArg(args[0]) {
+		return errors.New(i18n.T("init --continue takes the directory of the app, not a template repository"))
+	}
+
+	output, err := flags.GetString(OutputFlag)
+	if err != nil {
+		return err
+	}
+	if err = checkOutput(output); err != nil {
+		return err
+	}
+
+	run.step = stepRepository
+	store, err := resolveStorage(cmd.Context(), false, args)
+	if err != nil {
+		return err
+	}
+	run.dir = store.Worktree.Root()
+
+	repo, err := git.Open(store.Storer, store.Worktree)
+	if err != nil {
+		return err
+	}
+
+	stateStore, err := openState(repo)
+	if err != nil {
+		return err
+	}
+
+	operation, err := resume.Load(stateStore)
+	if err == nil && operation.Command != initCommand {
+		err = fmt.Errorf("%w: %s", resume.ErrInProgress, operation.Command)
+	}
+	if err != nil {
+		return err
+	}
+
+	merge, err := ort.State(repo)
+	if err != nil {
+		return err
+	}
+	if merge.InProgress {
+		return ort.ErrMergeInProgress
+	}
+
+	locked, err := lock.Load(stateStore)
+	if err != nil {
+		return err
+	}
+
+	recorded, err := loadProject(stateStore, locked)
+	if err != nil {
+		return err
+	}
+
+	installNetwork(cfg.Network, run.endpoints)
+
+	// A replayed init is continued with its lockfile again, the plugins left
+	// are merged at their locked commits
+	fromLock, err := flags.GetString(FromLockFlag)
+	if err != nil {
+		return err
+	}
+	var replayed *lock.Lock
+	if fromLock != "" {
+		if replayed, err = readLockfile(fromLock); err != nil {
+			return err
+		}
+	}
+
+	run.step = stepManifest
+	raw, err := flags.GetString(ManifestFlag)
+	if err != nil {
+		return err
+	}
+	if !flags.Changed(ManifestFlag) {
+		raw = locked.Manifest
+	}
+	// The app of a template repository has no manifest to look plugins up in
+	decoded := new(manifest.Manifest)
+	if raw != "" {
+		if decoded, err = loadManifest(raw, cfg.Network, run.endpoints); err != nil {
+			return err
+		}
+	}
+
+	run.step = stepPlugins
+	opts, err := initMergeOptions(cmd, cfg)
+	if err != nil {
+		return err
+	}
+
+	reporter, err := newReporter(cmd, run.recorder)
+	if err != nil {
+		return err
+	}
+
+	stdout := cmd.OutOrStdout()
+	report := &MergeReport{Base: locked.Base.Name, Total: ComponentReport{Component: "total"}}
+	for _, component := range locked.Plugins {
+		if !slices.Contains(operation.Pending, component.Name) {
+			report.Plugins = append(report.Plugins, component.Name)
+		}
+	}
+
+	// Plugins without a remote name are numbered in the order init selected them
+	selected := slices.Concat(operation.Done, operation.Pending)
+	for _, name := range slices.Clone(operation.Pending) {
+		started := time.Now()
+
+		var plugin *manifest.Base
+		if replayed != nil {
+			index := slices.IndexFunc(replayed.Plugins, func(component lock.Component) bool { return component.Name == name })
+			if index < 0 {
+				return fmt.Errorf("%s: no component %q", fromLock, name)
+			}
+			plugin = lockedEntry(decoded.Plugins, replayed.Plugins[index])
+		} else {
+			var plugins []manifest.Base
+			if plugins, err = manifest.Lookup(decoded.Plugins, name); err != nil {
+				return err
+			}
+			plugin = &plugins[0]
+		}
+		if plugin.Remote.Name == "" {
+			plugin.Remote.Name = fmt.Sprintf("plugin-%d", slices.Index(selected, name))
+		}
+
+		var head *plumbing.Reference
+		if head, err = repo.Head(); err != nil {
+			return err
+		}
+
+		// A plugin locked before its merge stopped is merged already once
+		// the conflict was concluded, HEAD is its merge
+		index := slices.IndexFunc(locked.Plugins, func(component lock.Component) bool { return component.Name == name })
+		if index >= 0 {
+			var concluded bool
+			if concluded, err = mergedInto(repo, head.Hash(), plumbing.NewHash(locked.Plugins[index].Commit)); err != nil {
+				return err
+			}
+			if concluded {
+				recorded.Merged(name, head.Hash().String())
+				report.Plugins = append(report.Plugins, name)
+				if err = saveInitProgress(stateStore, operation, locked, recorded, name); err != nil {
+					return err
+				}
+				continue
+			}
+			// Locked for an octopus merge that never happened, merged alone now
+			plugin.Remote.Name = locked.Plugins[index].Remote
+			if err = locked.Remove(name); err != nil {
+				return err
+			}
+		}
+
+		if err = checkRemote(cfg.Network, plugin.Remote.URL); err != nil {
+			return err
+		}
+		run.endpoints.Record("plugin:"+plugin.Name, plugin.Remote.URL)
+
+		// The remote of a plugin whose fetch failed exists already
+		var remote *git.Remote
+		remote, err = repo.Remote(plugin.Remote.Name)
+		if errors.Is(err, git.ErrRemoteNotFound) {
+			remote, err = repo.CreateRemote(&gitconfig.RemoteConfig{
+				Name: plugin.Remote.Name,
+				URLs: []string{plugin.Remote.URL},
+			})
+		}
+		if err != nil {
+			return err
+		}
+
+		pluginReporter := reporter.Scope("plugin:" + plugin.Name)
+		err = remote.FetchContext(cmd.Context(), fetchOptions(cfg, plugin.Remote, plugin.Remote.Name, pluginReporter.Scope("fetch").Writer()))
+		if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+			return err
+		}
+
+		var pluginRef *plumbing.Reference
+		if pluginRef, err = remoteRef(repo, plugin.Remote.Name, plugin.Remote.Ref); err != nil {
+			return err
+		}
+		if pluginRef, err = lockedRef(repo, pluginRef, replayed, plugin.Name); err != nil {
+			return err
+		}
+
+		if err = locked.Add(lock.Component{
+			Name:      plugin.Name,
+			Remote:    plugin.Remote.Name,
+			URL:       plugin.Remote.URL,
+			Ref:       plugin.Remote.Ref,
+			Commit:    pluginRef.Hash().String(),
+			Paths:     plugin.Paths,
+			Conflicts: plugin.Conflicts,
+		}); err != nil {
+			return err
+		}
+		report.Plugins = append(report.Plugins, plugin.Name)
+		if err = saveInitProgress(stateStore, operation, locked, recorded); err != nil {
+			return err
+		}
+
+		strategies := conflictStrategies(plugin.Conflicts)
+
+		pluginOpts := opts
+		pluginOpts.Labels = ort.Labels{Theirs: plugin.Name}
+		pluginOpts.Provenance = ort.Provenance{
+			Patterns:  plugin.Provenance,
+			Component: plugin.Name,
+			Version:   fmt.Sprintf("%s@%s", plugin.Remote.Ref, pluginRef.Hash().String()[:7]),
+		}
+		pluginOpts.ConflictStrategies = strategies
+		pluginOpts.PathSpecs = plugin.Paths
+		pluginOpts.Progress = pluginReporter.Scope("merge").Writer()
+		pluginOpts.Events = mergeEvents{reporter: pluginReporter.Scope("merge")}
+		pluginOpts.Deepen, pluginOpts.MaxDepth = deepenOptions(cmd.Context(), cfg, repo, []manifest.Remote{plugin.Remote}, pluginReporter.Scope("deepen").Writer())
+
+		var result *ort.MergeResult
+		result, err = ort.MergeContext(cmd.Context(), repo, *pluginRef, pluginOpts)
+		if errors.Is(err, ort.ErrMergeConflict) {
+			_ = report.writeConflicted(stdout, output, repo, plugin.Name, head.Hash(), result.Conflicts, started)
+		}
+		if err != nil {
+			return err
+		}
+		if err = report.add(repo, plugin.Name, head.Hash(), result.Commit, result.Conflicts, started); err != nil {
+			return err
+		}
+
+		var merged string
+		if result.Commit != head.Hash() {
+			merged = mergedCommit(result.Commit)
+		}
+		recorded.Merged(plugin.Name, merged)
+		if err = saveInitProgress(stateStore, operation, locked, recorded, plugin.Name); err != nil {
+			return err
+		}
+	}
+
+	entries := []manifest.Base{*lockedEntry(decoded.Base, locked.Base)}
+	for _, component := range locked.Plugins {
+		entries = append(entries, *lockedEntry(decoded.Plugins, component))
+	}
+	return finishInit(cmd, cfg, repo, run, entries, locked, recorded, report, output, false)
+}
+
+// initMergeOptions returns the options init merges the plugins with, from
+// the merge flags of init
+func initMergeOptions(cmd *cobra.Command, cfg *config.Config) (ort.MergeOptions, error) {
+	flags := cmd.Flags()
+
+	opts, err := flagMergeOptions(cmd, cfg, false)
+	if err != nil {
+		return opts, err
+	}
+
+	if opts.Union, err = flags.GetBool(UnionFlag); err != nil {
+		return opts, err
+	}
+	if opts.NoFastForward, err = flags.GetBool(NoFastForwardFlag); err != nil {
+		return opts, err
+	}
+	if opts.Backup, err = flags.GetBool(BackupFlag); err != nil {
+		return opts, err
+	}
+	if opts.AllowUnrelatedHistories, err = flags.GetBool(AllowUnrelatedHistoriesFlag); err != nil {
+		return opts, err
+	}
+	if opts.NoHooks, err = flags.GetBool(NoHooksFlag); err != nil {
+		return opts, err
+	}
+	opts.ParentOrder, err = parseParentOrder(flags)
+	return opts, err
+}
+
+// saveInitProgress saves locked and recorded, then marks the plugins done
+// in the interrupted init
+func saveInitProgress(store state.Store, operation *resume.Operation, locked *lock.Lock, recorded *project.Project, done ...string) error {
+	if err := locked.Save(store); err != nil {
+		return err
+	}
+	if err := recorded.Save(store); err != nil {
+		return err
+	}
+	for _, name := range done {
+		if err := operation.Complete(store, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergedInto reports whether commit is head or one of its ancestors
+func mergedInto(repo *git.Repository, head, commit plumbing.Hash) (bool, error) {
+	tip, err := repo.CommitObject(head)
+	if err != nil {
+		return false, err
+	}
+	merged, err := repo.CommitObject(commit)
+	if err != nil {
+		return false, err
+	}
+	return contains(tip, merged)
+}
+
+// newReporter returns the progress reporter selected by the flags, events
+// are recorded too for the logs of a failure
+func newReporter(cmd *cobra.Command, recorder *progress.Recorder) (*progress.Reporter, error) {
+	format, err := cmd.Flags().GetString(ProgressFlag)
+	if err != nil {
+		return nil, err
+	}
+
+	verbose, err := cmd.Flags().GetBool(VerboseFlag)
+	if err != nil {
+		return nil, err
+	}
+	if format == "" && verbose {
+		format = "text"
+	}
+
+	var sink progress.Sink
+	switch format {
+	case "":
+	case "text":
+		sink = progress.NewTextSink(cmd.OutOrStdout())
+	case "json":
+		sink = progress.NewJSONSink(cmd.OutOrStdout())
+	default:
+		return nil, fmt.Errorf("unsupported progress format %q", format)
+	}
+	return progress.New(progress.Tee(sink, recorder)), nil
+}
+
+// mergeEvents reports the events of a merge to reporter, the files being
+// merged are transient while conflicts and commits are kept
+type mergeEvents struct {
+	reporter *progress.Reporter
+}
+
+func (events mergeEvents) FileStarted(path string) {
+	events.reporter.Report("file-started", path, i18n.Tf("Merging %s", path), true)
+}
+
+func (events mergeEvents) FileMerged(path string) {
+	events.reporter.Report("file-merged", path, i18n.Tf("Merged %s", path), true)
+}
+
+func (events mergeEvents) ConflictDetected(path, reason string) {
+	if reason == "" {
+		reason = i18n.Tf("CONFLICT (content): merge conflict in %s", path)
+	}
+	events.reporter.Report("conflict", path, reason, false)
+}
+
+func (events mergeEvents) CommitCreated(hash plumbing.Hash) {
+	events.reporter.Report("commit", "", i18n.Tf("Committed %s", hash), false)
+}
+
+// chooseLicense returns the license chosen by the flags or the license
+// selector with the values filling it, no license when none is chosen
+func chooseLicense(cmd *cobra.Command, cfg *config.Config, repo *git.Repository) (chosen *license.License, data license.Data, err error) {
+	flags := cmd.Flags()
+
+	noLicense, err := flags.GetBool(NoLicenseFlag)
+	if err != nil || noLicense {
+		return
+	}
+
+	var licenseFlag string
+	licenseFlag, err = flags.GetString(LicenseFlag)
+	if err != nil {
+		return
+	}
+
+	if licenseFlag != "" {
+		chosen, err = license.Find(licenseFlag)
+		if err != nil {
+			return
+		}
+	} else if unattended(cmd) {
+		// No license is the default, --license picks one unattended
+		return
+	} else {
+		licenseSelector := components.NewLicenseSelector(license.Licenses...)
+		program := tea.NewProgram(
+			licenseSelector,
+			tea.WithInput(cmd.InOrStdin()),
+			tea.WithOutput(cmd.OutOrStdout()),
+			tea.WithContext(cmd.Context()),
+		)
+		if _, err = program.Run(); err != nil {
+			return
+		}
+		chosen = licenseSelector.Selected()
+	}
+	if chosen == nil {
+		return
+	}
+
+	var author string
+	author, err = flags.GetString(AuthorFlag)
+	if err != nil {
+		return
+	}
+	if author == "" {
+		author = cfg.Identity.Name
+	}
+	if author == "" {
+		var gitConfig *gitconfig.Config
+		gitConfig, err = repo.ConfigScoped(gitconfig.SystemScope)
+		if err != nil {
+			return
+		}
+		author = gitConfig.User.Name
+	}
+
+	return chosen, license.Data{Year: time.Now().Year(), Author: author}, nil
+}
+
+// reportDryMerge prints what merging component did to the in-memory app of a dry run
+func reportDryMerge(out io.Writer, component string, result *ort.MergeResult) {
+	if result == nil {
+		return
+	}
+	if len(result.Conflicts) > 0 {
+		_, _ = fmt.Fprintln(out, i18n.Tf("%s would conflict in %s", component, strings.Join(result.Conflicts, ", ")))
+		return
+	}
+	_, _ = fmt.Fprintln(out, i18n.Tf("%s would change %d files", component, len(result.Stats)))
+}
+
+// parseStrategyOptions reads the -X flags, the option is unset when neither
+// ours nor theirs is given and the last of each kind wins like in git
+func parseStrategyOptions(values []string) (option ort.StrategyOption, whitespace diff3.Whitespace, err error) {
+	for _, value := range values {
+		switch value {
+		case "ours":
+			option = ort.OursMergeStrategy
+		case "theirs":
+			option = ort.TheirsMergeStrategy
+		case "ignore-space-change":
+			whitespace = diff3.IgnoreSpaceChange
+		case "ignore-all-space":
+			whitespace = diff3.IgnoreAllSpace
+		default:
+			return option, whitespace, fmt.Errorf(
+				"unsupported strategy option %q, use ours, theirs, ignore-space-change or ignore-all-space", value,
+			)
+		}
+	}
+	return
+}
+
+// parseConflictStyle reads the --conflict-style flag, named like git's merge.conflictStyle
+func parseConflictStyle(value string) (diff3.ConflictStyle, error) {
+	switch value {
+	case "merge":
+		return diff3.StyleMerge, nil
+	case "diff3":
+		return diff3.StyleDiff3, nil
+	case "zdiff3":
+		return diff3.StyleZDiff3, nil
+	default:
+		return diff3.StyleMerge, fmt.Errorf("unsupported conflict style %q, use merge, diff3 or zdiff3", value)
+	}
+}
+
+// parseParentOrder reads the --parent-order flag
+func parseParentOrder(flags *pflag.FlagSet) (ort.ParentOrder, error) {
+	value, err := flags.GetString(ParentOrderFlag)
+	if err != nil {
+		return ort.OursFirst, err
+	}
+
+	switch value {
+	case "ours":
+		return ort.OursFirst, nil
+	case "theirs":
+		return ort.TheirsFirst, nil
+	default:
+		return ort.OursFirst, fmt.Errorf("unsupported parent order %q, use ours or theirs", value)
+	}
+}
+
+// mergeIdentity signs the merge commits with the configured identity, nil
+// leaves ort falling back to the git one
+func mergeIdentity(cfg *config.Config) *object.Signature {
+	if cfg.Identity.Name == "" || cfg.Identity.Email == "" {
+		return nil
+	}
+	return &object.Signature{Name: cfg.Identity.Name, Email: cfg.Identity.Email}
+}
+
+// EnvSignPassphrase decrypts the key of --sign-key
+const EnvSignPassphrase = "GRAVEL_SIGN_PASSPHRASE"
+
+// readSignKey reads the key of --sign-key, nil without the flag
+func readSignKey(flags *pflag.FlagSet) (git.Signer, error) {
+	path, err := flags.GetString(SignKeyFlag)
+	if err != nil || path == "" {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ort.ReadSignKey(content, []byte(os.Getenv(EnvSignPassphrase)))
+}
+
+// parseSecrets reads the --secrets flag, defaulting to the configured mode
+func parseSecrets(flags *pflag.FlagSet, cfg *config.Config) (ort.SecretMode, error) {
+	value, err := flags.GetString(SecretsFlag)
+	if err != nil {
+		return ort.SecretsWarn, err
+	}
+	if !flags.Changed(SecretsFlag) && cfg.Secrets != "" {
+		value = cfg.Secrets
+	}
+
+	switch value {
+	case "ignore":
+		return ort.SecretsIgnore, nil
+	case "warn":
+		return ort.SecretsWarn, nil
+	case "block":
+		return ort.SecretsBlock, nil
+	default:
+		return ort.SecretsWarn, fmt.Errorf("unsupported secrets mode %q, use ignore, warn or block", value)
+	}
+}
+
+// verifyPin checks the fetched commit against the hashes pinned by the manifest
+func verifyPin(repo *git.Repository, remote manifest.Remote, hash plumbing.Hash, allowDrift bool, out io.Writer) error {
+	if remote.Commit == "" && remote.Tree == "" {
+		return nil
+	}
+
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		return err
+	}
+
+	err = remote.Verify(commit.Hash.String(), commit.TreeHash.String())
+	if err != nil && allowDrift {
+		_, _ = fmt.Fprintln(out, i18n.Tf("warning: %s", err))
+		return nil
+	}
+	return err
+}
+
+// writeWorkspace writes the editor files of the entries and commits them
+func writeWorkspace(repo *git.Repository, entries []manifest.Base) error {
+	wt, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+
+	workspaces := make([]manifest.Workspace, len(entries))
+	for index, entry := range entries {
+		workspaces[index] = entry.Workspace
+	}
+
+	written, err := workspace.Emit(wt.Filesystem, workspaces...)
+	if err != nil || len(written) == 0 {
+		return err
+	}
+	for _, filepath := range written {
+		if _, err = wt.Add(filepath); err != nil {
+			return err
+		}
+	}
+
+	opts, err := commitOptions(repo)
+	if err != nil {
+		return err
+	}
+
+	_, err = wt.Commit("Add editor workspace", opts)
+	return err
+}
+
+// readLockfile reads the lockfile at path, for init --from-lock
+func readLockfile(path string) (*lock.Lock, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	locked, err := lock.Decode(content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return locked, nil
+}
+
+// lockedEntry returns the entry of entries named like component, with the
+// remote of component. Components missing from the manifest are merged
+// without its rules
+func lockedEntry(entries []manifest.Base, component lock.Component) *manifest.Base {
+	var entry manifest.Base
+	if index := slices.IndexFunc(entries, func(candidate manifest.Base) bool { return candidate.Name == component.Name }); index >= 0 {
+		entry = entries[index]
+	}
+	entry.Name = component.Name
+	// The pins of the manifest give way to the commit of the lockfile
+	entry.Remote = manifest.Remote{
+		URL:   component.URL,
+		Name:  component.Remote,
+		Ref:   component.Ref,
+		Fetch: entry.Remote.Fetch,
+	}
+	return &entry
+}
+
+// lockedRef returns ref moved to the commit locked for the component name,
+// which the fetch of ref must have brought in. Without a lockfile, ref is
+// returned as is
+func lockedRef(repo *git.Repository, ref *plumbing.Reference, locked *lock.Lock, name string) (*plumbing.Reference, error) {
+	if locked == nil {
+		return ref, nil
+	}
+	index := slices.IndexFunc(locked.Components(), func(component lock.Component) bool { return component.Name == name })
+	if index < 0 || locked.Components()[index].Commit == "" {
+		return ref, nil
+	}
+
+	hash := plumbing.NewHash(locked.Components()[index].Commit)
+	if _, err := repo.CommitObject(hash); err != nil {
+		return nil, fmt.Errorf("%s: locked commit %s is not in %s anymore: %w", name, hash, ref.Name().Short(), err)
+	}
+	return plumbing.NewHashReference(ref.Name(), hash), nil
+}
+
+// writeState saves locked and recorded in the state of the app and commits
+// them, the remotes of the components are marked for remotes sync
+func writeState(repo *git.Repository, locked *lock.Lock, recorded *project.Project) error {
+	for _, component := range locked.Components() {
+		if err := markRemote(repo, component.Remote, component.Name); err != nil {
+			return err
+		}
+	}
+
+	store, err := openState(repo)
+	if err != nil {
+		return err
+	}
+	if err = locked.Save(store); err != nil {
+		return err
+	}
+	if err = recorded.Save(store); err != nil {
+		return err
+	}
+	return commitState(repo, store, "Add "+lock.File)
+}
+
+// writeLicense renders the license into the LICENSE file and commits it
+func writeLicense(repo *git.Repository, chosen license.License, data license.Data) error {
+	wt, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+
+	file, err := wt.Filesystem.Create("LICENSE")
+	if err != nil {
+		return err
+	}
+
+	err = chosen.Render(file, data)
+	_ = file.Close()
+	if err != nil {
+		return err
+	}
+
+	if _, err = wt.Add("LICENSE"); err != nil {
+		return err
+	}
+
+	opts, err := commitOptions(repo)
+	if err != nil {
+		return err
+	}
+
+	_, err = wt.Commit(fmt.Sprintf("Add %s license", chosen.ID), opts)
+	return err
+}
+
+// commitOptions falls back to the HEAD signature, like ort does, when git has no identity
+func commitOptions(repo *git.Repository) (*git.CommitOptions, error) {
+	opts := &git.CommitOptions{}
+
+	gitConfig, err := repo.ConfigScoped(gitconfig.SystemScope)
+	if err != nil {
+		return nil, err
+	}
+	if gitConfig.User.Name != "" && gitConfig.User.Email != "" {
+		return opts, nil
+	}
+
+	ref, err := repo.Head()
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		return nil, err
+	}
+	opts.Author = &head.Author
+	opts.Committer = &head.Committer
+	return opts, nil
+}
+
+// fetchOptions fetches a remote with its manifest tuning, falling back to the configured one
+func fetchOptions(cfg *config.Config, remote manifest.Remote, name string, progress io.Writer) *git.FetchOptions {
+	tuning := remote.Fetch.Or(cfg.Fetch)
+
+	tags := plumbing.TagFollowing
+	switch tuning.Tags {
+	case "all":
+		tags = plumbing.AllTags
+	case "none":
+		tags = plumbing.NoTags
+	}
+
+	return &git.FetchOptions{
+		RemoteName: name,
+		Progress:   progress,
+		Auth:       remoteAuth(cfg, remote.URL),
+		Tags:       tags,
+		Prune:      tuning.Prune,
+		Depth:      tuning.Depth,
+	}
+}
+
+// deepenOptions returns the Deepen and MaxDepth of ort.MergeOptions, which
+// fetch more history of the remotes fetched shallow. Deepen is nil when
+// the history of every remote is complete
+func deepenOptions(ctx context.Context, cfg *config.Config, repo *git.Repository, remotes []manifest.Remote, progress io.Writer) (func(int) error, int) {
+	var shallow []manifest.Remote
+	maxDepth := 0
+	for _, remote := range remotes {
+		tuning := remote.Fetch.Or(cfg.Fetch)
+		if tuning.Depth == 0 {
+			continue
+		}
+		shallow = append(shallow, remote)
+		maxDepth = max(maxDepth, tuning.MaxDepth)
+	}
+	if len(shallow) == 0 {
+		return nil, 0
+	}
+
+	return func(depth int) error {
+		for _, remote := range shallow {
+			options := fetchOptions(cfg, remote, remote.Name, progress)
+			options.Depth = depth
+			if err := repo.FetchContext(ctx, options); err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+				return err
+			}
+		}
+		return nil
+	}, maxDepth
+}
+
+// defaultBranch returns the branch the HEAD of remote points to
+func defaultBranch(remote *git.Remote, auth transport.AuthMethod) (string, error) {
+	refs, err := remote.List(&git.ListOptions{Auth: auth})
+	if err != nil {
+		return "", err
+	}
+
+	var head *plumbing.Reference
+	for _, ref := range refs {
+		if ref.Name() == plumbing.HEAD {
+			head = ref
+		}
+	}
+	if head == nil {
+		return "", fmt.Errorf("remote %s has no HEAD, name a branch after an @", remote.Config().Name)
+	}
+	if head.Type() == plumbing.SymbolicReference {
+		return head.Target().Short(), nil
+	}
+
+	// Without the symref capability, HEAD is the branch at the same commit
+	for _, ref := range refs {
+		if ref.Name().IsBranch() && ref.Hash() == head.Hash() {
+			return ref.Name().Short(), nil
+		}
+	}
+	return "", fmt.Errorf("cannot tell the default branch of remote %s, name a branch after an @", remote.Config().Name)
+}
+
+// remoteRef returns the branch name of remote, or else the tag, peeled to
+// its commit so that manifests may pin release tags
+func remoteRef(repo *git.Repository, remote, name string) (*plumbing.Reference, error) {
+	ref, err := repo.Reference(plumbing.NewRemoteReferenceName(remote, name), true)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		ref, err = repo.Reference(plumbing.NewTagReferenceName(name), true)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	peeled, err := ort.Peel(repo, *ref)
+	if err != nil {
+		return nil, err
+	}
+	return &peeled, nil
+}
+
+// remoteAuth authenticates a remote with the token configured for its host
+func remoteAuth(cfg *config.Config, url string) transport.AuthMethod {
+	token := cfg.Token(url)
+	if token == "" {
+		return nil
+	}
+	// Forges ignore the username when the password is an access token
+	return &http.BasicAuth{Username: "gravel", Password: token}
 }
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gravel/config"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing/transport"
+	"github.com/go-git/go-git/v6/storage"
+)
+
+// localTransport serves the repositories of https URLs from dir/host/path,
+// like a forge would
+type localTransport struct {
+	transport.Transport
+	dir string
+}
+
+func (local localTransport) NewSession(st storage.Storer, endpoint *transport.Endpoint, _ transport.AuthMethod) (transport.Session, error) {
+	path := filepath.Join(local.dir, endpoint.Host, strings.TrimSuffix(endpoint.Path, ".git"))
+	served, err := transport.NewEndpoint("file://" + path)
+	if err != nil {
+		return nil, err
+	}
+	return local.Transport.NewSession(st, served, nil)
+}
+
+// serveHTTPS serves the https URLs from the repositories under dir
+func serveHTTPS(t *testing.T, dir string) {
+	t.Helper()
+	file, err := transport.Get("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if https, err := transport.Get("https"); err == nil {
+		t.Cleanup(func() { transport.Register("https", https) })
+	} else {
+		t.Cleanup(func() { transport.Unregister("https") })
+	}
+	transport.Register("https", localTransport{Transport: file, dir: dir})
+}
+
+func TestInitShorthand(t *testing.T) {
+	testConfig(t, config.Config{Identity: config.Identity{Name: "Gravel", Email: "gravel@example.com"}})
+	forge := t.TempDir()
+	serveHTTPS(t, forge)
+	template, err := git.PlainInit(filepath.Join(forge, "github.com", "org", "base-template"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFiles(t, template, "base", map[string]string{"README": "base template\n"})
+
+	// Run from the new app, which the shorthand must not be taken for
+	app := t.TempDir()
+	t.Chdir(app)
+	if out, err := execute(t, context.Background(), "init", "github.com/org/base-template"); err != nil {
+		t.Fatalf("init: %v\n%s", err, out)
+	}
+	content, err := os.ReadFile(filepath.Join(app, "README"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "base template\n" {
+		t.Errorf("README = %q, want the file of the template", content)
+	}
+	if _, err = os.Stat(filepath.Join(app, "github.com")); err == nil {
+		t.Error("init created the shorthand as a directory")
+	}
+
+	// An existing directory named like a shorthand is the app
+	if err = os.MkdirAll(filepath.Join("my.app", "src", "service"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if isTemplateArg("my.app/src/service") {
+		t.Error("isTemplateArg() of an existing directory = true, want false")
+	}
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"gravel/config"
+	"gravel/manifest"
+
+	"github.com/spf13/cobra"
+)
+
+// listCmd represents the list command
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the bases and plugins of the manifest",
+	Long: `
+Resolves the manifest like init does and prints its bases and plugins,
+without starting the selectors, for scripts and discovery.
+`,
+	Args: cobra.NoArgs,
+
+	RunE: RunList,
+
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+	listCmd.Flags().StringP(ManifestFlag, string(ManifestFlag[0]), Manifest, "sets the manifest")
+	listCmd.Flags().
+		StringP(OutputFlag, string(OutputFlag[0]), Output, "output format (text, json, yaml)")
+}
+
+// listEntry is a base or plugin as printed by list
+type listEntry struct {
+	Kind        string `json:"kind" yaml:"kind"`
+	Name        string `json:"name" yaml:"name"`
+	Source      string `json:"source" yaml:"source"`
+	Ref         string `json:"ref,omitempty" yaml:"ref,omitempty"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+func RunList(cmd *cobra.Command, args []string) error {
+	flags := cmd.Flags()
+
+	output, err := flags.GetString(OutputFlag)
+	if err != nil {
+		return err
+	}
+	if err = checkOutput(output); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	raw, err := flags.GetString(ManifestFlag)
+	if err != nil {
+		return err
+	}
+	if !flags.Changed(ManifestFlag) && cfg.Manifest != "" {
+		raw = cfg.Manifest
+	}
+
+	decoded, err := loadManifest(raw, cfg.Network, nil)
+	if err != nil {
+		return err
+	}
+
+	entries := make([]listEntry, 0, len(decoded.Base)+len(decoded.Plugins))
+	add := func(kind string, bases []manifest.Base) {
+		for _, base := range bases {
+			entries = append(entries, listEntry{
+				Kind:        kind,
+				Name:        base.Name,
+				Source:      base.Remote.URL,
+				Ref:         base.Remote.Ref,
+				Description: base.Description,
+			})
+		}
+	}
+	add("base", decoded.Base)
+	add("plugin", decoded.Plugins)
+
+	stdout := cmd.OutOrStdout()
+	if output != "text" {
+		return encodeOutput(stdout, output, entries)
+	}
+
+	table := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(table, "KIND\tNAME\tSOURCE\tREF\tDESCRIPTION")
+	for _, entry := range entries {
+		_, _ = fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\n", entry.Kind, entry.Name, entry.Source, entry.Ref, entry.Description)
+	}
+	return table.Flush()
+}
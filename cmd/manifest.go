@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"gravel/config"
+	"gravel/manifest"
+	"gravel/source"
+
+	"github.com/spf13/cobra"
+)
+
+// manifestCmd represents the manifest command
+var manifestCmd = &cobra.Command{
+	Use:   "manifest",
+	Short: "Inspect gravel manifests",
+}
+
+// manifestDiffCmd represents the manifest diff command
+var manifestDiffCmd = &cobra.Command{
+	Use:   "diff <old> <new>",
+	Short: "Show the entries changed between two manifests",
+	Long: `
+Compares two manifests entry by entry, matching bases and plugins by name,
+and reports the entries added, removed and modified: their remote and pins,
+minimum gravel release, conflict rules and variables. The variable aliases
+are compared too.
+
+Manifests are resolved like the --manifest flag of init (file://, http://,
+https:// or - for the standard input).
+`,
+	Args: cobra.ExactArgs(2),
+
+	RunE: RunManifestDiff,
+
+	SilenceUsage: true,
+}
+
+const (
+	OutputFlag = "output"
+	Output     = "text"
+)
+
+func init() {
+	rootCmd.AddCommand(manifestCmd)
+	manifestCmd.AddCommand(manifestDiffCmd)
+	manifestDiffCmd.Flags().
+		StringP(OutputFlag, string(OutputFlag[0]), Output, "output format (text, json)")
+}
+
+// loadManifest resolves, decodes and validates the manifest at raw, recording
+// the contacted endpoints when recorder is set
+func loadManifest(raw string, policy *source.Policy, recorder *source.Recorder) (*manifest.Manifest, error) {
+	reader, err := source.ResolveRecorded(raw, policy, recorder)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = reader.Close() }()
+
+	decodedManifest, err := manifest.Decode(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	err = decodedManifest.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return decodedManifest, nil
+}
+
+func RunManifestDiff(cmd *cobra.Command, args []string) error {
+	output, err := cmd.Flags().GetString(OutputFlag)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	oldManifest, err := loadManifest(args[0], cfg.Network, nil)
+	if err != nil {
+		return err
+	}
+
+	newManifest, err := loadManifest(args[1], cfg.Network, nil)
+	if err != nil {
+		return err
+	}
+
+	changes := manifest.Diff(oldManifest, newManifest)
+
+	stdout := cmd.OutOrStdout()
+	switch output {
+	case "json":
+		if changes == nil {
+			changes = make([]manifest.Change, 0)
+		}
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(changes)
+	case "text":
+		return manifest.WriteChanges(stdout, changes)
+	default:
+		return fmt.Errorf("unsupported output format %q", output)
+	}
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"gravel/config"
+	"gravel/i18n"
+	"gravel/ort"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/spf13/cobra"
+)
+
+// mergeCmd represents the merge command
+var mergeCmd = &cobra.Command{
+	Use:   "merge [directory]",
+	Short: "Manage a merge stopped on conflicts",
+	Long: `
+Operates on the merge left in progress by init when a plugin conflicts,
+in the given directory or the current one.
+
+--continue records how the conflicts were resolved, the resolutions are
+replayed when the same conflicts recur in a later init, add or update.
+`,
+	Args: cobra.MaximumNArgs(1),
+
+	RunE: RunMerge,
+
+	SilenceUsage: true,
+}
+
+const (
+	AbortFlag = "abort"
+	Abort     = false
+
+	ContinueFlag = "continue"
+	Continue     = false
+)
+
+func init() {
+	rootCmd.AddCommand(mergeCmd)
+	mergeCmd.Flags().
+		Bool(AbortFlag, Abort, "restores the pre-merge worktree and index and removes MERGE_HEAD")
+	mergeCmd.Flags().
+		Bool(ContinueFlag, Continue, "creates the merge commit once every conflict is resolved and staged")
+	mergeCmd.Flags().
+		String(ParentOrderFlag, ParentOrder, "first parent of the merge commit created by --continue (ours, theirs)")
+	mergeCmd.Flags().
+		String(SignKeyFlag, SignKey, "signs the merge commit created by --continue with the OpenPGP or SSH private key of the file, decrypted with GRAVEL_SIGN_PASSPHRASE")
+	mergeCmd.MarkFlagsMutuallyExclusive(AbortFlag, ContinueFlag)
+}
+
+// openRepository opens the existing repository of the target directory
+func openRepository(cmd *cobra.Command, args []string) (*git.Repository, error) {
+	store, err := resolveStorage(cmd.Context(), false, args)
+	if err != nil {
+		return nil, err
+	}
+	return git.Open(store.Storer, store.Worktree)
+}
+
+func RunMerge(cmd *cobra.Command, args []string) error {
+	flags := cmd.Flags()
+
+	abort, err := flags.GetBool(AbortFlag)
+	if err != nil {
+		return err
+	}
+
+	var cont bool
+	cont, err = flags.GetBool(ContinueFlag)
+	if err != nil {
+		return err
+	}
+
+	if !abort && !cont {
+		return errors.New(i18n.T("nothing to do, use --abort or --continue"))
+	}
+
+	repo, err := openRepository(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	if abort {
+		if err = ort.Abort(repo); err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintln(cmd.OutOrStdout(), i18n.T("Merge aborted"))
+		return err
+	}
+
+	parentOrder, err := parseParentOrder(flags)
+	if err != nil {
+		return err
+	}
+	signKey, err := readSignKey(flags)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	identity := mergeIdentity(cfg)
+	return ort.Continue(repo, ort.MergeOptions{
+		Progress:    cmd.OutOrStdout(),
+		ParentOrder: parentOrder,
+		Author:      identity,
+		Committer:   identity,
+		SignKey:     signKey,
+		Rerere:      true,
+	})
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gravel/config"
+	"gravel/ort"
+
+	"github.com/go-git/go-billy/v6/util"
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+	"golang.org/x/crypto/ssh"
+)
+
+// writeSignKey writes a new SSH private key and returns its file
+func writeSignKey(t *testing.T) string {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(key, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "id_ed25519")
+	if err = os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestMergeContinueSigned(t *testing.T) {
+	testConfig(t, config.Config{Identity: config.Identity{Name: "Gravel", Email: "gravel@example.com"}})
+	r, ctx := newTestStorage(t)
+	commitFiles(t, r, "base", map[string]string{"README": "base\n"})
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("plugin"), Create: true}); err != nil {
+		t.Fatal(err)
+	}
+	theirs := commitFiles(t, r, "theirs", map[string]string{"README": "theirs\n"})
+	if err = w.Checkout(&git.CheckoutOptions{Branch: plumbing.Master}); err != nil {
+		t.Fatal(err)
+	}
+	ours := commitFiles(t, r, "ours", map[string]string{"README": "ours\n"})
+
+	ref := plumbing.NewHashReference(plumbing.NewBranchReferenceName("plugin"), theirs)
+	if _, err = ort.Merge(r, *ref, ort.MergeOptions{Author: &testSignature, Committer: &testSignature}); !errors.Is(err, ort.ErrMergeConflict) {
+		t.Fatalf("Merge() error = %v, want %v", err, ort.ErrMergeConflict)
+	}
+	if err = util.WriteFile(w.Filesystem, "README", []byte("resolved\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err = ort.MarkResolved(r, "README"); err != nil {
+		t.Fatal(err)
+	}
+
+	if out, err := execute(t, ctx, "merge", "--continue", "--sign-key", writeSignKey(t)); err != nil {
+		t.Fatalf("merge --continue: %v\n%s", err, out)
+	}
+	head, err := r.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := r.CommitObject(head.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commit.ParentHashes) != 2 || commit.ParentHashes[0] != ours || commit.ParentHashes[1] != theirs {
+		t.Errorf("parents = %v, want [%s %s]", commit.ParentHashes, ours, theirs)
+	}
+	if !strings.Contains(commit.Signature, "BEGIN SSH SIGNATURE") {
+		t.Errorf("signature = %q, want an SSH signature", commit.Signature)
+	}
+	if commit.Author.Email != "gravel@example.com" || commit.Committer.Email != "gravel@example.com" {
+		t.Errorf("author = %s, committer = %s, want the configured identity", commit.Author, commit.Committer)
+	}
+}
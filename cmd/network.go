@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"gravel/i18n"
+	"gravel/source"
+
+	"github.com/go-git/go-git/v6/plumbing/transport"
+	githttp "github.com/go-git/go-git/v6/plumbing/transport/http"
+)
+
+// installNetwork routes the http(s) traffic of git through a client enforcing
+// the policy and recording the endpoints. The ssh and git transports dial on
+// their own: checkRemote refuses them unless the policy lists them, and then
+// checks the addresses their hosts resolve to. The callers record them
+func installNetwork(policy *source.Policy, recorder *source.Recorder) {
+	if policy == nil && recorder == nil {
+		return
+	}
+
+	client := http.DefaultClient
+	if policy != nil {
+		client = policy.HTTPClient()
+	}
+	gitClient := githttp.NewTransport(&githttp.TransportOptions{Client: recorder.Client(client)})
+	transport.Register("http", gitClient)
+	transport.Register("https", gitClient)
+}
+
+// checkRemote validates a remote URL against the policy, a nil policy allows everything
+func checkRemote(policy *source.Policy, url string) error {
+	if policy == nil {
+		return nil
+	}
+	return policy.Check(url)
+}
+
+// writeNetworkReport writes the recorded endpoints as JSON to path, or as
+// a table to out when path is -
+func writeNetworkReport(out io.Writer, path string, recorder *source.Recorder) error {
+	endpoints := recorder.Endpoints()
+	if path != source.Stdin {
+		content, err := json.MarshalIndent(endpoints, "", "  ")
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path, append(content, '\n'), 0o644)
+	}
+
+	_, _ = fmt.Fprintln(out, i18n.T("Network endpoints contacted:"))
+	for _, endpoint := range endpoints {
+		_, _ = fmt.Fprintf(out, "  %-5s %s:%s  %s\n",
+			endpoint.Scheme, endpoint.Host, endpoint.Port, strings.Join(endpoint.Purposes, ", "))
+	}
+	return nil
+}
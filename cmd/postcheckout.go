@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"gravel/i18n"
+
+	"github.com/spf13/cobra"
+)
+
+// ErrVerificationFailed is returned when a verify command of the manifest fails
+var ErrVerificationFailed = errors.New("verification failed")
+
+// shellCommand runs command with the platform shell
+func shellCommand(cmd *cobra.Command, dir, command string) *exec.Cmd {
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+
+	process := exec.CommandContext(cmd.Context(), shell, flag, command)
+	process.Dir = dir
+	process.Stdout = cmd.OutOrStdout()
+	process.Stderr = cmd.ErrOrStderr()
+	return process
+}
+
+// verifyCheckout runs the verify commands in the scaffolded app, streaming
+// their output, and stops at the first failure
+func verifyCheckout(cmd *cobra.Command, dir string, commands []string) error {
+	stdout := cmd.OutOrStdout()
+	if len(commands) == 0 {
+		_, err := fmt.Fprintln(stdout, i18n.T("No verify command declared by the manifest"))
+		return err
+	}
+
+	start := time.Now()
+	for _, command := range commands {
+		_, _ = fmt.Fprintf(stdout, "$ %s\n", command)
+		if err := shellCommand(cmd, dir, command).Run(); err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrVerificationFailed, command, err)
+		}
+	}
+
+	_, err := fmt.Fprintln(stdout, i18n.Tf("Verification passed: %d commands in %s", len(commands), time.Since(start).Round(time.Millisecond)))
+	return err
+}
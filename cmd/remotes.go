@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"gravel/i18n"
+	"gravel/lock"
+
+	gitconfig "github.com/go-git/go-git/v6/config"
+	"github.com/spf13/cobra"
+)
+
+// remotesCmd represents the remotes command
+var remotesCmd = &cobra.Command{
+	Use:   "remotes",
+	Short: "Maintain the git remotes of the components",
+}
+
+// remotesSyncCmd represents the remotes sync command
+var remotesSyncCmd = &cobra.Command{
+	Use:   "sync [directory]",
+	Short: "Reconcile the git remotes with the lockfile",
+	Long: `
+Makes the git remotes match the components recorded in ` + lock.File + `:
+  added    remotes missing, e.g. in a fresh clone of the app
+  updated  remotes whose URL differs from the lockfile
+  removed  remotes added by gravel for components no longer locked
+
+Remotes gravel did not add are left alone.
+`,
+	Args: cobra.MaximumNArgs(1),
+
+	RunE: RunRemotesSync,
+
+	SilenceUsage: true,
+}
+
+// remoteComponentKey marks, in the git config, the remotes managed by gravel
+// with the name of their component: remote.<name>.gravelComponent
+const remoteComponentKey = "gravelComponent"
+
+func init() {
+	rootCmd.AddCommand(remotesCmd)
+	remotesCmd.AddCommand(remotesSyncCmd)
+	remotesSyncCmd.Flags().
+		Bool(DryRunFlag, DryRun, "prints the changes without applying them")
+}
+
+func RunRemotesSync(cmd *cobra.Command, args []string) error {
+	dryRun, err := cmd.Flags().GetBool(DryRunFlag)
+	if err != nil {
+		return err
+	}
+
+	repo, err := openRepository(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	store, err := openState(repo)
+	if err != nil {
+		return err
+	}
+
+	locked, err := lock.Load(store)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := repo.Config()
+	if err != nil {
+		return err
+	}
+	raw := cfg.Raw.Section("remote")
+
+	stdout := cmd.OutOrStdout()
+	report := func(action, name, url string) {
+		_, _ = fmt.Fprintf(stdout, "%-8s %s %s\n", i18n.T(action), name, url)
+	}
+
+	components := make(map[string]lock.Component)
+	for _, component := range locked.Components() {
+		components[component.Remote] = component
+
+		remote, ok := cfg.Remotes[component.Remote]
+		switch {
+		case !ok:
+			cfg.Remotes[component.Remote] = &gitconfig.RemoteConfig{
+				Name: component.Remote,
+				URLs: []string{component.URL},
+				Fetch: []gitconfig.RefSpec{gitconfig.RefSpec(
+					fmt.Sprintf(gitconfig.DefaultFetchRefSpec, component.Remote),
+				)},
+			}
+			report("added", component.Remote, component.URL)
+		case !slices.Equal(remote.URLs, []string{component.URL}):
+			remote.URLs = []string{component.URL}
+			report("updated", component.Remote, component.URL)
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(cfg.Remotes)) {
+		if _, locked := components[name]; locked || !raw.HasSubsection(name) {
+			continue
+		}
+		if raw.Subsection(name).Option(remoteComponentKey) == "" {
+			continue
+		}
+		report("removed", name, cfg.Remotes[name].URLs[0])
+		delete(cfg.Remotes, name)
+	}
+
+	if dryRun {
+		return nil
+	}
+
+	// Marshal creates the raw sections of the added remotes before marking them
+	if _, err = cfg.Marshal(); err != nil {
+		return err
+	}
+	for name, component := range components {
+		raw.Subsection(name).SetOption(remoteComponentKey, component.Name)
+	}
+	return repo.SetConfig(cfg)
+}
@@ -0,0 +1,260 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"gravel/config"
+	"gravel/i18n"
+	"gravel/lock"
+	"gravel/ort"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/spf13/cobra"
+)
+
+// removeCmd represents the remove command
+var removeCmd = &cobra.Command{
+	Use:   "remove <plugin> [directory]",
+	Short: "Revert the merges of a plugin and forget it",
+	Long: `
+Backs out a plugin merged by init, add or update: each of its merge commits
+is reverted, newest first, then its remote is deleted and the plugin is
+removed from ` + lock.File + `.
+
+A revert stopping on conflicts is resolved in the worktree and staged, then
+remove --continue commits it and reverts the merges left. remove --abort
+restores HEAD as it was before the conflicted revert.
+
+A plugin fast-forwarded, or merged together with others, has no merge commit
+of its own to revert and cannot be removed.
+`,
+	Args: cobra.RangeArgs(1, 2),
+
+	RunE: RunRemove,
+
+	SilenceUsage: true,
+}
+
+// ErrNoPluginMerge is returned when a plugin has no merge commit to revert
+var ErrNoPluginMerge = errors.New("no merge commit of the plugin to revert")
+
+func init() {
+	rootCmd.AddCommand(removeCmd)
+	removeCmd.Flags().
+		Bool(ContinueFlag, Continue, "commits the resolved revert and reverts the merges left")
+	removeCmd.Flags().
+		Bool(AbortFlag, Abort, "restores HEAD as it was before the conflicted revert")
+	removeCmd.Flags().
+		String(ParentOrderFlag, ParentOrder, "first parent of the merge commits of the app (ours, theirs), their other parent is the plugin")
+	removeCmd.MarkFlagsMutuallyExclusive(AbortFlag, ContinueFlag)
+}
+
+func RunRemove(cmd *cobra.Command, args []string) error {
+	flags := cmd.Flags()
+
+	abort, err := flags.GetBool(AbortFlag)
+	if err != nil {
+		return err
+	}
+
+	var cont bool
+	if cont, err = flags.GetBool(ContinueFlag); err != nil {
+		return err
+	}
+
+	parentOrder, err := parseParentOrder(flags)
+	if err != nil {
+		return err
+	}
+
+	repo, err := openRepository(cmd, args[1:])
+	if err != nil {
+		return err
+	}
+
+	stdout := cmd.OutOrStdout()
+	if abort {
+		if err = ort.RevertAbort(repo); err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(stdout, i18n.T("Revert aborted"))
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	identity := mergeIdentity(cfg)
+	opts := ort.MergeOptions{
+		Progress:    stdout,
+		ParentOrder: parentOrder,
+		Author:      identity,
+		Committer:   identity,
+	}
+
+	if cont {
+		if _, err = ort.RevertContinue(repo, opts); err != nil {
+			return err
+		}
+	}
+
+	store, err := openState(repo)
+	if err != nil {
+		return err
+	}
+
+	locked, err := lock.Load(store)
+	if err != nil {
+		return err
+	}
+
+	recorded, err := loadProject(store, locked)
+	if err != nil {
+		return err
+	}
+
+	name := args[0]
+	var component lock.Component
+	for _, plugin := range locked.Plugins {
+		if plugin.Name == name {
+			component = plugin
+		}
+	}
+	// Refuses the base and unknown plugins before reverting anything
+	if err = locked.Remove(name); err != nil {
+		return err
+	}
+
+	var merged []string
+	if recordedPlugin := recorded.Component(name); recordedPlugin != nil {
+		merged = recordedPlugin.Merges
+	}
+	merges, err := pluginMerges(repo, locked, component, merged, parentOrder)
+	if err != nil {
+		return err
+	}
+	// The last merge may have been reverted by the continued revert
+	if len(merges) == 0 && !cont {
+		return fmt.Errorf("%w: %s", ErrNoPluginMerge, name)
+	}
+
+	for _, merge := range merges {
+		if _, err = ort.Revert(repo, merge, opts); err != nil {
+			if errors.Is(err, ort.ErrMergeConflict) {
+				_, _ = fmt.Fprintln(stdout, i18n.T("Resolve the conflicts, stage them, then run gravel remove --continue"))
+			}
+			return err
+		}
+	}
+
+	if err = locked.Save(store); err != nil {
+		return err
+	}
+	recorded.Remove(name)
+	if err = recorded.Save(store); err != nil {
+		return err
+	}
+	if err = commitState(repo, store, fmt.Sprintf("Remove %s from %s", name, lock.File)); err != nil {
+		return err
+	}
+
+	if err = repo.DeleteRemote(component.Remote); err != nil && !errors.Is(err, git.ErrRemoteNotFound) {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, i18n.Tf("Removed %s", name))
+	return err
+}
+
+// pluginMerges returns the merge commits of HEAD bringing in plugin,
+// newest first. Merges already reverted are left out. The merges recorded
+// in the project state are trusted, without them those of commits shared
+// with the other components of locked are left out too, like a plugin
+// forked from the base
+func pluginMerges(repo *git.Repository, locked *lock.Lock, plugin lock.Component, recorded []string, order ort.ParentOrder) ([]plumbing.Hash, error) {
+	commitOf := func(component lock.Component) (*object.Commit, error) {
+		commit, err := repo.CommitObject(plumbing.NewHash(component.Commit))
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", component.Name, component.Commit, err)
+		}
+		return commit, nil
+	}
+
+	tip, err := commitOf(plugin)
+	if err != nil {
+		return nil, err
+	}
+	var others []*object.Commit
+	for _, component := range locked.Components() {
+		commit, err := commitOf(component)
+		if err != nil {
+			return nil, err
+		}
+		others = append(others, commit)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return nil, err
+	}
+	commits, err := repo.Log(&git.LogOptions{From: head.Hash()})
+	if err != nil {
+		return nil, err
+	}
+
+	// The log walks from HEAD, reverts come before the merges they back out
+	reverted := make(map[string]bool)
+	var merges []plumbing.Hash
+	err = commits.ForEach(func(commit *object.Commit) error {
+		if _, rest, ok := strings.Cut(commit.Message, "This reverts commit "); ok {
+			if fields := strings.Fields(rest); len(fields) > 0 {
+				reverted[strings.TrimRight(fields[0], ".,")] = true
+			}
+		}
+		if commit.NumParents() != 2 || reverted[commit.Hash.String()] {
+			return nil
+		}
+		if len(recorded) > 0 {
+			if slices.Contains(recorded, commit.Hash.String()) {
+				merges = append(merges, commit.Hash)
+			}
+			return nil
+		}
+
+		side := 1
+		if order == ort.TheirsFirst {
+			side = 0
+		}
+		theirs, err := commit.Parent(side)
+		if err != nil {
+			return err
+		}
+
+		merged, err := contains(tip, theirs)
+		if err != nil || !merged {
+			return err
+		}
+		for _, other := range others {
+			var shared bool
+			if shared, err = contains(other, theirs); err != nil || shared {
+				return err
+			}
+		}
+		merges = append(merges, commit.Hash)
+		return nil
+	})
+	return merges, err
+}
+
+// contains reports whether commit is tip or one of its ancestors
+func contains(tip, commit *object.Commit) (bool, error) {
+	if commit.Hash == tip.Hash {
+		return true, nil
+	}
+	return commit.IsAncestor(tip)
+}
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"gravel/config"
+	"gravel/i18n"
+	"gravel/lock"
+	"gravel/project"
+	"gravel/render"
+	"gravel/state"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/spf13/cobra"
+)
+
+// renderCmd represents the render command
+var renderCmd = &cobra.Command{
+	Use:   "render [directory]",
+	Short: "Re-render template owned files with the current variables",
+	Long: `
+Substitutes the [[ name ]] placeholders of every template owned file again,
+using the variables listed by env over those the app was last rendered
+with, and commits the result.
+
+Only the files recorded in ` + render.OwnershipFile + ` are rendered. Files
+edited since their last render, committed or not, are skipped and reported
+as user owned, so user edits are never overwritten.
+`,
+	Args: cobra.MaximumNArgs(1),
+
+	RunE: RunRender,
+
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(renderCmd)
+	renderCmd.Flags().StringArray(SetFlag, nil, "sets a variable (name=value), can be repeated")
+	renderCmd.Flags().String(ProfileFlag, Profile, "applies the variables of a configured profile")
+}
+
+// renderTemplates renders the template owned files and commits them, discover
+// first records every file of HEAD referencing a variable as template owned.
+// The files edited by the user are returned as userOwned, unrendered
+func renderTemplates(repo *git.Repository, values map[string]string, discover bool) (changed, userOwned []string, err error) {
+	wt, err := repo.Worktree()
+	if err != nil {
+		return
+	}
+
+	store, err := openState(repo)
+	if err != nil {
+		return
+	}
+
+	ownership, err := render.LoadOwnership(store)
+	if err != nil {
+		return
+	}
+
+	if discover {
+		if err = render.Discover(repo, ownership); err != nil {
+			return
+		}
+	}
+	if len(ownership) == 0 {
+		return
+	}
+
+	changed, userOwned, err = render.Apply(repo, ownership, values)
+	if err != nil {
+		return
+	}
+	slices.Sort(userOwned)
+
+	if len(changed) == 0 && !discover {
+		return
+	}
+
+	// Records the pristine hashes of the rendered files
+	if err = ownership.Save(store); err != nil {
+		return
+	}
+	if path, tracked := store.Path(state.Ownership); tracked {
+		if _, err = wt.Add(path); err != nil {
+			return
+		}
+	} else if len(changed) == 0 {
+		// The ownership map is kept outside of the worktree, nothing to commit
+		return
+	}
+
+	opts, err := commitOptions(repo)
+	if err != nil {
+		return
+	}
+
+	_, err = wt.Commit("Render template variables", opts)
+	slices.Sort(changed)
+	return
+}
+
+// recordVariables saves values as the variables of the lockfile and of the
+// project state of the app, those it has, and commits them when they changed
+func recordVariables(repo *git.Repository, store state.Store, values map[string]string) error {
+	changed := false
+
+	locked, err := lock.Load(store)
+	switch {
+	case err == nil:
+		if !maps.Equal(locked.Variables, values) {
+			locked.Variables = values
+			if err = locked.Save(store); err != nil {
+				return err
+			}
+			changed = true
+		}
+	case !errors.Is(err, lock.ErrNoLockfile):
+		return err
+	}
+
+	recorded, err := project.Load(store)
+	switch {
+	case err == nil:
+		if !maps.Equal(recorded.Variables, values) {
+			recorded.Variables = values
+			if err = recorded.Save(store); err != nil {
+				return err
+			}
+			changed = true
+		}
+	case !errors.Is(err, project.ErrNoProject):
+		return err
+	}
+
+	if !changed {
+		return nil
+	}
+	return commitState(repo, store, "Record the template variables")
+}
+
+func RunRender(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	repo, err := openRepository(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	store, err := openState(repo)
+	if err != nil {
+		return err
+	}
+
+	// Apps created before the lockfile recorded variables render with the other origins
+	locked, recorded, err := recordedVariables(repo)
+	if err != nil {
+		return err
+	}
+
+	set, err := resolveVariables(cmd, cfg, locked, recorded)
+	if err != nil {
+		return err
+	}
+
+	changed, userOwned, err := renderTemplates(repo, set.Values(), false)
+	if err != nil {
+		return err
+	}
+
+	if err = recordVariables(repo, store, set.Values()); err != nil {
+		return err
+	}
+
+	stdout := cmd.OutOrStdout()
+	for _, path := range userOwned {
+		_, _ = fmt.Fprintln(stdout, i18n.Tf("kept %s, edited since its last render", path))
+	}
+	if len(changed) == 0 {
+		_, err = fmt.Fprintln(stdout, i18n.T("Nothing to render"))
+		return err
+	}
+	for _, path := range changed {
+		_, _ = fmt.Fprintln(stdout, i18n.Tf("rendered %s", path))
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/utils/merkletrie"
+	"gopkg.in/yaml.v3"
+)
+
+// ComponentReport summarizes what a component brought to the app
+type ComponentReport struct {
+	Component  string `json:"component" yaml:"component"`
+	Added      int    `json:"added" yaml:"added"`
+	Modified   int    `json:"modified" yaml:"modified"`
+	Deleted    int    `json:"deleted" yaml:"deleted"`
+	Conflicted int    `json:"conflicted" yaml:"conflicted"`
+	// Bytes is the size of the added and modified files
+	Bytes int64 `json:"bytes" yaml:"bytes"`
+	// Duration covers the fetch and the merge, in nanoseconds in JSON
+	Duration time.Duration `json:"duration" yaml:"duration"`
+}
+
+// MergeReport aggregates the components of an app, printed once init is done
+type MergeReport struct {
+	Base    string   `json:"base" yaml:"base"`
+	Plugins []string `json:"plugins" yaml:"plugins"`
+	// Commits are the commits HEAD moved to, created or fast-forwarded,
+	// oldest first
+	Commits []string `json:"commits" yaml:"commits"`
+	// Conflicts are the paths a merge stopped on, left to resolve
+	Conflicts  []string          `json:"conflicts,omitempty" yaml:"conflicts,omitempty"`
+	Components []ComponentReport `json:"components" yaml:"components"`
+	Total      ComponentReport   `json:"total" yaml:"total"`
+}
+
+// add reports the component that moved HEAD from before to after, before
+// is zero for the base and after when the merge conflicted
+func (report *MergeReport) add(repo *git.Repository, component string, before, after plumbing.Hash, conflicts []string, started time.Time) error {
+	entry := ComponentReport{
+		Component:  component,
+		Conflicted: len(conflicts),
+		Duration:   time.Since(started),
+	}
+
+	if !after.IsZero() {
+		from, err := commitTree(repo, before)
+		if err != nil {
+			return err
+		}
+		to, err := commitTree(repo, after)
+		if err != nil {
+			return err
+		}
+
+		changes, err := object.DiffTreeWithOptions(context.Background(), from, to, nil)
+		if err != nil {
+			return err
+		}
+		for _, change := range changes {
+			action, err := change.Action()
+			if err != nil {
+				return err
+			}
+			switch action {
+			case merkletrie.Insert:
+				entry.Added++
+			case merkletrie.Modify:
+				entry.Modified++
+			case merkletrie.Delete:
+				entry.Deleted++
+				continue
+			}
+			_, file, err := change.Files()
+			if err != nil {
+				return err
+			}
+			if file != nil {
+				entry.Bytes += file.Size
+			}
+		}
+	}
+
+	if !after.IsZero() && after != before {
+		report.Commits = append(report.Commits, after.String())
+	}
+	report.Conflicts = append(report.Conflicts, conflicts...)
+	report.Components = append(report.Components, entry)
+	report.Total.Added += entry.Added
+	report.Total.Modified += entry.Modified
+	report.Total.Deleted += entry.Deleted
+	report.Total.Conflicted += entry.Conflicted
+	report.Total.Bytes += entry.Bytes
+	report.Total.Duration += entry.Duration
+	return nil
+}
+
+// commitTree returns the tree of the commit, nil for the zero hash
+func commitTree(repo *git.Repository, hash plumbing.Hash) (*object.Tree, error) {
+	if hash.IsZero() {
+		return nil, nil
+	}
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		return nil, err
+	}
+	return commit.Tree()
+}
+
+// writeConflicted reports the component whose merge stopped on conflicts
+// then prints the report when the output is structured, for the wrappers
+// parsing the conflicts. The error page is printed for people instead
+func (report *MergeReport) writeConflicted(out io.Writer, output string, repo *git.Repository, component string, before plumbing.Hash, conflicts []string, started time.Time) error {
+	if output == "text" {
+		return nil
+	}
+	if err := report.add(repo, component, before, plumbing.ZeroHash, conflicts, started); err != nil {
+		return err
+	}
+	return report.write(out, output)
+}
+
+// write prints the report in the output format, a table for text
+func (report *MergeReport) write(out io.Writer, output string) error {
+	switch output {
+	case "json", "yaml":
+		return encodeOutput(out, output, report)
+	case "text":
+		table := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(table, "COMPONENT\tADDED\tMODIFIED\tDELETED\tCONFLICTED\tBYTES\tDURATION")
+		for _, entry := range append(report.Components, report.Total) {
+			_, _ = fmt.Fprintf(table, "%s\t%d\t%d\t%d\t%d\t%d\t%s\n",
+				entry.Component, entry.Added, entry.Modified, entry.Deleted, entry.Conflicted,
+				entry.Bytes, entry.Duration.Round(time.Millisecond))
+		}
+		return table.Flush()
+	default:
+		return fmt.Errorf("unsupported output format %q", output)
+	}
+}
+
+// checkOutput fails on the formats of --output other than text, json and yaml
+func checkOutput(output string) error {
+	switch output {
+	case "text", "json", "yaml":
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format %q", output)
+	}
+}
+
+// encodeOutput writes value in the structured format of --output, json or
+// yaml, the text of each command is its own
+func encodeOutput(out io.Writer, output string, value any) error {
+	switch output {
+	case "json":
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	case "yaml":
+		encoder := yaml.NewEncoder(out)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(value); err != nil {
+			return err
+		}
+		return encoder.Close()
+	default:
+		return fmt.Errorf("unsupported output format %q", output)
+	}
+}
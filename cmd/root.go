@@ -1,9 +1,20 @@
 package cmd
 
 import (
+	"cmp"
+	"context"
+	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+
+	"gravel/config"
+	"gravel/i18n"
+	"gravel/terminal"
+	"gravel/version"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -15,13 +26,128 @@ Command Line Interface for Gravel.
 
 It performs Git operations to retrieve and merge the project scaffoldings.
 `,
+	Version: version.Version,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return configureTerminal(cmd)
+	},
+}
+
+const (
+	LangFlag   = "lang"
+	Lang       = ""
+	ColorFlag  = "color"
+	Color      = ""
+	GlyphsFlag = "glyphs"
+	Glyphs     = ""
+
+	NonInteractiveFlag = "non-interactive"
+	NonInteractive     = false
+	// YesFlag is an alias of NonInteractiveFlag
+	YesFlag = "yes"
+)
+
+func init() {
+	rootCmd.PersistentFlags().
+		String(LangFlag, Lang, "language of the messages (default: from LC_ALL, LC_MESSAGES or LANG)")
+	rootCmd.PersistentFlags().
+		String(ColorFlag, Color, "color support (auto, truecolor, 256, 16, none), overrides GRAVEL_COLOR")
+	rootCmd.PersistentFlags().
+		String(GlyphsFlag, Glyphs, "glyphs of the selectors (auto, unicode, ascii), overrides GRAVEL_GLYPHS")
+	rootCmd.PersistentFlags().
+		BoolP(NonInteractiveFlag, "y", NonInteractive, "never prompts, uses the defaults of the manifest and fails when an answer is required (alias: --yes)")
+	rootCmd.SetGlobalNormalizationFunc(func(_ *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == YesFlag {
+			name = NonInteractiveFlag
+		}
+		return pflag.NormalizedName(name)
+	})
 }
 
+// Root returns the base command, embedders set its arguments and streams then
+// run it with ExecuteContext, optionally carrying WithStorage
+func Root() *cobra.Command { return rootCmd }
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	err := rootCmd.Execute()
+	// The language is needed before cobra parses the flags to translate the help
+	lang := langArg(os.Args[1:])
+	if lang == "" {
+		lang = i18n.Detect()
+	}
+	if err := i18n.Set(lang); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+	localize(rootCmd)
+
+	// Ctrl-C cancels the fetches and merges, which restore the worktree
+	// before returning. A second one kills the process as usual
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	context.AfterFunc(ctx, stop)
+
+	err := rootCmd.ExecuteContext(ctx)
+	stop()
 	if err != nil {
 		os.Exit(1)
 	}
 }
+
+// configureTerminal degrades the TUI to the detected terminal capabilities,
+// overridden by the config, then the environment, then the flags, and
+// colors it with the palette of the configured theme
+func configureTerminal(cmd *cobra.Command) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	color, glyphs := cfg.Color, cfg.Glyphs
+	if color == "" && cfg.Theme == "none" {
+		color = "none"
+	}
+	color = cmp.Or(os.Getenv(terminal.EnvColor), color)
+	glyphs = cmp.Or(os.Getenv(terminal.EnvGlyphs), glyphs)
+	if cmd.Flags().Changed(ColorFlag) {
+		color, _ = cmd.Flags().GetString(ColorFlag)
+	}
+	if cmd.Flags().Changed(GlyphsFlag) {
+		glyphs, _ = cmd.Flags().GetString(GlyphsFlag)
+	}
+
+	capabilities, err := terminal.Detect().Override(color, glyphs)
+	if err != nil {
+		return err
+	}
+	terminal.Apply(capabilities)
+	terminal.SetPalette(terminal.ThemePalette(cfg.Theme))
+	return nil
+}
+
+// langArg returns the value of --lang in args
+func langArg(args []string) string {
+	for index, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if value, ok := strings.CutPrefix(arg, "--"+LangFlag+"="); ok {
+			return value
+		}
+		if arg == "--"+LangFlag && index+1 < len(args) {
+			return args[index+1]
+		}
+	}
+	return ""
+}
+
+// localize translates the short descriptions and flag usages of cmd and its children
+func localize(cmd *cobra.Command) {
+	cmd.Short = i18n.T(cmd.Short)
+	translate := func(flag *pflag.Flag) { flag.Usage = i18n.T(flag.Usage) }
+	cmd.Flags().VisitAll(translate)
+	cmd.PersistentFlags().VisitAll(translate)
+
+	for _, child := range cmd.Commands() {
+		localize(child)
+	}
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gravel/config"
+
+	"github.com/go-git/go-billy/v6/memfs"
+	"github.com/go-git/go-billy/v6/util"
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/storage/memory"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var testSignature = object.Signature{Name: "Test", Email: "test@example.com", When: time.Unix(1700000000, 0).UTC()}
+
+// testConfig points the commands to a configuration file holding cfg
+func testConfig(t *testing.T, cfg config.Config) {
+	t.Helper()
+	t.Setenv(config.EnvConfig, filepath.Join(t.TempDir(), "config.yaml"))
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newTestStorage returns an in-memory repository and the context running
+// the commands on it
+func newTestStorage(t *testing.T) (*git.Repository, context.Context) {
+	t.Helper()
+	storage := Storage{Worktree: memfs.New(), Storer: memory.NewStorage()}
+	r, err := git.Init(storage.Storer, git.WithWorkTree(storage.Worktree))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r, WithStorage(context.Background(), storage)
+}
+
+// commitFiles writes files to the worktree of r and commits them on HEAD
+func commitFiles(t *testing.T, r *git.Repository, message string, files map[string]string) plumbing.Hash {
+	t.Helper()
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err = util.WriteFile(w.Filesystem, name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = w.Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hash, err := w.Commit(message, &git.CommitOptions{Author: &testSignature, Committer: &testSignature})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+// execute runs gravel with args, unattended, and returns its output. The
+// command is reset afterwards, for the next run
+func execute(t *testing.T, ctx context.Context, args ...string) (string, error) {
+	t.Helper()
+	in, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = in.Close() }()
+
+	var out bytes.Buffer
+	rootCmd.SetArgs(args)
+	rootCmd.SetIn(in)
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&out)
+	cmd, err := rootCmd.ExecuteContextC(ctx)
+	resetCommand(cmd)
+	return out.String(), err
+}
+
+// resetCommand restores the flags of cmd and its parents to their defaults
+// and drops the context cobra keeps from the first run of a subcommand
+func resetCommand(cmd *cobra.Command) {
+	for ; cmd != nil; cmd = cmd.Parent() {
+		cmd.SetContext(nil)
+		cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+			if slice, ok := flag.Value.(pflag.SliceValue); ok {
+				_ = slice.Replace(nil)
+			} else {
+				_ = flag.Value.Set(flag.DefValue)
+			}
+			flag.Changed = false
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"gravel/components"
+	"gravel/config"
+	"gravel/i18n"
+	"gravel/source"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+)
+
+// setupCmd represents the setup command
+var setupCmd = &cobra.Command{
+	Use:   "setup",
+	Short: "Configure gravel interactively",
+	Long: `
+Walks through the default manifest, identity, tokens and color theme, then
+writes the configuration file.
+
+The file location can be overridden with the GRAVEL_CONFIG environment variable.
+`,
+	Args: cobra.NoArgs,
+
+	RunE: RunSetup,
+
+	SilenceUsage: true,
+}
+
+// TokenHost is the host the token asked by setup authenticates
+const TokenHost = "github.com"
+
+func init() {
+	rootCmd.AddCommand(setupCmd)
+}
+
+func RunSetup(cmd *cobra.Command, args []string) error {
+	// Every setting is asked for, there is nothing to default to
+	if unattended(cmd) {
+		return inputRequired("setup asks for its settings, edit the configuration file instead")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	manifest := cfg.Manifest
+	if manifest == "" {
+		manifest = Manifest
+	}
+	theme := cfg.Theme
+	if theme == "" {
+		theme = config.Themes[0]
+	}
+
+	form := components.NewForm(
+		components.FormField{
+			Title: i18n.T("Default manifest"),
+			Value: manifest,
+			Validate: func(value string) error {
+				_, err := source.Extract(value)
+				return err
+			},
+		},
+		components.FormField{Title: i18n.T("Author name"), Value: cfg.Identity.Name},
+		components.FormField{Title: i18n.T("Author email"), Value: cfg.Identity.Email},
+		components.FormField{
+			Title:       i18n.Tf("Token for %s", TokenHost),
+			Placeholder: i18n.T("optional"),
+			Value:       cfg.Tokens[TokenHost],
+			Secret:      true,
+		},
+		components.FormField{
+			Title:       i18n.T("Color theme"),
+			Placeholder: strings.Join(config.Themes, ", "),
+			Value:       theme,
+			Validate: func(value string) error {
+				if !slices.Contains(config.Themes, value) {
+					return errors.New(i18n.Tf("theme must be one of %s", strings.Join(config.Themes, ", ")))
+				}
+				return nil
+			},
+		},
+	)
+
+	program := tea.NewProgram(
+		form,
+		tea.WithInput(cmd.InOrStdin()),
+		tea.WithOutput(cmd.OutOrStdout()),
+		tea.WithContext(cmd.Context()),
+	)
+	if _, err = program.Run(); err != nil {
+		return err
+	}
+	if form.Cancelled() {
+		return nil
+	}
+
+	values := form.Values()
+	cfg.Manifest = values[0]
+	cfg.Identity = config.Identity{Name: values[1], Email: values[2]}
+	if cfg.Tokens == nil {
+		cfg.Tokens = make(map[string]string)
+	}
+	cfg.Tokens[TokenHost] = values[3]
+	if values[3] == "" {
+		delete(cfg.Tokens, TokenHost)
+	}
+	cfg.Theme = values[4]
+
+	if err = cfg.Save(); err != nil {
+		return err
+	}
+
+	path, err := config.Path()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(cmd.OutOrStdout(), i18n.Tf("Configuration written to %s", path))
+	return err
+}
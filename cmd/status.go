@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"gravel/config"
+	"gravel/i18n"
+	"gravel/lock"
+	"gravel/ort"
+
+	"github.com/go-git/go-git/v6"
+	gitconfig "github.com/go-git/go-git/v6/config"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/spf13/cobra"
+)
+
+// statusCmd represents the status command
+var statusCmd = &cobra.Command{
+	Use:   "status [directory]",
+	Short: "Show the base and plugins of the app and their upstream",
+	Long: `
+Prints the components recorded in ` + lock.File + ` with the commit merged for
+each, and whether its ref has moved upstream since:
+  current    the ref is at the merged commit
+  outdated   the ref has new commits, gravel update merges them
+  unknown    the remote could not be listed, or --offline was given
+
+A merge stopped on conflicts is reported with the paths left to resolve.
+`,
+	Args: cobra.MaximumNArgs(1),
+
+	RunE: RunStatus,
+
+	SilenceUsage: true,
+}
+
+// OfflineFlag skips listing the remotes
+const OfflineFlag = "offline"
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+	statusCmd.Flags().
+		StringP(OutputFlag, string(OutputFlag[0]), Output, "output format (text, json, yaml)")
+	statusCmd.Flags().
+		Bool(OfflineFlag, false, "does not list the remotes, upstream refs are unknown")
+}
+
+// statusComponent is a locked component as printed by status
+type statusComponent struct {
+	Kind     string `json:"kind" yaml:"kind"`
+	Name     string `json:"name" yaml:"name"`
+	Ref      string `json:"ref" yaml:"ref"`
+	Commit   string `json:"commit" yaml:"commit"`
+	Upstream string `json:"upstream,omitempty" yaml:"upstream,omitempty"`
+	Status   string `json:"status" yaml:"status"`
+}
+
+// statusMerge is the merge in progress as printed by status
+type statusMerge struct {
+	MergeHead string   `json:"mergeHead" yaml:"mergeHead"`
+	Conflicts []string `json:"conflicts,omitempty" yaml:"conflicts,omitempty"`
+}
+
+// statusReport is printed by status
+type statusReport struct {
+	Manifest   string            `json:"manifest,omitempty" yaml:"manifest,omitempty"`
+	Components []statusComponent `json:"components" yaml:"components"`
+	Merge      *statusMerge      `json:"merge,omitempty" yaml:"merge,omitempty"`
+}
+
+func RunStatus(cmd *cobra.Command, args []string) error {
+	flags := cmd.Flags()
+
+	output, err := flags.GetString(OutputFlag)
+	if err != nil {
+		return err
+	}
+	if err = checkOutput(output); err != nil {
+		return err
+	}
+
+	offline, err := flags.GetBool(OfflineFlag)
+	if err != nil {
+		return err
+	}
+
+	repo, err := openRepository(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	store, err := openState(repo)
+	if err != nil {
+		return err
+	}
+
+	locked, err := lock.Load(store)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if !offline {
+		installNetwork(cfg.Network, nil)
+	}
+
+	report := statusReport{Manifest: locked.Manifest}
+	for index, component := range locked.Components() {
+		kind := "plugin"
+		if index == 0 {
+			kind = "base"
+		}
+		entry := statusComponent{
+			Kind:   kind,
+			Name:   component.Name,
+			Ref:    component.Ref,
+			Commit: component.Commit,
+			Status: "unknown",
+		}
+		if !offline {
+			if upstream, ok := upstreamCommit(cfg, repo, component); ok {
+				entry.Upstream = upstream.String()
+				entry.Status = "current"
+				if entry.Upstream != component.Commit {
+					entry.Status = "outdated"
+				}
+			}
+		}
+		report.Components = append(report.Components, entry)
+	}
+
+	merge, err := ort.State(repo)
+	if err != nil {
+		return err
+	}
+	if merge.InProgress {
+		report.Merge = &statusMerge{
+			MergeHead: merge.MergeHead.String(),
+			Conflicts: merge.Conflicts,
+		}
+	}
+
+	stdout := cmd.OutOrStdout()
+	if output != "text" {
+		return encodeOutput(stdout, output, report)
+	}
+	return report.write(stdout)
+}
+
+// upstreamCommit lists the remote of component for the commit of its ref,
+// peeled when it is an annotated tag. A remote that cannot be listed is not
+// an error, the upstream is unknown
+func upstreamCommit(cfg *config.Config, repo *git.Repository, component lock.Component) (plumbing.Hash, bool) {
+	if checkRemote(cfg.Network, component.URL) != nil {
+		return plumbing.ZeroHash, false
+	}
+
+	// The URL of the lockfile is listed, the remote may be missing from a clone
+	remote := git.NewRemote(repo.Storer, &gitconfig.RemoteConfig{
+		Name: component.Remote,
+		URLs: []string{component.URL},
+	})
+	refs, err := remote.List(&git.ListOptions{Auth: remoteAuth(cfg, component.URL)})
+	if err != nil {
+		return plumbing.ZeroHash, false
+	}
+
+	hashes := make(map[string]plumbing.Hash, len(refs))
+	for _, ref := range refs {
+		hashes[ref.Name().String()] = ref.Hash()
+	}
+	for _, name := range []string{
+		plumbing.NewBranchReferenceName(component.Ref).String(),
+		plumbing.NewTagReferenceName(component.Ref).String() + "^{}",
+		plumbing.NewTagReferenceName(component.Ref).String(),
+	} {
+		if hash, ok := hashes[name]; ok {
+			return hash, true
+		}
+	}
+	return plumbing.ZeroHash, false
+}
+
+// write prints the report as a table, followed by the merge in progress
+func (report statusReport) write(out io.Writer) error {
+	if report.Manifest != "" {
+		_, _ = fmt.Fprintln(out, i18n.Tf("Manifest %s", report.Manifest))
+	}
+
+	table := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(table, "KIND\tNAME\tREF\tCOMMIT\tSTATUS")
+	for _, component := range report.Components {
+		_, _ = fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\n", component.Kind, component.Name, component.Ref, shortHash(component.Commit), i18n.T(component.Status))
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+
+	if report.Merge == nil {
+		return nil
+	}
+	_, _ = fmt.Fprintln(out, i18n.Tf("Merge of %s in progress", shortHash(report.Merge.MergeHead)))
+	if len(report.Merge.Conflicts) > 0 {
+		_, _ = fmt.Fprintln(out, i18n.Tf("Unresolved conflicts in %s", strings.Join(report.Merge.Conflicts, ", ")))
+		_, _ = fmt.Fprintln(out, i18n.T("Resolve the conflicts, then run gravel merge --continue"))
+	}
+	return nil
+}
+
+// shortHash abbreviates hash like git does
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"gravel/components"
+	"gravel/config"
+	"gravel/lock"
+	"gravel/project"
+	"gravel/state"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/go-git/go-billy/v6"
+	"github.com/go-git/go-billy/v6/memfs"
+	"github.com/go-git/go-billy/v6/osfs"
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/cache"
+	"github.com/go-git/go-git/v6/storage"
+	"github.com/go-git/go-git/v6/storage/filesystem"
+	"github.com/go-git/go-git/v6/storage/memory"
+	"github.com/spf13/cobra"
+)
+
+// Storage supplies the worktree filesystem and the git storer a command operates on
+type Storage struct {
+	Worktree billy.Filesystem
+	Storer   storage.Storer
+}
+
+type storageKey struct{}
+
+// WithStorage makes the commands executed with ctx use storage instead of the
+// target directory, typically memfs and memory.Storage for embedding and tests
+func WithStorage(ctx context.Context, storage Storage) context.Context {
+	return context.WithValue(ctx, storageKey{}, storage)
+}
+
+// openState returns the store of the gravel state of repo, in the backend
+// recorded in the repository or else configured by the user
+func openState(repo *git.Repository) (state.Store, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	return state.Open(repo, cfg.State)
+}
+
+// stageState stages the lockfile and the project state saved in store,
+// when the store keeps them in the worktree
+func stageState(repo *git.Repository, store state.Store) (staged bool, err error) {
+	var wt *git.Worktree
+	for _, name := range []string{state.Lockfile, state.Project} {
+		path, tracked := store.Path(name)
+		if !tracked {
+			continue
+		}
+		if _, err = store.Read(name); errors.Is(err, state.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return
+		}
+
+		if wt == nil {
+			if wt, err = repo.Worktree(); err != nil {
+				return
+			}
+		}
+		if _, err = wt.Add(path); err != nil {
+			return
+		}
+		staged = true
+	}
+	return staged, nil
+}
+
+// commitState commits the documents staged by stageState
+func commitState(repo *git.Repository, store state.Store, message string) error {
+	staged, err := stageState(repo, store)
+	if err != nil || !staged {
+		return err
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	opts, err := commitOptions(repo)
+	if err != nil {
+		return err
+	}
+	_, err = wt.Commit(message, opts)
+	return err
+}
+
+// loadProject reads the project state of the app, the apps created before
+// it was recorded start one from their lockfile
+func loadProject(store state.Store, locked *lock.Lock) (*project.Project, error) {
+	recorded, err := project.Load(store)
+	if !errors.Is(err, project.ErrNoProject) {
+		return recorded, err
+	}
+
+	recorded = &project.Project{Base: project.Component{Name: locked.Base.Name}}
+	for _, plugin := range locked.Plugins {
+		recorded.Plugins = append(recorded.Plugins, project.Component{Name: plugin.Name})
+	}
+	return recorded, nil
+}
+
+// mergedCommit returns the commit HEAD moved to as recorded in the project
+// state, empty when the merge changed nothing
+func mergedCommit(hash plumbing.Hash) string {
+	if hash.IsZero() {
+		return ""
+	}
+	return hash.String()
+}
+
+// resolveStorage returns the storage supplied through the context, in-memory
+// storage on dry runs, or the target directory (first argument or current directory)
+func resolveStorage(ctx context.Context, dryRun bool, args []string) (Storage, error) {
+	if storage, ok := ctx.Value(storageKey{}).(Storage); ok {
+		return storage, nil
+	}
+
+	if dryRun {
+		return Storage{Worktree: memfs.New(), Storer: memory.NewStorage()}, nil
+	}
+
+	// Get current working directory
+	dir, err := os.Getwd()
+	if err != nil {
+		return Storage{}, fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	// Determine the target directory (use first arg if provided, else current dir)
+	targetDir := dir
+	if len(args) > 0 && args[0] != "" {
+		targetDir = args[0]
+	}
+
+	worktree := osfs.New(targetDir)
+	dot, _ := worktree.Chroot(git.GitDirName)
+	return Storage{
+		Worktree: worktree,
+		Storer:   filesystem.NewStorage(dot, cache.NewObjectLRUDefault()),
+	}, nil
+}
+
+// promptDirectory asks for the target directory when init is run
+// interactively without one, rather than filling the current directory. An
+// empty dir means the user cancelled
+func promptDirectory(cmd *cobra.Command) (dir string, err error) {
+	if _, ok := cmd.Context().Value(storageKey{}).(Storage); ok || !interactive(cmd) || unattended(cmd) {
+		return ".", nil
+	}
+
+	input := components.NewDirectoryInput("")
+	program := tea.NewProgram(
+		input,
+		tea.WithInput(cmd.InOrStdin()),
+		tea.WithOutput(cmd.OutOrStdout()),
+		tea.WithContext(cmd.Context()),
+	)
+	if _, err = program.Run(); err != nil {
+		return "", err
+	}
+	if input.Cancelled() || input.Selected() == "" {
+		return "", nil
+	}
+
+	if input.Create() {
+		if err = os.MkdirAll(input.Selected(), 0o755); err != nil {
+			return "", err
+		}
+	}
+	return input.Selected(), nil
+}
@@ -0,0 +1,296 @@
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
+
+	"gravel/config"
+	"gravel/i18n"
+	"gravel/lock"
+	"gravel/ort"
+	"gravel/state"
+	"gravel/terminal"
+	"gravel/version"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// supportBundleCmd represents the support-bundle command
+var supportBundleCmd = &cobra.Command{
+	Use:   "support-bundle [directory]",
+	Short: "Collect diagnostics to attach to a bug report",
+	Long: `
+Writes a zip archive of sanitized diagnostics:
+  version.txt      gravel, Go and go-git versions
+  environment.txt  platform, terminal and the names of the GRAVEL_ variables
+  config.yaml      configuration, tokens and variable values redacted
+  ` + lock.File + `      lockfile of the app in directory (current directory by default)
+  merge.txt        state left by the last merge and the git remotes
+  failure.log      logs of the last failed init
+
+Credentials embedded in URLs are redacted everywhere, review the archive
+before attaching it anyway.
+`,
+	Args: cobra.MaximumNArgs(1),
+
+	RunE: RunSupportBundle,
+
+	SilenceUsage: true,
+}
+
+const (
+	ArchiveFlag = "archive"
+	Archive     = ""
+)
+
+// redacted replaces secrets in the bundle
+const redacted = "REDACTED"
+
+// urlCredentials matches the user info of URLs, https://token@host
+var urlCredentials = regexp.MustCompile(`([A-Za-z][A-Za-z0-9+.-]*://)[^/@\s]+@`)
+
+func init() {
+	rootCmd.AddCommand(supportBundleCmd)
+	supportBundleCmd.Flags().
+		String(ArchiveFlag, Archive, "path of the archive (default gravel-support-<time>.zip)")
+}
+
+func RunSupportBundle(cmd *cobra.Command, args []string) error {
+	path, err := cmd.Flags().GetString(ArchiveFlag)
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		path = fmt.Sprintf("gravel-support-%d.zip", time.Now().Unix())
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	archive := zip.NewWriter(file)
+	add := func(name string, content []byte) error {
+		writer, err := archive.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = writer.Write(urlCredentials.ReplaceAll(content, []byte("${1}"+redacted+"@")))
+		return err
+	}
+
+	// A failing section is recorded in place of its content, the bundle is
+	// most needed when something is broken
+	sections := []struct {
+		name    string
+		collect func() ([]byte, error)
+	}{
+		{"version.txt", bundleVersion},
+		{"environment.txt", bundleEnvironment},
+		{"config.yaml", bundleConfig},
+		{lock.File, func() ([]byte, error) { return bundleLockfile(cmd, args) }},
+		{"merge.txt", func() ([]byte, error) { return bundleMergeState(cmd, args) }},
+		{"failure.log", bundleFailureLog},
+	}
+	for _, section := range sections {
+		content, err := section.collect()
+		if err != nil {
+			content = []byte(i18n.Tf("not collected: %s", err) + "\n")
+		}
+		if err = add(section.name, content); err != nil {
+			return err
+		}
+	}
+
+	if err = archive.Close(); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(cmd.OutOrStdout(), i18n.Tf("Support bundle written to %s, review it before attaching it to a bug report", path))
+	return err
+}
+
+func bundleVersion() ([]byte, error) {
+	var buffer bytes.Buffer
+	_, _ = fmt.Fprintf(&buffer, "gravel: %s\ngo: %s\n", version.Version, runtime.Version())
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dependency := range info.Deps {
+			if strings.HasPrefix(dependency.Path, "github.com/go-git/") {
+				_, _ = fmt.Fprintf(&buffer, "%s: %s\n", dependency.Path, dependency.Version)
+			}
+		}
+	}
+	return buffer.Bytes(), nil
+}
+
+func bundleEnvironment() ([]byte, error) {
+	var buffer bytes.Buffer
+	_, _ = fmt.Fprintf(&buffer, "os: %s\narch: %s\n", runtime.GOOS, runtime.GOARCH)
+	for _, name := range []string{"TERM", "COLORTERM", "TERM_PROGRAM", "LANG", "LC_ALL"} {
+		_, _ = fmt.Fprintf(&buffer, "%s: %s\n", name, os.Getenv(name))
+	}
+
+	capabilities := terminal.Current()
+	_, _ = fmt.Fprintf(&buffer, "color profile: %s\nunicode: %t\n", capabilities.Profile.Name(), capabilities.Unicode)
+
+	// Values may hold secrets, only the names tell what is overridden
+	var names []string
+	for _, entry := range os.Environ() {
+		if name, _, _ := strings.Cut(entry, "="); strings.HasPrefix(name, "GRAVEL_") {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	_, _ = fmt.Fprintf(&buffer, "gravel variables: %s\n", strings.Join(names, ", "))
+
+	if path, err := config.Path(); err == nil {
+		_, _ = fmt.Fprintf(&buffer, "config: %s\n", path)
+	}
+	return buffer.Bytes(), nil
+}
+
+func bundleConfig() ([]byte, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	for host := range cfg.Tokens {
+		cfg.Tokens[host] = redacted
+	}
+	// Variables may carry credentials such as database passwords
+	redactValues(cfg.Variables)
+	for name, profile := range cfg.Profiles {
+		profile.Variables = maps.Clone(profile.Variables)
+		redactValues(profile.Variables)
+		cfg.Profiles[name] = profile
+	}
+	cfg.Manifest = redactURL(cfg.Manifest)
+
+	return yaml.Marshal(cfg)
+}
+
+func redactValues(values map[string]string) {
+	for name := range values {
+		values[name] = redacted
+	}
+}
+
+// redactURL hides the user info of raw, left as is when it is not a URL
+func redactURL(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.User == nil {
+		return raw
+	}
+	parsed.User = url.User(redacted)
+	return parsed.String()
+}
+
+func bundleLockfile(cmd *cobra.Command, args []string) ([]byte, error) {
+	repo, err := openRepository(cmd, args)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := openState(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := store.Read(state.Lockfile)
+	if errors.Is(err, state.ErrNotExist) {
+		return nil, lock.ErrNoLockfile
+	}
+	return content, err
+}
+
+func bundleMergeState(cmd *cobra.Command, args []string) ([]byte, error) {
+	repo, err := openRepository(cmd, args)
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	if head, err := repo.Head(); err == nil {
+		_, _ = fmt.Fprintf(&buffer, "HEAD: %s %s\n", head.Name().Short(), head.Hash())
+	}
+
+	state, err := ort.State(repo)
+	if err != nil {
+		return nil, err
+	}
+	_, _ = fmt.Fprintf(&buffer, "merge in progress: %t\n", state.InProgress)
+	if state.InProgress {
+		_, _ = fmt.Fprintf(&buffer, "MERGE_HEAD: %s\n", state.MergeHead)
+	}
+	if !state.OrigHead.IsZero() {
+		_, _ = fmt.Fprintf(&buffer, "ORIG_HEAD: %s\n", state.OrigHead)
+	}
+	if len(state.Conflicts) > 0 {
+		_, _ = fmt.Fprintf(&buffer, "conflicts:\n  %s\n", strings.Join(state.Conflicts, "\n  "))
+	}
+	if state.Message != "" {
+		_, _ = fmt.Fprintf(&buffer, "MERGE_MSG:\n%s\n", state.Message)
+	}
+
+	if err = bundleRemotes(repo, &buffer); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func bundleRemotes(repo *git.Repository, buffer *bytes.Buffer) error {
+	remotes, err := repo.Remotes()
+	if err != nil {
+		return err
+	}
+	slices.SortFunc(remotes, func(a, b *git.Remote) int {
+		return strings.Compare(a.Config().Name, b.Config().Name)
+	})
+
+	buffer.WriteString("remotes:\n")
+	for _, remote := range remotes {
+		remoteConfig := remote.Config()
+		for _, remoteURL := range remoteConfig.URLs {
+			_, _ = fmt.Fprintf(buffer, "  %s %s\n", remoteConfig.Name, redactURL(remoteURL))
+		}
+	}
+	return nil
+}
+
+// bundleFailureLog returns the most recent log written by reportFailure
+func bundleFailureLog() ([]byte, error) {
+	logs, err := filepath.Glob(filepath.Join(os.TempDir(), "gravel-init-*.log"))
+	if err != nil {
+		return nil, err
+	}
+	if len(logs) == 0 {
+		return []byte(i18n.T("no failure recorded") + "\n"), nil
+	}
+
+	return os.ReadFile(slices.MaxFunc(logs, compareModTime))
+}
+
+func compareModTime(a, b string) int {
+	aInfo, aErr := os.Stat(a)
+	bInfo, bErr := os.Stat(b)
+	if aErr != nil || bErr != nil {
+		return strings.Compare(a, b)
+	}
+	return aInfo.ModTime().Compare(bInfo.ModTime())
+}
@@ -0,0 +1,376 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"gravel/config"
+	"gravel/i18n"
+	"gravel/lock"
+	"gravel/manifest"
+	"gravel/ort"
+	"gravel/progress"
+	"gravel/resume"
+
+	"github.com/go-git/go-git/v6"
+	gitconfig "github.com/go-git/go-git/v6/config"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/spf13/cobra"
+)
+
+// updateCmd represents the update command
+var updateCmd = &cobra.Command{
+	Use:   "update [directory]",
+	Short: "Merge the new commits of the base and the plugins",
+	Long: `
+Fetches the remote of every component recorded in ` + lock.File + ` again
+and merges the new commits of its ref into the app, one component at a
+time, recording the merged commits in the lockfile. Each component is
+reported:
+  updated   the new commits were merged
+  current   the ref has no new commit
+  conflict  the merge stopped on conflicts
+  outdated  the ref has new commits, reported by dry runs
+
+The conflict strategies the manifest declared for a component are recorded
+in the lockfile and resolve the paths both sides changed, like init. Other
+conflicts are resolved like those of init, with gravel merge --continue,
+then update --continue merges the components left. The paths a plugin is
+limited to by the manifest are recorded as well, it is updated within them.
+`,
+	Args: cobra.MaximumNArgs(1),
+
+	RunE: RunUpdate,
+
+	SilenceUsage: true,
+}
+
+// updateCommand names the update in the resume state
+const updateCommand = "update"
+
+func init() {
+	rootCmd.AddCommand(updateCmd)
+	updateCmd.Flags().
+		Bool(DryRunFlag, DryRun, "prints the components that would change without merging them")
+	updateCmd.Flags().
+		Bool(ContinueFlag, Continue, "merges the components left by an update stopped on conflicts")
+	updateCmd.Flags().
+		Bool(AbortFlag, Abort, "forgets the components left by an interrupted update, the merged ones stay")
+	updateCmd.Flags().Bool(VerboseFlag, Verbose, "runs in verbose mode")
+	updateCmd.Flags().
+		String(ProgressFlag, Progress, "progress format (text, json), --verbose implies text")
+	addMergeFlags(updateCmd)
+	updateCmd.MarkFlagsMutuallyExclusive(AbortFlag, ContinueFlag)
+	updateCmd.MarkFlagsMutuallyExclusive(DryRunFlag, ContinueFlag)
+}
+
+func RunUpdate(cmd *cobra.Command, args []string) error {
+	flags := cmd.Flags()
+
+	dryRun, err := flags.GetBool(DryRunFlag)
+	if err != nil {
+		return err
+	}
+
+	var cont, abort bool
+	if cont, err = flags.GetBool(ContinueFlag); err != nil {
+		return err
+	}
+	if abort, err = flags.GetBool(AbortFlag); err != nil {
+		return err
+	}
+
+	repo, err := openRepository(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	store, err := openState(repo)
+	if err != nil {
+		return err
+	}
+
+	stdout := cmd.OutOrStdout()
+	if abort {
+		if err = resume.Abort(store); err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(stdout, i18n.T("Update aborted"))
+		return err
+	}
+
+	locked, err := lock.Load(store)
+	if err != nil {
+		return err
+	}
+
+	recorded, err := loadProject(store, locked)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, component := range locked.Components() {
+		names = append(names, component.Name)
+	}
+
+	// Dry runs merge nothing, so leave nothing to continue
+	var operation *resume.Operation
+	switch {
+	case cont:
+		operation, err = resume.Load(store)
+		if err == nil && operation.Command != updateCommand {
+			err = fmt.Errorf("%w: %s", resume.ErrInProgress, operation.Command)
+		}
+	case !dryRun:
+		operation, err = resume.Start(store, updateCommand, names)
+	}
+	if err != nil {
+		return err
+	}
+	if operation != nil {
+		names = slices.Clone(operation.Pending)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	installNetwork(cfg.Network, nil)
+
+	opts, err := flagMergeOptions(cmd, cfg, dryRun)
+	if err != nil {
+		return err
+	}
+
+	reporter, err := newReporter(cmd, new(progress.Recorder))
+	if err != nil {
+		return err
+	}
+
+	updated := false
+	for _, name := range names {
+		index := slices.IndexFunc(locked.Components(), func(component lock.Component) bool {
+			return component.Name == name
+		})
+		// Components unlocked since the update was interrupted are dropped
+		if index < 0 {
+			if operation != nil {
+				if err = operation.Complete(store, name); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		component := locked.Components()[index]
+
+		var merged bool
+		merged, err = updateComponent(cmd.Context(), cfg, repo, &component, opts, reporter.Scope("component:"+name), stdout)
+		if err != nil {
+			return err
+		}
+		if dryRun {
+			continue
+		}
+
+		if merged {
+			if err = locked.Record(component); err != nil {
+				return err
+			}
+			if err = locked.Save(store); err != nil {
+				return err
+			}
+
+			// HEAD is the merge, or the merge concluded by gravel merge --continue
+			var head *plumbing.Reference
+			if head, err = repo.Head(); err != nil {
+				return err
+			}
+			recorded.Merged(name, head.Hash().String())
+			if err = recorded.Save(store); err != nil {
+				return err
+			}
+			updated = true
+		}
+		if err = operation.Complete(store, name); err != nil {
+			return err
+		}
+	}
+
+	if !updated {
+		return nil
+	}
+	return commitState(repo, store, "Update "+lock.File)
+}
+
+// addMergeFlags adds the flags read by flagMergeOptions to cmd
+func addMergeFlags(cmd *cobra.Command) {
+	cmd.Flags().
+		StringArrayP(StrategyOptionFlag, "X", nil, "merge option, can be repeated: ours or theirs resolves conflicting hunks, ignore-space-change or ignore-all-space resolves whitespace only changes")
+	cmd.Flags().
+		String(ConflictStyleFlag, ConflictStyle, "conflict markers (merge, diff3, zdiff3), diff3 and zdiff3 also show the base lines")
+	cmd.Flags().
+		String(SecretsFlag, Secrets, "secrets found in the merged templates and plugins (ignore, warn, block), block stops before writing them")
+	cmd.Flags().
+		String(SignKeyFlag, SignKey, "signs the merge commits with the OpenPGP or SSH private key of the file, decrypted with GRAVEL_SIGN_PASSPHRASE")
+}
+
+// flagMergeOptions returns the merge options of the commands merging
+// components into an existing app, from the flags of addMergeFlags
+func flagMergeOptions(cmd *cobra.Command, cfg *config.Config, dryRun bool) (ort.MergeOptions, error) {
+	flags := cmd.Flags()
+
+	values, err := flags.GetStringArray(StrategyOptionFlag)
+	if err != nil {
+		return ort.MergeOptions{}, err
+	}
+	strategyOption, whitespace, err := parseStrategyOptions(values)
+	if err != nil {
+		return ort.MergeOptions{}, err
+	}
+
+	style, err := flags.GetString(ConflictStyleFlag)
+	if err != nil {
+		return ort.MergeOptions{}, err
+	}
+	conflictStyle, err := parseConflictStyle(style)
+	if err != nil {
+		return ort.MergeOptions{}, err
+	}
+
+	secrets, err := parseSecrets(flags, cfg)
+	if err != nil {
+		return ort.MergeOptions{}, err
+	}
+
+	signKey, err := readSignKey(flags)
+	if err != nil {
+		return ort.MergeOptions{}, err
+	}
+
+	identity := mergeIdentity(cfg)
+	return ort.MergeOptions{
+		OrtMergeStrategyOption: strategyOption,
+		ConflictStyle:          conflictStyle,
+		Whitespace:             whitespace,
+		Author:                 identity,
+		Committer:              identity,
+		SignKey:                signKey,
+		Message:                cfg.MergeMessage,
+		RenameThreshold:        ort.DefaultRenameThreshold,
+		Rerere:                 true,
+		Secrets:                secrets,
+		DryRun:                 dryRun,
+	}, nil
+}
+
+// conflictStrategies maps the conflict rules of a component to the path
+// strategies of its merge
+func conflictStrategies(conflicts []manifest.Conflict) []ort.PathStrategy {
+	var strategies []ort.PathStrategy
+	for _, conflict := range conflicts {
+		strategies = append(strategies, ort.PathStrategy{
+			Pattern:  conflict.Path,
+			Strategy: ort.ConflictStrategy(conflict.Strategy),
+		})
+	}
+	return strategies
+}
+
+// updateComponent fetches the remote of component and merges the new
+// commits of its ref, reporting the outcome on out. Once merged, the commit
+// of component is the merged one
+func updateComponent(ctx context.Context, cfg *config.Config, repo *git.Repository, component *lock.Component, opts ort.MergeOptions, reporter *progress.Reporter, out io.Writer) (merged bool, err error) {
+	report := func(status, detail string) {
+		_, _ = fmt.Fprintf(out, "%-9s %s %s\n", i18n.T(status), component.Name, detail)
+	}
+
+	if err = checkRemote(cfg.Network, component.URL); err != nil {
+		return
+	}
+
+	// Remotes missing from a fresh clone are added back, like remotes sync
+	remote, err := repo.Remote(component.Remote)
+	if errors.Is(err, git.ErrRemoteNotFound) {
+		remote, err = repo.CreateRemote(&gitconfig.RemoteConfig{
+			Name: component.Remote,
+			URLs: []string{component.URL},
+		})
+	}
+	if err != nil {
+		return
+	}
+
+	fetched := manifest.Remote{URL: component.URL, Name: component.Remote, Ref: component.Ref}
+	err = remote.FetchContext(ctx, fetchOptions(cfg, fetched, component.Remote, reporter.Scope("fetch").Writer()))
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return
+	}
+
+	ref, err := remoteRef(repo, component.Remote, component.Ref)
+	if err != nil {
+		return
+	}
+	short := ref.Hash().String()[:7]
+	if ref.Hash().String() == component.Commit {
+		report("current", short)
+		return false, nil
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return
+	}
+	headCommit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return
+	}
+	theirCommit, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		return
+	}
+
+	// A conflict resolved since with gravel merge --continue is merged already
+	contained, err := theirCommit.IsAncestor(headCommit)
+	if err != nil {
+		return
+	}
+
+	if !contained {
+		opts.Labels = ort.Labels{Theirs: component.Name}
+		opts.Exclude = component.Exclude
+		opts.PathSpecs = component.Paths
+		opts.ConflictStrategies = conflictStrategies(component.Conflicts)
+		opts.Progress = reporter.Scope("merge").Writer()
+		opts.Events = mergeEvents{reporter: reporter.Scope("merge")}
+
+		var result *ort.MergeResult
+		result, err = ort.MergeContext(ctx, repo, *ref, opts)
+		if errors.Is(err, ort.ErrMergeConflict) {
+			report("conflict", strings.Join(result.Conflicts, ", "))
+			if opts.DryRun {
+				return false, nil
+			}
+			_, _ = fmt.Fprintln(out, i18n.T("Resolve the conflicts, run gravel merge --continue then gravel update --continue"))
+		}
+		if err != nil {
+			return
+		}
+	}
+
+	from := component.Commit
+	if len(from) > 7 {
+		from = from[:7]
+	}
+	if opts.DryRun {
+		report("outdated", from+".."+short)
+		return false, nil
+	}
+	report("updated", from+".."+short)
+	component.Commit = ref.Hash().String()
+	return true, nil
+}
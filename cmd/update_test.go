@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gravel/config"
+	"gravel/lock"
+	"gravel/ort"
+
+	"github.com/go-git/go-git/v6"
+)
+
+// openApp opens the repository of the app in dir, again after each command
+func openApp(t *testing.T, dir string) *git.Repository {
+	t.Helper()
+	r, err := git.PlainOpen(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestUpdateReplaysResolution(t *testing.T) {
+	testConfig(t, config.Config{Identity: config.Identity{Name: "Gravel", Email: "gravel@example.com"}})
+	ctx := context.Background()
+	dir := t.TempDir()
+	baseDir, app := filepath.Join(dir, "base"), filepath.Join(dir, "app")
+	base, err := git.PlainInit(baseDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFiles(t, base, "base", map[string]string{"README": "one\ntwo\nthree\n"})
+	if out, err := execute(t, ctx, "init", "file://"+baseDir, app, "--non-interactive"); err != nil {
+		t.Fatalf("init: %v\n%s", err, out)
+	}
+
+	commitFiles(t, openApp(t, app), "ours", map[string]string{"README": "one\nTWO\nthree\n"})
+	head, err := openApp(t, app).Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFiles(t, base, "theirs", map[string]string{"README": "one\nDeux\nthree\n"})
+
+	if _, err = execute(t, ctx, "update", app); !errors.Is(err, ort.ErrMergeConflict) {
+		t.Fatalf("update error = %v, want %v", err, ort.ErrMergeConflict)
+	}
+	const resolved = "one\nTWO Deux\nthree\n"
+	if err = os.WriteFile(filepath.Join(app, "README"), []byte(resolved), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err = ort.MarkResolved(openApp(t, app), "README"); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"merge", "--continue", app}, {"update", "--continue", app}} {
+		if out, err := execute(t, ctx, args...); err != nil {
+			t.Fatalf("%v: %v\n%s", args, err, out)
+		}
+	}
+
+	// The same update again meets the same conflict, resolved as recorded
+	w, err := openApp(t, app).Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Reset(&git.ResetOptions{Commit: head.Hash(), Mode: git.HardReset}); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := execute(t, ctx, "update", app); err != nil {
+		t.Fatalf("update again: %v\n%s", err, out)
+	}
+	content, err := os.ReadFile(filepath.Join(app, "README"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != resolved {
+		t.Errorf("README = %q, want the recorded resolution %q", content, resolved)
+	}
+}
+
+func TestUpdatePathScopedPlugin(t *testing.T) {
+	testConfig(t, config.Config{Identity: config.Identity{Name: "Gravel", Email: "gravel@example.com"}})
+	ctx := context.Background()
+	dir := t.TempDir()
+	baseDir, pluginDir, app := filepath.Join(dir, "base"), filepath.Join(dir, "plugin"), filepath.Join(dir, "app")
+	base, err := git.PlainInit(baseDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFiles(t, base, "base", map[string]string{"README": "base\n", "deploy/app.yaml": "replicas: 1\n"})
+	plugin, err := git.PlainClone(pluginDir, &git.CloneOptions{URL: baseDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFiles(t, plugin, "deploy", map[string]string{"README": "plugin\n", "deploy/app.yaml": "replicas: 2\n"})
+
+	manifestFile := filepath.Join(dir, "manifest.yaml")
+	content := fmt.Sprintf(`base:
+  - name: base
+    remote: {name: origin, url: file://%s, ref: master}
+plugins:
+  - name: deploy
+    remote: {name: deploy, url: file://%s, ref: master}
+    paths: [deploy/]
+`, baseDir, pluginDir)
+	if err = os.WriteFile(manifestFile, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := execute(t, ctx, "init", "--manifest", "file://"+manifestFile, "--base", "base", "--plugin", "deploy", "--non-interactive", app)
+	if err != nil {
+		t.Fatalf("init: %v\n%s", err, out)
+	}
+
+	commitFiles(t, plugin, "deploy v2", map[string]string{"README": "plugin v2\n", "deploy/app.yaml": "replicas: 3\n"})
+	if out, err = execute(t, ctx, "update", app); err != nil {
+		t.Fatalf("update: %v\n%s", err, out)
+	}
+	for name, want := range map[string]string{"README": "base\n", "deploy/app.yaml": "replicas: 3\n"} {
+		got, err := os.ReadFile(filepath.Join(app, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	store, err := openState(openApp(t, app))
+	if err != nil {
+		t.Fatal(err)
+	}
+	locked, err := lock.Load(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paths := locked.Plugins[0].Paths; !reflect.DeepEqual(paths, []string{"deploy/"}) {
+		t.Errorf("locked paths = %v, want [deploy/]", paths)
+	}
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"gravel/i18n"
+	"gravel/lock"
+	"gravel/render"
+
+	"github.com/spf13/cobra"
+)
+
+// verifyWorktreeCmd represents the verify-worktree command
+var verifyWorktreeCmd = &cobra.Command{
+	Use:   "verify-worktree [directory]",
+	Short: "Compare the worktree with the recorded template composition",
+	Long: `
+Checks the template owned files recorded in ` + render.OwnershipFile + ` against
+the components locked in ` + lock.File + ` and the worktree, and reports:
+  modified    owned files whose content differs from their last render, or
+              from their template when they were never rendered
+  deleted     owned files missing from the worktree
+  extraneous  owned files no locked component ships at its locked commit,
+              and tracked files referencing variables without being owned
+
+Only template owned files are compared: the other files the components ship
+are merged with the changes of the app and have no pristine content.
+
+Exits with an error when any difference is found.
+`,
+	Args: cobra.MaximumNArgs(1),
+
+	RunE: RunVerifyWorktree,
+
+	SilenceUsage: true,
+}
+
+// ErrWorktreeDrift is returned when the worktree differs from the composition
+var ErrWorktreeDrift = errors.New("worktree differs from the recorded composition")
+
+func init() {
+	rootCmd.AddCommand(verifyWorktreeCmd)
+	verifyWorktreeCmd.Flags().
+		StringP(OutputFlag, string(OutputFlag[0]), Output, "output format (text, json)")
+}
+
+func RunVerifyWorktree(cmd *cobra.Command, args []string) error {
+	output, err := cmd.Flags().GetString(OutputFlag)
+	if err != nil {
+		return err
+	}
+
+	repo, err := openRepository(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	store, err := openState(repo)
+	if err != nil {
+		return err
+	}
+
+	locked, err := lock.Load(store)
+	if err != nil {
+		return err
+	}
+
+	ownership, err := render.LoadOwnership(store)
+	if err != nil {
+		return err
+	}
+
+	drifts, err := render.Verify(repo, locked, ownership)
+	if err != nil {
+		return err
+	}
+	slices.SortFunc(drifts, func(a, b render.Drift) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	stdout := cmd.OutOrStdout()
+	switch output {
+	case "json":
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if drifts == nil {
+			drifts = []render.Drift{}
+		}
+		if err = encoder.Encode(drifts); err != nil {
+			return err
+		}
+	case "text":
+		if len(drifts) == 0 {
+			_, err = fmt.Fprintln(stdout, i18n.Tf("%d template owned files match", len(ownership)))
+			return err
+		}
+		for _, drift := range drifts {
+			_, _ = fmt.Fprintf(stdout, "%-10s  %s\n", drift.Kind, drift.Path)
+		}
+	default:
+		return fmt.Errorf("unsupported output format %q", output)
+	}
+
+	if len(drifts) > 0 {
+		return ErrWorktreeDrift
+	}
+	return nil
+}
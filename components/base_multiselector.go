@@ -5,6 +5,7 @@ import (
 	"io"
 
 	"gravel/manifest"
+	"gravel/terminal"
 
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
@@ -27,18 +28,25 @@ func (mbd multiBaseItemDelegate) Render(w io.Writer, m list.Model, index int, li
 		return
 	}
 
-	char := "○"
+	glyphs := terminal.Current().Glyphs()
+	char := glyphs.Unselected
 
 	style := lipgloss.NewStyle().Foreground(lipgloss.Color(item.Color))
+	name := item.Name
+	if hint := item.hint(); hint != "" {
+		style = lipgloss.NewStyle().Faint(true)
+		name += " " + hint
+	}
+
 	fn := style.PaddingLeft(2).Render
 	if index == m.Index() {
-		fn = func(s ...string) string { return "> " + style.Render(s...) }
+		fn = func(s ...string) string { return glyphs.Cursor + " " + style.Render(s...) }
 	}
 	if _, ok := mbd.selector.selected[index]; ok {
-		char = "●"
+		char = glyphs.Selected
 	}
 
-	_, _ = fmt.Fprint(w, fn(char, item.Name))
+	_, _ = fmt.Fprint(w, fn(char, name))
 }
 
 func NewBaseMultiSelector(bases ...manifest.Base) *BaseMultiSelector {
@@ -75,7 +83,7 @@ func (m *BaseMultiSelector) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, tea.Quit
 
 		case tea.KeySpace:
-			if selected, ok := m.list.SelectedItem().(baseItem); ok {
+			if selected, ok := m.list.SelectedItem().(baseItem); ok && selected.hint() == "" {
 				if _, ok := m.selected[m.list.Index()]; ok {
 					delete(m.selected, m.list.Index())
 				} else {
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"io"
 
+	"gravel/i18n"
 	"gravel/manifest"
+	"gravel/terminal"
 
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
@@ -33,12 +35,27 @@ func (baseItemDelegate) Render(w io.Writer, m list.Model, index int, listItem li
 	}
 
 	style := lipgloss.NewStyle().Foreground(lipgloss.Color(i.Color))
+	name := i.Name
+	if hint := i.hint(); hint != "" {
+		style = lipgloss.NewStyle().Faint(true)
+		name += " " + hint
+	}
+
 	fn := style.PaddingLeft(2).Render
 	if index == m.Index() {
-		fn = func(s ...string) string { return "> " + style.Render(s...) }
+		fn = func(s ...string) string { return terminal.Current().Glyphs().Cursor + " " + style.Render(s...) }
 	}
 
-	_, _ = fmt.Fprint(w, fn(i.Name))
+	_, _ = fmt.Fprint(w, fn(name))
+}
+
+// hint explains why an item cannot be selected, empty when it can
+func (i baseItem) hint() string {
+	base := manifest.Base(i)
+	if base.Compatible() != nil {
+		return i18n.Tf("(requires gravel %s, upgrade to select)", i.MinVersion)
+	}
+	return ""
 }
 
 func NewBaseSelector(bases ...manifest.Base) *BaseSelector {
@@ -69,7 +86,7 @@ func (m *BaseSelector) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, tea.Quit
 
 		case tea.KeyEnter:
-			if selected, ok := m.list.SelectedItem().(baseItem); ok {
+			if selected, ok := m.list.SelectedItem().(baseItem); ok && selected.hint() == "" {
 				value := manifest.Base(selected)
 				m.selected = &value
 				m.list.SetSize(0, 0)
@@ -0,0 +1,206 @@
+package components
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gravel/i18n"
+	"gravel/terminal"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// DirectoryInput asks for the directory to create an app in, completing
+// paths with tab. Existing files and non-empty directories are refused and
+// creating a missing directory is confirmed
+type DirectoryInput struct {
+	input     textinput.Model
+	err       error
+	confirm   *YesNo
+	selected  string
+	create    bool
+	cancelled bool
+}
+
+// NewDirectoryInput creates a DirectoryInput prefilled with value
+func NewDirectoryInput(value string) *DirectoryInput {
+	input := textinput.New()
+	input.Prompt = i18n.T("Directory of the app") + ": "
+	input.SetValue(value)
+	input.Focus()
+	return &DirectoryInput{input: input}
+}
+
+// Selected returns the absolute path of the chosen directory, empty when the
+// user cancelled
+func (m *DirectoryInput) Selected() string { return m.selected }
+
+// Create tells whether the selected directory does not exist yet
+func (m *DirectoryInput) Create() bool { return m.create }
+
+// Cancelled reports whether the user left the prompt without a directory.
+func (m *DirectoryInput) Cancelled() bool { return m.cancelled }
+
+// Init implements tea.Model
+func (m *DirectoryInput) Init() tea.Cmd { return textinput.Blink }
+
+// Update handles user input.
+func (m *DirectoryInput) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if m.confirm != nil {
+		return m.updateConfirm(msg)
+	}
+
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.Type {
+		case tea.KeyCtrlC, tea.KeyEsc:
+			m.cancelled = true
+			return m, tea.Quit
+
+		case tea.KeyTab:
+			m.input.SetValue(completeDirectory(m.input.Value()))
+			m.input.CursorEnd()
+			return m, nil
+
+		case tea.KeyEnter:
+			dir, exists, err := checkDirectory(strings.TrimSpace(m.input.Value()))
+			if m.err = err; err != nil {
+				return m, nil
+			}
+			m.selected = dir
+			if exists {
+				return m, tea.Quit
+			}
+			m.create = true
+			m.confirm = NewYesNo(i18n.Tf("%s does not exist, create it?", dir))
+			return m, m.confirm.Init()
+		}
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+// updateConfirm forwards msg to the create confirmation, a refusal returns
+// to the path input
+func (m *DirectoryInput) updateConfirm(msg tea.Msg) (tea.Model, tea.Cmd) {
+	_, cmd := m.confirm.Update(msg)
+	if !m.confirm.done {
+		return m, cmd
+	}
+
+	if m.confirm.GetResult() {
+		return m, tea.Quit
+	}
+	m.confirm = nil
+	m.selected, m.create = "", false
+	return m, textinput.Blink
+}
+
+func (m *DirectoryInput) View() string {
+	if m.cancelled {
+		return ""
+	}
+
+	var view strings.Builder
+	view.WriteString(m.input.View())
+	view.WriteString("\n")
+	if m.err != nil {
+		view.WriteString(lipgloss.NewStyle().Foreground(terminal.CurrentPalette().Error).Render(m.err.Error()))
+		view.WriteString("\n")
+	}
+	if m.confirm != nil {
+		view.WriteString(m.confirm.View())
+	}
+	return view.String()
+}
+
+// checkDirectory returns the absolute path of value, refusing files and
+// non-empty directories, exists is false when the directory is missing
+func checkDirectory(value string) (dir string, exists bool, err error) {
+	if value == "" {
+		return "", false, errors.New(i18n.T("enter a directory"))
+	}
+
+	dir, err = filepath.Abs(expandHome(value))
+	if err != nil {
+		return "", false, err
+	}
+
+	info, err := os.Stat(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return dir, false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	if !info.IsDir() {
+		return "", false, errors.New(i18n.Tf("%s is not a directory", dir))
+	}
+
+	file, err := os.Open(dir)
+	if err != nil {
+		return "", false, err
+	}
+	defer func() { _ = file.Close() }()
+	if _, err = file.Readdirnames(1); !errors.Is(err, io.EOF) {
+		if err != nil {
+			return "", false, err
+		}
+		return "", false, errors.New(i18n.Tf("%s is not empty", dir))
+	}
+	return dir, true, nil
+}
+
+// completeDirectory extends value with the longest prefix shared by the
+// directories it starts, a single match gets a trailing separator
+func completeDirectory(value string) string {
+	matches, err := filepath.Glob(expandHome(value) + "*")
+	if err != nil {
+		return value
+	}
+
+	var dirs []string
+	for _, match := range matches {
+		if info, err := os.Stat(match); err == nil && info.IsDir() {
+			dirs = append(dirs, match)
+		}
+	}
+	if len(dirs) == 0 {
+		return value
+	}
+
+	prefix := dirs[0]
+	for _, dir := range dirs[1:] {
+		for !strings.HasPrefix(dir, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if len(dirs) == 1 {
+		prefix += string(filepath.Separator)
+	}
+
+	// Keep the ~ the user typed
+	if home, err := os.UserHomeDir(); err == nil && strings.HasPrefix(value, "~") {
+		prefix = "~" + strings.TrimPrefix(prefix, home)
+	}
+	return prefix
+}
+
+// expandHome replaces a leading ~ with the home directory
+func expandHome(value string) string {
+	rest, ok := strings.CutPrefix(value, "~")
+	if !ok || (rest != "" && !strings.HasPrefix(rest, string(filepath.Separator))) {
+		return value
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return value
+	}
+	return home + rest
+}
@@ -0,0 +1,81 @@
+package components
+
+import (
+	"fmt"
+	"strings"
+
+	"gravel/i18n"
+	"gravel/terminal"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ErrorReport describes a failed step of a wizard
+type ErrorReport struct {
+	Step  string
+	Class string
+	Err   error
+	// Log is the file holding the collected logs, empty when none were written
+	Log string
+	// Next are the commands suggested to recover
+	Next []string
+}
+
+// String renders the report as a plain block, for terminals without input
+func (report ErrorReport) String() string {
+	return report.render(lipgloss.NewStyle(), lipgloss.NewStyle(), lipgloss.NewStyle())
+}
+
+func (report ErrorReport) render(title, label, hint lipgloss.Style) string {
+	var view strings.Builder
+
+	view.WriteString(title.Render(i18n.Tf("%s failed", report.Step)))
+	view.WriteString("\n\n")
+
+	row := func(name, value string) {
+		_, _ = fmt.Fprintf(&view, "%s %s\n", label.Render(name+":"), value)
+	}
+	row(i18n.T("Error"), report.Class)
+	row(i18n.T("Cause"), report.Err.Error())
+	if report.Log != "" {
+		row(i18n.T("Logs"), report.Log)
+	}
+
+	if len(report.Next) > 0 {
+		view.WriteString("\n")
+		view.WriteString(label.Render(i18n.T("Next steps:")))
+		view.WriteString("\n")
+		for _, command := range report.Next {
+			view.WriteString("  " + hint.Render(command) + "\n")
+		}
+	}
+	return view.String()
+}
+
+// ErrorPage is the final screen of a wizard that failed, any key quits
+type ErrorPage struct {
+	report ErrorReport
+}
+
+func NewErrorPage(report ErrorReport) *ErrorPage {
+	return &ErrorPage{report: report}
+}
+
+func (ErrorPage) Init() tea.Cmd { return nil }
+
+func (m *ErrorPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if _, ok := msg.(tea.KeyMsg); ok {
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m *ErrorPage) View() string {
+	view := m.report.render(
+		lipgloss.NewStyle().Bold(true).Foreground(terminal.CurrentPalette().Error),
+		lipgloss.NewStyle().Bold(true),
+		lipgloss.NewStyle().Foreground(terminal.CurrentPalette().Accent),
+	)
+	return view + "\n" + lipgloss.NewStyle().Faint(true).Render(i18n.T("Press any key to exit")) + "\n"
+}
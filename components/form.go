@@ -0,0 +1,128 @@
+package components
+
+import (
+	"fmt"
+	"strings"
+
+	"gravel/terminal"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// FormField is a single step of a Form.
+type FormField struct {
+	Title       string
+	Placeholder string
+	Value       string
+	Secret      bool
+	// Validate rejects the entered value, the step is repeated until it returns nil
+	Validate func(string) error
+}
+
+// Form is a multi-step prompt asking for one field at a time.
+type Form struct {
+	fields    []FormField
+	input     textinput.Model
+	step      int
+	err       error
+	cancelled bool
+}
+
+// NewForm creates a Form walking through the given fields.
+func NewForm(fields ...FormField) *Form {
+	form := &Form{fields: fields}
+	form.input = textinput.New()
+	form.focus()
+	return form
+}
+
+func (m *Form) focus() {
+	if m.step >= len(m.fields) {
+		return
+	}
+	field := m.fields[m.step]
+
+	m.input.Reset()
+	m.input.Prompt = field.Title + ": "
+	m.input.Placeholder = field.Placeholder
+	m.input.SetValue(field.Value)
+	m.input.EchoMode = textinput.EchoNormal
+	if field.Secret {
+		m.input.EchoMode = textinput.EchoPassword
+	}
+	m.input.Focus()
+}
+
+// Values returns the value of every field, in order.
+func (m *Form) Values() []string {
+	values := make([]string, len(m.fields))
+	for index, field := range m.fields {
+		values[index] = field.Value
+	}
+	return values
+}
+
+// Cancelled reports whether the user left the form before completing it.
+func (m *Form) Cancelled() bool { return m.cancelled }
+
+// Init implements tea.Model
+func (m *Form) Init() tea.Cmd { return textinput.Blink }
+
+// Update handles user input.
+func (m *Form) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.Type {
+		case tea.KeyCtrlC, tea.KeyEsc:
+			m.cancelled = true
+			return m, tea.Quit
+
+		case tea.KeyEnter:
+			value := strings.TrimSpace(m.input.Value())
+			field := &m.fields[m.step]
+			if field.Validate != nil {
+				if m.err = field.Validate(value); m.err != nil {
+					return m, nil
+				}
+			}
+			m.err = nil
+			field.Value = value
+
+			m.step++
+			if m.step == len(m.fields) {
+				return m, tea.Quit
+			}
+			m.focus()
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+func (m *Form) View() string {
+	var view strings.Builder
+
+	done := lipgloss.NewStyle().Faint(true)
+	for _, field := range m.fields[:min(m.step, len(m.fields))] {
+		value := field.Value
+		if field.Secret && value != "" {
+			value = strings.Repeat("*", 8)
+		}
+		view.WriteString(done.Render(fmt.Sprintf("%s: %s", field.Title, value)))
+		view.WriteString("\n")
+	}
+
+	if m.step < len(m.fields) && !m.cancelled {
+		view.WriteString(m.input.View())
+		view.WriteString("\n")
+		if m.err != nil {
+			view.WriteString(lipgloss.NewStyle().Foreground(terminal.CurrentPalette().Error).Render(m.err.Error()))
+			view.WriteString("\n")
+		}
+	}
+	return view.String()
+}
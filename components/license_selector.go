@@ -0,0 +1,83 @@
+package components
+
+import (
+	"fmt"
+	"io"
+
+	"gravel/license"
+	"gravel/terminal"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type LicenseSelector struct {
+	list     list.Model
+	selected *license.License
+}
+
+type licenseItem license.License
+
+func (i licenseItem) FilterValue() string { return i.ID }
+func (i licenseItem) Title() string       { return i.Name }
+
+type licenseItemDelegate struct{ baseItemDelegate }
+
+func (licenseItemDelegate) Render(w io.Writer, m list.Model, index int, listItem list.Item) {
+	i, ok := listItem.(licenseItem)
+	if !ok {
+		return
+	}
+
+	line := fmt.Sprintf("%s (%s)", i.Name, i.ID)
+	if index == m.Index() {
+		_, _ = fmt.Fprint(w, terminal.Current().Glyphs().Cursor+" "+line)
+		return
+	}
+	_, _ = fmt.Fprint(w, "  "+line)
+}
+
+func NewLicenseSelector(licenses ...license.License) *LicenseSelector {
+	var items []list.Item
+	for _, value := range licenses {
+		items = append(items, licenseItem(value))
+	}
+
+	l := list.New(items, licenseItemDelegate{}, 0, 0)
+	l.SetShowStatusBar(false)
+	l.SetFilteringEnabled(false)
+	l.SetShowTitle(false)
+	l.SetShowHelp(false)
+	return &LicenseSelector{list: l}
+}
+
+func (LicenseSelector) Init() tea.Cmd { return nil }
+
+func (m *LicenseSelector) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.list.SetSize(msg.Width, msg.Height-2)
+		return m, nil
+
+	case tea.KeyMsg:
+		switch msg.Type {
+		case tea.KeyCtrlC, tea.KeyCtrlD, tea.KeyEsc:
+			return m, tea.Quit
+
+		case tea.KeyEnter:
+			if selected, ok := m.list.SelectedItem().(licenseItem); ok {
+				value := license.License(selected)
+				m.selected = &value
+				m.list.SetSize(0, 0)
+				return m, tea.Quit
+			}
+		}
+	}
+
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
+func (m LicenseSelector) View() string               { return m.list.View() }
+func (m LicenseSelector) Selected() *license.License { return m.selected }
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"strings"
 
+	"gravel/i18n"
+
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -19,7 +21,7 @@ type YesNo struct {
 func NewYesNo(question string) *YesNo {
 	ti := textinput.New()
 	ti.Focus()
-	ti.Prompt = fmt.Sprintf("%s [Y/n] ", question)
+	ti.Prompt = fmt.Sprintf("%s %s ", question, i18n.T("[Y/n]"))
 	ti.CharLimit = 3
 	ti.Width = 5
 
@@ -0,0 +1,151 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"gravel/manifest"
+	"gravel/source"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvConfig overrides the location of the configuration file
+const EnvConfig = "GRAVEL_CONFIG"
+
+// Identity is the author recorded in commits and licenses
+type Identity struct {
+	Name  string `yaml:"name,omitempty"`
+	Email string `yaml:"email,omitempty"`
+}
+
+// Profile is a named selection init applies without prompting
+type Profile struct {
+	// Manifest replaces the default value of the --manifest flag for this profile
+	Manifest string `yaml:"manifest,omitempty"`
+	// Base and Plugins are entry names of the manifest
+	Base    string   `yaml:"base"`
+	Plugins []string `yaml:"plugins,omitempty"`
+	// Variables provides default values to template variables, over the global ones
+	Variables map[string]string `yaml:"variables,omitempty"`
+}
+
+// Config holds the user preferences shared by every command
+type Config struct {
+	// Manifest replaces the default value of the --manifest flag
+	Manifest string   `yaml:"manifest,omitempty"`
+	Identity Identity `yaml:"identity,omitempty"`
+	// Tokens authenticates remotes, keyed by host (e.g. github.com)
+	Tokens map[string]string `yaml:"tokens,omitempty"`
+	// Theme selects the palette of the TUI, one of Themes
+	Theme string `yaml:"theme,omitempty"`
+	// Color and Glyphs override the detected terminal capabilities, see terminal.Colors and terminal.GlyphSets
+	Color  string `yaml:"color,omitempty"`
+	Glyphs string `yaml:"glyphs,omitempty"`
+	// Variables provides default values to template variables
+	Variables map[string]string `yaml:"variables,omitempty"`
+	// Fetch is the default fetch tuning of every remote, manifests may override it
+	Fetch manifest.Fetch `yaml:"fetch,omitempty"`
+	// Profiles are selections of init, keyed by name
+	Profiles map[string]Profile `yaml:"profiles,omitempty"`
+	// Features enables experimental features, see gravel features
+	Features []string `yaml:"features,omitempty"`
+	// State selects where the state of new apps is kept, see state.Backends
+	State string `yaml:"state,omitempty"`
+	// MergeMessage templates the message of the merge commits of init, see
+	// ort.MergeOptions.Message
+	MergeMessage string `yaml:"mergeMessage,omitempty"`
+	// Secrets replaces the default value of the --secrets flag of init
+	Secrets string `yaml:"secrets,omitempty"`
+	// Network restricts the hosts manifests and remotes are fetched from, unrestricted when nil
+	Network *source.Policy `yaml:"network,omitempty"`
+}
+
+// Themes lists the accepted values of Config.Theme
+var Themes = []string{"auto", "dark", "light", "none"}
+
+// Profile returns the profile named name
+func (cfg *Config) Profile(name string) (*Profile, error) {
+	profile, ok := cfg.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q", name)
+	}
+	return &profile, nil
+}
+
+// Path returns the location of the configuration file
+func Path() (string, error) {
+	if path := os.Getenv(EnvConfig); path != "" {
+		return path, nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gravel", "config.yaml"), nil
+}
+
+// Load reads the configuration file, a missing file yields an empty Config
+func Load() (*Config, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := new(Config)
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	err = yaml.NewDecoder(file).Decode(cfg)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Save writes the configuration file, readable only by the user as it may hold tokens
+func (cfg *Config) Save() error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o700)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	encoder := yaml.NewEncoder(file)
+	encoder.SetIndent(2)
+	if err = encoder.Encode(cfg); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// Token returns the token configured for the host of a remote URL
+func (cfg *Config) Token(remote string) string {
+	parsed, err := url.Parse(remote)
+	if err != nil {
+		return ""
+	}
+	return cfg.Tokens[parsed.Hostname()]
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestLoadSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gravel", "config.yaml")
+	t.Setenv(EnvConfig, path)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cfg, new(Config)) {
+		t.Fatalf("Load() without a file = %+v, want an empty configuration", cfg)
+	}
+
+	cfg = &Config{
+		Identity: Identity{Name: "Jane Doe", Email: "jane@example.com"},
+		Tokens:   map[string]string{"github.com": "secret"},
+		Profiles: map[string]Profile{"api": {Base: "Go", Plugins: []string{"auth"}}},
+		Features: []string{"octopus"},
+	}
+	if err = cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, cfg) {
+		t.Fatalf("Load() = %+v, want %+v", loaded, cfg)
+	}
+
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0o600 {
+			t.Errorf("configuration mode = %v, want it readable by the user only", info.Mode().Perm())
+		}
+	}
+}
+
+func TestLoadEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv(EnvConfig, path)
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(); err != nil {
+		t.Fatalf("Load() of an empty file = %v", err)
+	}
+}
+
+func TestToken(t *testing.T) {
+	cfg := &Config{Tokens: map[string]string{"github.com": "secret"}}
+	for remote, want := range map[string]string{
+		"https://github.com/org/repo.git":      "secret",
+		"https://user@github.com:443/org/repo": "secret",
+		"https://gitlab.com/org/repo":          "",
+		"::not a url":                          "",
+	} {
+		if got := cfg.Token(remote); got != want {
+			t.Errorf("Token(%q) = %q, want %q", remote, got, want)
+		}
+	}
+}
+
+func TestProfile(t *testing.T) {
+	cfg := &Config{Profiles: map[string]Profile{"api": {Base: "Go"}}}
+	if profile, err := cfg.Profile("api"); err != nil || profile.Base != "Go" {
+		t.Fatalf("Profile() = %+v, %v", profile, err)
+	}
+	if _, err := cfg.Profile("web"); err == nil {
+		t.Fatal("Profile() of an unknown profile succeeded")
+	}
+}
@@ -0,0 +1,69 @@
+package features
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// EnvFeatures enables experimental features, comma separated (e.g. octopus,prefix-merge)
+const EnvFeatures = "GRAVEL_FEATURES"
+
+// Feature names an experimental behavior disabled by default
+type Feature string
+
+const (
+	// Octopus merges every plugin at once into a single commit
+	Octopus Feature = "octopus"
+	// PrefixMerge merges a plugin under a subdirectory
+	PrefixMerge Feature = "prefix-merge"
+	// ParallelMerge fetches and merges independent plugins concurrently
+	ParallelMerge Feature = "parallel-merge"
+)
+
+// Features lists every known feature
+var Features = []Feature{Octopus, PrefixMerge, ParallelMerge}
+
+// Set holds the enabled features
+type Set map[Feature]bool
+
+// Parse enables the listed features, unknown names are rejected so typos do not go unnoticed
+func (set Set) Parse(names ...string) error {
+	for _, name := range names {
+		feature := Feature(strings.TrimSpace(name))
+		if feature == "" {
+			continue
+		}
+		if !slices.Contains(Features, feature) {
+			return fmt.Errorf("unknown feature %q", feature)
+		}
+		set[feature] = true
+	}
+	return nil
+}
+
+// Load enables the features of the configuration and of EnvFeatures
+func Load(configured []string) (Set, error) {
+	set := make(Set)
+	if err := set.Parse(configured...); err != nil {
+		return nil, err
+	}
+	if err := set.Parse(strings.Split(os.Getenv(EnvFeatures), ",")...); err != nil {
+		return nil, fmt.Errorf("%s: %w", EnvFeatures, err)
+	}
+	return set, nil
+}
+
+// Enabled reports whether the feature is enabled
+func (set Set) Enabled(feature Feature) bool {
+	return set[feature]
+}
+
+// Require fails unless the feature is enabled, for behaviors shipped dark
+func (set Set) Require(feature Feature) error {
+	if set.Enabled(feature) {
+		return nil
+	}
+	return fmt.Errorf("%s is experimental, enable it with %s=%s", feature, EnvFeatures, feature)
+}
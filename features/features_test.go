@@ -0,0 +1,32 @@
+package features
+
+import "testing"
+
+func TestLoad(t *testing.T) {
+	t.Setenv(EnvFeatures, " prefix-merge ,")
+
+	set, err := Load([]string{"octopus"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !set.Enabled(Octopus) || !set.Enabled(PrefixMerge) || set.Enabled(ParallelMerge) {
+		t.Fatalf("Load() = %v, want octopus and prefix-merge", set)
+	}
+	if err = set.Require(ParallelMerge); err == nil {
+		t.Fatal("Require() of a disabled feature succeeded")
+	}
+	if err = set.Require(Octopus); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadUnknown(t *testing.T) {
+	if _, err := Load([]string{"octopuss"}); err == nil {
+		t.Error("Load() of a misspelled configured feature succeeded")
+	}
+
+	t.Setenv(EnvFeatures, "teleport")
+	if _, err := Load(nil); err == nil {
+		t.Error("Load() of an unknown feature in the environment succeeded")
+	}
+}
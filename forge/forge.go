@@ -0,0 +1,169 @@
+package forge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"gravel/manifest"
+)
+
+// GitHubAPI is the REST API of github.com
+const GitHubAPI = "https://api.github.com"
+
+// Repository identifies a repository hosted by a forge
+type Repository struct {
+	Host  string
+	Owner string
+	Name  string
+}
+
+func (repository Repository) String() string {
+	return repository.Host + "/" + repository.Owner + "/" + repository.Name
+}
+
+// Parse reads the repository out of a remote URL, in the https, ssh or scp
+// like (git@host:owner/name.git) forms
+func Parse(remote string) (*Repository, error) {
+	var host, path string
+	if strings.Contains(remote, "://") {
+		parsed, err := url.Parse(remote)
+		if err != nil {
+			return nil, err
+		}
+		host, path = parsed.Hostname(), parsed.Path
+	} else {
+		address, rest, found := strings.Cut(remote, ":")
+		if !found {
+			return nil, fmt.Errorf("%s: not a remote URL", remote)
+		}
+		_, host, _ = strings.Cut(address, "@")
+		if host == "" {
+			host = address
+		}
+		path = rest
+	}
+
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	owner, name, found := strings.Cut(path, "/")
+	if !found || host == "" || owner == "" || name == "" || strings.Contains(name, "/") {
+		return nil, fmt.Errorf("%s: expected a remote of the form host/owner/name", remote)
+	}
+	return &Repository{Host: strings.ToLower(host), Owner: owner, Name: name}, nil
+}
+
+// GitHub applies repository settings through the REST API of GitHub
+type GitHub struct {
+	// API is the base URL of the REST API, GitHubAPI unless GitHub Enterprise
+	API string
+	// Token authenticates the requests, it needs the administration permission
+	Token  string
+	Client *http.Client
+}
+
+// For returns the forge hosting repository, only GitHub is supported
+func For(repository *Repository, token string, client *http.Client) (*GitHub, error) {
+	if repository.Host != "github.com" {
+		return nil, fmt.Errorf("%s: no forge provider for host %s", repository, repository.Host)
+	}
+	if token == "" {
+		return nil, fmt.Errorf("%s: a token for %s is required, add one with gravel setup", repository, repository.Host)
+	}
+	return &GitHub{API: GitHubAPI, Token: token, Client: client}, nil
+}
+
+// protection is the body of the branch protection endpoint, which requires
+// every field even when null
+type protection struct {
+	RequiredStatusChecks       *statusChecks `json:"required_status_checks"`
+	EnforceAdmins              bool          `json:"enforce_admins"`
+	RequiredPullRequestReviews *pullRequests `json:"required_pull_request_reviews"`
+	Restrictions               *struct{}     `json:"restrictions"`
+}
+
+type statusChecks struct {
+	Strict   bool     `json:"strict"`
+	Contexts []string `json:"contexts"`
+}
+
+type pullRequests struct {
+	RequiredApprovingReviewCount int `json:"required_approving_review_count"`
+}
+
+// Apply sets the visibility, topics and branch protections of settings on
+// repository, the settings left empty are not changed
+func (github *GitHub) Apply(ctx context.Context, repository *Repository, settings *manifest.Repository) error {
+	base := fmt.Sprintf("/repos/%s/%s", url.PathEscape(repository.Owner), url.PathEscape(repository.Name))
+
+	if settings.Visibility != "" {
+		err := github.do(ctx, http.MethodPatch, base, map[string]string{"visibility": settings.Visibility})
+		if err != nil {
+			return fmt.Errorf("visibility: %w", err)
+		}
+	}
+
+	if settings.Topics != nil {
+		err := github.do(ctx, http.MethodPut, base+"/topics", map[string][]string{"names": settings.Topics})
+		if err != nil {
+			return fmt.Errorf("topics: %w", err)
+		}
+	}
+
+	for _, rule := range settings.Protection {
+		body := protection{EnforceAdmins: rule.EnforceAdmins}
+		if len(rule.RequiredChecks) > 0 {
+			body.RequiredStatusChecks = &statusChecks{Contexts: rule.RequiredChecks}
+		}
+		if rule.RequiredReviews > 0 {
+			body.RequiredPullRequestReviews = &pullRequests{RequiredApprovingReviewCount: rule.RequiredReviews}
+		}
+
+		err := github.do(ctx, http.MethodPut, base+"/branches/"+url.PathEscape(rule.Branch)+"/protection", body)
+		if err != nil {
+			return fmt.Errorf("protection of %s: %w", rule.Branch, err)
+		}
+	}
+	return nil
+}
+
+// do sends body as JSON to the endpoint at path, failing on error statuses
+// with the message of the API
+func (github *GitHub) do(ctx context.Context, method, path string, body any) error {
+	content, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(github.API, "/")+path, bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+github.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := github.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, res.Body)
+		return nil
+	}
+	var failure struct {
+		Message string `json:"message"`
+	}
+	_ = json.NewDecoder(res.Body).Decode(&failure)
+	return fmt.Errorf("%s %s: %s %s", method, path, res.Status, failure.Message)
+}
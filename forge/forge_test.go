@@ -0,0 +1,100 @@
+package forge
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gravel/manifest"
+)
+
+func TestParse(t *testing.T) {
+	for remote, want := range map[string]string{
+		"https://github.com/gravel-dev-1/vanilla.git": "github.com/gravel-dev-1/vanilla",
+		"https://GitHub.com/gravel-dev-1/vanilla/":    "github.com/gravel-dev-1/vanilla",
+		"ssh://git@github.com/gravel-dev-1/vanilla":   "github.com/gravel-dev-1/vanilla",
+		"git@github.com:gravel-dev-1/vanilla.git":     "github.com/gravel-dev-1/vanilla",
+	} {
+		repository, err := Parse(remote)
+		if err != nil {
+			t.Errorf("Parse(%q) = %v", remote, err)
+			continue
+		}
+		if repository.String() != want {
+			t.Errorf("Parse(%q) = %s, want %s", remote, repository, want)
+		}
+	}
+
+	for _, remote := range []string{"https://github.com/gravel-dev-1", "https://github.com/a/b/c", "vanilla"} {
+		if _, err := Parse(remote); err == nil {
+			t.Errorf("Parse(%q) succeeded", remote)
+		}
+	}
+}
+
+func TestFor(t *testing.T) {
+	if _, err := For(&Repository{Host: "gitlab.com", Owner: "a", Name: "b"}, "token", nil); err == nil {
+		t.Error("For() of an unsupported host succeeded")
+	}
+	if _, err := For(&Repository{Host: "github.com", Owner: "a", Name: "b"}, "", nil); err == nil {
+		t.Error("For() without a token succeeded")
+	}
+}
+
+func TestApply(t *testing.T) {
+	requests := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		requests[r.Method+" "+r.URL.Path] = strings.TrimSpace(string(body))
+	}))
+	defer server.Close()
+
+	github := &GitHub{API: server.URL, Token: "secret", Client: server.Client()}
+	err := github.Apply(context.Background(), &Repository{Host: "github.com", Owner: "org", Name: "app"}, &manifest.Repository{
+		Visibility: "private",
+		Topics:     []string{"gravel"},
+		Protection: []manifest.BranchProtection{
+			{Branch: "main", RequiredChecks: []string{"build"}, RequiredReviews: 1},
+			{Branch: "release", EnforceAdmins: true},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for request, want := range map[string]string{
+		"PATCH /repos/org/app":                           `{"visibility":"private"}`,
+		"PUT /repos/org/app/topics":                      `{"names":["gravel"]}`,
+		"PUT /repos/org/app/branches/main/protection":    `{"required_status_checks":{"strict":false,"contexts":["build"]},"enforce_admins":false,"required_pull_request_reviews":{"required_approving_review_count":1},"restrictions":null}`,
+		"PUT /repos/org/app/branches/release/protection": `{"required_status_checks":null,"enforce_admins":true,"required_pull_request_reviews":null,"restrictions":null}`,
+	} {
+		if got := requests[request]; got != want {
+			t.Errorf("%s = %s, want %s", request, got, want)
+		}
+	}
+	if len(requests) != 4 {
+		t.Errorf("requests = %v, want only the declared settings", requests)
+	}
+}
+
+func TestApplyFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_ = json.NewEncoder(w).Encode(map[string]string{"message": "Resource not accessible"})
+	}))
+	defer server.Close()
+
+	github := &GitHub{API: server.URL, Token: "secret", Client: server.Client()}
+	err := github.Apply(context.Background(), &Repository{Owner: "org", Name: "app"}, &manifest.Repository{Topics: []string{}})
+	if err == nil || !strings.Contains(err.Error(), "Resource not accessible") {
+		t.Fatalf("Apply() = %v, want the message of the API", err)
+	}
+}
@@ -0,0 +1,115 @@
+package i18n
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Catalogs map English messages to their translation, missing entries stay in English
+//
+//go:embed locales/*.yaml
+var locales embed.FS
+
+// Default is the language messages are written in
+const Default = "en"
+
+var (
+	mu       sync.RWMutex
+	language = Default
+	catalog  map[string]string
+)
+
+// Languages lists the available languages
+func Languages() []string {
+	languages := []string{Default}
+
+	entries, _ := locales.ReadDir("locales")
+	for _, entry := range entries {
+		languages = append(languages, strings.TrimSuffix(entry.Name(), path.Ext(entry.Name())))
+	}
+	slices.Sort(languages)
+	return languages
+}
+
+// normalize reduces a locale such as fr_FR.UTF-8 to its language
+func normalize(locale string) string {
+	locale, _, _ = strings.Cut(locale, ".")
+	locale, _, _ = strings.Cut(locale, "@")
+	locale, _, _ = strings.Cut(locale, "_")
+	locale, _, _ = strings.Cut(locale, "-")
+	locale = strings.ToLower(locale)
+	if locale == "c" || locale == "posix" {
+		return Default
+	}
+	return locale
+}
+
+// Detect returns the language of the environment like gettext does, the
+// default when it is not available
+func Detect() string {
+	for _, variable := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := os.Getenv(variable)
+		if value == "" {
+			continue
+		}
+		if detected := normalize(value); slices.Contains(Languages(), detected) {
+			return detected
+		}
+		return Default
+	}
+	return Default
+}
+
+// Set selects the language of the messages
+func Set(lang string) error {
+	lang = normalize(lang)
+	if !slices.Contains(Languages(), lang) {
+		return fmt.Errorf("unsupported language %q, available: %s", lang, strings.Join(Languages(), ", "))
+	}
+
+	var messages map[string]string
+	if lang != Default {
+		content, err := locales.ReadFile(path.Join("locales", lang+".yaml"))
+		if err != nil {
+			return err
+		}
+		if err = yaml.Unmarshal(content, &messages); err != nil {
+			return fmt.Errorf("%s catalog: %w", lang, err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	language, catalog = lang, messages
+	return nil
+}
+
+// Language returns the selected language
+func Language() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return language
+}
+
+// T translates an English message
+func T(message string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if translated, ok := catalog[message]; ok && translated != "" {
+		return translated
+	}
+	return message
+}
+
+// Tf translates an English format then formats it
+func Tf(format string, args ...any) string {
+	return fmt.Sprintf(T(format), args...)
+}
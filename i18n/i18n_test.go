@@ -0,0 +1,82 @@
+package i18n
+
+import (
+	"path"
+	"regexp"
+	"slices"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestDetect(t *testing.T) {
+	for _, test := range []struct {
+		all, messages, lang string
+		want                string
+	}{
+		{lang: "fr_FR.UTF-8", want: "fr"},
+		{messages: "es_ES@euro", lang: "fr_FR", want: "es"},
+		{all: "C", lang: "fr_FR", want: Default},
+		{lang: "de_DE.UTF-8", want: Default},
+		{want: Default},
+	} {
+		t.Setenv("LC_ALL", test.all)
+		t.Setenv("LC_MESSAGES", test.messages)
+		t.Setenv("LANG", test.lang)
+		if got := Detect(); got != test.want {
+			t.Errorf("Detect() with LC_ALL=%q LC_MESSAGES=%q LANG=%q = %q, want %q", test.all, test.messages, test.lang, got, test.want)
+		}
+	}
+}
+
+func TestSet(t *testing.T) {
+	t.Cleanup(func() { _ = Set(Default) })
+
+	if err := Set("fr-FR"); err != nil {
+		t.Fatal(err)
+	}
+	if Language() != "fr" {
+		t.Fatalf("Language() = %q, want fr", Language())
+	}
+	if got := T("an untranslated message"); got != "an untranslated message" {
+		t.Errorf("T() of a message missing from the catalog = %q", got)
+	}
+
+	if err := Set("klingon"); err == nil {
+		t.Fatal("Set() of an unsupported language succeeded")
+	}
+	if Language() != "fr" {
+		t.Fatalf("Language() = %q, a failed Set() must keep fr", Language())
+	}
+}
+
+// verb matches the fmt verbs of a message, their order must be kept by
+// translations since Tf formats them with the arguments of the message
+var verb = regexp.MustCompile(`%[-+# 0]*[0-9]*(\.[0-9]+)?[a-zA-Z%]`)
+
+func TestCatalogs(t *testing.T) {
+	for _, lang := range Languages() {
+		if lang == Default {
+			continue
+		}
+		t.Run(lang, func(t *testing.T) {
+			content, err := locales.ReadFile(path.Join("locales", lang+".yaml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var messages map[string]string
+			if err = yaml.Unmarshal(content, &messages); err != nil {
+				t.Fatal(err)
+			}
+
+			for message, translated := range messages {
+				if translated == "" {
+					continue
+				}
+				if want, got := verb.FindAllString(message, -1), verb.FindAllString(translated, -1); !slices.Equal(got, want) {
+					t.Errorf("%q translates %q with the verbs %v, want %v", message, translated, got, want)
+				}
+			}
+		})
+	}
+}
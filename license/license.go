@@ -0,0 +1,65 @@
+package license
+
+import (
+	"embed"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+//go:embed templates/*.tmpl
+var templates embed.FS
+
+// License identifies a license by its SPDX identifier
+type License struct {
+	ID   string
+	Name string
+}
+
+// Licenses lists the licenses available in the chooser
+var Licenses = []License{
+	{ID: "MIT", Name: "MIT License"},
+	{ID: "BSD-2-Clause", Name: "BSD 2-Clause \"Simplified\" License"},
+	{ID: "BSD-3-Clause", Name: "BSD 3-Clause \"New\" or \"Revised\" License"},
+	{ID: "ISC", Name: "ISC License"},
+	{ID: "0BSD", Name: "BSD Zero Clause License"},
+	{ID: "Unlicense", Name: "The Unlicense"},
+}
+
+// Data holds the values substituted into a license text
+type Data struct {
+	Year   int
+	Author string
+}
+
+// Variables returns the template variables describing the license, so that
+// templates can reference it as [[ license.spdx ]], [[ license.holder ]]
+// and [[ license.year ]]
+func (license License) Variables(data Data) map[string]string {
+	return map[string]string{
+		"license.spdx":   license.ID,
+		"license.holder": data.Author,
+		"license.year":   strconv.Itoa(data.Year),
+	}
+}
+
+// Find returns the license matching the SPDX identifier, ignoring case
+func Find(id string) (*License, error) {
+	for _, license := range Licenses {
+		if strings.EqualFold(license.ID, id) {
+			return &license, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown license %q", id)
+}
+
+// Render writes the license text filled with data into w
+func (license License) Render(w io.Writer, data Data) error {
+	tmpl, err := template.ParseFS(templates, "templates/"+license.ID+".tmpl")
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, data)
+}
@@ -0,0 +1,44 @@
+package license
+
+import (
+	"maps"
+	"strings"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	license, err := Find("bsd-3-clause")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if license.ID != "BSD-3-Clause" {
+		t.Fatalf("Find() = %s, want BSD-3-Clause", license.ID)
+	}
+	if _, err = Find("GPL-9.0"); err == nil {
+		t.Fatal("Find() of an unknown license succeeded")
+	}
+}
+
+func TestRender(t *testing.T) {
+	data := Data{Year: 2026, Author: "Jane Doe"}
+	for _, license := range Licenses {
+		t.Run(license.ID, func(t *testing.T) {
+			var text strings.Builder
+			if err := license.Render(&text, data); err != nil {
+				t.Fatal(err)
+			}
+			// The Unlicense dedicates the work, it names no holder
+			if license.ID != "Unlicense" && (!strings.Contains(text.String(), "2026") || !strings.Contains(text.String(), "Jane Doe")) {
+				t.Fatalf("%s license misses the copyright line:\n%s", license.ID, text.String())
+			}
+		})
+	}
+}
+
+func TestVariables(t *testing.T) {
+	got := License{ID: "MIT"}.Variables(Data{Year: 2026, Author: "Jane Doe"})
+	want := map[string]string{"license.spdx": "MIT", "license.holder": "Jane Doe", "license.year": "2026"}
+	if !maps.Equal(got, want) {
+		t.Fatalf("Variables() = %v, want %v", got, want)
+	}
+}
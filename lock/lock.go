@@ -0,0 +1,173 @@
+package lock
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"gravel/manifest"
+	"gravel/state"
+
+	"gopkg.in/yaml.v3"
+)
+
+// File is the lockfile at the root of generated apps
+const File = state.Lockfile
+
+// ErrNoLockfile is returned by Load when the worktree has no lockfile
+var ErrNoLockfile = errors.New(File + " not found, the app was not created by gravel init")
+
+// Component is a base or plugin as it was merged into the app
+type Component struct {
+	Name string `yaml:"name"`
+	// Remote is the name of the git remote fetching the component
+	Remote string `yaml:"remote"`
+	URL    string `yaml:"url"`
+	Ref    string `yaml:"ref"`
+	// Commit is the resolved hash of Ref when it was merged
+	Commit string `yaml:"commit"`
+	// Exclude are the path patterns of the component never merged into the
+	// app, e.g. a CI directory the app replaced
+	Exclude []string `yaml:"exclude,omitempty"`
+	// Paths are the globs or directories of the only files of the plugin
+	// merged into the app, from the paths of its manifest entry
+	Paths []string `yaml:"paths,omitempty"`
+	// Conflicts are the strategies of the manifest resolving the paths both
+	// sides changed, applied again when the component is updated
+	Conflicts []manifest.Conflict `yaml:"conflicts,omitempty"`
+	// Octopus marks the plugins init merged together in one octopus merge,
+	// the plugins are merged in the order of the lockfile otherwise
+	Octopus bool `yaml:"octopus,omitempty"`
+}
+
+// Lock records the composition of an app, making it reproducible
+type Lock struct {
+	// Gravel is the release that created the app
+	Gravel string `yaml:"gravel,omitempty"`
+	// Manifest is empty for the apps of a template repository
+	Manifest string      `yaml:"manifest,omitempty"`
+	Base     Component   `yaml:"base"`
+	Plugins  []Component `yaml:"plugins,omitempty"`
+	// Variables are the values the templates were last rendered with, so
+	// that init --from-lock renders the app the same way
+	Variables map[string]string `yaml:"variables,omitempty"`
+}
+
+// Components returns the base followed by the plugins
+func (lock *Lock) Components() []Component {
+	return append([]Component{lock.Base}, lock.Plugins...)
+}
+
+// Record replaces the locked component of the same name, so that the
+// lockfile follows a command merging the components one at a time. The
+// exclusions configured by the user, the paths, the conflict strategies and
+// how init merged it are kept
+func (lock *Lock) Record(component Component) error {
+	if lock.Base.Name == component.Name {
+		component.Exclude = lock.Base.Exclude
+		component.Paths = lock.Base.Paths
+		component.Conflicts = lock.Base.Conflicts
+		lock.Base = component
+		return nil
+	}
+	for index := range lock.Plugins {
+		if lock.Plugins[index].Name == component.Name {
+			component.Exclude = lock.Plugins[index].Exclude
+			component.Paths = lock.Plugins[index].Paths
+			component.Conflicts = lock.Plugins[index].Conflicts
+			component.Octopus = lock.Plugins[index].Octopus
+			lock.Plugins[index] = component
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: no component %q", File, component.Name)
+}
+
+// Add locks a new plugin, failing when a component has the same name
+func (lock *Lock) Add(component Component) error {
+	for _, existing := range lock.Components() {
+		if existing.Name == component.Name {
+			return fmt.Errorf("%s: component %q is already locked", File, component.Name)
+		}
+	}
+	lock.Plugins = append(lock.Plugins, component)
+	return nil
+}
+
+// Remove unlocks the plugin named name, the base cannot be removed
+func (lock *Lock) Remove(name string) error {
+	if lock.Base.Name == name {
+		return fmt.Errorf("%s: %q is the base, it cannot be removed", File, name)
+	}
+	for index := range lock.Plugins {
+		if lock.Plugins[index].Name == name {
+			lock.Plugins = slices.Delete(lock.Plugins, index, index+1)
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: no component %q", File, name)
+}
+
+// Validate checks every component can be fetched again, excludes valid
+// patterns and resolves conflicts with known strategies
+func (lock *Lock) Validate() error {
+	for _, component := range lock.Components() {
+		if component.Remote == "" || component.URL == "" {
+			return fmt.Errorf("%s: component %q needs a remote and a url", File, component.Name)
+		}
+		for _, pattern := range component.Exclude {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("%s: component %q excludes %q: %w", File, component.Name, pattern, err)
+			}
+		}
+		for _, pattern := range component.Paths {
+			if _, err := path.Match(strings.TrimSuffix(pattern, "/"), ""); err != nil {
+				return fmt.Errorf("%s: component %q paths %q: %w", File, component.Name, pattern, err)
+			}
+		}
+		for _, conflict := range component.Conflicts {
+			if err := conflict.Validate(); err != nil {
+				return fmt.Errorf("%s: component %q: %w", File, component.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Load reads the lockfile of the app
+func Load(store state.Store) (*Lock, error) {
+	content, err := store.Read(state.Lockfile)
+	if errors.Is(err, state.ErrNotExist) {
+		return nil, ErrNoLockfile
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Decode(content)
+}
+
+// Decode parses and validates the content of a lockfile
+func Decode(content []byte) (*Lock, error) {
+	lock := new(Lock)
+	if err := yaml.Unmarshal(content, lock); err != nil {
+		return nil, err
+	}
+	return lock, lock.Validate()
+}
+
+// Save writes the lockfile of the app
+func (lock *Lock) Save(store state.Store) error {
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(lock); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+	return store.Write(state.Lockfile, buffer.Bytes())
+}
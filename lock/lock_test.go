@@ -0,0 +1,130 @@
+package lock
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gravel/manifest"
+	"gravel/state"
+
+	"github.com/go-git/go-billy/v6/memfs"
+)
+
+func testLock() *Lock {
+	return &Lock{
+		Base: Component{
+			Name: "web", Remote: "web", URL: "https://example.com/web", Ref: "main", Commit: "1111111",
+			Exclude:   []string{".github"},
+			Conflicts: []manifest.Conflict{{Path: "package.json", Strategy: "json-merge"}},
+		},
+		Plugins: []Component{
+			{Name: "auth", Remote: "auth", URL: "https://example.com/auth", Ref: "main", Commit: "2222222", Paths: []string{"auth/"}, Octopus: true},
+		},
+		Variables: map[string]string{"name": "demo"},
+	}
+}
+
+func TestRecord(t *testing.T) {
+	lock := testLock()
+
+	if err := lock.Record(Component{Name: "web", Remote: "web", URL: "https://example.com/web", Ref: "v2", Commit: "3333333"}); err != nil {
+		t.Fatal(err)
+	}
+	if lock.Base.Commit != "3333333" || !reflect.DeepEqual(lock.Base.Exclude, []string{".github"}) || len(lock.Base.Conflicts) != 1 {
+		t.Errorf("base = %+v, want the new commit, the exclusions and the conflict strategies kept", lock.Base)
+	}
+
+	if err := lock.Record(Component{Name: "auth", Commit: "4444444"}); err != nil {
+		t.Fatal(err)
+	}
+	if plugin := lock.Plugins[0]; plugin.Commit != "4444444" || !plugin.Octopus || !reflect.DeepEqual(plugin.Paths, []string{"auth/"}) {
+		t.Errorf("plugin = %+v, want the new commit, octopus and the paths kept", plugin)
+	}
+
+	if err := lock.Record(Component{Name: "db"}); err == nil {
+		t.Error("Record() of an unknown component succeeded")
+	}
+}
+
+func TestAddRemove(t *testing.T) {
+	lock := testLock()
+
+	if err := lock.Add(Component{Name: "auth"}); err == nil {
+		t.Error("Add() of a locked plugin succeeded")
+	}
+	if err := lock.Add(Component{Name: "db"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := lock.Remove("web"); err == nil {
+		t.Error("Remove() of the base succeeded")
+	}
+	if err := lock.Remove("auth"); err != nil {
+		t.Fatal(err)
+	}
+	if err := lock.Remove("auth"); err == nil {
+		t.Error("Remove() of an unlocked plugin succeeded")
+	}
+
+	var names []string
+	for _, component := range lock.Components() {
+		names = append(names, component.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"web", "db"}) {
+		t.Fatalf("components = %v, want web then db", names)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	for _, test := range []struct {
+		name, content, want string
+	}{
+		{
+			name:    "missing remote",
+			content: "base:\n  name: web\n  url: https://example.com/web\n",
+			want:    `component "web" needs a remote and a url`,
+		},
+		{
+			name:    "bad exclude",
+			content: "base:\n  name: web\n  remote: web\n  url: https://example.com/web\n  exclude: ['[']\n",
+			want:    `component "web" excludes "["`,
+		},
+		{
+			name:    "bad paths",
+			content: "base:\n  name: web\n  remote: web\n  url: https://example.com/web\n  paths: ['[/']\n",
+			want:    `component "web" paths "[/"`,
+		},
+		{
+			name:    "unknown strategy",
+			content: "base:\n  name: web\n  remote: web\n  url: https://example.com/web\n  conflicts: [{path: '*.json', strategy: mine}]\n",
+			want:    `component "web": conflicts.strategy must be one of`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Decode([]byte(test.content))
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Fatalf("Decode() = %v, want %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	store := state.NewWorktree(memfs.New())
+	if _, err := Load(store); !errors.Is(err, ErrNoLockfile) {
+		t.Fatalf("Load() without a lockfile = %v, want %v", err, ErrNoLockfile)
+	}
+
+	lock := testLock()
+	if err := lock.Save(store); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, lock) {
+		t.Fatalf("Load() = %+v, want %+v", loaded, lock)
+	}
+}
@@ -0,0 +1,96 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Decode reads a manifest made of one or more YAML documents separated by
+// ---. Documents are decoded one at a time as they are read, each one after
+// the first patches the manifest built so far: bases and plugins are matched
+// by name, the fields a document sets override those of the matched entry
+// and unknown entries are appended. Other sections are merged the same way,
+// so an internal overrides document can follow a public catalog
+func Decode(reader io.Reader) (*Manifest, error) {
+	decoder := yaml.NewDecoder(reader)
+	manifest := new(Manifest)
+
+	for position := 1; ; position++ {
+		var document yaml.Node
+		err := decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			if position == 1 {
+				return nil, errors.New("manifest is empty")
+			}
+			return manifest, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if err = manifest.patch(&document); err != nil {
+			return nil, fmt.Errorf("manifest document %d: %w", position, err)
+		}
+	}
+}
+
+// patch decodes a document over the manifest
+func (manifest *Manifest) patch(document *yaml.Node) error {
+	if len(document.Content) == 0 {
+		return nil
+	}
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: expected a mapping", root.Line)
+	}
+
+	// Entries are patched by name, the remaining keys decode over the
+	// current values which keeps the fields they do not set
+	rest := &yaml.Node{Kind: yaml.MappingNode, Tag: root.Tag}
+	for index := 0; index+1 < len(root.Content); index += 2 {
+		key, value := root.Content[index], root.Content[index+1]
+		var err error
+		switch key.Value {
+		case "base":
+			manifest.Base, err = patchEntries(manifest.Base, value)
+		case "plugins":
+			manifest.Plugins, err = patchEntries(manifest.Plugins, value)
+		default:
+			rest.Content = append(rest.Content, key, value)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", key.Value, err)
+		}
+	}
+	return rest.Decode(manifest)
+}
+
+// patchEntries decodes a sequence of entries over those with the same name
+func patchEntries(entries []Base, sequence *yaml.Node) ([]Base, error) {
+	if sequence.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("line %d: expected a list of entries", sequence.Line)
+	}
+
+	for _, node := range sequence.Content {
+		var named struct {
+			Name string `yaml:"name"`
+		}
+		if err := node.Decode(&named); err != nil {
+			return nil, err
+		}
+
+		index := slices.IndexFunc(entries, func(entry Base) bool { return entry.Name == named.Name })
+		if index < 0 {
+			entries = append(entries, Base{})
+			index = len(entries) - 1
+		}
+		if err := node.Decode(&entries[index]); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,66 @@
+package manifest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodePatches(t *testing.T) {
+	manifest, err := Decode(strings.NewReader(`base:
+  - name: web
+    color: blue
+    remote:
+      url: https://example.com/web
+      ref: main
+plugins:
+  - name: auth
+    remote:
+      url: https://example.com/auth
+---
+base:
+  - name: web
+    remote:
+      url: https://mirror.example.com/web
+      ref: main
+plugins:
+  - name: db
+    remote:
+      url: https://example.com/db
+aliases:
+  color: base.web.color
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(manifest.Base) != 1 {
+		t.Fatalf("bases = %v, want web patched in place", manifest.Base)
+	}
+	web := manifest.Base[0]
+	if web.Color != "blue" || web.Remote.URL != "https://mirror.example.com/web" {
+		t.Errorf("web = %+v, want the color kept and the url overridden", web)
+	}
+	if len(manifest.Plugins) != 2 || manifest.Plugins[0].Name != "auth" || manifest.Plugins[1].Name != "db" {
+		t.Errorf("plugins = %v, want db appended after auth", manifest.Plugins)
+	}
+	if manifest.Aliases["color"] != "base.web.color" {
+		t.Errorf("aliases = %v", manifest.Aliases)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for _, test := range []struct {
+		name, input, want string
+	}{
+		{name: "empty", input: "", want: "manifest is empty"},
+		{name: "not a mapping", input: "- web\n", want: "manifest document 1: line 1: expected a mapping"},
+		{name: "entries not a list", input: "base: {}\n---\nplugins: web\n", want: "manifest document 1: base: line 1: expected a list of entries"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Decode(strings.NewReader(test.input))
+			if err == nil || err.Error() != test.want {
+				t.Fatalf("Decode() = %v, want %q", err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package manifest
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ChangeKind tells how an entry differs between two manifests
+type ChangeKind string
+
+const (
+	Added    ChangeKind = "added"
+	Removed  ChangeKind = "removed"
+	Modified ChangeKind = "modified"
+)
+
+// FieldChange records the old and new value of a modified field
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// Change describes an entry added, removed or modified in a section
+type Change struct {
+	Section string        `json:"section"`
+	Name    string        `json:"name"`
+	Kind    ChangeKind    `json:"kind"`
+	Fields  []FieldChange `json:"fields,omitempty"`
+}
+
+// Diff compares the entries of two manifests, matching them by name, then
+// their variable aliases
+func Diff(old, new *Manifest) (changes []Change) {
+	changes = append(changes, diffSection("base", old.Base, new.Base)...)
+	changes = append(changes, diffSection("plugins", old.Plugins, new.Plugins)...)
+	changes = append(changes, diffAliases(old.Aliases, new.Aliases)...)
+	return
+}
+
+// diffAliases compares the aliases, sorted by name
+func diffAliases(old, new map[string]string) (changes []Change) {
+	names := slices.Collect(maps.Keys(old))
+	for name := range maps.Keys(new) {
+		if _, ok := old[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		oldTarget, inOld := old[name]
+		newTarget, inNew := new[name]
+		switch {
+		case !inNew:
+			changes = append(changes, Change{Section: "aliases", Name: name, Kind: Removed})
+		case !inOld:
+			changes = append(changes, Change{Section: "aliases", Name: name, Kind: Added})
+		case oldTarget != newTarget:
+			changes = append(changes, Change{
+				Section: "aliases",
+				Name:    name,
+				Kind:    Modified,
+				Fields:  []FieldChange{{Field: "target", Old: oldTarget, New: newTarget}},
+			})
+		}
+	}
+	return
+}
+
+func diffSection(section string, old, new []Base) (changes []Change) {
+	find := func(bases []Base, name string) *Base {
+		index := slices.IndexFunc(bases, func(base Base) bool { return base.Name == name })
+		if index < 0 {
+			return nil
+		}
+		return &bases[index]
+	}
+
+	for _, base := range old {
+		if find(new, base.Name) == nil {
+			changes = append(changes, Change{Section: section, Name: base.Name, Kind: Removed})
+		}
+	}
+
+	for _, base := range new {
+		previous := find(old, base.Name)
+		if previous == nil {
+			changes = append(changes, Change{Section: section, Name: base.Name, Kind: Added})
+			continue
+		}
+
+		fields := diffFields(previous, &base)
+		if len(fields) > 0 {
+			changes = append(changes, Change{
+				Section: section,
+				Name:    base.Name,
+				Kind:    Modified,
+				Fields:  fields,
+			})
+		}
+	}
+	return
+}
+
+func diffFields(old, new *Base) (fields []FieldChange) {
+	compare := func(field, oldValue, newValue string) {
+		if oldValue != newValue {
+			fields = append(fields, FieldChange{Field: field, Old: oldValue, New: newValue})
+		}
+	}
+
+	compare("color", old.Color, new.Color)
+	compare("description", old.Description, new.Description)
+	compare("default", strconv.FormatBool(old.Default), strconv.FormatBool(new.Default))
+	compare("remote.url", old.Remote.URL, new.Remote.URL)
+	compare("remote.name", old.Remote.Name, new.Remote.Name)
+	compare("remote.ref", old.Remote.Ref, new.Remote.Ref)
+	compare("remote.commit", old.Remote.Commit, new.Remote.Commit)
+	compare("remote.tree", old.Remote.Tree, new.Remote.Tree)
+	compare("minGravelVersion", old.MinVersion, new.MinVersion)
+	compare("conflicts", formatConflicts(old.Conflicts), formatConflicts(new.Conflicts))
+
+	// Variables are matched by name, in the order of the new entry
+	oldVariables := make(map[string]string)
+	for _, variable := range old.Variables {
+		oldVariables[variable.Name] = formatVariable(variable)
+	}
+	for _, variable := range new.Variables {
+		compare("variables."+variable.Name, oldVariables[variable.Name], formatVariable(variable))
+		delete(oldVariables, variable.Name)
+	}
+	for _, variable := range old.Variables {
+		if value, ok := oldVariables[variable.Name]; ok {
+			compare("variables."+variable.Name, value, "")
+		}
+	}
+	return
+}
+
+// formatVariable describes a declared variable, never empty
+func formatVariable(variable Variable) string {
+	value := "default=" + variable.Default
+	if variable.Description != "" {
+		value += ", description=" + variable.Description
+	}
+	return value
+}
+
+func formatConflicts(conflicts []Conflict) string {
+	var rules []string
+	for _, conflict := range conflicts {
+		rules = append(rules, conflict.Path+"="+conflict.Strategy)
+	}
+	return strings.Join(rules, ",")
+}
+
+// WriteChanges prints changes in a diff like format
+func WriteChanges(w io.Writer, changes []Change) (err error) {
+	for _, change := range changes {
+		sign := "~"
+		switch change.Kind {
+		case Added:
+			sign = "+"
+		case Removed:
+			sign = "-"
+		}
+
+		_, err = fmt.Fprintf(w, "%s %s %q\n", sign, change.Section, change.Name)
+		if err != nil {
+			return
+		}
+
+		for _, field := range change.Fields {
+			_, err = fmt.Fprintf(w, "    %s: %q -> %q\n", field.Field, field.Old, field.New)
+			if err != nil {
+				return
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,86 @@
+package manifest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	old := &Manifest{
+		Base: []Base{{
+			Name:   "web",
+			Remote: Remote{URL: "https://example.com/web", Ref: "main", Commit: "1234567"},
+			Variables: []Variable{
+				{Name: "port", Default: "8080"},
+				{Name: "host", Default: "localhost"},
+			},
+		}},
+		Plugins: []Base{
+			{Name: "auth", Remote: Remote{URL: "https://example.com/auth"}},
+			{Name: "db", Remote: Remote{URL: "https://example.com/db"}},
+		},
+		Aliases: map[string]string{"port": "base.web.port", "dsn": "plugin.db.dsn"},
+	}
+	new := &Manifest{
+		Base: []Base{{
+			Name:       "web",
+			MinVersion: "1.2.0",
+			Remote:     Remote{URL: "https://example.com/web", Ref: "main", Commit: "89abcde", Tree: "fedcba9"},
+			Variables: []Variable{
+				{Name: "port", Default: "3000", Description: "listening port"},
+				{Name: "name", Default: "app"},
+			},
+		}},
+		Plugins: []Base{
+			{Name: "auth", Remote: Remote{URL: "https://example.com/auth"}},
+			{Name: "cache", Remote: Remote{URL: "https://example.com/cache"}},
+		},
+		Aliases: map[string]string{"port": "base.web.port", "name": "base.web.name", "dsn": "plugin.cache.dsn"},
+	}
+
+	want := []Change{
+		{Section: "base", Name: "web", Kind: Modified, Fields: []FieldChange{
+			{Field: "remote.commit", Old: "1234567", New: "89abcde"},
+			{Field: "remote.tree", Old: "", New: "fedcba9"},
+			{Field: "minGravelVersion", Old: "", New: "1.2.0"},
+			{Field: "variables.port", Old: "default=8080", New: "default=3000, description=listening port"},
+			{Field: "variables.name", Old: "", New: "default=app"},
+			{Field: "variables.host", Old: "default=localhost", New: ""},
+		}},
+		{Section: "plugins", Name: "db", Kind: Removed},
+		{Section: "plugins", Name: "cache", Kind: Added},
+		{Section: "aliases", Name: "dsn", Kind: Modified, Fields: []FieldChange{
+			{Field: "target", Old: "plugin.db.dsn", New: "plugin.cache.dsn"},
+		}},
+		{Section: "aliases", Name: "name", Kind: Added},
+	}
+	if got := Diff(old, new); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Diff() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestDiffUnchanged(t *testing.T) {
+	manifest := &Manifest{
+		Base:    []Base{{Name: "web", Variables: []Variable{{Name: "port", Default: "8080"}}}},
+		Aliases: map[string]string{"port": "base.web.port"},
+	}
+	if changes := Diff(manifest, manifest); len(changes) != 0 {
+		t.Fatalf("Diff() of a manifest with itself = %+v", changes)
+	}
+}
+
+func TestWriteChanges(t *testing.T) {
+	var out strings.Builder
+	err := WriteChanges(&out, []Change{
+		{Section: "plugins", Name: "cache", Kind: Added},
+		{Section: "base", Name: "web", Kind: Modified, Fields: []FieldChange{{Field: "remote.ref", Old: "main", New: "v2"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "+ plugins \"cache\"\n~ base \"web\"\n    remote.ref: \"main\" -> \"v2\"\n"
+	if out.String() != want {
+		t.Fatalf("WriteChanges() = %q, want %q", out.String(), want)
+	}
+}
@@ -1,6 +1,14 @@
 package manifest
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"gravel/version"
+)
 
 type Validate interface {
 	Validate() error
@@ -9,31 +17,247 @@ type Validate interface {
 type Remote struct {
 	URL  string `yaml:"url"`
 	Name string `yaml:"name"`
-	Ref  string `yaml:"ref"`
+	// Ref is a branch of the remote or one of its tags, annotated or not,
+	// fetched unless Fetch.Tags is none
+	Ref string `yaml:"ref"`
+
+	// Commit and Tree pin the expected hashes of the fetched ref (optional)
+	Commit string `yaml:"commit"`
+	Tree   string `yaml:"tree"`
+
+	// Fetch tunes how the remote is fetched, unset fields fall back to the configuration
+	Fetch Fetch `yaml:"fetch"`
+}
+
+// Fetch tunes the fetch of a remote, template repositories with many tags
+// or a long history are cheaper to fetch with tags none and a depth
+type Fetch struct {
+	// Tags is following (default), all or none
+	Tags string `yaml:"tags,omitempty"`
+	// Prune deletes remote-tracking references gone from the remote
+	Prune bool `yaml:"prune,omitempty"`
+	// Depth limits the fetched history to the given number of commits, 0 fetches everything
+	Depth int `yaml:"depth,omitempty"`
+	// MaxDepth bounds how deep a shallow history is fetched when a merge
+	// needs more of it, 0 leaves the bound to gravel
+	MaxDepth int `yaml:"maxDepth,omitempty"`
+}
+
+func (fetch *Fetch) Validate() error {
+	switch fetch.Tags {
+	case "", "following", "all", "none":
+	default:
+		return fmt.Errorf("fetch.tags must be one of following, all or none")
+	}
+	if fetch.Depth < 0 {
+		return fmt.Errorf("fetch.depth cannot be negative")
+	}
+	if fetch.MaxDepth < 0 {
+		return fmt.Errorf("fetch.maxDepth cannot be negative")
+	}
+	return nil
+}
+
+// Or fills the unset fields of fetch with those of defaults
+func (fetch Fetch) Or(defaults Fetch) Fetch {
+	if fetch.Tags == "" {
+		fetch.Tags = defaults.Tags
+	}
+	fetch.Prune = fetch.Prune || defaults.Prune
+	if fetch.Depth == 0 {
+		fetch.Depth = defaults.Depth
+	}
+	if fetch.MaxDepth == 0 {
+		fetch.MaxDepth = defaults.MaxDepth
+	}
+	return fetch
 }
 
 func (remote *Remote) Validate() error {
 	if remote.URL == "" {
 		return fmt.Errorf("remote.url cannot be empty")
 	}
+	if !isHash(remote.Commit) {
+		return fmt.Errorf("remote.commit must be a hexadecimal hash of at least 7 characters")
+	}
+	if !isHash(remote.Tree) {
+		return fmt.Errorf("remote.tree must be a hexadecimal hash of at least 7 characters")
+	}
+	return remote.Fetch.Validate()
+}
+
+// ErrPinMismatch is returned by Verify when a fetched ref drifted from its pins
+var ErrPinMismatch = errors.New("does not match pinned")
+
+// Verify fails when the fetched commit or tree do not match the pinned hashes,
+// pins may be abbreviated
+func (remote *Remote) Verify(commit, tree string) error {
+	if remote.Commit != "" && !strings.HasPrefix(commit, strings.ToLower(remote.Commit)) {
+		return fmt.Errorf("%s %s: commit %s %w %s", remote.URL, remote.Ref, commit, ErrPinMismatch, remote.Commit)
+	}
+	if remote.Tree != "" && !strings.HasPrefix(tree, strings.ToLower(remote.Tree)) {
+		return fmt.Errorf("%s %s: tree %s %w %s", remote.URL, remote.Ref, tree, ErrPinMismatch, remote.Tree)
+	}
 	return nil
 }
 
+// isHash accepts empty or abbreviated hexadecimal hashes
+func isHash(value string) bool {
+	if value == "" {
+		return true
+	}
+	return len(value) >= 7 && strings.Trim(strings.ToLower(value), "0123456789abcdef") == ""
+}
+
+// Conflict declares the strategy resolving paths matching a glob that both
+// sides changed when the component is merged
+type Conflict struct {
+	Path     string `yaml:"path"`
+	Strategy string `yaml:"strategy"`
+}
+
+func (conflict *Conflict) Validate() error {
+	if conflict.Path == "" {
+		return fmt.Errorf("conflicts.path cannot be empty")
+	}
+	if _, err := path.Match(conflict.Path, ""); err != nil {
+		return fmt.Errorf("conflicts.path %q: %w", conflict.Path, err)
+	}
+
+	switch conflict.Strategy {
+	case "ours", "theirs", "union", "json-merge":
+		return nil
+	default:
+		return fmt.Errorf("conflicts.strategy must be one of ours, theirs, union or json-merge")
+	}
+}
+
 type Base struct {
 	Name  string `yaml:"name"`
 	Color string `yaml:"color"`
 
+	// Description tells what the entry scaffolds, printed by list
+	Description string `yaml:"description"`
+
+	// Default marks the base, or the plugins, init picks without prompting
+	// when run with --non-interactive
+	Default bool `yaml:"default"`
+
+	// MinVersion is the oldest gravel release able to scaffold the entry
+	MinVersion string `yaml:"minGravelVersion"`
+
 	Remote Remote `yaml:"remote"`
+
+	Conflicts []Conflict `yaml:"conflicts"`
+
+	// Provenance lists globs of the files receiving a "managed by gravel"
+	// header when they are merged in unchanged, a glob without a slash
+	// matches the file name in any directory
+	Provenance []string `yaml:"provenance"`
+
+	// Paths limits the files merged in as a plugin to those matching these
+	// globs or under these directories, the others keep the files of the app
+	Paths []string `yaml:"paths"`
+
+	// Verify lists shell commands building or testing the scaffolded app, run by init --post-checkout
+	Verify []string `yaml:"verify"`
+
+	// Variables are the template variables the entry renders
+	Variables []Variable `yaml:"variables"`
+
+	// Workspace is the editor metadata of the entry
+	Workspace Workspace `yaml:"workspace"`
+}
+
+// Compatible fails when the running gravel is older than the entry requires
+func (base *Base) Compatible() error {
+	if !version.Satisfies(version.Version, base.MinVersion) {
+		return fmt.Errorf(
+			"%s requires gravel %s or newer (running %s), please upgrade",
+			base.Name, base.MinVersion, version.Version,
+		)
+	}
+	return nil
 }
 
 func (base *Base) Validate() (err error) {
 	err = base.Remote.Validate()
+	if err != nil {
+		return
+	}
+
+	for _, conflict := range base.Conflicts {
+		err = conflict.Validate()
+		if err != nil {
+			return
+		}
+	}
+	for _, pattern := range base.Paths {
+		if _, err = path.Match(strings.TrimSuffix(pattern, "/"), ""); err != nil {
+			return fmt.Errorf("paths %q: %w", pattern, err)
+		}
+	}
+	for _, variable := range base.Variables {
+		err = variable.Validate()
+		if err != nil {
+			return
+		}
+	}
+	return base.Workspace.Validate()
+}
+
+// BranchProtection describes the protection rules applied to a branch of the
+// generated repository
+type BranchProtection struct {
+	Branch          string   `yaml:"branch"`
+	RequiredChecks  []string `yaml:"requiredChecks"`
+	RequiredReviews int      `yaml:"requiredReviews"`
+	EnforceAdmins   bool     `yaml:"enforceAdmins"`
+}
+
+func (protection *BranchProtection) Validate() error {
+	if protection.Branch == "" {
+		return fmt.Errorf("repository.protection.branch cannot be empty")
+	}
+	if protection.RequiredReviews < 0 {
+		return fmt.Errorf("repository.protection.requiredReviews cannot be negative")
+	}
+	return nil
+}
+
+// Repository holds the settings a forge provider applies to the remote
+// repository of a scaffolded app, see gravel forge apply
+type Repository struct {
+	Visibility string             `yaml:"visibility"`
+	Topics     []string           `yaml:"topics"`
+	Protection []BranchProtection `yaml:"protection"`
+}
+
+func (repository *Repository) Validate() (err error) {
+	switch repository.Visibility {
+	case "", "public", "private", "internal":
+	default:
+		return fmt.Errorf("repository.visibility must be one of public, private or internal")
+	}
+
+	for _, protection := range repository.Protection {
+		err = protection.Validate()
+		if err != nil {
+			return
+		}
+	}
 	return
 }
 
 type Manifest struct {
 	Base    []Base `yaml:"base"`
 	Plugins []Base `yaml:"plugins"`
+
+	// Repository is optional, its settings are applied by gravel forge apply
+	Repository *Repository `yaml:"repository"`
+
+	// Aliases give short names to qualified variables, port: plugin.auth.port
+	Aliases map[string]string `yaml:"aliases"`
 }
 
 func (manifest *Manifest) Validate() (err error) {
@@ -56,5 +280,60 @@ func (manifest *Manifest) Validate() (err error) {
 			return
 		}
 	}
+	if manifest.Repository != nil {
+		err = manifest.Repository.Validate()
+		if err != nil {
+			return
+		}
+	}
+
+	var defaults []string
+	for _, base := range manifest.Base {
+		if base.Default {
+			defaults = append(defaults, base.Name)
+		}
+	}
+	if len(defaults) > 1 {
+		return fmt.Errorf("only one base can be the default, not %s", strings.Join(defaults, ", "))
+	}
+	return manifest.validateAliases()
+}
+
+// ErrNoDefaultBase is returned by DefaultBase when the manifest offers
+// several bases and marks none of them as the default
+var ErrNoDefaultBase = errors.New("the manifest has no default base")
+
+// DefaultBase returns the base marked as the default, or the only one
+func (manifest *Manifest) DefaultBase() (*Base, error) {
+	for index := range manifest.Base {
+		if manifest.Base[index].Default {
+			return &manifest.Base[index], nil
+		}
+	}
+	if len(manifest.Base) == 1 {
+		return &manifest.Base[0], nil
+	}
+	return nil, ErrNoDefaultBase
+}
+
+// DefaultPlugins returns the plugins marked as defaults
+func (manifest *Manifest) DefaultPlugins() (plugins []Base) {
+	for _, plugin := range manifest.Plugins {
+		if plugin.Default {
+			plugins = append(plugins, plugin)
+		}
+	}
+	return
+}
+
+// Lookup returns the entries named names, in the order of names
+func Lookup(entries []Base, names ...string) (found []Base, err error) {
+	for _, name := range names {
+		index := slices.IndexFunc(entries, func(entry Base) bool { return entry.Name == name })
+		if index < 0 {
+			return nil, fmt.Errorf("manifest has no entry named %q", name)
+		}
+		found = append(found, entries[index])
+	}
 	return
 }
@@ -0,0 +1,161 @@
+package manifest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"gravel/version"
+)
+
+func TestValidate(t *testing.T) {
+	remote := Remote{URL: "https://example.com/web"}
+	for _, test := range []struct {
+		name     string
+		manifest Manifest
+		want     string
+	}{
+		{
+			name:     "valid",
+			manifest: Manifest{Base: []Base{{Name: "web", Remote: remote}}, Repository: &Repository{Visibility: "private", Protection: []BranchProtection{{Branch: "main", RequiredReviews: 1}}}},
+		},
+		{
+			name:     "missing url",
+			manifest: Manifest{Base: []Base{{Name: "web"}}},
+			want:     "remote.url cannot be empty",
+		},
+		{
+			name:     "unknown visibility",
+			manifest: Manifest{Repository: &Repository{Visibility: "secret"}},
+			want:     "repository.visibility must be one of",
+		},
+		{
+			name:     "unnamed protected branch",
+			manifest: Manifest{Repository: &Repository{Protection: []BranchProtection{{RequiredReviews: 1}}}},
+			want:     "repository.protection.branch cannot be empty",
+		},
+		{
+			name:     "negative reviews",
+			manifest: Manifest{Repository: &Repository{Protection: []BranchProtection{{Branch: "main", RequiredReviews: -1}}}},
+			want:     "requiredReviews cannot be negative",
+		},
+		{
+			name:     "unknown strategy",
+			manifest: Manifest{Base: []Base{{Name: "web", Remote: remote, Conflicts: []Conflict{{Path: "*.json", Strategy: "mine"}}}}},
+			want:     "conflicts.strategy must be one of",
+		},
+		{
+			name:     "short commit",
+			manifest: Manifest{Plugins: []Base{{Name: "auth", Remote: Remote{URL: remote.URL, Commit: "abc"}}}},
+			want:     "remote.commit must be a hexadecimal hash",
+		},
+		{
+			name:     "aliased variable",
+			manifest: Manifest{Base: []Base{{Name: "web", Remote: remote, Variables: []Variable{{Name: "port"}}}}, Aliases: map[string]string{"port": "base.web.port"}},
+		},
+		{
+			name:     "dotted variable",
+			manifest: Manifest{Base: []Base{{Name: "web", Remote: remote, Variables: []Variable{{Name: "app.port"}}}}},
+			want:     "cannot contain dots",
+		},
+		{
+			name:     "undeclared alias",
+			manifest: Manifest{Base: []Base{{Name: "web", Remote: remote}}, Aliases: map[string]string{"port": "base.web.port"}},
+			want:     `aliases.port: no entry declares the variable "base.web.port"`,
+		},
+		{
+			name:     "several defaults",
+			manifest: Manifest{Base: []Base{{Name: "web", Remote: remote, Default: true}, {Name: "api", Remote: remote, Default: true}}},
+			want:     "only one base can be the default, not web, api",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.manifest.Validate()
+			if test.want == "" {
+				if err != nil {
+					t.Fatalf("Validate() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Fatalf("Validate() = %v, want %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	const commit = "89abcdef0123456789abcdef0123456789abcdef"
+	const tree = "fedcba9876543210fedcba9876543210fedcba98"
+
+	for _, test := range []struct {
+		name   string
+		remote Remote
+		want   error
+	}{
+		{name: "unpinned", remote: Remote{}},
+		{name: "abbreviated", remote: Remote{Commit: "89ABCDE", Tree: "fedcba9"}},
+		{name: "commit drifted", remote: Remote{Commit: "1234567"}, want: ErrPinMismatch},
+		{name: "tree drifted", remote: Remote{Commit: commit, Tree: "0123456"}, want: ErrPinMismatch},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.remote.Verify(commit, tree); !errors.Is(err, test.want) {
+				t.Fatalf("Verify() = %v, want %v", err, test.want)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	entries := []Base{{Name: "auth"}, {Name: "db"}, {Name: "cache"}}
+
+	found, err := Lookup(entries, "cache", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 || found[0].Name != "cache" || found[1].Name != "auth" {
+		t.Fatalf("Lookup() = %v, want cache then auth", found)
+	}
+
+	if _, err = Lookup(entries, "queue"); err == nil {
+		t.Fatal("Lookup() of an unknown entry succeeded")
+	}
+}
+
+func TestDefaultBase(t *testing.T) {
+	only := &Manifest{Base: []Base{{Name: "web"}}}
+	if base, err := only.DefaultBase(); err != nil || base.Name != "web" {
+		t.Errorf("DefaultBase() of a single base = %v, %v", base, err)
+	}
+
+	marked := &Manifest{Base: []Base{{Name: "web"}, {Name: "api", Default: true}}}
+	if base, err := marked.DefaultBase(); err != nil || base.Name != "api" {
+		t.Errorf("DefaultBase() = %v, %v, want api", base, err)
+	}
+
+	unmarked := &Manifest{Base: []Base{{Name: "web"}, {Name: "api"}}}
+	if _, err := unmarked.DefaultBase(); !errors.Is(err, ErrNoDefaultBase) {
+		t.Errorf("DefaultBase() without a default = %v, want %v", err, ErrNoDefaultBase)
+	}
+}
+
+func TestCompatible(t *testing.T) {
+	defer func(running string) { version.Version = running }(version.Version)
+
+	for _, test := range []struct {
+		running, minimum string
+		want             bool
+	}{
+		{running: "v1.4.0", want: true},
+		{running: "v1.4.0", minimum: "1.4.0", want: true},
+		{running: "v1.4.0", minimum: "1.5.0"},
+		{running: "v1.5.0-rc.1", minimum: "1.5.0"},
+		{running: "dev", minimum: "9.0.0", want: true},
+	} {
+		version.Version = test.running
+		base := Base{Name: "go", MinVersion: test.minimum}
+		if err := base.Compatible(); (err == nil) != test.want {
+			t.Errorf("Compatible() of %s requiring %q = %v, want compatible %v", test.running, test.minimum, err, test.want)
+		}
+	}
+}
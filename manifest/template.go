@@ -0,0 +1,57 @@
+package manifest
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// IsTemplate tells whether arg is the URL of a template repository, with a
+// scheme or scp-like such as git@github.com:org/base-template. A
+// host/owner/repository shorthand may also name a directory, see IsShorthand
+func IsTemplate(arg string) bool {
+	return strings.Contains(arg, "://") || strings.HasPrefix(arg, "git@")
+}
+
+// IsShorthand tells whether arg is a host/owner/repository shorthand such as
+// github.com/org/base-template
+func IsShorthand(arg string) bool {
+	segments := strings.Split(arg, "/")
+	return len(segments) >= 3 &&
+		strings.Contains(segments[0], ".") &&
+		!strings.HasPrefix(segments[0], ".")
+}
+
+// FromTemplate builds the implicit manifest of a template repository, its
+// only base is the repository. A branch may follow an @, the default branch
+// of the remote is used otherwise
+func FromTemplate(template string) (*Manifest, error) {
+	if !IsTemplate(template) && !IsShorthand(template) {
+		return nil, fmt.Errorf("%q is not a template repository, expected host/owner/repository or a URL", template)
+	}
+
+	url, ref := template, ""
+	if slash := strings.LastIndex(template, "/"); slash >= 0 {
+		if at := strings.LastIndex(template[slash:], "@"); at >= 0 {
+			url, ref = template[:slash+at], template[slash+at+1:]
+		}
+	}
+
+	if !strings.Contains(url, "://") && !strings.HasPrefix(url, "git@") {
+		url = "https://" + strings.TrimSuffix(url, ".git") + ".git"
+	}
+
+	name := strings.TrimSuffix(path.Base(url), ".git")
+
+	manifest := &Manifest{
+		Base: []Base{{
+			Name: name,
+			Remote: Remote{
+				URL:  url,
+				Name: "origin",
+				Ref:  ref,
+			},
+		}},
+	}
+	return manifest, manifest.Validate()
+}
@@ -0,0 +1,51 @@
+package manifest
+
+import "testing"
+
+func TestIsTemplate(t *testing.T) {
+	for _, test := range []struct {
+		arg                 string
+		template, shorthand bool
+	}{
+		{arg: "https://github.com/org/base-template", template: true},
+		{arg: "git@github.com:org/base-template.git", template: true},
+		{arg: "github.com/org/base-template", shorthand: true},
+		{arg: "github.com/org/base-template@v1", shorthand: true},
+		// Directories may read like a shorthand, init checks whether they exist
+		{arg: "my.app/src/service", shorthand: true},
+		{arg: "./org/base-template"},
+		{arg: "../apps/new"},
+		{arg: "apps/new"},
+		{arg: "base-template"},
+	} {
+		if got := IsTemplate(test.arg); got != test.template {
+			t.Errorf("IsTemplate(%q) = %v, want %v", test.arg, got, test.template)
+		}
+		if got := IsShorthand(test.arg); got != test.shorthand {
+			t.Errorf("IsShorthand(%q) = %v, want %v", test.arg, got, test.shorthand)
+		}
+	}
+}
+
+func TestFromTemplate(t *testing.T) {
+	for _, test := range []struct {
+		template, url, ref, name string
+	}{
+		{template: "github.com/org/base-template", url: "https://github.com/org/base-template.git", name: "base-template"},
+		{template: "github.com/org/base-template.git@v1", url: "https://github.com/org/base-template.git", ref: "v1", name: "base-template"},
+		{template: "https://gitlab.com/org/base@main", url: "https://gitlab.com/org/base", ref: "main", name: "base"},
+	} {
+		decoded, err := FromTemplate(test.template)
+		if err != nil {
+			t.Fatalf("FromTemplate(%q): %v", test.template, err)
+		}
+		base := decoded.Base[0]
+		if base.Name != test.name || base.Remote.URL != test.url || base.Remote.Ref != test.ref {
+			t.Errorf("FromTemplate(%q) = %s %s@%s, want %s %s@%s", test.template, base.Name, base.Remote.URL, base.Remote.Ref, test.name, test.url, test.ref)
+		}
+	}
+
+	if _, err := FromTemplate("apps/new"); err == nil {
+		t.Error("FromTemplate() of a directory succeeded")
+	}
+}
@@ -0,0 +1,70 @@
+package manifest
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"gravel/variables"
+)
+
+// Variable declares a template variable of an entry, templates reference it
+// as [[ name ]] or, when several entries declare the same name, by its
+// qualified name [[ plugin.<entry>.<name> ]]
+type Variable struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Default     string `yaml:"default"`
+}
+
+func (variable *Variable) Validate() error {
+	if variable.Name == "" {
+		return fmt.Errorf("variables.name cannot be empty")
+	}
+	if strings.Contains(variable.Name, ".") {
+		return fmt.Errorf("variables.name %q cannot contain dots, they separate namespaces", variable.Name)
+	}
+	return nil
+}
+
+// Declarations returns the variables declared by the base and the plugins,
+// qualified by their entry
+func Declarations(base *Base, plugins []Base) (declarations []variables.Declaration) {
+	declare := func(namespace string, entry *Base) {
+		for _, variable := range entry.Variables {
+			declarations = append(declarations, variables.Declaration{
+				Name:        variables.Qualify(namespace, entry.Name, variable.Name),
+				Short:       variable.Name,
+				Description: variable.Description,
+				Default:     variable.Default,
+			})
+		}
+	}
+
+	declare(variables.BaseNamespace, base)
+	for index := range plugins {
+		declare(variables.PluginNamespace, &plugins[index])
+	}
+	return
+}
+
+// validateAliases fails when an alias targets a variable no entry declares
+func (manifest *Manifest) validateAliases() error {
+	declared := make(map[string]bool)
+	for index := range manifest.Base {
+		for _, declaration := range Declarations(&manifest.Base[index], nil) {
+			declared[declaration.Name] = true
+		}
+	}
+	for _, declaration := range Declarations(&Base{}, manifest.Plugins) {
+		declared[declaration.Name] = true
+	}
+
+	for _, alias := range slices.Sorted(maps.Keys(manifest.Aliases)) {
+		if target := manifest.Aliases[alias]; !declared[target] {
+			return fmt.Errorf("aliases.%s: no entry declares the variable %q", alias, target)
+		}
+	}
+	return nil
+}
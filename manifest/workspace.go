@@ -0,0 +1,29 @@
+package manifest
+
+import "fmt"
+
+// Workspace is the editor metadata an entry contributes to the scaffolded
+// app, written by init --emit-workspace
+type Workspace struct {
+	// Extensions are the identifiers of the recommended VS Code extensions
+	Extensions []string `yaml:"extensions"`
+	// Settings are VS Code settings, later entries override earlier ones
+	Settings map[string]any `yaml:"settings"`
+	// Run are written as JetBrains shell run configurations
+	Run []RunConfiguration `yaml:"run"`
+}
+
+// RunConfiguration is a named shell command run from the root of the app
+type RunConfiguration struct {
+	Name    string `yaml:"name"`
+	Command string `yaml:"command"`
+}
+
+func (workspace *Workspace) Validate() error {
+	for _, run := range workspace.Run {
+		if run.Name == "" || run.Command == "" {
+			return fmt.Errorf("workspace.run entries need a name and a command")
+		}
+	}
+	return nil
+}
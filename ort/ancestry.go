@@ -0,0 +1,147 @@
+package ort
+
+import (
+	"errors"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+	commitgraphfmt "github.com/go-git/go-git/v6/plumbing/format/commitgraph"
+	"github.com/go-git/go-git/v6/plumbing/object/commitgraph"
+)
+
+// ErrShallowHistory is returned when the history of a shallow repository
+// is too short to tell whether a commit contains another
+var ErrShallowHistory = errors.New("the shallow history is too short to tell the ancestry of the commits, fetch more of it")
+
+const (
+	// DefaultMaxDepth bounds the history fetched to deepen a shallow repository
+	DefaultMaxDepth = 1000
+	// deepenDepth is the first depth asked to deepen a shallow repository
+	deepenDepth = 100
+)
+
+// maxDepth returns MaxDepth or its default
+func (opts MergeOptions) maxDepth() int {
+	if opts.MaxDepth > 0 {
+		return opts.MaxDepth
+	}
+	return DefaultMaxDepth
+}
+
+// ancestry answers ancestry queries, with the generation numbers of the
+// commit-graph file when git wrote one: a commit never reaches a commit of
+// a greater generation, so the walk stops as soon as it gets below the
+// generation of the commit looked for. Commits newer than the graph have
+// an infinite generation and are walked as usual
+type ancestry struct {
+	graph commitgraphfmt.Index
+	nodes commitgraph.CommitNodeIndex
+	// shallow lists the commits whose parents are missing, if any
+	shallow []plumbing.Hash
+}
+
+// newAncestry opens the commit-graph of r, if any, close releases it
+func newAncestry(r *git.Repository) *ancestry {
+	// Not having a shallow list is fine, the history is complete
+	shallow, _ := r.Storer.Shallow()
+
+	// A missing or unreadable commit-graph only makes the walk slower
+	var graph commitgraphfmt.Index
+	if fs := gitDir(r); fs != nil {
+		graph, _ = commitgraphfmt.OpenChainOrFileIndex(fs)
+	}
+
+	return &ancestry{
+		graph:   graph,
+		nodes:   commitgraph.NewGraphCommitNodeIndex(graph, r.Storer),
+		shallow: shallow,
+	}
+}
+
+func (a *ancestry) close() error {
+	if a.graph == nil {
+		return nil
+	}
+	return a.graph.Close()
+}
+
+// isAncestor reports whether ancestor is reachable from commit, commit
+// included. When the walk reaches the missing parents of the shallow
+// commits without finding ancestor, the answer lies in the missing history
+// and ErrShallowHistory is returned
+func (a *ancestry) isAncestor(ancestor, commit plumbing.Hash) (bool, error) {
+	if ancestor == commit {
+		return true, nil
+	}
+
+	target, err := a.nodes.Get(ancestor)
+	if err != nil {
+		return false, err
+	}
+	generation := target.Generation()
+
+	start, err := a.nodes.Get(commit)
+	if err != nil {
+		return false, err
+	}
+
+	truncated := false
+	seen := map[plumbing.Hash]struct{}{commit: {}}
+	queue := []commitgraph.CommitNode{start}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+
+		for index, parent := range node.ParentHashes() {
+			if parent == ancestor {
+				return true, nil
+			}
+			if _, ok := seen[parent]; ok {
+				continue
+			}
+			seen[parent] = struct{}{}
+
+			var parentNode commitgraph.CommitNode
+			parentNode, err = node.ParentNode(index)
+			// The parents of the shallow commits are missing. The shallow
+			// list itself is not trusted to end the walk, go-git does not
+			// update it when a fetch deepens the history
+			if errors.Is(err, plumbing.ErrObjectNotFound) && len(a.shallow) > 0 {
+				truncated = true
+				continue
+			}
+			if err != nil {
+				return false, err
+			}
+			// Below the generation of ancestor, nothing can reach it anymore
+			if parentNode.Generation() < generation {
+				continue
+			}
+			queue = append(queue, parentNode)
+		}
+	}
+	if truncated {
+		return false, ErrShallowHistory
+	}
+	return false, nil
+}
+
+// isFastForward reports whether newHash contains old, so that moving HEAD
+// from old to newHash loses nothing. A shallow history too short to tell is
+// deepened with opts.Deepen, doubling the depth up to opts.MaxDepth
+func isFastForward(r *git.Repository, old, newHash plumbing.Hash, opts MergeOptions) (bool, error) {
+	depth := 0
+	for {
+		a := newAncestry(r)
+		ff, err := a.isAncestor(old, newHash)
+		_ = a.close()
+		if !errors.Is(err, ErrShallowHistory) || opts.Deepen == nil || depth >= opts.maxDepth() {
+			return ff, err
+		}
+
+		depth = min(max(2*depth, deepenDepth), opts.maxDepth())
+		if err = opts.Deepen(depth); err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+			return false, err
+		}
+	}
+}
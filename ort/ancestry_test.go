@@ -0,0 +1,111 @@
+package ort
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+)
+
+// copyObjects stores the objects of from into r, but skipped
+func copyObjects(t *testing.T, from, r *git.Repository, skipped ...plumbing.Hash) {
+	t.Helper()
+	objects, err := from.Storer.IterEncodedObjects(plumbing.AnyObject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = objects.ForEach(func(object plumbing.EncodedObject) error {
+		if slices.Contains(skipped, object.Hash()) {
+			return nil
+		}
+		_, err := r.Storer.SetEncodedObject(object)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIsAncestor(t *testing.T) {
+	r := newTestRepository(t)
+	root := commitFiles(t, r, "root", map[string]string{"README": "root\n"})
+	left := commitFiles(t, r, "left", map[string]string{"README": "left\n"}, root)
+	right := commitFiles(t, r, "right", map[string]string{"README": "right\n"}, root)
+	merge := commitFiles(t, r, "merge", map[string]string{"README": "merge\n"}, left, right)
+
+	a := newAncestry(r)
+	defer func() { _ = a.close() }()
+	for _, test := range []struct {
+		name             string
+		ancestor, commit plumbing.Hash
+		want             bool
+	}{
+		{name: "itself", ancestor: left, commit: left, want: true},
+		{name: "parent", ancestor: root, commit: left, want: true},
+		{name: "second parent", ancestor: right, commit: merge, want: true},
+		{name: "grandparent", ancestor: root, commit: merge, want: true},
+		{name: "child", ancestor: merge, commit: root},
+		{name: "sibling", ancestor: right, commit: left},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := a.isAncestor(test.ancestor, test.commit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("isAncestor() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestIsFastForwardShallow(t *testing.T) {
+	full := newTestRepository(t)
+	root := commitFiles(t, full, "root", map[string]string{"README": "root\n"})
+	parent := commitFiles(t, full, "parent", map[string]string{"README": "parent\n"}, root)
+	mid := commitFiles(t, full, "mid", map[string]string{"README": "mid\n"}, parent)
+	tip := commitFiles(t, full, "tip", map[string]string{"README": "tip\n"}, mid)
+
+	// r has root, where HEAD is, but not parent of its shallow commit mid
+	r := newTestRepository(t)
+	copyObjects(t, full, r, parent)
+	if err := r.Storer.SetShallow([]plumbing.Hash{mid}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := isFastForward(r, root, tip, MergeOptions{}); !errors.Is(err, ErrShallowHistory) {
+		t.Fatalf("isFastForward() error = %v, want %v", err, ErrShallowHistory)
+	}
+
+	var depths []int
+	opts := MergeOptions{MaxDepth: 300, Deepen: func(depth int) error {
+		depths = append(depths, depth)
+		return git.NoErrAlreadyUpToDate
+	}}
+	if _, err := isFastForward(r, root, tip, opts); !errors.Is(err, ErrShallowHistory) {
+		t.Fatalf("isFastForward() error = %v, want %v", err, ErrShallowHistory)
+	}
+	if want := []int{100, 200, 300}; !slices.Equal(depths, want) {
+		t.Errorf("deepened to %v, want %v", depths, want)
+	}
+
+	// Deepening fetches parent, which leads to root
+	depths = nil
+	opts.Deepen = func(depth int) error {
+		depths = append(depths, depth)
+		copyObjects(t, full, r)
+		return nil
+	}
+	ff, err := isFastForward(r, root, tip, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ff {
+		t.Error("isFastForward() = false, want true")
+	}
+	if want := []int{100}; !slices.Equal(depths, want) {
+		t.Errorf("deepened to %v, want %v", depths, want)
+	}
+}
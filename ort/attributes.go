@@ -0,0 +1,70 @@
+package ort
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-billy/v6"
+	"github.com/go-git/go-git/v6/plumbing/format/gitattributes"
+)
+
+// mergeAttribute is the gitattributes attribute naming the merge driver of a path
+const mergeAttribute = "merge"
+
+// markerSizeAttribute is the gitattributes attribute setting the length of
+// the conflict markers of a path
+const markerSizeAttribute = "conflict-marker-size"
+
+// attributeStrategies reads the merge drivers and line endings declared by
+// the .gitattributes files of the worktree, which holds our side when
+// merging like git
+func attributeStrategies(fs billy.Filesystem) (gitattributes.Matcher, error) {
+	patterns, err := gitattributes.ReadPatterns(fs, nil)
+	if err != nil {
+		return nil, err
+	}
+	return gitattributes.NewMatcher(patterns), nil
+}
+
+// attributeStrategy returns the strategy named by the merge attribute of
+// filepath, such as git's built-in merge=union driver
+func attributeStrategy(matcher gitattributes.Matcher, filepath string) ConflictStrategy {
+	attributes, matched := matcher.Match(strings.Split(filepath, "/"), []string{mergeAttribute})
+	if !matched {
+		return ""
+	}
+
+	attribute, ok := attributes[mergeAttribute]
+	if !ok || !attribute.IsValueSet() {
+		return ""
+	}
+
+	switch strategy := ConflictStrategy(attribute.Value()); strategy {
+	case StrategyOurs, StrategyTheirs, StrategyUnion, StrategyJSONMerge:
+		return strategy
+	default:
+		return ""
+	}
+}
+
+// attributeMarkerSize returns the conflict-marker-size attribute of
+// filepath, fallback when it is not set to a positive number
+func attributeMarkerSize(matcher gitattributes.Matcher, filepath string, fallback int) int {
+	if matcher == nil {
+		return fallback
+	}
+	attributes, matched := matcher.Match(strings.Split(filepath, "/"), []string{markerSizeAttribute})
+	if !matched {
+		return fallback
+	}
+
+	attribute, ok := attributes[markerSizeAttribute]
+	if !ok || !attribute.IsValueSet() {
+		return fallback
+	}
+	size, err := strconv.Atoi(attribute.Value())
+	if err != nil || size <= 0 {
+		return fallback
+	}
+	return size
+}
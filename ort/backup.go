@@ -0,0 +1,34 @@
+package ort
+
+import (
+	"github.com/go-git/go-git/v6"
+)
+
+// backupSuffix is appended to the paths of the backups, like git mergetool
+const backupSuffix = ".orig"
+
+// backupConflicts writes the content of ours of each conflict next to it
+// with backupSuffix, unstaged. The worktree matched ours before the merge
+func backupConflicts(w *git.Worktree, conflicts []conflictEntry) error {
+	for _, conflict := range conflicts {
+		if conflict.ours == nil {
+			continue
+		}
+		if err := backupFile(w, conflict); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func backupFile(w *git.Worktree, conflict conflictEntry) error {
+	reader, err := conflict.ours.Reader()
+	if err != nil {
+		return err
+	}
+	err = writeAtomic(w, conflict.path+backupSuffix, reader, conflict.ours.Mode)
+	if closeErr := reader.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
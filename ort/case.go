@@ -0,0 +1,107 @@
+package ort
+
+import (
+	"errors"
+	"io"
+	"strings"
+
+	"gravel/i18n"
+
+	"github.com/go-git/go-git/v6/plumbing/filemode"
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+// caseCollisions maps the paths their side adds to the file or directory of
+// our tree they differ from only in case. A case-insensitive filesystem, the
+// default on macOS and Windows, would store both in the same file. Paths
+// their side renames or deletes away do not collide, a case-only rename is
+// not a collision
+func caseCollisions(ourTree *object.Tree, theirChanges object.Changes) (map[string]string, error) {
+	ours := make(map[string]string)
+	walker := object.NewTreeWalker(ourTree, true, nil)
+	defer walker.Close()
+	for {
+		name, _, err := walker.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		ours[strings.ToLower(name)] = name
+	}
+
+	removed := make(map[string]bool)
+	for _, change := range theirChanges {
+		if change.From.Name != "" && change.From.Name != change.To.Name {
+			removed[change.From.Name] = true
+		}
+	}
+
+	collisions := make(map[string]string)
+	for _, change := range theirChanges {
+		added := change.To.Name
+		if added == "" || added == change.From.Name {
+			continue
+		}
+
+		// Parent directories collide too, Docs/a.md lands in docs/
+		for prefix := range pathPrefixes(added) {
+			existing, ok := ours[strings.ToLower(prefix)]
+			if ok && existing != prefix && !removed[existing] {
+				collisions[added] = existing
+				break
+			}
+		}
+	}
+	return collisions, nil
+}
+
+// pathPrefixes yields the directories of filepath then filepath itself
+func pathPrefixes(filepath string) func(yield func(string) bool) {
+	return func(yield func(string) bool) {
+		for index, r := range filepath {
+			if r == '/' && !yield(filepath[:index]) {
+				return
+			}
+		}
+		yield(filepath)
+	}
+}
+
+// theirTarget is the path their change writes to, empty for a deletion
+func theirTarget(theirs *object.Change) string {
+	if theirs == nil {
+		return ""
+	}
+	return theirs.To.Name
+}
+
+// caseConflicts records a collision: our file stays in the worktree, their
+// file only lives in the index until the user renames one of them
+func caseConflicts(ourTree *object.Tree, theirs *object.Change, existing string) ([]conflictEntry, error) {
+	added := theirs.To.Name
+	// The colliding directory of added, or added itself
+	colliding := added[:min(len(existing), len(added))]
+	conflicts := []conflictEntry{{
+		path:   added,
+		theirs: entryFile(theirs.To),
+		reason: i18n.Tf(
+			"CONFLICT (case collision): %s and %s differ only in case, rename one of them",
+			colliding, existing,
+		),
+	}}
+
+	entry, err := ourTree.FindEntry(existing)
+	if err != nil {
+		return nil, err
+	}
+	// A directory has no stage, its files stay as they are
+	if entry.Mode != filemode.Dir {
+		conflicts = append(conflicts, conflictEntry{
+			path: existing,
+			ours: &object.File{Name: existing, Mode: entry.Mode, Blob: object.Blob{Hash: entry.Hash}},
+		})
+	}
+	return conflicts, nil
+}
@@ -15,6 +15,7 @@ import (
 	"slices"
 	"sort"
 	"strings"
+	"unicode"
 
 	"github.com/epiclabs-io/diff3/linereader"
 )
@@ -24,10 +25,32 @@ const (
 	ConflictOurMarker = "<<<<<<<"
 	// Git conflict marker that split our changes from their changes
 	ConflictSplitMarker = "======="
+	// ConflictBaseMarker introduces the base lines of a diff3 style conflict
+	ConflictBaseMarker = "|||||||"
 	// Git conflict marker that indicates their changes
 	ConflictTheirMarker = ">>>>>>>"
+	// DefaultMarkerSize is the length of the conflict markers, like git's
+	// conflict-marker-size attribute
+	DefaultMarkerSize = 7
 )
 
+// Marker returns marker, one of the conflict markers, with size characters,
+// DefaultMarkerSize when size is not positive
+func Marker(marker string, size int) string {
+	if size <= 0 {
+		size = DefaultMarkerSize
+	}
+	return strings.Repeat(marker[:1], size)
+}
+
+// IsMarker reports whether line is marker with size characters, followed by
+// a label or nothing: longer or shorter runs of the character are content
+func IsMarker(line, marker string, size int) bool {
+	marker = Marker(marker, size)
+	rest, ok := strings.CutPrefix(line, marker)
+	return ok && (rest == "" || rest[0] == ' ' || rest[0] == '\r' || rest[0] == '\n')
+}
+
 type candidate struct {
 	file1index int
 	file2index int
@@ -423,9 +446,22 @@ type Diff3MergeResult struct {
 // construct the merged file; the returned result alternates
 // between 'ok' and 'conflict' blocks.
 func Diff3Merge(a, o, b []string, excludeFalseConflicts bool) []*Diff3MergeResult {
+	return diff3Merge(a, o, b, excludeFalseConflicts, WhitespaceExact)
+}
+
+// diff3Merge is Diff3Merge comparing lines after normalizing their
+// whitespace. Lines only one side changed in whitespace count as unchanged,
+// the common lines are then taken from a so that its whitespace wins
+func diff3Merge(a, o, b []string, excludeFalseConflicts bool, whitespace Whitespace) []*Diff3MergeResult {
 	var result []*Diff3MergeResult
 	files := [][]string{a, o, b}
-	indices := diff3MergeIndices(a, o, b)
+
+	na, no, nb := a, o, b
+	if whitespace != WhitespaceExact {
+		na, no, nb = whitespace.normalizeAll(a), whitespace.normalizeAll(o), whitespace.normalizeAll(b)
+		files[1] = commonLines(a, na, no)
+	}
+	indices := diff3MergeIndices(na, no, nb)
 
 	var okLines []string
 	flushOk := func() {
@@ -448,7 +484,7 @@ func Diff3Merge(a, o, b []string, excludeFalseConflicts bool) []*Diff3MergeResul
 		aoff := rec[1]
 		boff := rec[5]
 		for j := 0; j < rec[2]; j++ {
-			if a[j+aoff] != b[j+boff] {
+			if na[j+aoff] != nb[j+boff] {
 				return true
 			}
 		}
@@ -483,63 +519,246 @@ func Diff3Merge(a, o, b []string, excludeFalseConflicts bool) []*Diff3MergeResul
 	return result
 }
 
+// Whitespace selects how whitespace differences are compared
+type Whitespace int
+
+const (
+	// WhitespaceExact compares lines as they are
+	WhitespaceExact Whitespace = iota
+	// IgnoreSpaceChange ignores whitespace at the end of lines and treats
+	// runs of whitespace as equal, like -Xignore-space-change
+	IgnoreSpaceChange
+	// IgnoreAllSpace ignores every whitespace, like -Xignore-all-space
+	IgnoreAllSpace
+)
+
+func (whitespace Whitespace) normalize(line string) string {
+	switch whitespace {
+	case IgnoreSpaceChange:
+		var builder strings.Builder
+		space := false
+		for _, r := range strings.TrimRightFunc(line, unicode.IsSpace) {
+			if unicode.IsSpace(r) {
+				space = true
+				continue
+			}
+			if space {
+				builder.WriteByte(' ')
+				space = false
+			}
+			builder.WriteRune(r)
+		}
+		return builder.String()
+	case IgnoreAllSpace:
+		return strings.Join(strings.FieldsFunc(line, unicode.IsSpace), "")
+	default:
+		return line
+	}
+}
+
+func (whitespace Whitespace) normalizeAll(lines []string) []string {
+	normalized := make([]string, len(lines))
+	for index, line := range lines {
+		normalized[index] = whitespace.normalize(line)
+	}
+	return normalized
+}
+
+// commonLines returns o with the lines a kept, whitespace aside, replaced by
+// their version in a. na and no are the normalized a and o
+func commonLines(a, na, no []string) []string {
+	common := slices.Clone(no)
+	oIndex, aIndex := 0, 0
+	keep := func(until int) {
+		for ; oIndex < until; oIndex, aIndex = oIndex+1, aIndex+1 {
+			common[oIndex] = a[aIndex]
+		}
+	}
+	for _, change := range diffIndices(no, na) {
+		keep(change.file1[0])
+		oIndex += change.file1[1]
+		aIndex += change.file2[1]
+	}
+	keep(len(no))
+	return common
+}
+
 // MergeResult describes a merge result
 type MergeResult struct {
 	Conflicts bool      // Conflict indicates if there is any merge conflict
 	Result    io.Reader // returns a reader that contains the merge result
+	Resolved  int       // Resolved counts the conflicting hunks Favor resolved
 }
 
-func addConflictMarkers(lines, conflictA, conflictB []string, labelA, labelB string) []string {
-	lines = append(lines, fmt.Sprintf("%s %s", ConflictOurMarker, labelA))
+func addConflictMarkers(lines, conflictA, conflictO, conflictB []string, opts Options) []string {
+	lines = append(lines, fmt.Sprintf("%s %s", Marker(ConflictOurMarker, opts.MarkerSize), opts.LabelA))
 	lines = append(lines, conflictA...)
-	lines = append(lines, ConflictSplitMarker)
+	if opts.Style == StyleDiff3 || opts.Style == StyleZDiff3 {
+		lines = append(lines, fmt.Sprintf("%s %s", Marker(ConflictBaseMarker, opts.MarkerSize), opts.LabelO))
+		lines = append(lines, conflictO...)
+	}
+	lines = append(lines, Marker(ConflictSplitMarker, opts.MarkerSize))
 	lines = append(lines, conflictB...)
-	lines = append(lines, fmt.Sprintf("%s %s", ConflictTheirMarker, labelB))
+	lines = append(lines, fmt.Sprintf("%s %s", Marker(ConflictTheirMarker, opts.MarkerSize), opts.LabelB))
 	return lines
 }
 
+// Favor selects how conflicting hunks are resolved
+type Favor int
+
+const (
+	// FavorNone leaves conflicting hunks surrounded by conflict markers
+	FavorNone Favor = iota
+	// FavorOurs resolves conflicting hunks with our lines
+	FavorOurs
+	// FavorTheirs resolves conflicting hunks with their lines
+	FavorTheirs
+	// FavorUnion resolves conflicting hunks with our lines followed by their lines
+	FavorUnion
+)
+
+// ConflictStyle selects the markers written around conflicting hunks
+type ConflictStyle int
+
+const (
+	// StyleMerge writes our and their lines
+	StyleMerge ConflictStyle = iota
+	// StyleDiff3 also writes the base lines between ||||||| and =======,
+	// conflicts are not narrowed down so the base stays meaningful
+	StyleDiff3
+	// StyleZDiff3 is StyleDiff3 with the lines both sides share at the start
+	// and the end of a conflict moved out of it
+	StyleZDiff3
+)
+
+// Options tunes how Merge produces its result
+type Options struct {
+	Detailed bool          // Detailed narrows conflicts down to the lines that actually differ
+	LabelA   string        // LabelA is written after the marker of our side
+	LabelO   string        // LabelO is written after the marker of the base in the diff3 style
+	LabelB   string        // LabelB is written after the marker of their side
+	Favor    Favor         // Favor resolves conflicting hunks instead of emitting markers
+	Style    ConflictStyle // Style selects the conflict markers
+	// Whitespace resolves the hunks differing only in whitespace
+	Whitespace Whitespace
+	// MarkerSize is the length of the conflict markers, 0 selects
+	// DefaultMarkerSize. Files holding marker-like lines need longer ones
+	MarkerSize int
+}
+
 // Merge takes three streams and returns the merged result
 func Merge(a, o, b io.Reader, detailed bool, labelA string, labelB string) (*MergeResult, error) {
-	al, err := linereader.GetLines(a)
+	return MergeWithOptions(a, o, b, Options{Detailed: detailed, LabelA: labelA, LabelB: labelB})
+}
+
+// MergeWithOptions takes three streams and returns the merged result tuned by opts
+func MergeWithOptions(a, o, b io.Reader, opts Options) (*MergeResult, error) {
+	ta, to, tb := &tailReader{Reader: a}, &tailReader{Reader: o}, &tailReader{Reader: b}
+	al, err := linereader.GetLines(ta)
 	if err != nil {
 		return nil, err
 	}
-	ol, err := linereader.GetLines(o)
+	ol, err := linereader.GetLines(to)
 	if err != nil {
 		return nil, err
 	}
-	bl, err := linereader.GetLines(b)
+	bl, err := linereader.GetLines(tb)
 	if err != nil {
 		return nil, err
 	}
 
-	merger := Diff3Merge(al, ol, bl, true)
+	merger := diff3Merge(al, ol, bl, true, opts.Whitespace)
 	conflicts := false
+	resolved := 0
 	var lines []string
+
+	resolve := func(conflictA, conflictO, conflictB []string) {
+		switch opts.Favor {
+		case FavorOurs:
+			resolved++
+			lines = append(lines, conflictA...)
+		case FavorTheirs:
+			resolved++
+			lines = append(lines, conflictB...)
+		case FavorUnion:
+			resolved++
+			lines = append(lines, conflictA...)
+			lines = append(lines, conflictB...)
+		default:
+			conflicts = true
+			lines = addConflictMarkers(lines, conflictA, conflictO, conflictB, opts)
+		}
+	}
+
 	for i := 0; i < len(merger); i++ {
 		item := merger[i]
 		if item.ok != nil {
 			lines = append(lines, item.ok...)
 		} else {
-			if detailed {
+			if opts.Style == StyleZDiff3 {
+				a, o, b := item.conflict.a, item.conflict.o, item.conflict.b
+				prefix := 0
+				for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+					prefix++
+				}
+				suffix := 0
+				for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+					a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+					suffix++
+				}
+
+				lines = append(lines, a[:prefix]...)
+				// Both sides made the same change, nothing is left to conflict
+				if prefix < len(a) || prefix < len(b) {
+					resolve(a[prefix:len(a)-suffix], o, b[prefix:len(b)-suffix])
+					lines = append(lines, a[len(a)-suffix:]...)
+				}
+			} else if opts.Detailed && opts.Style == StyleMerge {
 				c := diffComm(item.conflict.a, item.conflict.b)
 				for j := 0; j < len(c); j++ {
 					inner := c[j]
 					if inner.common != nil {
 						lines = append(lines, inner.common...)
 					} else {
-						conflicts = true
-						lines = addConflictMarkers(lines, inner.file1, inner.file2, labelA, labelB)
+						resolve(inner.file1, nil, inner.file2)
 					}
 				}
 			} else {
-				conflicts = true
-				lines = addConflictMarkers(lines, item.conflict.a, item.conflict.b, labelA, labelB)
+				resolve(item.conflict.a, item.conflict.o, item.conflict.b)
 			}
 		}
 	}
+	result := strings.Join(lines, "\n")
+	// The final newline merges like a line, markers always end with one
+	newline := ta.newline()
+	if ta.newline() == to.newline() {
+		newline = tb.newline()
+	}
+	if len(lines) > 0 && (newline || conflicts && IsMarker(lines[len(lines)-1], ConflictTheirMarker, opts.MarkerSize)) {
+		result += "\n"
+	}
 	return &MergeResult{
 		Conflicts: conflicts,
-		Result:    strings.NewReader(strings.Join(lines, "\n")),
+		Result:    strings.NewReader(result),
+		Resolved:  resolved,
 	}, nil
 }
+
+// tailReader remembers the last byte read, to tell whether the file ends
+// with a newline once split into lines
+type tailReader struct {
+	io.Reader
+	last byte
+}
+
+func (reader *tailReader) Read(p []byte) (int, error) {
+	n, err := reader.Reader.Read(p)
+	if n > 0 {
+		reader.last = p[n-1]
+	}
+	return n, err
+}
+
+func (reader *tailReader) newline() bool {
+	return reader.last == '\n'
+}
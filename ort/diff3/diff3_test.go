@@ -0,0 +1,213 @@
+package diff3
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+// merge runs MergeWithOptions on strings and returns the result
+func merge(t *testing.T, a, o, b string, opts Options) (string, *MergeResult) {
+	t.Helper()
+	result, err := MergeWithOptions(strings.NewReader(a), strings.NewReader(o), strings.NewReader(b), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged, err := io.ReadAll(result.Result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(merged), result
+}
+
+func TestConflictStyles(t *testing.T) {
+	const (
+		base   = "one\ntwo\nthree\n"
+		ours   = "one\nTWO\nthree\n"
+		theirs = "one\nDeux\nthree\n"
+	)
+	for _, test := range []struct {
+		name  string
+		style ConflictStyle
+		want  string
+	}{
+		{name: "merge", style: StyleMerge, want: "one\n<<<<<<< ours\nTWO\n=======\nDeux\n>>>>>>> theirs\nthree\n"},
+		{name: "diff3", style: StyleDiff3, want: "one\n<<<<<<< ours\nTWO\n||||||| base\ntwo\n=======\nDeux\n>>>>>>> theirs\nthree\n"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, result := merge(t, ours, base, theirs, Options{LabelA: "ours", LabelO: "base", LabelB: "theirs", Style: test.style})
+			if !result.Conflicts {
+				t.Error("Conflicts = false, want true")
+			}
+			if got != test.want {
+				t.Errorf("merged:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCleanMerge(t *testing.T) {
+	got, result := merge(t, "ONE\ntwo\nthree\n", "one\ntwo\nthree\n", "one\ntwo\nTHREE\n", Options{Style: StyleDiff3})
+	if result.Conflicts {
+		t.Error("Conflicts = true, want false")
+	}
+	if want := "ONE\ntwo\nTHREE\n"; got != want {
+		t.Errorf("merged = %q, want %q", got, want)
+	}
+}
+
+func TestZDiff3(t *testing.T) {
+	const (
+		base   = "one\ntwo\nthree\n"
+		ours   = "one\nstart\nTWO\nend\nthree\n"
+		theirs = "one\nstart\nDeux\nend\nthree\n"
+	)
+	for _, test := range []struct {
+		name  string
+		style ConflictStyle
+		want  string
+	}{
+		{name: "diff3", style: StyleDiff3, want: "one\n<<<<<<< ours\nstart\nTWO\nend\n||||||| base\ntwo\n=======\nstart\nDeux\nend\n>>>>>>> theirs\nthree\n"},
+		{name: "zdiff3", style: StyleZDiff3, want: "one\nstart\n<<<<<<< ours\nTWO\n||||||| base\ntwo\n=======\nDeux\n>>>>>>> theirs\nend\nthree\n"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, _ := merge(t, ours, base, theirs, Options{LabelA: "ours", LabelO: "base", LabelB: "theirs", Style: test.style})
+			if got != test.want {
+				t.Errorf("merged:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMarkerSize(t *testing.T) {
+	// The content holds a default size marker that must not end the conflict
+	const (
+		base   = "one\n=======\ntwo\n"
+		ours   = "one\n=======\nTWO\n"
+		theirs = "one\n=======\nDeux\n"
+	)
+	got, result := merge(t, ours, base, theirs, Options{LabelA: "ours", LabelO: "base", LabelB: "theirs", Style: StyleDiff3, MarkerSize: 10})
+	if !result.Conflicts {
+		t.Error("Conflicts = false, want true")
+	}
+	if want := "one\n=======\n<<<<<<<<<< ours\nTWO\n|||||||||| base\ntwo\n==========\nDeux\n>>>>>>>>>> theirs\n"; got != want {
+		t.Errorf("merged:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestIsMarker(t *testing.T) {
+	for _, test := range []struct {
+		line string
+		size int
+		want bool
+	}{
+		{line: "<<<<<<< ours", want: true},
+		{line: "<<<<<<<", want: true},
+		{line: "<<<<<<<\r", want: true},
+		{line: "<<<<<<<< ours", want: false},
+		{line: "<<<<<<<<<< ours", size: 10, want: true},
+		{line: "<<<<<<< ours", size: 10, want: false},
+		{line: "<<<<<<<x", want: false},
+	} {
+		if got := IsMarker(test.line, ConflictOurMarker, test.size); got != test.want {
+			t.Errorf("IsMarker(%q, %d) = %v, want %v", test.line, test.size, got, test.want)
+		}
+	}
+}
+
+func TestWhitespace(t *testing.T) {
+	const base = "one\ntwo three\nfour\n"
+	for _, test := range []struct {
+		name       string
+		whitespace Whitespace
+		ours       string
+		theirs     string
+		want       string
+		conflicts  bool
+	}{
+		{
+			name:      "exact",
+			ours:      "one\ntwo  three\nfour\n",
+			theirs:    "one\ntwo three \nfour\n",
+			want:      "one\n<<<<<<< ours\ntwo  three\n=======\ntwo three \n>>>>>>> theirs\nfour\n",
+			conflicts: true,
+		},
+		{
+			name:       "space change",
+			whitespace: IgnoreSpaceChange,
+			ours:       "one\ntwo  three\nfour\n",
+			theirs:     "one\ntwo three \nfour\n",
+			want:       "one\ntwo  three\nfour\n",
+		},
+		{
+			name:       "space change keeps their real change",
+			whitespace: IgnoreSpaceChange,
+			ours:       "one\ntwo  three\nfour\n",
+			theirs:     "one\ntwo three\nFOUR\n",
+			want:       "one\ntwo  three\nFOUR\n",
+		},
+		{
+			name:       "space change sees removed space",
+			whitespace: IgnoreSpaceChange,
+			ours:       "one\ntwothree\nfour\n",
+			theirs:     "one\ntwo  three\nfour\n",
+			want:       "one\ntwothree\nfour\n",
+		},
+		{
+			name:       "space change conflict",
+			whitespace: IgnoreSpaceChange,
+			ours:       "one\ntwothree\nfour\n",
+			theirs:     "one\ntwo\tthree\nFOUR\n",
+			want:       "one\n<<<<<<< ours\ntwothree\nfour\n=======\ntwo\tthree\nFOUR\n>>>>>>> theirs\n",
+			conflicts:  true,
+		},
+		{
+			name:       "all space",
+			whitespace: IgnoreAllSpace,
+			ours:       "one\ntwothree\nfour\n",
+			theirs:     "one\n two three\nfour\n",
+			want:       "one\ntwothree\nfour\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, result := merge(t, test.ours, base, test.theirs, Options{LabelA: "ours", LabelB: "theirs", Whitespace: test.whitespace})
+			if result.Conflicts != test.conflicts {
+				t.Errorf("Conflicts = %v, want %v", result.Conflicts, test.conflicts)
+			}
+			if got != test.want {
+				t.Errorf("merged:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFavor(t *testing.T) {
+	const (
+		base   = "one\ntwo\nthree\n"
+		ours   = "one\nTWO\nthree\n"
+		theirs = "one\nDeux\nthree\n"
+	)
+	for _, test := range []struct {
+		name  string
+		favor Favor
+		want  string
+	}{
+		{name: "none", favor: FavorNone, want: "one\n<<<<<<< ours\nTWO\n=======\nDeux\n>>>>>>> theirs\nthree\n"},
+		{name: "ours", favor: FavorOurs, want: "one\nTWO\nthree\n"},
+		{name: "theirs", favor: FavorTheirs, want: "one\nDeux\nthree\n"},
+		{name: "union", favor: FavorUnion, want: "one\nTWO\nDeux\nthree\n"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, result := merge(t, ours, base, theirs, Options{LabelA: "ours", LabelB: "theirs", Favor: test.favor})
+			if conflicts := test.favor == FavorNone; result.Conflicts != conflicts {
+				t.Errorf("Conflicts = %v, want %v", result.Conflicts, conflicts)
+			}
+			if resolved := min(int(test.favor), 1); result.Resolved != resolved {
+				t.Errorf("Resolved = %d, want %d", result.Resolved, resolved)
+			}
+			if got != test.want {
+				t.Errorf("merged:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package ort
+
+import (
+	"errors"
+
+	"github.com/go-git/go-billy/v6/memfs"
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/storer"
+	"github.com/go-git/go-git/v6/storage/memory"
+)
+
+// overlay reads the objects of a repository and keeps the written ones, the
+// references, the index and the config in memory
+type overlay struct {
+	*memory.Storage
+	base storer.EncodedObjectStorer
+}
+
+func (s *overlay) EncodedObject(t plumbing.ObjectType, h plumbing.Hash) (plumbing.EncodedObject, error) {
+	obj, err := s.Storage.EncodedObject(t, h)
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		return s.base.EncodedObject(t, h)
+	}
+	return obj, err
+}
+
+func (s *overlay) HasEncodedObject(h plumbing.Hash) error {
+	if err := s.Storage.HasEncodedObject(h); err == nil {
+		return nil
+	}
+	return s.base.HasEncodedObject(h)
+}
+
+func (s *overlay) EncodedObjectSize(h plumbing.Hash) (int64, error) {
+	size, err := s.Storage.EncodedObjectSize(h)
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		return s.base.EncodedObjectSize(h)
+	}
+	return size, err
+}
+
+// sandbox returns a copy of r whose changes stay in memory, its worktree is
+// HEAD checked out, empty when HEAD is unborn
+func sandbox(r *git.Repository) (*git.Repository, error) {
+	storage := &overlay{Storage: memory.NewStorage(), base: r.Storer}
+
+	refs, err := r.Storer.IterReferences()
+	if err != nil {
+		return nil, err
+	}
+	err = refs.ForEach(storage.SetReference)
+	if err != nil {
+		return nil, err
+	}
+	// Pseudo references may be missing from the iteration
+	for _, name := range []plumbing.ReferenceName{plumbing.HEAD, MERGE_HEAD, ORIG_HEAD} {
+		ref, err := r.Storer.Reference(name)
+		if errors.Is(err, plumbing.ErrReferenceNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err = storage.SetReference(ref); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg, err := r.Config()
+	if err != nil {
+		return nil, err
+	}
+	if err = storage.SetConfig(cfg); err != nil {
+		return nil, err
+	}
+
+	shallow, err := r.Storer.Shallow()
+	if err != nil {
+		return nil, err
+	}
+	if err = storage.SetShallow(shallow); err != nil {
+		return nil, err
+	}
+
+	copied, err := git.Open(storage, memfs.New())
+	if err != nil {
+		return nil, err
+	}
+
+	// An unborn HEAD has nothing to check out
+	head, err := copied.Head()
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return copied, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	w, err := copied.Worktree()
+	if err != nil {
+		return nil, err
+	}
+	if err = w.Reset(&git.ResetOptions{Commit: head.Hash(), Mode: git.HardReset}); err != nil {
+		return nil, err
+	}
+	return copied, nil
+}
+
+// dryRun runs merge on a sandbox of r
+func dryRun(r *git.Repository, opts MergeOptions, merge func(*git.Repository, MergeOptions) (*MergeResult, error)) (*MergeResult, error) {
+	copied, err := sandbox(r)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.DryRun = false
+	return merge(copied, opts)
+}
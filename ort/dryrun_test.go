@@ -0,0 +1,33 @@
+package ort
+
+import "testing"
+
+func TestMergeDryRun(t *testing.T) {
+	r := newTestRepository(t)
+	base := commitFiles(t, r, "base", map[string]string{"README": "base\n"})
+	ours := commitFiles(t, r, "ours", map[string]string{"README": "base\n", "ours": "ours\n"}, base)
+	theirs := commitFiles(t, r, "theirs", map[string]string{"README": "theirs\n"}, base)
+	checkoutBranch(t, r, "main", ours)
+
+	result, err := Merge(r, branchRef("theirs", theirs), MergeOptions{Author: &testSignature, Committer: &testSignature, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Commit.IsZero() {
+		t.Fatal("the dry run reported no commit")
+	}
+	if _, err = r.CommitObject(result.Commit); err == nil {
+		t.Error("the dry run stored its commit")
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Hash() != ours {
+		t.Errorf("HEAD = %s, want it left at %s", head.Hash(), ours)
+	}
+	if got := readWorktree(t, r, "README"); got != "base\n" {
+		t.Errorf("README = %q, want the worktree untouched", got)
+	}
+}
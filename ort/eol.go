@@ -0,0 +1,107 @@
+package ort
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/config"
+	"github.com/go-git/go-git/v6/plumbing/format/gitattributes"
+)
+
+const (
+	lf   = "\n"
+	crlf = "\r\n"
+)
+
+// The gitattributes attributes choosing the line endings of a path
+const (
+	eolAttribute    = "eol"
+	textAttribute   = "text"
+	binaryAttribute = "binary"
+)
+
+// lineEndings chooses the line endings of the merged files. Sides are
+// normalized to LF before diff3, so that a CRLF side and a LF side only
+// conflict where their lines do, and the result gets the chosen endings
+type lineEndings struct {
+	attributes gitattributes.Matcher
+	// autocrlf and eol are core.autocrlf and core.eol
+	autocrlf string
+	eol      string
+}
+
+// newLineEndings reads core.autocrlf and core.eol from the configuration of
+// r, an unreadable configuration is ignored like a missing one
+func newLineEndings(r *git.Repository, attributes gitattributes.Matcher) lineEndings {
+	endings := lineEndings{attributes: attributes}
+	cfg, err := r.ConfigScoped(config.SystemScope)
+	if err != nil {
+		return endings
+	}
+	core := cfg.Raw.Section("core")
+	endings.autocrlf = strings.ToLower(core.Option("autocrlf"))
+	endings.eol = strings.ToLower(core.Option("eol"))
+	return endings
+}
+
+// of returns the line ending of filepath: its eol attribute, then
+// core.autocrlf, then core.eol for the files with the text attribute, and
+// the ending of most lines of ours otherwise
+func (endings lineEndings) of(filepath string, ours []byte) string {
+	text := false
+	if endings.attributes != nil {
+		attributes, _ := endings.attributes.Match(strings.Split(filepath, "/"), []string{eolAttribute, textAttribute, binaryAttribute})
+		if attribute, ok := attributes[eolAttribute]; ok && attribute.IsValueSet() {
+			switch attribute.Value() {
+			case "crlf":
+				return crlf
+			case "lf":
+				return lf
+			}
+		}
+		if attribute, ok := attributes[textAttribute]; ok {
+			text = attribute.IsSet() || attribute.IsValueSet() && attribute.Value() == "auto"
+			if attribute.IsUnset() {
+				return dominantEnding(ours)
+			}
+		}
+		if attribute, ok := attributes[binaryAttribute]; ok && attribute.IsSet() {
+			return dominantEnding(ours)
+		}
+	}
+
+	switch {
+	case endings.autocrlf == "true":
+		return crlf
+	case endings.autocrlf == "input":
+		return lf
+	case text && endings.eol == "crlf":
+		return crlf
+	case text && endings.eol == "lf":
+		return lf
+	}
+	return dominantEnding(ours)
+}
+
+// dominantEnding returns CRLF when most lines of content end with it
+func dominantEnding(content []byte) string {
+	crlfs := bytes.Count(content, []byte(crlf))
+	if crlfs > bytes.Count(content, []byte(lf))-crlfs {
+		return crlf
+	}
+	return lf
+}
+
+// toLF normalizes the CRLF line endings of content to LF
+func toLF(content []byte) []byte {
+	return bytes.ReplaceAll(content, []byte(crlf), []byte(lf))
+}
+
+// withEnding converts the line endings of content to ending
+func withEnding(content []byte, ending string) []byte {
+	if ending != crlf {
+		return toLF(content)
+	}
+	return bytes.ReplaceAll(toLF(content), []byte(lf), []byte(crlf))
+}
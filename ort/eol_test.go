@@ -0,0 +1,70 @@
+package ort
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v6/plumbing/format/gitattributes"
+)
+
+func TestLineEndings(t *testing.T) {
+	var patterns []gitattributes.MatchAttribute
+	for _, line := range []string{"*.bat eol=crlf", "*.sh eol=lf", "*.txt text", "*.png binary", "*.csv -text"} {
+		parsed, err := gitattributes.ReadAttributes(strings.NewReader(line), nil, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		patterns = append(patterns, parsed...)
+	}
+	attributes := gitattributes.NewMatcher(patterns)
+	const ours = "a\r\nb\r\n"
+
+	for _, test := range []struct {
+		name     string
+		endings  lineEndings
+		filepath string
+		want     string
+	}{
+		{name: "eol attribute", endings: lineEndings{attributes: attributes, autocrlf: "input"}, filepath: "run.bat", want: crlf},
+		{name: "eol attribute over ours", endings: lineEndings{attributes: attributes}, filepath: "run.sh", want: lf},
+		{name: "autocrlf", endings: lineEndings{attributes: attributes, autocrlf: "input"}, filepath: "notes.txt", want: lf},
+		{name: "core.eol of text", endings: lineEndings{attributes: attributes, eol: "lf"}, filepath: "notes.txt", want: lf},
+		{name: "core.eol without text", endings: lineEndings{attributes: attributes, eol: "lf"}, filepath: "main.go", want: crlf},
+		{name: "binary", endings: lineEndings{attributes: attributes, autocrlf: "input"}, filepath: "logo.png", want: crlf},
+		{name: "unset text", endings: lineEndings{attributes: attributes, autocrlf: "input"}, filepath: "data.csv", want: crlf},
+		{name: "ours", endings: lineEndings{}, filepath: "main.go", want: crlf},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.endings.of(test.filepath, []byte(ours)); got != test.want {
+				t.Fatalf("of(%q) = %q, want %q", test.filepath, got, test.want)
+			}
+		})
+	}
+}
+
+func TestWithEnding(t *testing.T) {
+	if got := string(withEnding([]byte("a\r\nb\nc"), crlf)); got != "a\r\nb\r\nc" {
+		t.Errorf("withEnding(CRLF) = %q", got)
+	}
+	if got := string(withEnding([]byte("a\r\nb\n"), lf)); got != "a\nb\n" {
+		t.Errorf("withEnding(LF) = %q", got)
+	}
+	if got := dominantEnding([]byte("a\r\nb\nc\n")); got != lf {
+		t.Errorf("dominantEnding() = %q, want LF", got)
+	}
+}
+
+func TestMergeLineEndings(t *testing.T) {
+	r := newTestRepository(t)
+	base := commitFiles(t, r, "base", map[string]string{"README": "one\ntwo\nthree\n"})
+	ours := commitFiles(t, r, "ours", map[string]string{"README": "ONE\r\ntwo\r\nthree\r\n"}, base)
+	theirs := commitFiles(t, r, "theirs", map[string]string{"README": "one\ntwo\nTHREE\n"}, base)
+	checkoutBranch(t, r, "main", ours)
+
+	if _, err := Merge(r, branchRef("theirs", theirs), MergeOptions{Author: &testSignature, Committer: &testSignature}); err != nil {
+		t.Fatal(err)
+	}
+	if got := headFiles(t, r)["README"]; got != "ONE\r\ntwo\r\nTHREE\r\n" {
+		t.Fatalf("README = %q, want both changes with the endings of ours", got)
+	}
+}
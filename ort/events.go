@@ -0,0 +1,99 @@
+package ort
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/go-git/go-git/v6/plumbing"
+)
+
+// Events receives the progress of a merge as it happens, for a TUI to render
+// it live or a CLI to log it in a machine-readable form. Every merge,
+// rebase and revert reports to MergeOptions.Events
+type Events interface {
+	// FileStarted is called before the changes of a path are merged
+	FileStarted(path string)
+	// FileMerged is called once the path merged cleanly
+	FileMerged(path string)
+	// ConflictDetected is called for every conflicting path, reason is the
+	// CONFLICT line git prints, empty for conflicts git describes no further
+	ConflictDetected(path, reason string)
+	// CommitCreated is called when HEAD moves to a new commit, or is fast-forwarded
+	CommitCreated(hash plumbing.Hash)
+}
+
+// WriterEvents adapts w to Events, printing the CONFLICT lines like git.
+// MergeOptions.Progress is adapted this way when Events is nil
+func WriterEvents(w io.Writer) Events {
+	return writerEvents{w: w}
+}
+
+type writerEvents struct {
+	w io.Writer
+}
+
+func (writerEvents) FileStarted(string) {}
+
+func (writerEvents) FileMerged(string) {}
+
+func (events writerEvents) ConflictDetected(_, reason string) {
+	if reason != "" {
+		_, _ = fmt.Fprintln(events.w, reason)
+	}
+}
+
+func (writerEvents) CommitCreated(plumbing.Hash) {}
+
+// discardEvents is the Events of a merge without Events nor Progress
+type discardEvents struct{}
+
+func (discardEvents) FileStarted(string) {}
+
+func (discardEvents) FileMerged(string) {}
+
+func (discardEvents) ConflictDetected(string, string) {}
+
+func (discardEvents) CommitCreated(plumbing.Hash) {}
+
+// events returns opts.Events, or Progress adapted by WriterEvents
+func (opts MergeOptions) events() Events {
+	switch {
+	case opts.Events != nil:
+		return opts.Events
+	case opts.Progress != nil:
+		return WriterEvents(opts.Progress)
+	default:
+		return discardEvents{}
+	}
+}
+
+// pathEvents reports the paths of the merge loop, whose many branches end
+// with continue: a path is finished when the next one starts or the loop
+// ends, conflicting when entries were added to conflicts meanwhile
+type pathEvents struct {
+	events    Events
+	path      string
+	conflicts int
+}
+
+// start finishes the previous path and starts path
+func (p *pathEvents) start(path string, conflicts []conflictEntry) {
+	p.finish(conflicts)
+	p.path = path
+	p.conflicts = len(conflicts)
+	p.events.FileStarted(path)
+}
+
+// finish reports the outcome of the current path
+func (p *pathEvents) finish(conflicts []conflictEntry) {
+	if p.path == "" {
+		return
+	}
+	if len(conflicts) == p.conflicts {
+		p.events.FileMerged(p.path)
+	}
+	for _, conflict := range conflicts[p.conflicts:] {
+		p.events.ConflictDetected(conflict.path, conflict.reason)
+	}
+	p.path = ""
+}
@@ -0,0 +1,70 @@
+package ort
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+// matches tells whether filepath or one of its directories matches a
+// pattern, so that a pattern naming a directory covers its files
+func matches(patterns []string, filepath string) bool {
+	for name := filepath; name != "." && name != "/"; name = path.Dir(name) {
+		for _, pattern := range patterns {
+			if matched, _ := path.Match(strings.TrimSuffix(pattern, "/"), name); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// outside tells whether the changes of their side to filepath are left out
+// of the merge: it is excluded, or PathSpecs are set and none matches it
+func (opts MergeOptions) outside(filepath string) bool {
+	if filepath == "" {
+		return false
+	}
+	if len(opts.PathSpecs) > 0 && !matches(opts.PathSpecs, filepath) {
+		return true
+	}
+	return matches(opts.Exclude, filepath)
+}
+
+// scopeChanges drops the changes from or to a path outside of the merge, a
+// rename crossing its bounds is dropped as a whole
+func (opts MergeOptions) scopeChanges(changes object.Changes) object.Changes {
+	if len(opts.Exclude) == 0 && len(opts.PathSpecs) == 0 {
+		return changes
+	}
+
+	kept := make(object.Changes, 0, len(changes))
+	for _, change := range changes {
+		if opts.outside(change.From.Name) || opts.outside(change.To.Name) {
+			continue
+		}
+		kept = append(kept, change)
+	}
+	return kept
+}
+
+// keepsOutside tells whether moving from ours to theirs leaves the paths
+// outside of the merge untouched
+func keepsOutside(ours, theirs *object.Commit, opts MergeOptions) (bool, error) {
+	ourTree, err := ours.Tree()
+	if err != nil {
+		return false, err
+	}
+	theirTree, err := theirs.Tree()
+	if err != nil {
+		return false, err
+	}
+
+	changes, err := object.DiffTreeWithOptions(context.Background(), ourTree, theirTree, nil)
+	if err != nil {
+		return false, err
+	}
+	return len(opts.scopeChanges(changes)) == len(changes), nil
+}
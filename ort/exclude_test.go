@@ -0,0 +1,121 @@
+package ort
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestMatches(t *testing.T) {
+	for _, test := range []struct {
+		patterns []string
+		filepath string
+		want     bool
+	}{
+		{patterns: []string{"*.md"}, filepath: "README.md", want: true},
+		{patterns: []string{"docs/"}, filepath: "docs/guide/index.md", want: true},
+		{patterns: []string{"docs"}, filepath: "docs", want: true},
+		{patterns: []string{"*.md"}, filepath: "docs/index.txt"},
+		{patterns: []string{"doc"}, filepath: "docs/index.md"},
+		{filepath: "README.md"},
+	} {
+		if got := matches(test.patterns, test.filepath); got != test.want {
+			t.Errorf("matches(%q, %q) = %v, want %v", test.patterns, test.filepath, got, test.want)
+		}
+	}
+}
+
+func TestMergeExclude(t *testing.T) {
+	r := newTestRepository(t)
+	base := commitFiles(t, r, "base", map[string]string{"README": "base\n", "ci.yml": "base\n"})
+	ours := commitFiles(t, r, "ours", map[string]string{"README": "base\n", "ci.yml": "base\n", "main.go": "ours\n"}, base)
+	theirs := commitFiles(t, r, "theirs", map[string]string{"README": "theirs\n", "ci.yml": "theirs\n", "ci.lock": "theirs\n"}, base)
+	checkoutBranch(t, r, "main", ours)
+
+	_, err := Merge(r, branchRef("theirs", theirs), MergeOptions{Author: &testSignature, Committer: &testSignature, Exclude: []string{"ci.*"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"README": "theirs\n", "ci.yml": "base\n", "main.go": "ours\n"}
+	if got := headFiles(t, r); !maps.Equal(got, want) {
+		t.Fatalf("merged files = %v, want %v", got, want)
+	}
+}
+
+func TestMergeExcludeKeepsOurs(t *testing.T) {
+	r := newTestRepository(t)
+	base := commitFiles(t, r, "base", map[string]string{"README": "base\n", "ci.yml": "base\n"})
+	ours := commitFiles(t, r, "ours", map[string]string{"README": "base\n", "ci.yml": "ours\n"}, base)
+	theirs := commitFiles(t, r, "theirs", map[string]string{"README": "theirs\n", "ci.yml": "theirs\n"}, base)
+	checkoutBranch(t, r, "main", ours)
+
+	_, err := Merge(r, branchRef("theirs", theirs), MergeOptions{Author: &testSignature, Committer: &testSignature, Exclude: []string{"ci.yml"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"README": "theirs\n", "ci.yml": "ours\n"}
+	if got := headFiles(t, r); !maps.Equal(got, want) {
+		t.Fatalf("merged files = %v, want %v", got, want)
+	}
+}
+
+func TestMergeExcludeFastForward(t *testing.T) {
+	r := newTestRepository(t)
+	base := commitFiles(t, r, "base", map[string]string{"README": "base\n", "ci.yml": "base\n"})
+	theirs := commitFiles(t, r, "theirs", map[string]string{"README": "theirs\n", "ci.yml": "theirs\n"}, base)
+	checkoutBranch(t, r, "main", base)
+
+	_, err := Merge(r, branchRef("theirs", theirs), MergeOptions{Author: &testSignature, Committer: &testSignature, Exclude: []string{"ci.yml"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Hash() == theirs {
+		t.Fatal("fast-forwarded over an excluded path")
+	}
+	want := map[string]string{"README": "theirs\n", "ci.yml": "base\n"}
+	if got := headFiles(t, r); !maps.Equal(got, want) {
+		t.Fatalf("merged files = %v, want %v", got, want)
+	}
+}
+
+func TestKeepsOutside(t *testing.T) {
+	r := newTestRepository(t)
+	ours := commitFiles(t, r, "ours", map[string]string{"README": "base\n", "ci.yml": "base\n", "docs/guide.md": "base\n"})
+	theirs := commitFiles(t, r, "theirs", map[string]string{"README": "theirs\n", "ci.yml": "theirs\n", "docs/guide.md": "theirs\n"})
+	ourCommit, err := r.CommitObject(ours)
+	if err != nil {
+		t.Fatal(err)
+	}
+	theirCommit, err := r.CommitObject(theirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		name string
+		opts MergeOptions
+		want bool
+	}{
+		{name: "no scope", want: true},
+		{name: "excluded", opts: MergeOptions{Exclude: []string{"ci.yml"}}},
+		{name: "excluded directory", opts: MergeOptions{Exclude: []string{"docs/"}}},
+		{name: "excluded untouched", opts: MergeOptions{Exclude: []string{"*.lock"}}, want: true},
+		{name: "pathspec", opts: MergeOptions{PathSpecs: []string{"README"}}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := keepsOutside(ourCommit, theirCommit, test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("keepsOutside() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package ort
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/config"
+)
+
+// ErrHookFailed is returned when a pre-merge-commit or commit-msg hook
+// rejects the merge commit
+var ErrHookFailed = errors.New("hook failed")
+
+// The hooks git runs around a merge
+const (
+	preMergeCommitHook = "pre-merge-commit"
+	commitMsgHook      = "commit-msg"
+	postMergeHook      = "post-merge"
+)
+
+// mergeHooks runs the hooks of a repository on disk, from the worktree like
+// git. In-memory repositories and dry runs have none
+type mergeHooks struct {
+	// dir is core.hooksPath or the hooks directory of the git directory,
+	// empty when no hook runs
+	dir      string
+	gitDir   string
+	worktree string
+	output   io.Writer
+}
+
+// newMergeHooks finds the hooks of r, none when opts.NoHooks is set
+func newMergeHooks(r *git.Repository, opts MergeOptions) mergeHooks {
+	fs := gitDir(r)
+	if fs == nil || opts.NoHooks {
+		return mergeHooks{}
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		return mergeHooks{}
+	}
+
+	hooks := mergeHooks{
+		dir:      filepath.Join(fs.Root(), "hooks"),
+		gitDir:   fs.Root(),
+		worktree: w.Filesystem.Root(),
+		output:   opts.Progress,
+	}
+	// Like git, a relative core.hooksPath is relative to the worktree
+	if cfg, err := r.ConfigScoped(config.SystemScope); err == nil {
+		if path := cfg.Raw.Section("core").Option("hooksPath"); path != "" {
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(hooks.worktree, path)
+			}
+			hooks.dir = path
+		}
+	}
+	return hooks
+}
+
+// path returns the executable of the hook name, empty when it does not
+// exist or is not executable, git ignores those
+func (hooks mergeHooks) path(name string) string {
+	if hooks.dir == "" {
+		return ""
+	}
+	path := filepath.Join(hooks.dir, name)
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() || info.Mode()&0o111 == 0 {
+		return ""
+	}
+	return path
+}
+
+// run runs the hook name with args, a missing hook succeeds
+func (hooks mergeHooks) run(ctx context.Context, name string, args ...string) error {
+	path := hooks.path(name)
+	if path == "" {
+		return nil
+	}
+
+	process := exec.CommandContext(ctx, path, args...)
+	process.Dir = hooks.worktree
+	process.Stdout = hooks.output
+	process.Stderr = hooks.output
+	if err := process.Run(); err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrHookFailed, name, err)
+	}
+	return nil
+}
+
+// verify runs pre-merge-commit then commit-msg on message, written to
+// MERGE_MSG for the hook to edit, and returns the message to commit
+func (hooks mergeHooks) verify(ctx context.Context, r *git.Repository, message string) (string, error) {
+	if err := hooks.run(ctx, preMergeCommitHook); err != nil {
+		return message, err
+	}
+	if hooks.path(commitMsgHook) == "" {
+		return message, nil
+	}
+
+	// Ended by a newline for the hook to append trailers
+	if err := writeMergeMsg(r, strings.TrimRight(message, "\n")+"\n"); err != nil {
+		return message, err
+	}
+	if err := hooks.run(ctx, commitMsgHook, filepath.Join(hooks.gitDir, MERGE_MSG)); err != nil {
+		return message, err
+	}
+	edited, err := readMergeMsg(r)
+	if err != nil {
+		return message, err
+	}
+	if edited == "" {
+		return message, fmt.Errorf("%w: %s: empty message", ErrHookFailed, commitMsgHook)
+	}
+	return edited, removeMergeMsg(r)
+}
+
+// merged runs post-merge once HEAD or, for a squash, the worktree holds
+// the merge. Its exit status cannot undo the merge and is ignored like git
+func (hooks mergeHooks) merged(ctx context.Context, squash bool) {
+	flag := "0"
+	if squash {
+		flag = "1"
+	}
+	_ = hooks.run(ctx, postMergeHook, flag)
+}
@@ -0,0 +1,192 @@
+package ort
+
+import (
+	"errors"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v6"
+)
+
+// newHookRepository returns a repository on disk, where hooks run, with the
+// hooks named by their script
+func newHookRepository(t *testing.T, hooks map[string]string) *git.Repository {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("hooks need a shell")
+	}
+	dir := t.TempDir()
+	r, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.MkdirAll(filepath.Join(dir, ".git", "hooks"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, script := range hooks {
+		if err = os.WriteFile(filepath.Join(dir, ".git", "hooks", name), []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return r
+}
+
+func TestMergeHookFailure(t *testing.T) {
+	for _, hook := range []string{preMergeCommitHook, commitMsgHook} {
+		t.Run(hook, func(t *testing.T) {
+			r := newHookRepository(t, map[string]string{hook: "exit 1\n"})
+			base := commitFiles(t, r, "base", map[string]string{"README": "base\n", "main.go": "base\n"})
+			ours := commitFiles(t, r, "ours", map[string]string{"README": "ours\n", "main.go": "base\n"}, base)
+			theirs := commitFiles(t, r, "theirs", map[string]string{"README": "base\n", "main.go": "theirs\n"}, base)
+			checkoutBranch(t, r, "main", ours)
+
+			_, err := Merge(r, branchRef("theirs", theirs), MergeOptions{Author: &testSignature, Committer: &testSignature})
+			if !errors.Is(err, ErrHookFailed) {
+				t.Fatalf("Merge() error = %v, want %v", err, ErrHookFailed)
+			}
+			head, err := r.Head()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if head.Hash() != ours {
+				t.Errorf("HEAD = %s, want %s", head.Hash(), ours)
+			}
+			if _, err = r.Reference(MERGE_HEAD, false); err != nil {
+				t.Errorf("MERGE_HEAD: %v", err)
+			}
+			if got := readWorktree(t, r, "main.go"); got != "theirs\n" {
+				t.Errorf("main.go = %q, want the merged file", got)
+			}
+		})
+	}
+}
+
+func TestMergeHooks(t *testing.T) {
+	r := newHookRepository(t, map[string]string{
+		commitMsgHook: "echo 'Reviewed-by: hook' >> \"$1\"\n",
+		postMergeHook: "echo \"$1\" > merged\n",
+	})
+	base := commitFiles(t, r, "base", map[string]string{"README": "base\n", "main.go": "base\n"})
+	ours := commitFiles(t, r, "ours", map[string]string{"README": "ours\n", "main.go": "base\n"}, base)
+	theirs := commitFiles(t, r, "theirs", map[string]string{"README": "base\n", "main.go": "theirs\n"}, base)
+	checkoutBranch(t, r, "main", ours)
+
+	if _, err := Merge(r, branchRef("theirs", theirs), MergeOptions{Author: &testSignature, Committer: &testSignature}); err != nil {
+		t.Fatal(err)
+	}
+	head, err := r.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := r.CommitObject(head.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(commit.Message, "\nReviewed-by: hook") {
+		t.Errorf("message = %q, want the trailer of commit-msg", commit.Message)
+	}
+	if got := readWorktree(t, r, "merged"); got != "0\n" {
+		t.Errorf("post-merge flag = %q, want %q", got, "0\n")
+	}
+}
+
+func TestMergeNoHooks(t *testing.T) {
+	r := newHookRepository(t, map[string]string{preMergeCommitHook: "exit 1\n"})
+	base := commitFiles(t, r, "base", map[string]string{"README": "base\n", "main.go": "base\n"})
+	ours := commitFiles(t, r, "ours", map[string]string{"README": "ours\n", "main.go": "base\n"}, base)
+	theirs := commitFiles(t, r, "theirs", map[string]string{"README": "base\n", "main.go": "theirs\n"}, base)
+	checkoutBranch(t, r, "main", ours)
+
+	_, err := Merge(r, branchRef("theirs", theirs), MergeOptions{Author: &testSignature, Committer: &testSignature, NoHooks: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"README": "ours\n", "main.go": "theirs\n"}
+	if got := headFiles(t, r); !maps.Equal(got, want) {
+		t.Fatalf("merged files = %v, want %v", got, want)
+	}
+}
+
+func TestContinueHookFailure(t *testing.T) {
+	r := newHookRepository(t, map[string]string{
+		commitMsgHook: "exit 1\n",
+		postMergeHook: "echo \"$1\" > merged\n",
+	})
+	base := commitFiles(t, r, "base", map[string]string{"README": "base\n", "main.go": "base\n"})
+	ours := commitFiles(t, r, "ours", map[string]string{"README": "ours\n", "main.go": "base\n"}, base)
+	theirs := commitFiles(t, r, "theirs", map[string]string{"README": "base\n", "main.go": "theirs\n"}, base)
+	checkoutBranch(t, r, "main", ours)
+
+	opts := MergeOptions{Author: &testSignature, Committer: &testSignature}
+	if _, err := Merge(r, branchRef("theirs", theirs), opts); !errors.Is(err, ErrHookFailed) {
+		t.Fatalf("Merge() error = %v, want %v", err, ErrHookFailed)
+	}
+	if err := Continue(r, opts); !errors.Is(err, ErrHookFailed) {
+		t.Fatalf("Continue() error = %v, want %v", err, ErrHookFailed)
+	}
+	head, err := r.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Hash() != ours {
+		t.Errorf("HEAD = %s, want %s", head.Hash(), ours)
+	}
+	if _, err = r.Reference(MERGE_HEAD, false); err != nil {
+		t.Errorf("MERGE_HEAD: %v", err)
+	}
+
+	opts.NoHooks = true
+	if err = Continue(r, opts); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"README": "ours\n", "main.go": "theirs\n"}
+	if got := headFiles(t, r); !maps.Equal(got, want) {
+		t.Fatalf("merged files = %v, want %v", got, want)
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.Filesystem.Stat("merged"); err == nil {
+		t.Error("post-merge ran with NoHooks")
+	}
+}
+
+func TestContinueHooks(t *testing.T) {
+	r := newHookRepository(t, map[string]string{
+		commitMsgHook: "echo 'Reviewed-by: hook' >> \"$1\"\n",
+		postMergeHook: "echo \"$1\" > merged\n",
+	})
+	base := commitFiles(t, r, "base", map[string]string{"README": "base\n", "main.go": "base\n"})
+	ours := commitFiles(t, r, "ours", map[string]string{"README": "ours\n", "main.go": "base\n"}, base)
+	theirs := commitFiles(t, r, "theirs", map[string]string{"README": "base\n", "main.go": "theirs\n"}, base)
+	checkoutBranch(t, r, "main", ours)
+
+	opts := MergeOptions{Author: &testSignature, Committer: &testSignature}
+	noCommit := opts
+	noCommit.NoCommit = true
+	if _, err := Merge(r, branchRef("theirs", theirs), noCommit); err != nil {
+		t.Fatal(err)
+	}
+	if err := Continue(r, opts); err != nil {
+		t.Fatal(err)
+	}
+	head, err := r.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := r.CommitObject(head.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(commit.Message, "\nReviewed-by: hook") {
+		t.Errorf("message = %q, want the trailer of commit-msg", commit.Message)
+	}
+	if got := readWorktree(t, r, "merged"); got != "0\n" {
+		t.Errorf("post-merge flag = %q, want %q", got, "0\n")
+	}
+}
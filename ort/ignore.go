@@ -0,0 +1,128 @@
+package ort
+
+import (
+	"path"
+	"slices"
+	"strings"
+)
+
+// ignoreFiles are merged pattern by pattern instead of line by line
+var ignoreFiles = []string{".gitignore", ".dockerignore"}
+
+func isIgnoreFile(filepath string) bool {
+	return slices.Contains(ignoreFiles, path.Base(filepath))
+}
+
+// ignoreSection is a run of comment lines followed by the patterns they describe
+type ignoreSection struct {
+	header   []string
+	patterns []string
+}
+
+func isPattern(line string) bool {
+	line = strings.TrimSpace(line)
+	return line != "" && !strings.HasPrefix(line, "#")
+}
+
+func splitLines(content string) []string {
+	content = strings.TrimRight(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	if content == "" {
+		return nil
+	}
+	return strings.Split(content, "\n")
+}
+
+func patternSet(lines []string) map[string]bool {
+	set := make(map[string]bool)
+	for _, line := range lines {
+		if isPattern(line) {
+			set[strings.TrimSpace(line)] = true
+		}
+	}
+	return set
+}
+
+func ignoreSections(lines []string) (sections []ignoreSection) {
+	var current ignoreSection
+	for _, line := range lines {
+		switch {
+		case isPattern(line):
+			current.patterns = append(current.patterns, line)
+		case strings.TrimSpace(line) == "":
+			if len(current.patterns) > 0 {
+				sections = append(sections, current)
+				current = ignoreSection{}
+			}
+		default:
+			// A comment after patterns starts a new section
+			if len(current.patterns) > 0 {
+				sections = append(sections, current)
+				current = ignoreSection{}
+			}
+			current.header = append(current.header, line)
+		}
+	}
+	if len(current.patterns) > 0 || len(current.header) > 0 {
+		sections = append(sections, current)
+	}
+	return
+}
+
+// mergeIgnore merges ignore files as sets of patterns: our layout is kept,
+// patterns they removed are dropped and patterns they added are appended to
+// the section carrying the same comment, or to a copy of their section.
+// Patterns of the base we removed stay removed. The result has LF endings
+func mergeIgnore(base, ours, theirs string) string {
+	baseLines, ourLines, theirLines := splitLines(base), splitLines(ours), splitLines(theirs)
+	basePatterns, theirPatterns := patternSet(baseLines), patternSet(theirLines)
+
+	var result []string
+	present := make(map[string]bool)
+	for _, line := range ourLines {
+		if isPattern(line) {
+			pattern := strings.TrimSpace(line)
+			if basePatterns[pattern] && !theirPatterns[pattern] || present[pattern] {
+				continue
+			}
+			present[pattern] = true
+		}
+		result = append(result, line)
+	}
+
+	for _, section := range ignoreSections(theirLines) {
+		var missing []string
+		for _, line := range section.patterns {
+			pattern := strings.TrimSpace(line)
+			// Patterns of the base are ours already, or we removed them
+			if !present[pattern] && !basePatterns[pattern] {
+				present[pattern] = true
+				missing = append(missing, line)
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+
+		// Insert after the last pattern following our copy of the section comment
+		at := -1
+		if len(section.header) > 0 {
+			at = slices.Index(result, section.header[len(section.header)-1])
+		}
+		if at >= 0 {
+			end := at + 1
+			for end < len(result) && isPattern(result[end]) {
+				end++
+			}
+			result = slices.Insert(result, end, missing...)
+			continue
+		}
+
+		if len(result) > 0 && strings.TrimSpace(result[len(result)-1]) != "" {
+			result = append(result, "")
+		}
+		result = append(result, section.header...)
+		result = append(result, missing...)
+	}
+
+	return strings.Join(result, "\n") + "\n"
+}
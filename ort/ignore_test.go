@@ -0,0 +1,76 @@
+package ort
+
+import "testing"
+
+func TestMergeIgnore(t *testing.T) {
+	for _, test := range []struct {
+		name               string
+		base, ours, theirs string
+		want               string
+	}{
+		{
+			name:   "both add",
+			base:   "node_modules\n",
+			ours:   "node_modules\ndist\n",
+			theirs: "node_modules\n.env\n",
+			want:   "node_modules\ndist\n\n.env\n",
+		},
+		{
+			name:   "they remove",
+			base:   "node_modules\ndist\n",
+			ours:   "node_modules\ndist\n",
+			theirs: "node_modules\n",
+			want:   "node_modules\n",
+		},
+		{
+			name:   "we remove",
+			base:   "node_modules\ndist\n",
+			ours:   "node_modules\n",
+			theirs: "node_modules\ndist\n.env\n",
+			want:   "node_modules\n\n.env\n",
+		},
+		{
+			name:   "section comment",
+			base:   "# build\ndist\n",
+			ours:   "# build\ndist\n\n# editors\n.idea\n",
+			theirs: "# build\ndist\nout\n",
+			want:   "# build\ndist\nout\n\n# editors\n.idea\n",
+		},
+		{
+			name:   "new section",
+			base:   "dist\n",
+			ours:   "dist\n",
+			theirs: "dist\n\n# secrets\n.env\n",
+			want:   "dist\n\n# secrets\n.env\n",
+		},
+		{
+			name:   "crlf",
+			base:   "dist\r\n",
+			ours:   "dist\r\nout\r\n",
+			theirs: "dist\r\n.env\r\n",
+			want:   "dist\nout\n\n.env\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := mergeIgnore(test.base, test.ours, test.theirs); got != test.want {
+				t.Errorf("mergeIgnore() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMergeIgnoreFileEndings(t *testing.T) {
+	r := newTestRepository(t)
+	base := commitFiles(t, r, "base", map[string]string{".gitignore": "dist\r\n"})
+	ours := commitFiles(t, r, "ours", map[string]string{".gitignore": "dist\r\nout\r\n"}, base)
+	theirs := commitFiles(t, r, "theirs", map[string]string{".gitignore": "dist\r\n.env\r\n"}, base)
+	checkoutBranch(t, r, "main", ours)
+
+	_, err := Merge(r, branchRef("theirs", theirs), MergeOptions{Author: &testSignature, Committer: &testSignature})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := readWorktree(t, r, ".gitignore"), "dist\r\nout\r\n\r\n.env\r\n"; got != want {
+		t.Fatalf(".gitignore = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,101 @@
+package ort
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing/format/index"
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+// conflictEntry holds the three sides of a conflicted path, a side is nil
+// when the file does not exist there
+type conflictEntry struct {
+	path   string
+	base   *object.File
+	ours   *object.File
+	theirs *object.File
+	// reason describes conflicts other than conflicting content, like a
+	// rename/delete
+	reason string
+}
+
+// conflictPaths returns the sorted paths of the conflicts
+func conflictPaths(conflicts []conflictEntry) []string {
+	paths := make([]string, 0, len(conflicts))
+	for _, conflict := range conflicts {
+		paths = append(paths, conflict.path)
+	}
+	slices.Sort(paths)
+	return slices.Compact(paths)
+}
+
+// writeConflictStages replaces the index entries of conflicted paths with
+// their base (1), ours (2) and theirs (3) stages, like git does on conflict
+func writeConflictStages(r *git.Repository, conflicts []conflictEntry) error {
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	idx, err := r.Storer.Index()
+	if err != nil {
+		return err
+	}
+
+	for _, conflict := range conflicts {
+		idx.Entries = slices.DeleteFunc(idx.Entries, func(entry *index.Entry) bool {
+			return entry.Name == conflict.path
+		})
+
+		for stage, file := range map[index.Stage]*object.File{
+			index.AncestorMode: conflict.base,
+			index.OurMode:      conflict.ours,
+			index.TheirMode:    conflict.theirs,
+		} {
+			if file == nil {
+				continue
+			}
+			entry := idx.Add(conflict.path)
+			entry.Hash = file.Hash
+			entry.Mode = file.Mode
+			entry.Stage = stage
+		}
+	}
+
+	// The encoder sorts entries by name only, git requires stages of a path in
+	// ascending order: a slice already sorted by both is left untouched
+	slices.SortStableFunc(idx.Entries, func(a, b *index.Entry) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.Stage, b.Stage))
+	})
+
+	return r.Storer.SetIndex(idx)
+}
+
+// MarkResolved stages the worktree content of conflicted paths, dropping their
+// conflict stages like `git add` does. go-git's Worktree.Add keeps the stages
+func MarkResolved(r *git.Repository, paths ...string) error {
+	idx, err := r.Storer.Index()
+	if err != nil {
+		return err
+	}
+
+	idx.Entries = slices.DeleteFunc(idx.Entries, func(entry *index.Entry) bool {
+		return entry.Stage != 0 && slices.Contains(paths, entry.Name)
+	})
+	if err = r.Storer.SetIndex(idx); err != nil {
+		return err
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		if _, err = w.Add(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package ort
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+
+	"gravel/i18n"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+// ErrBranchCheckedOut is returned when MergeInto is given the branch of
+// HEAD, whose worktree it would leave behind. Merge it with Merge instead
+var ErrBranchCheckedOut = errors.New("the branch is checked out in the worktree, merge into HEAD instead")
+
+// MergeInto merges ref into the local branch with MergeTrees, leaving HEAD,
+// the index and the worktree untouched. The branch fast-forwards or moves
+// to the merge commit. On a conflict the branch does not move, the
+// conflicts are returned in the result with ErrMergeConflict. Of opts, the
+// options of MergeTrees, Strategy, NoFastForward, AllowUnrelatedHistories,
+// ParentOrder, Author, Committer, Message, SignKey, Deepen, MaxDepth and
+// DryRun apply
+func MergeInto(r *git.Repository, branch plumbing.ReferenceName, ref plumbing.Reference, opts MergeOptions) (*MergeResult, error) {
+	if opts.DryRun {
+		return dryRun(r, opts, func(sandbox *git.Repository, opts MergeOptions) (*MergeResult, error) {
+			return MergeInto(sandbox, branch, ref, opts)
+		})
+	}
+
+	result := &MergeResult{}
+	return result, mergeInto(r, branch, ref, opts, result)
+}
+
+func mergeInto(r *git.Repository, branch plumbing.ReferenceName, ref plumbing.Reference, opts MergeOptions, result *MergeResult) error {
+	if opts.Strategy != OrtMerge &&
+		opts.Strategy != FastForwardMerge &&
+		opts.Strategy != FastForwardOnly {
+		return git.ErrUnsupportedMergeStrategy
+	}
+	if opts.Strategy == FastForwardOnly && opts.NoFastForward {
+		return ErrFastForwardOptions
+	}
+
+	ref, err := Peel(r, ref)
+	if err != nil {
+		return err
+	}
+
+	target, err := r.Reference(branch, true)
+	if err != nil {
+		return err
+	}
+	// Bare repositories have no worktree to leave behind
+	if _, err = r.Worktree(); err == nil {
+		var head *plumbing.Reference
+		head, err = r.Reference(plumbing.HEAD, false)
+		if err != nil {
+			return err
+		}
+		if head.Target() == target.Name() {
+			return ErrBranchCheckedOut
+		}
+	} else if !errors.Is(err, git.ErrIsBareRepository) {
+		return err
+	}
+
+	ourCommit, err := r.CommitObject(target.Hash())
+	if err != nil {
+		return err
+	}
+	theirCommit, err := r.CommitObject(ref.Hash())
+	if err != nil {
+		return err
+	}
+	if err = checkSecrets(ourCommit, theirCommit, opts); err != nil {
+		return err
+	}
+
+	ff, err := isFastForward(r, ourCommit.Hash, theirCommit.Hash, opts)
+	if err != nil {
+		return err
+	}
+	// A fast-forward would bring the paths outside of the merge, merge instead
+	if ff && (len(opts.Exclude) > 0 || len(opts.PathSpecs) > 0) {
+		ff, err = keepsOutside(ourCommit, theirCommit, opts)
+		if err != nil {
+			return err
+		}
+	}
+
+	if ff && !opts.NoFastForward && ourCommit.Hash != theirCommit.Hash {
+		if err = moveBranch(r, target, theirCommit.Hash); err != nil {
+			return err
+		}
+		if err = describe(result, ourCommit, theirCommit); err != nil {
+			return err
+		}
+		result.FastForward = true
+
+		if opts.Progress != nil {
+			_, _ = fmt.Fprintf(opts.Progress,
+				"%s\n%s\n%s%s\n",
+				i18n.Tf("Updating %s...%s", ourCommit.Hash.String()[:7], theirCommit.Hash.String()[:7]),
+				i18n.T("Fast-forward"),
+				result.Stats,
+				result.Summary)
+		}
+		opts.events().CommitCreated(theirCommit.Hash)
+		return nil
+	}
+
+	if opts.Strategy == FastForwardOnly {
+		return git.ErrFastForwardMergeNotPossible
+	}
+
+	baseCommits, err := ourCommit.MergeBase(theirCommit)
+	if err != nil {
+		return err
+	}
+	if len(baseCommits) < 1 && !opts.AllowUnrelatedHistories {
+		return ErrUnrelatedHistories
+	}
+
+	var baseTree *object.Tree
+	baseLabel := emptyTree.String()[:7]
+	if len(baseCommits) > 0 {
+		baseTree, err = baseCommits[0].Tree()
+		if err != nil {
+			return err
+		}
+		baseLabel = baseCommits[0].Hash.String()[:7]
+	}
+	ourTree, err := ourCommit.Tree()
+	if err != nil {
+		return err
+	}
+	theirTree, err := theirCommit.Tree()
+	if err != nil {
+		return err
+	}
+
+	opts.Labels = Labels{
+		Ours:   cmp.Or(opts.Labels.Ours, target.Name().Short()),
+		Base:   cmp.Or(opts.Labels.Base, baseLabel),
+		Theirs: cmp.Or(opts.Labels.Theirs, ref.Name().Short()),
+	}
+	merged, err := MergeTrees(r.Storer, baseTree, ourTree, theirTree, opts)
+	if err != nil {
+		return err
+	}
+
+	if len(merged.Conflicts) > 0 {
+		result.TreeConflicts = merged.Conflicts
+		for _, conflict := range merged.Conflicts {
+			result.Conflicts = append(result.Conflicts, conflict.Path)
+			if opts.Progress != nil {
+				_, _ = fmt.Fprintln(opts.Progress, conflict.Reason)
+			}
+		}
+		result.Summary.Conflicted = len(merged.Conflicts)
+		return ErrMergeConflict
+	}
+
+	// Nothing to commit means up to date, unless a descendant adding no
+	// change was asked not to be fast-forwarded
+	noFastForward := ff && opts.NoFastForward && ourCommit.Hash != theirCommit.Hash
+	if merged.Tree == ourCommit.TreeHash && !noFastForward {
+		return nil
+	}
+
+	author, committer := opts.signatures(r, ourCommit)
+	commit := &object.Commit{
+		Author:       *author,
+		Committer:    *committer,
+		Message:      opts.message(mergeMessage(target, &ref), target, ref),
+		TreeHash:     merged.Tree,
+		ParentHashes: opts.ParentOrder.parents(ourCommit.Hash, theirCommit.Hash),
+	}
+	newHash, err := storeCommit(r, commit, opts.SignKey)
+	if err != nil {
+		return err
+	}
+	if err = moveBranch(r, target, newHash); err != nil {
+		return err
+	}
+
+	newCommit, err := r.CommitObject(newHash)
+	if err != nil {
+		return err
+	}
+	if err = describe(result, ourCommit, newCommit); err != nil {
+		return err
+	}
+
+	opts.events().CommitCreated(newHash)
+	if opts.Progress != nil {
+		_, _ = fmt.Fprintf(opts.Progress, "%s\n%s%s\n", i18n.T("Merge made by the 'ort' strategy."), result.Stats, result.Summary)
+	}
+	return nil
+}
+
+// moveBranch moves target to hash, unless another writer moved it since
+func moveBranch(r *git.Repository, target *plumbing.Reference, hash plumbing.Hash) error {
+	return r.Storer.CheckAndSetReference(plumbing.NewHashReference(target.Name(), hash), target)
+}
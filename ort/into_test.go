@@ -0,0 +1,79 @@
+package ort
+
+import (
+	"errors"
+	"maps"
+	"testing"
+
+	"github.com/go-git/go-git/v6/plumbing"
+)
+
+func TestMergeInto(t *testing.T) {
+	r := newTestRepository(t)
+	base := commitFiles(t, r, "base", map[string]string{"README": "base\n"})
+	release := commitFiles(t, r, "release", map[string]string{"README": "base\n", "CHANGELOG": "1.0\n"}, base)
+	theirs := commitFiles(t, r, "theirs", map[string]string{"README": "theirs\n"}, base)
+	checkoutBranch(t, r, "main", base)
+	branch := plumbing.NewBranchReferenceName("release")
+	if err := r.Storer.SetReference(plumbing.NewHashReference(branch, release)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := MergeInto(r, plumbing.NewBranchReferenceName("main"), branchRef("theirs", theirs), MergeOptions{}); !errors.Is(err, ErrBranchCheckedOut) {
+		t.Fatalf("MergeInto() the branch of HEAD = %v, want %v", err, ErrBranchCheckedOut)
+	}
+
+	result, err := MergeInto(r, branch, branchRef("theirs", theirs), MergeOptions{Author: &testSignature, Committer: &testSignature})
+	if err != nil {
+		t.Fatal(err)
+	}
+	moved, err := r.Reference(branch, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.Hash() != result.Commit {
+		t.Fatalf("release = %s, want the merge commit %s", moved.Hash(), result.Commit)
+	}
+	commit, err := r.CommitObject(result.Commit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"README": "theirs\n", "CHANGELOG": "1.0\n"}
+	if got := treeFiles(t, r, commit.TreeHash); !maps.Equal(got, want) {
+		t.Errorf("merged files = %v, want %v", got, want)
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Hash() != base {
+		t.Errorf("HEAD = %s, want it left at %s", head.Hash(), base)
+	}
+	if got := readWorktree(t, r, "README"); got != "base\n" {
+		t.Errorf("README = %q, want the worktree untouched", got)
+	}
+}
+
+func TestMergeIntoLabels(t *testing.T) {
+	r := newTestRepository(t)
+	base := commitFiles(t, r, "base", map[string]string{"README": "base\n"})
+	release := commitFiles(t, r, "release", map[string]string{"README": "release\n"}, base)
+	theirs := commitFiles(t, r, "theirs", map[string]string{}, base)
+	checkoutBranch(t, r, "main", base)
+	branch := plumbing.NewBranchReferenceName("release")
+	if err := r.Storer.SetReference(plumbing.NewHashReference(branch, release)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The unset labels default to the branches
+	opts := MergeOptions{Author: &testSignature, Committer: &testSignature, Labels: Labels{Theirs: "upstream"}}
+	result, err := MergeInto(r, branch, branchRef("theirs", theirs), opts)
+	if !errors.Is(err, ErrMergeConflict) {
+		t.Fatalf("MergeInto() error = %v, want %v", err, ErrMergeConflict)
+	}
+	want := "CONFLICT (modify/delete): README deleted in upstream and modified in release"
+	if len(result.TreeConflicts) != 1 || result.TreeConflicts[0].Reason != want {
+		t.Fatalf("conflicts = %+v, want %q", result.TreeConflicts, want)
+	}
+}
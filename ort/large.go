@@ -0,0 +1,42 @@
+package ort
+
+import (
+	"gravel/i18n"
+
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+// DefaultLargeFileThreshold is the size above which files are not line
+// merged, diff3 holds the three sides in memory
+const DefaultLargeFileThreshold int64 = 64 << 20
+
+// largeFileThreshold returns the threshold of opts, the default when unset
+func (opts MergeOptions) largeFileThreshold() int64 {
+	if opts.LargeFileThreshold > 0 {
+		return opts.LargeFileThreshold
+	}
+	return DefaultLargeFileThreshold
+}
+
+// unmergeable returns why the files cannot be line merged, empty when they
+// can. Only the size and the first bytes of the blobs are read
+func unmergeable(filepath string, threshold int64, labels Labels, files ...*object.File) (string, error) {
+	for _, file := range files {
+		if file == nil {
+			continue
+		}
+		if file.Size > threshold {
+			return i18n.Tf("CONFLICT (large file): %s is larger than %d bytes, cannot merge it (%s vs. %s)",
+				filepath, threshold, labels.Ours, labels.Theirs), nil
+		}
+
+		binary, err := file.IsBinary()
+		if err != nil {
+			return "", err
+		}
+		if binary {
+			return i18n.Tf("CONFLICT (binary): merge conflict in %s", filepath), nil
+		}
+	}
+	return "", nil
+}
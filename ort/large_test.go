@@ -0,0 +1,76 @@
+package ort
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestMergeLargeFile(t *testing.T) {
+	const threshold = 32
+	for _, test := range []struct {
+		name       string
+		filepath   string
+		base       string
+		ours       string
+		theirs     string
+		strategies []PathStrategy
+		conflicts  []string
+	}{
+		{
+			name:       "json",
+			filepath:   "data.json",
+			base:       `{"a": 1}`,
+			ours:       `{"a": 1, "ours": "a value past the threshold"}`,
+			theirs:     `{"a": 1, "theirs": 2}`,
+			strategies: []PathStrategy{{Pattern: "*.json", Strategy: StrategyJSONMerge}},
+			conflicts:  []string{"data.json"},
+		},
+		{
+			name:      "ignore file",
+			filepath:  ".gitignore",
+			base:      "bin/\n",
+			ours:      "bin/\nnode_modules/\ncoverage/\ndist/\n",
+			theirs:    "bin/\ntmp/\n",
+			conflicts: []string{".gitignore"},
+		},
+		{
+			name:       "small json",
+			filepath:   "data.json",
+			base:       `{"a": 1}`,
+			ours:       `{"a": 1, "b": 2}`,
+			theirs:     `{"a": 1, "c": 3}`,
+			strategies: []PathStrategy{{Pattern: "*.json", Strategy: StrategyJSONMerge}},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRepository(t)
+			base := commitFiles(t, r, "base", map[string]string{test.filepath: test.base})
+			ours := commitFiles(t, r, "ours", map[string]string{test.filepath: test.ours}, base)
+			theirs := commitFiles(t, r, "theirs", map[string]string{test.filepath: test.theirs}, base)
+			checkoutBranch(t, r, "main", ours)
+
+			result, err := Merge(r, branchRef("theirs", theirs), MergeOptions{
+				Author:             &testSignature,
+				Committer:          &testSignature,
+				ConflictStrategies: test.strategies,
+				LargeFileThreshold: threshold,
+			})
+			if test.conflicts == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrMergeConflict) {
+				t.Fatalf("Merge() error = %v, want %v", err, ErrMergeConflict)
+			}
+			if !slices.Equal(result.Conflicts, test.conflicts) {
+				t.Errorf("conflicts = %v, want %v", result.Conflicts, test.conflicts)
+			}
+			if got := readWorktree(t, r, test.filepath); got != test.ours {
+				t.Errorf("%s = %q, want ours kept whole", test.filepath, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package ort
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/go-git/go-billy/v6"
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/format/index"
+	"github.com/go-git/go-git/v6/storage"
+)
+
+// ErrIndexLocked is returned when index.lock exists: another git process is
+// running in the repository, or one crashed and left it like git
+var ErrIndexLocked = errors.New("index.lock exists, another git process seems to be running in this repository")
+
+const (
+	indexFile     = "index"
+	indexLockFile = "index.lock"
+)
+
+// indexLock holds index.lock while a merge stages its changes. The index
+// is kept in memory by standing in for the storer of a copy of the
+// repository, so that the index on disk is untouched until commit swaps it
+// in at once and the repository of the caller is left as it was
+type indexLock struct {
+	storage.Storer
+	// r is the copy of the locked repository staging into the lock, it
+	// shares the worktree of the locked one
+	r *git.Repository
+	// fs is the git directory, nil for in-memory repositories which need
+	// no lock
+	fs      billy.Filesystem
+	index   *index.Index
+	changed bool
+	// released is set once the index is handed back to the storer,
+	// committed once the staged index replaced the index on disk
+	released  bool
+	committed bool
+}
+
+// lockIndex takes index.lock of r and returns a copy of r staging the
+// index in memory until commit or release
+func lockIndex(r *git.Repository) (*indexLock, error) {
+	var worktree billy.Filesystem
+	w, err := r.Worktree()
+	if err == nil {
+		worktree = w.Filesystem
+	} else if !errors.Is(err, git.ErrIsBareRepository) {
+		return nil, err
+	}
+
+	lock := &indexLock{Storer: r.Storer, fs: gitDir(r)}
+	if lock.fs != nil {
+		file, err := lock.fs.OpenFile(indexLockFile, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o666)
+		if errors.Is(err, os.ErrExist) {
+			return nil, ErrIndexLocked
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err = file.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	lock.r, err = git.Open(lock, worktree)
+	if err != nil {
+		return nil, lock.abort(err)
+	}
+	return lock, nil
+}
+
+// Filesystem keeps the git directory reachable through the lock, for gitDir
+func (lock *indexLock) Filesystem() billy.Filesystem {
+	return lock.fs
+}
+
+func (lock *indexLock) Index() (*index.Index, error) {
+	if lock.released {
+		return lock.Storer.Index()
+	}
+	if lock.index == nil {
+		idx, err := lock.Storer.Index()
+		if err != nil {
+			return nil, err
+		}
+		lock.index = idx
+	}
+	return lock.index, nil
+}
+
+func (lock *indexLock) SetIndex(idx *index.Index) error {
+	if lock.released {
+		return lock.Storer.SetIndex(idx)
+	}
+	lock.index = idx
+	lock.changed = true
+	return nil
+}
+
+// commit writes the staged index into index.lock and renames it over the
+// index, the copy then reads and writes the index on disk again
+func (lock *indexLock) commit() error {
+	if lock.released {
+		return nil
+	}
+	lock.released = true
+
+	if err := lock.swap(); err != nil {
+		return err
+	}
+	lock.committed = true
+	return nil
+}
+
+func (lock *indexLock) swap() error {
+	if !lock.changed {
+		return lock.unlock()
+	}
+	if lock.fs == nil {
+		return lock.Storer.SetIndex(lock.index)
+	}
+
+	cfg, err := lock.Storer.Config()
+	if err != nil {
+		return lock.abort(err)
+	}
+	file, err := lock.fs.OpenFile(indexLockFile, os.O_WRONLY|os.O_TRUNC, 0o666)
+	if err != nil {
+		return lock.abort(err)
+	}
+	buffered := bufio.NewWriter(file)
+	hasher := plumbing.NewHasher(cfg.Extensions.ObjectFormat, plumbing.AnyObject, 0)
+	err = index.NewEncoder(buffered, hasher.Hash).Encode(lock.index)
+	if err == nil {
+		err = buffered.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return lock.abort(err)
+	}
+	if err = lock.fs.Rename(indexLockFile, indexFile); err != nil {
+		return lock.abort(err)
+	}
+	return nil
+}
+
+// release drops the staged index and index.lock unless commit swapped them
+// in, the index on disk stays as it was before the merge
+func (lock *indexLock) release() error {
+	if lock.released {
+		return nil
+	}
+	lock.released = true
+	return lock.unlock()
+}
+
+func (lock *indexLock) unlock() error {
+	if lock.fs == nil {
+		return nil
+	}
+	return lock.fs.Remove(indexLockFile)
+}
+
+// abort releases index.lock once writing the index failed with cause
+func (lock *indexLock) abort(cause error) error {
+	if err := lock.unlock(); err != nil {
+		return fmt.Errorf("%w, removing %s: %w", cause, indexLockFile, err)
+	}
+	return cause
+}
@@ -0,0 +1,40 @@
+package ort
+
+import "testing"
+
+func TestLockIndexKeepsRepository(t *testing.T) {
+	r := newTestRepository(t)
+	checkoutBranch(t, r, "main", commitFiles(t, r, "base", map[string]string{"README": "base\n"}))
+	storer := r.Storer
+
+	lock, err := lockIndex(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Storer != storer {
+		t.Fatal("lockIndex replaced the storer of the locked repository")
+	}
+
+	writeWorktree(t, lock.r, "notes", "notes\n")
+	w, err := lock.r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.Add("notes"); err != nil {
+		t.Fatal(err)
+	}
+	if err = lock.commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	idx, err := r.Storer.Index()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = idx.Entry("notes"); err != nil {
+		t.Fatalf("the committed index is missing notes: %v", err)
+	}
+	if err = lock.release(); err != nil {
+		t.Fatal(err)
+	}
+}
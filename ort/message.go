@@ -0,0 +1,212 @@
+package ort
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"gravel/render"
+
+	"github.com/go-git/go-billy/v6"
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/config"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+const (
+	// MERGE_MSG holds the prepared message of a merge stopped on conflicts
+	MERGE_MSG = "MERGE_MSG"
+	// SQUASH_MSG holds the prepared message of a squashed merge
+	SQUASH_MSG = "SQUASH_MSG"
+)
+
+// mergeMessage is the message of the commit merging ref into head
+func mergeMessage(head, ref *plumbing.Reference) string {
+	return fmt.Sprintf(
+		"Merge %s with %s",
+		plumbing.NewBranchReferenceName(head.Name().Short()),
+		ref.Name(),
+	)
+}
+
+// message renders the Message template of opts for the merge of refs into
+// head, fallback is the message git would write without one
+func (opts MergeOptions) message(fallback string, head *plumbing.Reference, refs ...plumbing.Reference) string {
+	if opts.Message == "" {
+		return fallback
+	}
+
+	names := make([]string, len(refs))
+	hashes := make([]string, len(refs))
+	for index, ref := range refs {
+		names[index] = ref.Name().Short()
+		hashes[index] = ref.Hash().String()[:7]
+	}
+	return string(render.Render([]byte(opts.Message), map[string]string{
+		"branch": head.Name().Short(),
+		"theirs": strings.Join(names, ", "),
+		"commit": strings.Join(hashes, ", "),
+	}))
+}
+
+// signatures returns the author and committer of the commits made on top of
+// ours: those of opts, then the author, committer and user identities of
+// the git configuration like git, then the signatures of ours
+func (opts MergeOptions) signatures(r *git.Repository, ours *object.Commit) (author, committer *object.Signature) {
+	now := time.Now()
+	author, committer = dated(opts.Author, now), dated(opts.Committer, now)
+	if author != nil && committer != nil {
+		return author, committer
+	}
+
+	// An unreadable configuration is ignored like a missing identity
+	var name, email, authorName, authorEmail, committerName, committerEmail string
+	if cfg, err := r.ConfigScoped(config.SystemScope); err == nil {
+		name, email = cfg.User.Name, cfg.User.Email
+		authorName, authorEmail = cmp.Or(cfg.Author.Name, name), cmp.Or(cfg.Author.Email, email)
+		committerName, committerEmail = cmp.Or(cfg.Committer.Name, name), cmp.Or(cfg.Committer.Email, email)
+	}
+
+	if author == nil {
+		author = &ours.Author
+		if authorName != "" && authorEmail != "" {
+			author = &object.Signature{Name: authorName, Email: authorEmail, When: now}
+		}
+	}
+	if committer == nil {
+		committer = &ours.Committer
+		if committerName != "" && committerEmail != "" {
+			committer = &object.Signature{Name: committerName, Email: committerEmail, When: now}
+		}
+	}
+	return author, committer
+}
+
+// dated returns signature dated now when it has no date
+func dated(signature *object.Signature, now time.Time) *object.Signature {
+	if signature == nil || !signature.When.IsZero() {
+		return signature
+	}
+	return &object.Signature{Name: signature.Name, Email: signature.Email, When: now}
+}
+
+// squashMessage lists the squashed commits, those of theirs not reachable from the merge bases
+func squashMessage(theirs *object.Commit, bases []*object.Commit) (string, error) {
+	var ignore []plumbing.Hash
+	for _, base := range bases {
+		ignore = append(ignore, base.Hash)
+	}
+
+	var builder strings.Builder
+	builder.WriteString("Squashed commit of the following:\n")
+	err := object.NewCommitPreorderIter(theirs, nil, ignore).ForEach(func(commit *object.Commit) error {
+		_, _ = fmt.Fprintf(&builder, "\ncommit %s\nAuthor: %s <%s>\nDate:   %s\n\n",
+			commit.Hash,
+			commit.Author.Name,
+			commit.Author.Email,
+			commit.Author.When.Format(object.DateFormat),
+		)
+		for _, line := range strings.Split(strings.TrimRight(commit.Message, "\n"), "\n") {
+			_, _ = fmt.Fprintf(&builder, "    %s\n", line)
+		}
+		return nil
+	})
+	return builder.String(), err
+}
+
+// conflictMessage appends the commented conflict list git writes into MERGE_MSG
+func conflictMessage(message string, conflicts []conflictEntry) string {
+	var builder strings.Builder
+	builder.WriteString(message)
+	builder.WriteString("\n\n# Conflicts:\n")
+	for _, conflict := range conflicts {
+		_, _ = fmt.Fprintf(&builder, "#\t%s\n", conflict.path)
+	}
+	return builder.String()
+}
+
+// gitDir returns the filesystem of the git directory, nil for storers which
+// are not backed by one such as in-memory repositories
+func gitDir(r *git.Repository) billy.Filesystem {
+	storer, ok := r.Storer.(interface{ Filesystem() billy.Filesystem })
+	if !ok {
+		return nil
+	}
+	return storer.Filesystem()
+}
+
+// writeMergeMsg writes MERGE_MSG into the git directory
+func writeMergeMsg(r *git.Repository, message string) error {
+	return writeGitFile(r, MERGE_MSG, message)
+}
+
+// writeSquashMsg writes SQUASH_MSG into the git directory
+func writeSquashMsg(r *git.Repository, message string) error {
+	return writeGitFile(r, SQUASH_MSG, message)
+}
+
+func writeGitFile(r *git.Repository, name, content string) error {
+	fs := gitDir(r)
+	if fs == nil {
+		return nil
+	}
+
+	file, err := fs.Create(name)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	_, err = io.WriteString(file, content)
+	return err
+}
+
+// readMergeMsg returns MERGE_MSG without its comment lines, empty when missing
+func readMergeMsg(r *git.Repository) (string, error) {
+	fs := gitDir(r)
+	if fs == nil {
+		return "", nil
+	}
+
+	file, err := fs.Open(MERGE_MSG)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = file.Close() }()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return "", err
+	}
+
+	// Strip comments like `git commit --cleanup=strip`
+	var lines []string
+	for _, line := range strings.Split(string(content), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n")), nil
+}
+
+// removeMergeMsg deletes MERGE_MSG, a missing file is not an error
+func removeMergeMsg(r *git.Repository) error {
+	fs := gitDir(r)
+	if fs == nil {
+		return nil
+	}
+
+	err := fs.Remove(MERGE_MSG)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,40 @@
+package ort
+
+import (
+	"github.com/go-git/go-billy/v6"
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing/filemode"
+)
+
+// mergeMode three-way merges the modes of a path like its content, ok is
+// false when both sides changed the mode differently, ours is kept then
+func mergeMode(base, ours, theirs filemode.FileMode) (mode filemode.FileMode, ok bool) {
+	switch {
+	case ours == theirs, theirs == base:
+		return ours, true
+	case ours == base:
+		return theirs, true
+	default:
+		return ours, false
+	}
+}
+
+// applyMode sets the permissions of a worktree file to the mode of its tree
+// entry, Worktree.Add stages the mode it reads from the filesystem. Symlinks
+// and filesystems without permissions are left alone
+func applyMode(w *git.Worktree, filepath string, mode filemode.FileMode) error {
+	if mode != filemode.Regular && mode != filemode.Executable {
+		return nil
+	}
+
+	chmod, ok := w.Filesystem.(billy.Chmod)
+	if !ok {
+		return nil
+	}
+
+	osMode, err := mode.ToOSFileMode()
+	if err != nil {
+		return err
+	}
+	return chmod.Chmod(filepath, osMode.Perm())
+}
@@ -0,0 +1,348 @@
+package ort
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gravel/i18n"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+// ErrOctopusOptions is returned when MergeMany is asked to squash or not to commit
+var ErrOctopusOptions = errors.New("octopus merges cannot be squashed or left uncommitted")
+
+// MergeMany merges every ref into HEAD with a single octopus commit. When a
+// ref conflicts, HEAD is restored and the refs are merged one at a time
+// instead, stopping at the first conflict like Merge does. The result of
+// such a fallback is the one of the last merge. Tracked files must not have
+// uncommitted changes, untracked ones are only refused where a merge writes
+func MergeMany(r *git.Repository, refs []plumbing.Reference, opts MergeOptions) (*MergeResult, error) {
+	return MergeManyContext(context.Background(), r, refs, opts)
+}
+
+// MergeManyContext is MergeMany checking ctx between the files it merges,
+// like MergeContext
+func MergeManyContext(ctx context.Context, r *git.Repository, refs []plumbing.Reference, opts MergeOptions) (*MergeResult, error) {
+	if opts.DryRun {
+		return dryRun(r, opts, func(sandbox *git.Repository, opts MergeOptions) (*MergeResult, error) {
+			return MergeManyContext(ctx, sandbox, refs, opts)
+		})
+	}
+
+	result := &MergeResult{}
+	return result, mergeMany(ctx, r, refs, opts, result)
+}
+
+func mergeMany(ctx context.Context, r *git.Repository, refs []plumbing.Reference, opts MergeOptions, result *MergeResult) error {
+	if opts.Squash || opts.NoCommit {
+		return ErrOctopusOptions
+	}
+	if len(refs) == 1 {
+		single, err := MergeContext(ctx, r, refs[0], opts)
+		*result = *single
+		return err
+	}
+
+	if _, err := mergeHead(r); err == nil {
+		return ErrMergeInProgress
+	} else if !errors.Is(err, ErrNoMergeInProgress) {
+		return err
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+	if err = checkTracked(w); err != nil {
+		return err
+	}
+
+	refs, err = peelAll(r, refs)
+	if err != nil {
+		return err
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		return err
+	}
+
+	ourCommit, err := r.CommitObject(head.Hash())
+	if err != nil {
+		return err
+	}
+
+	// Scanned once against HEAD, the pairwise merges would report again
+	for _, ref := range refs {
+		var theirCommit *object.Commit
+		if theirCommit, err = r.CommitObject(ref.Hash()); err != nil {
+			return err
+		}
+		if err = checkSecrets(ourCommit, theirCommit, opts); err != nil {
+			return err
+		}
+	}
+	opts.Secrets = SecretsIgnore
+
+	// Merge each ref on top of the previous one, only the final tree is kept
+	pairwise := opts
+	pairwise.Progress = nil
+	pairwise.Events = nil
+	pairwise.NoHooks = true
+	pairwise.Backup = false
+	for _, ref := range refs {
+		_, err = MergeContext(ctx, r, ref, pairwise)
+		if errors.Is(err, ErrMergeConflict) {
+			return fallback(ctx, r, ourCommit, ref, refs, opts, result)
+		}
+		// The refs merged so far are dropped with the failing one
+		if err != nil {
+			return restoreHead(r, ourCommit, err)
+		}
+	}
+
+	history := newAncestry(r)
+	defer func() { _ = history.close() }()
+	hooks := newMergeHooks(r, opts)
+
+	var theirs []plumbing.Hash
+	var names []string
+	var merging []plumbing.Reference
+	for _, ref := range refs {
+		var theirCommit *object.Commit
+		theirCommit, err = r.CommitObject(ref.Hash())
+		if err != nil {
+			return err
+		}
+
+		// Refs already contained in HEAD add nothing to the history
+		var merged bool
+		merged, err = history.isAncestor(theirCommit.Hash, ourCommit.Hash)
+		if err != nil {
+			return err
+		}
+		if merged {
+			continue
+		}
+		theirs = append(theirs, theirCommit.Hash)
+		names = append(names, ref.Name().Short())
+		merging = append(merging, ref)
+	}
+
+	merged, err := r.Head()
+	if err != nil {
+		return err
+	}
+
+	mergedCommit, err := r.CommitObject(merged.Hash())
+	if err != nil {
+		return err
+	}
+
+	// A single side left is an ordinary merge, already committed by Merge
+	if len(theirs) < 2 {
+		if err = describe(result, ourCommit, mergedCommit); err != nil {
+			return err
+		}
+		if mergedCommit.Hash != ourCommit.Hash {
+			opts.events().CommitCreated(mergedCommit.Hash)
+		}
+		if err = setOrigHead(r, head); err != nil {
+			return err
+		}
+		hooks.merged(ctx, false)
+		return nil
+	}
+
+	// Without MERGE_HEAD for every ref, a rejected octopus is undone
+	message := opts.message(fmt.Sprintf("Merge %s into %s", strings.Join(names, ", "), head.Name().Short()), head, merging...)
+	message, err = hooks.verify(ctx, r, message)
+	if err != nil {
+		return restoreHead(r, ourCommit, err)
+	}
+
+	author, committer := opts.signatures(r, ourCommit)
+	octopus := &object.Commit{
+		Author:       *author,
+		Committer:    *committer,
+		Message:      message,
+		TreeHash:     mergedCommit.TreeHash,
+		ParentHashes: opts.ParentOrder.parents(ourCommit.Hash, theirs...),
+	}
+
+	hash, err := storeCommit(r, octopus, opts.SignKey)
+	if err != nil {
+		return err
+	}
+
+	err = r.Storer.SetReference(plumbing.NewHashReference(head.Name(), hash))
+	if err != nil {
+		return err
+	}
+	if err = setOrigHead(r, head); err != nil {
+		return err
+	}
+	opts.events().CommitCreated(hash)
+
+	newCommit, err := r.CommitObject(hash)
+	if err != nil {
+		return err
+	}
+	if err = describe(result, ourCommit, newCommit); err != nil {
+		return err
+	}
+
+	if opts.Progress != nil {
+		_, _ = fmt.Fprintf(opts.Progress, "%s\n%s%s\n", i18n.T("Merge made by the 'octopus' strategy."), result.Stats, result.Summary)
+	}
+	hooks.merged(ctx, false)
+	return nil
+}
+
+// describe records in result the commit HEAD moved to from ours
+func describe(result *MergeResult, ours, head *object.Commit) error {
+	patch, err := ours.Patch(head)
+	if err != nil {
+		return err
+	}
+	result.Commit = head.Hash
+	result.Stats = patch.Stats()
+	result.Summary = summarizePatch(patch)
+	return nil
+}
+
+// restoreHead moves HEAD back to ours, once the pairwise merges were
+// interrupted by cause
+func restoreHead(r *git.Repository, ours *object.Commit, cause error) error {
+	if err := resetMerged(r, ours); err != nil {
+		return fmt.Errorf("%w, restoring HEAD: %w", cause, err)
+	}
+	return cause
+}
+
+// resetMerged moves HEAD back to ours with the index. Only the files the
+// pairwise merges wrote are reset in the worktree: those HEAD changed since
+// ours and, for a conflicted merge of theirs, those differing in theirs.
+// The tracked files were clean, checkTracked saw to it, and the untracked
+// ones are never written, so no change of the user is lost
+func resetMerged(r *git.Repository, ours *object.Commit, theirs ...plumbing.Hash) error {
+	head, err := r.Head()
+	if err != nil {
+		return err
+	}
+	current, err := r.CommitObject(head.Hash())
+	if err != nil {
+		return err
+	}
+
+	paths, err := diffPaths(ours, current)
+	if err != nil {
+		return err
+	}
+	for _, hash := range theirs {
+		var commit *object.Commit
+		if commit, err = r.CommitObject(hash); err != nil {
+			return err
+		}
+		var written []string
+		if written, err = diffPaths(current, commit); err != nil {
+			return err
+		}
+		paths = append(paths, written...)
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+	// The whole index is reset, its conflict stages with it
+	if err = w.Reset(&git.ResetOptions{Commit: ours.Hash, Mode: git.MixedReset}); err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+	return w.Reset(&git.ResetOptions{Commit: ours.Hash, Mode: git.HardReset, Files: paths})
+}
+
+// diffPaths returns the paths that differ between the trees of from and to
+func diffPaths(from, to *object.Commit) ([]string, error) {
+	if from.TreeHash == to.TreeHash {
+		return nil, nil
+	}
+	fromTree, err := from.Tree()
+	if err != nil {
+		return nil, err
+	}
+	toTree, err := to.Tree()
+	if err != nil {
+		return nil, err
+	}
+	changes, err := object.DiffTree(fromTree, toTree)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, change := range changes {
+		for _, name := range []string{change.From.Name, change.To.Name} {
+			if name != "" {
+				paths = append(paths, name)
+			}
+		}
+	}
+	return paths, nil
+}
+
+// checkTracked returns ErrDirtyWorktree when a tracked file has uncommitted
+// changes, which restoring HEAD after the pairwise merges would lose
+func checkTracked(w *git.Worktree) error {
+	status, err := w.Status()
+	if err != nil {
+		return err
+	}
+	for _, file := range status {
+		if file.Worktree == git.Untracked {
+			continue
+		}
+		if file.Staging != git.Unmodified || file.Worktree != git.Unmodified {
+			return ErrDirtyWorktree
+		}
+	}
+	return nil
+}
+
+// fallback restores HEAD to ourCommit once the pairwise merge of conflicted
+// stopped on conflicts, and merges the refs one at a time
+func fallback(ctx context.Context, r *git.Repository, ourCommit *object.Commit, conflicted plumbing.Reference, refs []plumbing.Reference, opts MergeOptions, result *MergeResult) error {
+	if err := resetMerged(r, ourCommit, conflicted.Hash()); err != nil {
+		return err
+	}
+	if err := removeMergeMsg(r); err != nil {
+		return err
+	}
+	if err := removeMergeRR(r); err != nil {
+		return err
+	}
+	if err := r.Storer.RemoveReference(MERGE_HEAD); err != nil {
+		return err
+	}
+
+	if opts.Progress != nil {
+		_, _ = fmt.Fprintln(opts.Progress, i18n.T("Octopus merge conflicts, merging one ref at a time."))
+	}
+
+	for _, ref := range refs {
+		merged, err := MergeContext(ctx, r, ref, opts)
+		*result = *merged
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package ort
+
+import (
+	"errors"
+	"maps"
+	"testing"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+)
+
+// octopusRepository returns a repository on base with the commits of the
+// files first and second on base
+func octopusRepository(t *testing.T, first, second map[string]string) (r *git.Repository, base, firstCommit, secondCommit plumbing.Hash) {
+	t.Helper()
+	r = newTestRepository(t)
+	base = commitFiles(t, r, "base", map[string]string{"README": "base\n"})
+	firstCommit = commitFiles(t, r, "first", first, base)
+	secondCommit = commitFiles(t, r, "second", second, base)
+	checkoutBranch(t, r, "main", base)
+	return
+}
+
+func TestMergeMany(t *testing.T) {
+	r, _, first, second := octopusRepository(t,
+		map[string]string{"README": "base\n", "first": "first\n"},
+		map[string]string{"README": "base\n", "second": "second\n"},
+	)
+
+	result, err := MergeMany(r, []plumbing.Reference{branchRef("first", first), branchRef("second", second)}, MergeOptions{Author: &testSignature, Committer: &testSignature})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commit, err := r.CommitObject(result.Commit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commit.NumParents() != 3 {
+		t.Fatalf("octopus commit has %d parents, want 3", commit.NumParents())
+	}
+	want := map[string]string{"README": "base\n", "first": "first\n", "second": "second\n"}
+	if got := headFiles(t, r); !maps.Equal(got, want) {
+		t.Fatalf("merged files = %v, want %v", got, want)
+	}
+}
+
+func TestMergeManyRestoresHead(t *testing.T) {
+	r, base, first, second := octopusRepository(t,
+		map[string]string{"README": "base\n", "first": "first\n"},
+		map[string]string{"README": "base\n", "second": "second\n"},
+	)
+	// The merge of second would overwrite it once first is merged
+	writeWorktree(t, r, "second", "mine\n")
+
+	_, err := MergeMany(r, []plumbing.Reference{branchRef("first", first), branchRef("second", second)}, MergeOptions{Author: &testSignature, Committer: &testSignature})
+	if !errors.Is(err, ErrLocalChanges) {
+		t.Fatalf("err = %v, want %v", err, ErrLocalChanges)
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Hash() != base {
+		t.Fatalf("HEAD = %s, want it restored to %s", head.Hash(), base)
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.Filesystem.Stat("first"); err == nil {
+		t.Error("the file of the dropped pairwise merge is left in the worktree")
+	}
+	if got := readWorktree(t, r, "second"); got != "mine\n" {
+		t.Errorf("untracked second = %q, want it kept", got)
+	}
+}
+
+func TestMergeManyFallbackKeepsUntracked(t *testing.T) {
+	r, _, first, second := octopusRepository(t,
+		map[string]string{"README": "first\n"},
+		map[string]string{"README": "second\n"},
+	)
+	writeWorktree(t, r, "notes", "mine\n")
+
+	_, err := MergeMany(r, []plumbing.Reference{branchRef("first", first), branchRef("second", second)}, MergeOptions{Author: &testSignature, Committer: &testSignature})
+	if !errors.Is(err, ErrMergeConflict) {
+		t.Fatalf("err = %v, want %v", err, ErrMergeConflict)
+	}
+
+	// Merged one at a time, first fast-forwards then second conflicts
+	head, err := r.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Hash() != first {
+		t.Fatalf("HEAD = %s, want %s", head.Hash(), first)
+	}
+	if got := readWorktree(t, r, "notes"); got != "mine\n" {
+		t.Errorf("untracked notes = %q, want it kept", got)
+	}
+}
+
+func TestMergeManyDirtyWorktree(t *testing.T) {
+	r, _, first, second := octopusRepository(t,
+		map[string]string{"README": "base\n", "first": "first\n"},
+		map[string]string{"README": "base\n", "second": "second\n"},
+	)
+	writeWorktree(t, r, "README", "changed\n")
+
+	_, err := MergeMany(r, []plumbing.Reference{branchRef("first", first), branchRef("second", second)}, MergeOptions{})
+	if !errors.Is(err, ErrDirtyWorktree) {
+		t.Fatalf("err = %v, want %v", err, ErrDirtyWorktree)
+	}
+	if got := readWorktree(t, r, "README"); got != "changed\n" {
+		t.Errorf("README = %q, want the uncommitted change kept", got)
+	}
+}
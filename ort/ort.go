@@ -1,17 +1,26 @@
 package ort
 
 import (
+	"bytes"
+	"cmp"
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
 
+	"gravel/i18n"
 	"gravel/ort/diff3"
 
 	"github.com/go-git/go-git/v6"
 	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/filemode"
+	"github.com/go-git/go-git/v6/plumbing/format/diff"
 	"github.com/go-git/go-git/v6/plumbing/format/index"
 	"github.com/go-git/go-git/v6/plumbing/object"
-	"github.com/go-git/go-git/v6/plumbing/storer"
 	"github.com/go-git/go-git/v6/utils/merkletrie"
 )
 
@@ -23,26 +32,274 @@ const (
 
 const (
 	MERGE_HEAD plumbing.ReferenceName = "MERGE_HEAD"
+	// ORIG_HEAD records HEAD before a merge moves it, `git reset --hard ORIG_HEAD` undoes the merge
+	ORIG_HEAD plumbing.ReferenceName = "ORIG_HEAD"
 )
 
 var (
 	ErrUnrelatedHistories = errors.New("no common ancestor: unrelated histories")
 	ErrMergeConflict      = errors.New("merge conflict")
+	ErrMergeInProgress    = errors.New("a merge is in progress (MERGE_HEAD exists), conclude or abort it first")
+	// ErrLocalChanges is returned, with the paths, when a merge would write
+	// over uncommitted changes
+	ErrLocalChanges = errors.New("your local changes would be overwritten by merge, commit or stash them first")
+	// ErrFastForwardOptions is returned when a FastForwardOnly merge is asked not to fast-forward
+	ErrFastForwardOptions = errors.New("a fast-forward only merge cannot be asked not to fast-forward")
 )
 
+// emptyTree is the hash of the tree without entries, the base of unrelated histories
+var emptyTree = plumbing.NewHash("4b825dc642cb6eb9a060e54bf8d69288fbee4904")
+
 type MergeOptions struct {
-	Strategy               git.MergeStrategy
-	OrtMergeStrategyOption git.OrtMergeStrategyOption
-	Progress               io.Writer
+	Strategy git.MergeStrategy
+
+	// OrtMergeStrategyOption resolves conflicting hunks with our or their
+	// lines like `git merge -X`, the zero value leaves conflict markers
+	OrtMergeStrategyOption StrategyOption
+
+	// Progress receives the messages git would print, and the CONFLICT lines
+	// unless Events is set
+	Progress io.Writer
+
+	// Events receives the files merged, the conflicts and the commits as
+	// they happen, Progress adapted by WriterEvents when nil
+	Events Events
+
+	// ConflictStyle selects the conflict markers, diff3 and zdiff3 also write the base lines
+	ConflictStyle diff3.ConflictStyle
+
+	// Whitespace resolves the hunks only a side changed in whitespace like
+	// `git merge -Xignore-space-change` or -Xignore-all-space
+	Whitespace diff3.Whitespace
+
+	// Provenance adds a header comment to the matching files taken wholly from their side
+	Provenance Provenance
+
+	// ParentOrder orders the parents of the merge commit, ours first by
+	// default so that `git log --first-parent` follows the merging branch
+	ParentOrder ParentOrder
+
+	// Labels are written after the conflict markers, empty ones default to the
+	// branch, the base commit and the merged ref
+	Labels Labels
+
+	// ConflictStrategies resolves paths changed by both sides, first match
+	// wins over the merge attribute of .gitattributes
+	ConflictStrategies []PathStrategy
+
+	// Union resolves the paths without a conflict strategy like the union
+	// driver, keeping the lines of both sides without conflict markers
+	Union bool
+
+	// NoFastForward creates a merge commit even when HEAD could be
+	// fast-forwarded to the merged commit, like `git merge --no-ff`
+	NoFastForward bool
+
+	// AllowUnrelatedHistories merges histories without a common ancestor
+	// from an empty base, like `git merge --allow-unrelated-histories`,
+	// instead of returning ErrUnrelatedHistories
+	AllowUnrelatedHistories bool
+
+	// Squash applies the merge to the worktree and index without committing
+	// or recording MERGE_HEAD, the prepared message is written to SQUASH_MSG
+	Squash bool
+
+	// NoCommit stages the merge result and records MERGE_HEAD and MERGE_MSG
+	// without committing, Continue concludes it
+	NoCommit bool
+
+	// Backup writes <path>.orig with the content of ours for each
+	// conflicting path, like git mergetool, to recover it while resolving
+	Backup bool
+
+	// Author and Committer sign the commits created, nil ones default to
+	// the identity of the git configuration, then to the signatures of HEAD
+	Author    *object.Signature
+	Committer *object.Signature
+
+	// Message templates the message of the merge commits, where
+	// [[ branch ]] is the branch merged into, [[ theirs ]] the merged refs
+	// and [[ commit ]] their short hashes. Empty keeps the message of git
+	Message string
+
+	// SignKey signs the commits created, see ReadSignKey, OpenPGPSigner and
+	// SSHSigner. Nil leaves them unsigned
+	SignKey git.Signer
+
+	// NoHooks skips the pre-merge-commit, commit-msg and post-merge hooks of
+	// the repository. When they run, a rejected merge commit is left
+	// uncommitted like NoCommit and ErrHookFailed is returned
+	NoHooks bool
+
+	// RenameThreshold is the similarity percentage (1-100) above which a
+	// deleted and an added file are considered a rename, 0 disables detection
+	RenameThreshold uint
+
+	// Rerere reuses the recorded resolutions of recurring conflicts and
+	// records the conflicts left to the user, Continue recording how they
+	// were resolved, like `git rerere`
+	Rerere bool
+
+	// OnConflict is called for the files the line merge leaves conflicting,
+	// before the conflict markers are written, to resolve them interactively
+	OnConflict ConflictHandler
+
+	// DryRun runs the merge on an in-memory copy of the repository, as if
+	// its worktree were clean, and only returns the result. Nothing is
+	// written to the worktree, the index, the references or the objects
+	DryRun bool
+
+	// Exclude are path patterns, matched like ConflictStrategies, whose
+	// changes on their side are never merged, not even as conflicts. A
+	// pattern matching a directory excludes its files
+	Exclude []string
+
+	// PathSpecs are path patterns, matched like Exclude, limiting the
+	// changes merged from their side to the matching paths, the others
+	// keep ours. Empty merges every path
+	PathSpecs []string
+
+	// LargeFileThreshold is the size in bytes above which files changed by
+	// both sides are not line merged but kept whole like binary files, 0
+	// selects DefaultLargeFileThreshold
+	LargeFileThreshold int64
+
+	// Deepen fetches depth commits of history from the tips of the remote
+	// the merged ref comes from, when a shallow history is too short to tell
+	// whether the merge is a fast-forward. The depth doubles on every call
+	// up to MaxDepth, nil fails the merge with ErrShallowHistory instead
+	Deepen func(depth int) error
+
+	// MaxDepth bounds the depth asked from Deepen, 0 selects DefaultMaxDepth
+	MaxDepth int
+
+	// MarkerSize is the length of the conflict markers, 0 selects
+	// diff3.DefaultMarkerSize. The conflict-marker-size attribute of
+	// .gitattributes overrides it per path, like git
+	MarkerSize int
+
+	// Secrets scans the files their side adds or changes for private keys,
+	// cloud credentials and .env secrets before touching the repository
+	Secrets SecretMode
+}
+
+// ParentOrder orders the parents of generated merge commits
+type ParentOrder int
+
+const (
+	// OursFirst records HEAD as the first parent, like git
+	OursFirst ParentOrder = iota
+	// TheirsFirst records the merged commits first, their history becomes the first-parent one
+	TheirsFirst
+)
+
+// parents returns ours and theirs in order
+func (order ParentOrder) parents(ours plumbing.Hash, theirs ...plumbing.Hash) []plumbing.Hash {
+	if order == TheirsFirst {
+		return append(slices.Clone(theirs), ours)
+	}
+	return append([]plumbing.Hash{ours}, theirs...)
+}
+
+// Labels name the sides of a conflict
+type Labels struct {
+	Ours   string
+	Base   string
+	Theirs string
+}
+
+// DefaultRenameThreshold matches the similarity git requires by default
+const DefaultRenameThreshold uint = 50
+
+// MergeResult describes the outcome of a merge
+type MergeResult struct {
+	// FastForward is set when HEAD moved to the merged commit
+	FastForward bool
+	// Commit is HEAD after the merge, zero when the merge conflicted, was
+	// squashed or left uncommitted. Dry runs report the commit they would
+	// have created, which is not stored
+	Commit plumbing.Hash
+	// Conflicts are the conflicting paths
+	Conflicts []string
+	// TreeConflicts detail the conflicts of the merges left out of the
+	// worktree, like MergeInto, whose markers are in no file
+	TreeConflicts []TreeConflict
+	// Stats are the changes brought to HEAD, nil without a commit
+	Stats object.FileStats
+	// Summary counts what the merge did with the files their side changed
+	Summary MergeSummary
+}
+
+// Merge merges ref into HEAD, the result is returned with ErrMergeConflict too.
+// In a bare repository, the branch of HEAD is merged like MergeInto. An
+// unborn branch, without commits yet, is created at ref and checked out.
+// index.lock is held while the files are merged, ErrIndexLocked is returned
+// when another process holds it
+func Merge(r *git.Repository, ref plumbing.Reference, opts MergeOptions) (*MergeResult, error) {
+	return MergeContext(context.Background(), r, ref, opts)
 }
 
-func Merge(r *git.Repository, ref plumbing.Reference, opts MergeOptions) error {
+// MergeContext is Merge checking ctx between the files it merges. Once ctx
+// is done, the files already merged are restored as in HEAD and the error
+// of ctx is returned
+func MergeContext(ctx context.Context, r *git.Repository, ref plumbing.Reference, opts MergeOptions) (*MergeResult, error) {
+	if opts.DryRun {
+		return dryRun(r, opts, func(sandbox *git.Repository, opts MergeOptions) (*MergeResult, error) {
+			return MergeContext(ctx, sandbox, ref, opts)
+		})
+	}
+
+	result := &MergeResult{}
+	return result, merge(ctx, r, ref, opts, result)
+}
+
+func merge(ctx context.Context, r *git.Repository, ref plumbing.Reference, opts MergeOptions, result *MergeResult) (err error) {
 	// Check strategy before moving HEAD
 	if opts.Strategy != OrtMerge &&
 		opts.Strategy != FastForwardMerge &&
 		opts.Strategy != FastForwardOnly {
 		return git.ErrUnsupportedMergeStrategy
 	}
+	if opts.Strategy == FastForwardOnly && opts.NoFastForward {
+		return ErrFastForwardOptions
+	}
+
+	// Annotated tags are merged as the commit they point at
+	ref, err = Peel(r, ref)
+	if err != nil {
+		return err
+	}
+
+	_, err = mergeHead(r)
+	if err == nil {
+		return ErrMergeInProgress
+	}
+	if !errors.Is(err, ErrNoMergeInProgress) {
+		return err
+	}
+
+	// Before the first commit, HEAD is merged as the empty tree
+	unborn, err := unbornBranch(r)
+	if err != nil {
+		return err
+	}
+	if unborn != "" {
+		return mergeUnborn(ctx, r, unborn, ref, opts, result)
+	}
+
+	// Bare repositories have no worktree to merge in, the branch of HEAD is
+	// merged from object storage and its conflicts returned as data
+	if _, err = r.Worktree(); errors.Is(err, git.ErrIsBareRepository) {
+		if opts.Squash || opts.NoCommit {
+			return err
+		}
+		var head *plumbing.Reference
+		head, err = r.Reference(plumbing.HEAD, false)
+		if err != nil {
+			return err
+		}
+		return mergeInto(r, head.Target(), ref, opts, result)
+	}
 
 	head, err := r.Head()
 	if err != nil {
@@ -59,34 +316,81 @@ func Merge(r *git.Repository, ref plumbing.Reference, opts MergeOptions) error {
 		return err
 	}
 
-	// Ignore error as not having a shallow list is optional here.
-	shallowList, _ := r.Storer.Shallow()
-	var earliestShallow *plumbing.Hash
-	if len(shallowList) > 0 {
-		earliestShallow = &shallowList[0]
+	if err = checkSecrets(ourCommit, theirCommit, opts); err != nil {
+		return err
+	}
+
+	ff, err := isFastForward(r, head.Hash(), ref.Hash(), opts)
+	if err != nil {
+		return err
+	}
+
+	// A fast-forward would bring the paths outside of the merge, merge instead
+	if ff && (len(opts.Exclude) > 0 || len(opts.PathSpecs) > 0) {
+		ff, err = keepsOutside(ourCommit, theirCommit, opts)
+		if err != nil {
+			return err
+		}
 	}
 
-	ff, err := isFastForward(r.Storer, head.Hash(), ref.Hash(), earliestShallow)
+	hooks := newMergeHooks(r, opts)
+
+	// The index is staged in memory and swapped in once the files are
+	// merged, the merge goes on with the copy of r staging it
+	lock, err := lockIndex(r)
 	if err != nil {
 		return err
 	}
+	defer func() {
+		if releaseErr := lock.release(); err == nil {
+			err = releaseErr
+		}
+	}()
+	r = lock.r
 
 	var patch *object.Patch
-	// All strategies allow FF unless explicitly disabled
-	if ff {
+	// All strategies allow FF unless explicitly disabled, a squash never moves HEAD
+	if ff && !opts.Squash && !opts.NoFastForward {
 		patch, err = ourCommit.Patch(theirCommit)
 		if err != nil {
 			return err
 		}
 
+		var w *git.Worktree
+		w, err = r.Worktree()
+		if err != nil {
+			return err
+		}
+		if err = checkOverwrite(w, patchPaths(patch)); err != nil {
+			return err
+		}
+		if err = setOrigHead(r, head); err != nil {
+			return err
+		}
+
+		result.FastForward = true
+		result.Commit = ref.Hash()
+		result.Stats = patch.Stats()
+		result.Summary = summarizePatch(patch)
+
 		if opts.Progress != nil {
 			_, _ = fmt.Fprintf(opts.Progress,
-				"Updating %s...%s\nFast-forward\n%s",
-				head.Hash().String()[:7],
-				ref.Hash().String()[:7],
-				patch.Stats())
+				"%s\n%s\n%s%s\n",
+				i18n.Tf("Updating %s...%s", head.Hash().String()[:7], ref.Hash().String()[:7]),
+				i18n.T("Fast-forward"),
+				patch.Stats(),
+				result.Summary)
 		}
-		return r.Storer.SetReference(plumbing.NewHashReference(head.Name(), ref.Hash()))
+		// Moves the branch and updates the index and worktree, keeping local changes
+		if err = w.Reset(&git.ResetOptions{Commit: ref.Hash(), Mode: git.MergeReset}); err != nil {
+			return err
+		}
+		if err = lock.commit(); err != nil {
+			return err
+		}
+		opts.events().CommitCreated(ref.Hash())
+		hooks.merged(ctx, false)
+		return nil
 	}
 
 	if opts.Strategy == FastForwardOnly {
@@ -99,14 +403,21 @@ func Merge(r *git.Repository, ref plumbing.Reference, opts MergeOptions) error {
 		return err
 	}
 
-	if len(baseCommits) < 1 {
+	if len(baseCommits) < 1 && !opts.AllowUnrelatedHistories {
 		return ErrUnrelatedHistories
 	}
 	// TODO: recursive merging
 
-	baseTree, err := baseCommits[0].Tree()
-	if err != nil {
-		return err
+	// Unrelated histories merge from the empty tree, a nil one, both sides
+	// adding their files
+	var baseTree *object.Tree
+	baseLabel := emptyTree.String()[:7]
+	if len(baseCommits) > 0 {
+		baseTree, err = baseCommits[0].Tree()
+		if err != nil {
+			return err
+		}
+		baseLabel = baseCommits[0].Hash.String()[:7]
 	}
 
 	ourTree, err := ourCommit.Tree()
@@ -119,27 +430,37 @@ func Merge(r *git.Repository, ref plumbing.Reference, opts MergeOptions) error {
 		return err
 	}
 
-	baseToOur, err := baseTree.Diff(ourTree)
+	diffOptions := &object.DiffTreeOptions{
+		DetectRenames: opts.RenameThreshold > 0,
+		RenameScore:   min(opts.RenameThreshold, 100),
+	}
+
+	baseToOur, err := object.DiffTreeWithOptions(ctx, baseTree, ourTree, diffOptions)
+	if errors.Is(err, object.ErrCanceled) {
+		return ctx.Err()
+	}
 	if err != nil {
 		return err
 	}
 
-	baseToTheir, err := baseTree.Diff(theirTree)
+	baseToTheir, err := object.DiffTreeWithOptions(ctx, baseTree, theirTree, diffOptions)
+	if errors.Is(err, object.ErrCanceled) {
+		return ctx.Err()
+	}
 	if err != nil {
 		return err
 	}
+	baseToTheir = opts.scopeChanges(baseToTheir)
 
-	// Prepare changes per files using filename as keys
-	changes := make(map[string]struct {
-		ours   *object.Change
-		theirs *object.Change
-	})
+	// Prepare changes per files using the base filename as keys, so a rename
+	// on one side pairs with a modification of the same file on the other
+	changes := make(map[string]changePair)
 
 	for _, change := range baseToOur {
-		path := change.To.Name
-		// If it was deleted find its name using .From
+		path := change.From.Name
+		// If it was inserted find its name using .To
 		if path == "" {
-			path = change.From.Name
+			path = change.To.Name
 		}
 		pair := changes[path]
 		pair.ours = change
@@ -147,9 +468,9 @@ func Merge(r *git.Repository, ref plumbing.Reference, opts MergeOptions) error {
 	}
 
 	for _, change := range baseToTheir {
-		path := change.To.Name
+		path := change.From.Name
 		if path == "" {
-			path = change.From.Name
+			path = change.To.Name
 		}
 		pair := changes[path]
 		pair.theirs = change
@@ -161,11 +482,108 @@ func Merge(r *git.Repository, ref plumbing.Reference, opts MergeOptions) error {
 		return err
 	}
 
+	attributes, err := attributeStrategies(w.Filesystem)
+	if err != nil {
+		return err
+	}
+	endings := newLineEndings(r, attributes)
+
+	labels := Labels{
+		Ours:   cmp.Or(opts.Labels.Ours, head.Name().Short()),
+		Base:   cmp.Or(opts.Labels.Base, baseLabel),
+		Theirs: cmp.Or(opts.Labels.Theirs, ref.Name().Short()),
+	}
+
+	collisions, err := caseCollisions(ourTree, baseToTheir)
+	if err != nil {
+		return err
+	}
+
 	mergeHasConflict := false
+	var conflicts []conflictEntry
+	// Conflicted files recorded for rerere, by path
+	rerereConflicts := make(map[string]rerereConflict)
+
+	events := opts.events()
+	paths := &pathEvents{events: events}
+	if err = checkOverwrite(w, changedPaths(changes)); err != nil {
+		return err
+	}
+	// Recorded before the worktree changes, a conflicted merge sets it too like git
+	if !opts.Squash {
+		if err = setOrigHead(r, head); err != nil {
+			return err
+		}
+	}
+
+	// A merge failing before the index is swapped in leaves the files as in
+	// HEAD, like the index on disk
+	defer func() {
+		if err != nil && !lock.committed {
+			err = restorePaths(w, ourCommit, changes, err)
+		}
+	}()
+	for basePath, pair := range changes {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		paths.start(basePath, conflicts)
 
-	for filepath, pair := range changes {
 		var baseFile, ourFile, theirFile *object.File
-		var baseReader, ourReader, theirReader io.ReadCloser
+
+		if existing, collides := collisions[theirTarget(pair.theirs)]; collides {
+			var caseConflictEntries []conflictEntry
+			caseConflictEntries, err = caseConflicts(ourTree, pair.theirs, existing)
+			if err != nil {
+				return err
+			}
+			mergeHasConflict = true
+			conflicts = append(conflicts, caseConflictEntries...)
+			continue
+		}
+
+		renameConflicts, handled, err := mergeRenames(r, w, basePath, pair.ours, pair.theirs, labels)
+		if err != nil {
+			return err
+		}
+		if handled {
+			mergeHasConflict = true
+			conflicts = append(conflicts, renameConflicts...)
+			continue
+		}
+
+		// Follow renames, the merged content lands at the new path
+		filepath := basePath
+		switch {
+		case isRename(pair.ours):
+			filepath = pair.ours.To.Name
+		case isRename(pair.theirs):
+			filepath = pair.theirs.To.Name
+		}
+
+		// Our copy lives under the base name when they renamed it
+		ourPath := basePath
+		if pair.ours != nil {
+			ourPath = pair.ours.To.Name
+		}
+		if ourPath != "" && ourPath != filepath {
+			if _, err = w.Remove(ourPath); err != nil && !errors.Is(err, index.ErrEntryNotFound) {
+				return err
+			}
+		}
+
+		if isGitlink(pair.ours) || isGitlink(pair.theirs) {
+			var conflict *conflictEntry
+			conflict, err = mergeGitlink(r, w, filepath, pair.ours, pair.theirs)
+			if err != nil {
+				return err
+			}
+			if conflict != nil {
+				mergeHasConflict = true
+				conflicts = append(conflicts, *conflict)
+			}
+			continue
+		}
 
 		switch {
 		// If only our file has changed
@@ -182,24 +600,7 @@ func Merge(r *git.Repository, ref plumbing.Reference, opts MergeOptions) error {
 				if err != nil {
 					return err
 				}
-
-				ourReader, err = ourFile.Reader()
-				if err != nil {
-					return err
-				}
-
-				var dst io.WriteCloser
-				dst, err = w.Filesystem.Create(filepath)
-				if err != nil {
-					return err
-				}
-				defer func() { _ = dst.Close() }()
-
-				if _, err = io.Copy(dst, ourReader); err != nil {
-					return err
-				}
-
-				if _, err = w.Add(filepath); err != nil {
+				if err = writeFile(w, filepath, ourFile); err != nil {
 					return err
 				}
 
@@ -229,23 +630,27 @@ func Merge(r *git.Repository, ref plumbing.Reference, opts MergeOptions) error {
 					return err
 				}
 
-				theirReader, err = theirFile.Reader()
-				if err != nil {
-					return err
-				}
-
-				var dst io.WriteCloser
-				dst, err := w.Filesystem.Create(filepath)
-				if err != nil {
-					return err
-				}
-				defer func() { _ = dst.Close() }()
+				if header, ok := opts.Provenance.header(filepath); ok {
+					var binary bool
+					binary, err = theirFile.IsBinary()
+					if err != nil {
+						return err
+					}
 
-				if _, err = io.Copy(dst, theirReader); err != nil {
-					return err
+					if !binary {
+						var content string
+						content, err = theirFile.Contents()
+						if err != nil {
+							return err
+						}
+						if err = writeContent(w, filepath, withHeader([]byte(content), header), theirFile.Mode); err != nil {
+							return err
+						}
+						continue
+					}
 				}
 
-				if _, err = w.Add(filepath); err != nil {
+				if err = writeFile(w, filepath, theirFile); err != nil {
 					return err
 				}
 
@@ -291,62 +696,218 @@ func Merge(r *git.Repository, ref plumbing.Reference, opts MergeOptions) error {
 			case ourAction == merkletrie.Modify && theirAction == merkletrie.Modify,
 				ourAction == merkletrie.Insert && theirAction == merkletrie.Insert:
 
+				// Modes merge apart from the content, a side may chmod +x
+				// while the other edits
+				baseMode := filemode.Empty
+				if baseFile != nil {
+					baseMode = baseFile.Mode
+				}
+				mode, modeMerged := mergeMode(baseMode, ourFile.Mode, theirFile.Mode)
+				conflict := conflictEntry{
+					path:   filepath,
+					base:   baseFile,
+					ours:   ourFile,
+					theirs: theirFile,
+				}
+
 				// If they made the same changes
 				if ourFile.Hash == theirFile.Hash {
+					if err = applyMode(w, filepath, mode); err != nil {
+						return err
+					}
+					if !modeMerged {
+						mergeHasConflict = true
+						conflicts = append(conflicts, conflict)
+						continue
+					}
 					if _, err = w.Add(filepath); err != nil {
 						return err
 					}
 					continue // Skip
 				}
 
-				baseReader, err = baseFile.Reader()
-				if err != nil {
-					return err
+				strategy := strategyFor(opts.ConflictStrategies, filepath)
+				if strategy == "" {
+					strategy = attributeStrategy(attributes, filepath)
+				}
+				// Binary and large files are kept whole like git does with
+				// binary files, -X ours or theirs picks the side. Checked
+				// before the structural merges, which read the files whole
+				if strategy != StrategyOurs && strategy != StrategyTheirs {
+					var reason string
+					reason, err = unmergeable(filepath, opts.largeFileThreshold(), labels, baseFile, ourFile, theirFile)
+					if err != nil {
+						return err
+					}
+					if reason != "" {
+						side := ourFile
+						favor := optionFavor(opts.OrtMergeStrategyOption)
+						if favor == diff3.FavorTheirs {
+							side = theirFile
+						}
+						if err = writeFile(w, filepath, side); err != nil {
+							return err
+						}
+						if favor == diff3.FavorNone {
+							mergeHasConflict = true
+							conflict.reason = reason
+							conflicts = append(conflicts, conflict)
+						}
+						continue
+					}
 				}
-				defer func() { _ = baseReader.Close() }()
 
-				ourReader, err = ourFile.Reader()
-				if err != nil {
-					return err
+				if strategy == "" && isIgnoreFile(filepath) {
+					if err = mergeIgnoreFile(w, filepath, baseFile, ourFile, theirFile, mode, endings); err != nil {
+						return err
+					}
+					if !modeMerged {
+						mergeHasConflict = true
+						conflicts = append(conflicts, conflict)
+					}
+					continue
+				}
+				if strategy == "" && filepath == gitmodulesFile {
+					var resolved bool
+					resolved, err = mergeGitmodulesFile(w, baseFile, ourFile, theirFile, mode)
+					if err != nil {
+						return err
+					}
+					if resolved {
+						if !modeMerged {
+							mergeHasConflict = true
+							conflicts = append(conflicts, conflict)
+						}
+						continue
+					}
+				}
+				if strategy == "" && opts.Union {
+					strategy = StrategyUnion
 				}
-				defer func() { _ = ourReader.Close() }()
 
-				_, theirFile, err = pair.theirs.Files()
-				if err != nil {
-					return err
+				favor := optionFavor(opts.OrtMergeStrategyOption)
+				switch strategy {
+				case StrategyOurs:
+					if err = writeFile(w, filepath, ourFile); err != nil {
+						return err
+					}
+					continue
+
+				case StrategyTheirs:
+					if err = writeFile(w, filepath, theirFile); err != nil {
+						return err
+					}
+					continue
+
+				case StrategyUnion:
+					favor = diff3.FavorUnion
+
+				case StrategyJSONMerge:
+					var resolved bool
+					resolved, err = mergeJSONFile(w, filepath, baseFile, ourFile, theirFile, mode)
+					if err != nil {
+						return err
+					}
+					if resolved {
+						if !modeMerged {
+							mergeHasConflict = true
+							conflicts = append(conflicts, conflict)
+						}
+						continue
+					}
+					// Fallback to a line based merge
 				}
 
-				theirReader, err = theirFile.Reader()
+				sides, err := contents(baseFile, ourFile, theirFile)
 				if err != nil {
 					return err
 				}
-				defer func() { _ = theirReader.Close() }()
-
-				mergeResult, err := diff3.Merge(
-					ourReader,
-					baseReader,
-					theirReader,
-					true,
-					head.Name().Short(),
-					ref.Name().Short(),
+				// Merged with LF endings, written with those of the path
+				ending := endings.of(filepath, []byte(sides[1]))
+				markerSize := attributeMarkerSize(attributes, filepath, opts.MarkerSize)
+
+				mergeResult, err := diff3.MergeWithOptions(
+					bytes.NewReader(toLF([]byte(sides[1]))),
+					bytes.NewReader(toLF([]byte(sides[0]))),
+					bytes.NewReader(toLF([]byte(sides[2]))),
+					diff3.Options{
+						Detailed:   true,
+						LabelA:     labels.Ours,
+						LabelO:     labels.Base,
+						LabelB:     labels.Theirs,
+						Favor:      favor,
+						Style:      opts.ConflictStyle,
+						Whitespace: opts.Whitespace,
+						MarkerSize: markerSize,
+					},
 				)
 				if err != nil {
 					return err
 				}
+				result.Summary.ResolvedHunks += mergeResult.Resolved
+
+				if mergeResult.Conflicts && opts.Rerere {
+					var content, resolution []byte
+					if content, err = io.ReadAll(mergeResult.Result); err != nil {
+						return err
+					}
+					mergeResult.Result = bytes.NewReader(content)
+
+					var replayed bool
+					if fs := gitDir(r); fs != nil {
+						resolution, _, replayed, err = replayResolution(fs, content, markerSize)
+						if err != nil {
+							return err
+						}
+					}
+					if replayed {
+						if err = writeContent(w, filepath, withEnding(resolution, ending), mode); err != nil {
+							return err
+						}
+						if opts.Progress != nil {
+							_, _ = fmt.Fprintln(opts.Progress, i18n.Tf("Resolved '%s' using previous resolution.", filepath))
+						}
+						if !modeMerged {
+							mergeHasConflict = true
+							conflicts = append(conflicts, conflict)
+						}
+						continue
+					}
+					rerereConflicts[filepath] = rerereConflict{content: content, markerSize: markerSize}
+				}
+
+				if mergeResult.Conflicts && opts.OnConflict != nil {
+					var resolved bool
+					resolved, err = resolveConflict(w, filepath, baseFile, ourFile, theirFile, mode, opts.OnConflict)
+					if err != nil {
+						return err
+					}
+					if resolved {
+						if !modeMerged {
+							mergeHasConflict = true
+							conflicts = append(conflicts, conflict)
+						}
+						continue
+					}
+				}
 
-				file, err := w.Filesystem.Create(filepath)
+				merged, err := io.ReadAll(mergeResult.Result)
 				if err != nil {
 					return err
 				}
-				defer func() { _ = file.Close() }()
 
-				if _, err = io.Copy(file, mergeResult.Result); err != nil {
+				if err = writeAtomic(w, filepath, bytes.NewReader(withEnding(merged, ending)), mode); err != nil {
 					return err
 				}
 
-				mergeHasConflict = mergeHasConflict || mergeResult.Conflicts
-
-				if !mergeResult.Conflicts {
+				if mergeResult.Conflicts || !modeMerged {
+					// Both sides added the path, git calls it add/add
+					if mergeResult.Conflicts && baseFile == nil {
+						conflict.reason = i18n.Tf("CONFLICT (add/add): merge conflict in %s", filepath)
+					}
+					mergeHasConflict = true
+					conflicts = append(conflicts, conflict)
+				} else {
 					if _, err = w.Add(filepath); err != nil {
 						return err
 					}
@@ -364,50 +925,91 @@ func Merge(r *git.Repository, ref plumbing.Reference, opts MergeOptions) error {
 					return err
 				}
 
-				// Inserted / Modified by us, deleted by them
-			case (ourAction == merkletrie.Insert || ourAction == merkletrie.Modify) && theirAction == merkletrie.Delete:
-				var dst io.Writer
-				dst, err = w.Filesystem.Create(filepath)
-				if err != nil {
-					return err
+			// Inserted / Modified by one side, deleted by the other: like git
+			// the modified file is left in the worktree and the sides
+			// staged, unless the strategy of the path picks one
+			case ourAction == merkletrie.Delete || theirAction == merkletrie.Delete:
+				modified, deletedIn, modifiedIn := theirFile, labels.Ours, labels.Theirs
+				if theirAction == merkletrie.Delete {
+					modified, deletedIn, modifiedIn = ourFile, labels.Theirs, labels.Ours
 				}
 
-				ourReader, err = ourFile.Reader()
-				if err != nil {
-					return err
+				strategy := strategyFor(opts.ConflictStrategies, filepath)
+				if strategy == "" {
+					strategy = attributeStrategy(attributes, filepath)
 				}
-
-				if _, err = io.Copy(dst, ourReader); err != nil {
-					return err
+				if strategy == StrategyOurs && ourFile == nil || strategy == StrategyTheirs && theirFile == nil {
+					if _, err = w.Remove(filepath); err != nil && !errors.Is(err, index.ErrEntryNotFound) {
+						return err
+					}
+					continue
 				}
 
-				if _, err = w.Add(filepath); err != nil {
+				if err = writeFile(w, filepath, modified); err != nil {
 					return err
 				}
-				// TODO: mark in index
-
-			// Inserted / Modified by them, deleted by us
-			case (theirAction == merkletrie.Insert || theirAction == merkletrie.Modify) && ourAction == merkletrie.Delete:
-				dstFile, err := w.Filesystem.Create(filepath)
-				if err != nil {
-					return err
-				}
-				theirReader, err = theirFile.Reader()
-				if err != nil {
-					return err
-				}
-				if _, err = io.Copy(dstFile, theirReader); err != nil {
-					return err
+				if strategy == StrategyOurs || strategy == StrategyTheirs {
+					continue
 				}
-				if _, err = w.Add(filepath); err != nil {
-					return err
-				}
-				// TODO: mark in index
+				mergeHasConflict = true
+				conflicts = append(conflicts, conflictEntry{
+					path:   filepath,
+					base:   baseFile,
+					ours:   ourFile,
+					theirs: theirFile,
+					reason: i18n.Tf("CONFLICT (modify/delete): %s deleted in %s and modified in %s", filepath, deletedIn, modifiedIn),
+				})
 			}
 		}
 	}
 
+	paths.finish(conflicts)
+
+	result.Summary, err = summarize(changes, conflicts, result.Summary.ResolvedHunks)
+	if err != nil {
+		return err
+	}
+
 	if mergeHasConflict {
+		result.Conflicts = conflictPaths(conflicts)
+		if opts.Progress != nil {
+			_, _ = fmt.Fprintln(opts.Progress, result.Summary)
+		}
+
+		err = writeConflictStages(r, conflicts)
+		if err != nil {
+			return err
+		}
+		if err = lock.commit(); err != nil {
+			return err
+		}
+		if opts.Backup {
+			if err = backupConflicts(w, conflicts); err != nil {
+				return err
+			}
+		}
+
+		if opts.Squash {
+			var message string
+			message, err = squashMessage(theirCommit, baseCommits)
+			if err != nil {
+				return err
+			}
+			err = writeSquashMsg(r, conflictMessage(message, conflicts))
+			if err != nil {
+				return err
+			}
+			return ErrMergeConflict
+		}
+
+		err = writeMergeMsg(r, conflictMessage(opts.message(mergeMessage(head, &ref), head, ref), conflicts))
+		if err != nil {
+			return err
+		}
+		if err = recordConflicts(r, rerereConflicts); err != nil {
+			return err
+		}
+
 		err = r.Storer.SetReference(plumbing.NewHashReference(MERGE_HEAD, ref.Hash()))
 		if err != nil {
 			return err
@@ -415,26 +1017,72 @@ func Merge(r *git.Repository, ref plumbing.Reference, opts MergeOptions) error {
 		return ErrMergeConflict
 	}
 
+	if err = lock.commit(); err != nil {
+		return err
+	}
 	status, err := w.Status()
 	if err != nil {
 		return err
 	}
 
-	if status.IsClean() {
+	// Nothing to commit means up to date, unless a descendant adding no
+	// change was asked not to be fast-forwarded
+	noFastForward := ff && opts.NoFastForward && ourCommit.Hash != theirCommit.Hash
+	if status.IsClean() && !noFastForward {
+		return nil
+	}
+
+	if opts.Squash {
+		var message string
+		message, err = squashMessage(theirCommit, baseCommits)
+		if err != nil {
+			return err
+		}
+		if err = writeSquashMsg(r, message); err != nil {
+			return err
+		}
+		if opts.Progress != nil {
+			_, _ = fmt.Fprintf(opts.Progress, "%s\n%s\n", i18n.T("Squash commit -- not updating HEAD"), result.Summary)
+		}
+		hooks.merged(ctx, true)
+		return nil
+	}
+
+	message := opts.message(mergeMessage(head, &ref), head, ref)
+	var rejected error
+	if !opts.NoCommit {
+		message, rejected = hooks.verify(ctx, r, message)
+	}
+
+	// A merge commit rejected by the hooks is left for Continue too
+	if opts.NoCommit || rejected != nil {
+		if err = writeMergeMsg(r, message); err != nil {
+			return err
+		}
+		err = r.Storer.SetReference(plumbing.NewHashReference(MERGE_HEAD, ref.Hash()))
+		if err != nil {
+			return err
+		}
+		if rejected != nil {
+			return rejected
+		}
+		if opts.Progress != nil {
+			_, _ = fmt.Fprintf(opts.Progress, "%s\n%s\n", i18n.T("Automatic merge went well; stopped before committing as requested"), result.Summary)
+		}
 		return nil
 	}
 
+	author, committer := opts.signatures(r, ourCommit)
 	var newHash plumbing.Hash
 	newHash, err = w.Commit(
-		fmt.Sprintf(
-			"Merge %s with %s",
-			plumbing.NewBranchReferenceName(head.Name().Short()),
-			ref.Name(),
-		),
+		message,
 		&git.CommitOptions{
-			Author:    &ourCommit.Author,
-			Committer: &ourCommit.Committer,
-			Parents:   []plumbing.Hash{ourCommit.Hash, theirCommit.Hash},
+			Author:    author,
+			Committer: committer,
+			Parents:   opts.ParentOrder.parents(ourCommit.Hash, theirCommit.Hash),
+			Signer:    opts.SignKey,
+			// The merged commits may add nothing to the tree of ours
+			AllowEmptyCommits: noFastForward,
 		},
 	)
 	if err != nil {
@@ -451,43 +1099,219 @@ func Merge(r *git.Repository, ref plumbing.Reference, opts MergeOptions) error {
 	if err != nil {
 		return err
 	}
+	result.Commit = newHash
+	result.Stats = patch.Stats()
 
+	events.CommitCreated(newHash)
 	if opts.Progress != nil {
-		_, _ = fmt.Fprintf(opts.Progress, "Merge made by the 'ort' strategy.\n%s", patch.Stats())
+		_, _ = fmt.Fprintf(opts.Progress, "%s\n%s%s\n", i18n.T("Merge made by the 'ort' strategy."), patch.Stats(), result.Summary)
 	}
+	hooks.merged(ctx, false)
 
 	return err
 }
 
-func isFastForward(s storer.EncodedObjectStorer, old, newHash plumbing.Hash, earliestShallow *plumbing.Hash) (bool, error) {
-	c, err := object.GetCommit(s, newHash)
+// changePair holds the changes of both sides to a path of the base
+type changePair struct {
+	ours   *object.Change
+	theirs *object.Change
+}
+
+// restorePaths restores the paths of changes in the index and worktree as
+// they are in ours, once the merge was interrupted by cause
+func restorePaths(w *git.Worktree, ours *object.Commit, changes map[string]changePair, cause error) error {
+	var paths []string
+	for _, pair := range changes {
+		for _, change := range []*object.Change{pair.ours, pair.theirs} {
+			if change == nil {
+				continue
+			}
+			for _, name := range []string{change.From.Name, change.To.Name} {
+				if name != "" {
+					paths = append(paths, name)
+				}
+			}
+		}
+	}
+
+	err := w.Reset(&git.ResetOptions{Commit: ours.Hash, Mode: git.HardReset, Files: paths})
 	if err != nil {
-		return false, err
+		return fmt.Errorf("%w, restoring the merged files: %w", cause, err)
 	}
+	return cause
+}
 
-	parentsToIgnore := []plumbing.Hash{}
-	if earliestShallow != nil {
-		earliestCommit, err := object.GetCommit(s, *earliestShallow)
-		if err != nil {
-			return false, err
+// changedPaths returns the paths a merge of changes writes: those of their
+// side, and where ours renamed them
+func changedPaths(changes map[string]changePair) []string {
+	var paths []string
+	for _, pair := range changes {
+		if pair.theirs == nil {
+			continue
 		}
+		for _, change := range []*object.Change{pair.ours, pair.theirs} {
+			if change == nil {
+				continue
+			}
+			for _, name := range []string{change.From.Name, change.To.Name} {
+				if name != "" {
+					paths = append(paths, name)
+				}
+			}
+		}
+	}
+	return paths
+}
+
+// patchPaths returns the paths patch changes
+func patchPaths(patch *object.Patch) []string {
+	var paths []string
+	for _, file := range patch.FilePatches() {
+		from, to := file.Files()
+		for _, side := range []diff.File{from, to} {
+			if side != nil {
+				paths = append(paths, side.Path())
+			}
+		}
+	}
+	return paths
+}
+
+// checkOverwrite returns ErrLocalChanges with the paths a merge would
+// write which have uncommitted changes, or are untracked, like git
+func checkOverwrite(w *git.Worktree, paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	status, err := w.Status()
+	if err != nil {
+		return err
+	}
+
+	var changed []string
+	for _, filepath := range paths {
+		if file, ok := status[filepath]; ok && !(file.Staging == git.Unmodified && file.Worktree == git.Unmodified) {
+			changed = append(changed, filepath)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	slices.Sort(changed)
+	return fmt.Errorf("%w: %s", ErrLocalChanges, strings.Join(slices.Compact(changed), ", "))
+}
+
+// setOrigHead points ORIG_HEAD at the commit HEAD resolves to
+func setOrigHead(r *git.Repository, head *plumbing.Reference) error {
+	return r.Storer.SetReference(plumbing.NewHashReference(ORIG_HEAD, head.Hash()))
+}
+
+// isRename reports whether the change moved a file to another path
+func isRename(change *object.Change) bool {
+	return change != nil &&
+		change.From.Name != "" &&
+		change.To.Name != "" &&
+		change.From.Name != change.To.Name
+}
+
+// writeFile copies the content of file into the worktree at filepath and stages it
+func writeFile(w *git.Worktree, filepath string, file *object.File) error {
+	reader, err := file.Reader()
+	if err != nil {
+		return err
+	}
+	err = writeAtomic(w, filepath, reader, file.Mode)
+	if closeErr := reader.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	_, err = w.Add(filepath)
+	return err
+}
 
-		parentsToIgnore = earliestCommit.ParentHashes
+// writeContent writes content with mode into the worktree at filepath and stages it
+func writeContent(w *git.Worktree, filepath string, content []byte, mode filemode.FileMode) error {
+	if err := writeAtomic(w, filepath, bytes.NewReader(content), mode); err != nil {
+		return err
 	}
+	_, err := w.Add(filepath)
+	return err
+}
 
-	found := false
-	// stop iterating at the earliest shallow commit, ignoring its parents
-	// note: when pull depth is smaller than the number of new changes on the remote, this fails due to missing parents.
-	//       as far as i can tell, without the commits in-between the shallow pull and the earliest shallow, there's no
-	//       real way of telling whether it will be a fast-forward merge.
-	iter := object.NewCommitPreorderIter(c, nil, parentsToIgnore)
-	err = iter.ForEach(func(c *object.Commit) error {
-		if c.Hash != old {
-			return nil
+// tempSuffix ends the temporary files writeAtomic renames into place
+const tempSuffix = ".gravel-merge"
+
+// writeAtomic writes content with mode into a temporary file next to
+// filepath and renames it over filepath, which is left as it was on error.
+// The file is closed before returning, so merges keep one open at a time
+func writeAtomic(w *git.Worktree, filepath string, content io.Reader, mode filemode.FileMode) error {
+	tmp := path.Join(path.Dir(filepath), "."+path.Base(filepath)+tempSuffix)
+	dst, err := w.Filesystem.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o666)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(dst, content)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = applyMode(w, tmp, mode)
+	}
+	if err == nil {
+		err = w.Filesystem.Rename(tmp, filepath)
+	}
+	if err != nil {
+		_ = w.Filesystem.Remove(tmp)
+	}
+	return err
+}
+
+// contents reads the three sides of a merge, a nil file reads as empty
+func contents(files ...*object.File) ([]string, error) {
+	result := make([]string, len(files))
+	for index, file := range files {
+		if file == nil {
+			continue
 		}
 
-		found = true
-		return storer.ErrStop
-	})
-	return found, err
+		content, err := file.Contents()
+		if err != nil {
+			return nil, err
+		}
+		result[index] = content
+	}
+	return result, nil
+}
+
+// mergeJSONFile merges JSON documents into the worktree, resolved is false when
+// the documents conflict or are not valid JSON
+func mergeJSONFile(w *git.Worktree, filepath string, baseFile, ourFile, theirFile *object.File, mode filemode.FileMode) (resolved bool, err error) {
+	sides, err := contents(baseFile, ourFile, theirFile)
+	if err != nil {
+		return
+	}
+
+	result, ok, jsonErr := mergeJSON([]byte(sides[0]), []byte(sides[1]), []byte(sides[2]))
+	if jsonErr != nil || !ok {
+		return
+	}
+
+	if err = writeContent(w, filepath, result, mode); err != nil {
+		return
+	}
+	return true, nil
+}
+
+// mergeIgnoreFile merges ignore files pattern by pattern into the worktree,
+// written with the line endings of the path
+func mergeIgnoreFile(w *git.Worktree, filepath string, baseFile, ourFile, theirFile *object.File, mode filemode.FileMode, endings lineEndings) error {
+	sides, err := contents(baseFile, ourFile, theirFile)
+	if err != nil {
+		return err
+	}
+	merged := mergeIgnore(sides[0], sides[1], sides[2])
+	return writeContent(w, filepath, withEnding([]byte(merged), endings.of(filepath, []byte(sides[1]))), mode)
 }
@@ -0,0 +1,218 @@
+package ort
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v6/memfs"
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/filemode"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/storage/memory"
+)
+
+// testSignature dates every commit the same so that the hashes are reproducible
+var testSignature = object.Signature{Name: "gravel", Email: "test@gravel", When: time.Unix(0, 0).UTC()}
+
+// newTestRepository returns an in-memory repository with a worktree and no
+// commit
+func newTestRepository(t *testing.T) *git.Repository {
+	t.Helper()
+	r, err := git.Init(memory.NewStorage(), git.WithWorkTree(memfs.New()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// commitFiles stores a commit of the files, at the root of its tree, on
+// parents
+func commitFiles(t *testing.T, r *git.Repository, message string, files map[string]string, parents ...plumbing.Hash) plumbing.Hash {
+	t.Helper()
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	tree := &object.Tree{}
+	for _, name := range names {
+		blob := r.Storer.NewEncodedObject()
+		blob.SetType(plumbing.BlobObject)
+		writer, err := blob.Writer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = writer.Write([]byte(files[name])); err != nil {
+			t.Fatal(err)
+		}
+		if err = writer.Close(); err != nil {
+			t.Fatal(err)
+		}
+		hash, err := r.Storer.SetEncodedObject(blob)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tree.Entries = append(tree.Entries, object.TreeEntry{Name: name, Mode: filemode.Regular, Hash: hash})
+	}
+	treeHash := storeObject(t, r, tree)
+
+	commit := &object.Commit{
+		Author:       testSignature,
+		Committer:    testSignature,
+		Message:      message,
+		TreeHash:     treeHash,
+		ParentHashes: parents,
+	}
+	return storeObject(t, r, commit)
+}
+
+// storeObject encodes value into the storer of r
+func storeObject(t *testing.T, r *git.Repository, value interface {
+	Encode(plumbing.EncodedObject) error
+}) plumbing.Hash {
+	t.Helper()
+	encoded := r.Storer.NewEncodedObject()
+	if err := value.Encode(encoded); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := r.Storer.SetEncodedObject(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+// checkoutBranch points the branch name at commit and checks it out
+func checkoutBranch(t *testing.T, r *git.Repository, name string, commit plumbing.Hash) {
+	t.Helper()
+	branch := plumbing.NewBranchReferenceName(name)
+	if err := r.Storer.SetReference(plumbing.NewHashReference(branch, commit)); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, branch)); err != nil {
+		t.Fatal(err)
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Reset(&git.ResetOptions{Commit: commit, Mode: git.HardReset}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// branchRef returns a reference named branch to commit, for the commit to
+// merge
+func branchRef(name string, commit plumbing.Hash) plumbing.Reference {
+	return *plumbing.NewHashReference(plumbing.NewBranchReferenceName(name), commit)
+}
+
+// headFiles returns the files of the tree of HEAD with their content
+func headFiles(t *testing.T, r *git.Repository) map[string]string {
+	t.Helper()
+	head, err := r.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := r.CommitObject(head.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := commit.Files()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents := make(map[string]string)
+	err = files.ForEach(func(file *object.File) error {
+		content, err := file.Contents()
+		contents[file.Name] = content
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return contents
+}
+
+// readWorktree returns the content of the file name of the worktree
+func readWorktree(t *testing.T, r *git.Repository, name string) string {
+	t.Helper()
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := w.Filesystem.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = file.Close() }()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+// writeWorktree writes the file name of the worktree, leaving it uncommitted
+func writeWorktree(t *testing.T, r *git.Repository, name, content string) {
+	t.Helper()
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := w.Filesystem.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = file.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err = file.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMergeRefusesLocalChanges(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		ours map[string]string
+	}{
+		{name: "fast-forward"},
+		{name: "merge", ours: map[string]string{"README": "base\n", "main.go": "ours\n"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRepository(t)
+			base := commitFiles(t, r, "base", map[string]string{"README": "base\n"})
+			head := base
+			if test.ours != nil {
+				head = commitFiles(t, r, "ours", test.ours, base)
+			}
+			theirs := commitFiles(t, r, "theirs", map[string]string{"README": "theirs\n"}, base)
+			checkoutBranch(t, r, "main", head)
+			writeWorktree(t, r, "README", "mine\n")
+
+			var progress bytes.Buffer
+			_, err := Merge(r, branchRef("theirs", theirs), MergeOptions{Author: &testSignature, Committer: &testSignature, Progress: &progress})
+			if !errors.Is(err, ErrLocalChanges) {
+				t.Fatalf("err = %v, want %v", err, ErrLocalChanges)
+			}
+			if progress.Len() > 0 {
+				t.Errorf("progress = %q, want nothing for a refused merge", progress.String())
+			}
+			if _, err = r.Reference(ORIG_HEAD, false); err == nil {
+				t.Error("a refused merge set ORIG_HEAD")
+			}
+			if got := readWorktree(t, r, "README"); got != "mine\n" {
+				t.Errorf("README = %q, want the local changes", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package ort
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Provenance marks the files taken wholly from their side with a header
+// comment warning that local changes may be overwritten by updates
+type Provenance struct {
+	// Patterns are globs of the paths receiving the header, none disables it
+	Patterns []string
+	// Component and Version identify the merged side in the header
+	Component string
+	Version   string
+}
+
+// commentSyntax is the opening and closing of a comment, keyed by extension
+// or by file name when it has none
+var commentSyntax = map[string][2]string{
+	".go": {"// ", ""}, ".js": {"// ", ""}, ".jsx": {"// ", ""}, ".ts": {"// ", ""},
+	".tsx": {"// ", ""}, ".mjs": {"// ", ""}, ".cjs": {"// ", ""}, ".java": {"// ", ""},
+	".kt": {"// ", ""}, ".swift": {"// ", ""}, ".rs": {"// ", ""}, ".c": {"// ", ""},
+	".h": {"// ", ""}, ".cpp": {"// ", ""}, ".cs": {"// ", ""}, ".php": {"// ", ""},
+	".scss": {"// ", ""}, ".css": {"/* ", " */"},
+	".py": {"# ", ""}, ".rb": {"# ", ""}, ".sh": {"# ", ""}, ".bash": {"# ", ""},
+	".yaml": {"# ", ""}, ".yml": {"# ", ""}, ".toml": {"# ", ""}, ".tf": {"# ", ""},
+	".env": {"# ", ""}, "Dockerfile": {"# ", ""}, "Makefile": {"# ", ""},
+	".gitignore": {"# ", ""}, ".dockerignore": {"# ", ""},
+	".sql": {"-- ", ""}, ".lua": {"-- ", ""},
+	".html": {"<!-- ", " -->"}, ".xml": {"<!-- ", " -->"}, ".vue": {"<!-- ", " -->"},
+	".svelte": {"<!-- ", " -->"}, ".md": {"<!-- ", " -->"},
+}
+
+// header returns the comment to insert in filepath, false when the path is
+// not matched or its language has no known comment syntax
+func (provenance Provenance) header(filepath string) (string, bool) {
+	if !slices.ContainsFunc(provenance.Patterns, func(pattern string) bool { return matchesPath(pattern, filepath) }) {
+		return "", false
+	}
+
+	syntax, ok := commentSyntax[path.Ext(filepath)]
+	if !ok {
+		syntax, ok = commentSyntax[path.Base(filepath)]
+	}
+	if !ok {
+		return "", false
+	}
+
+	source := strings.TrimSpace(provenance.Component + " " + provenance.Version)
+	return fmt.Sprintf(
+		"%sManaged by gravel from %s, local changes may be overwritten by updates%s\n",
+		syntax[0], source, syntax[1],
+	), true
+}
+
+// matchesPath tells whether pattern matches filepath, a pattern without a
+// slash matches the file name in any directory like in .gitignore
+func matchesPath(pattern, filepath string) bool {
+	if !strings.Contains(pattern, "/") {
+		filepath = path.Base(filepath)
+	}
+	ok, _ := path.Match(pattern, filepath)
+	return ok
+}
+
+// prologs start the first lines the header goes after: a shebang, the PHP
+// opening tag, before which lines are output, and the XML declaration,
+// which must come first
+var prologs = []string{"#!", "<?php", "<?xml"}
+
+// withHeader inserts header at the top of content, after a prolog line,
+// unless it is already there
+func withHeader(content []byte, header string) []byte {
+	at := 0
+	if slices.ContainsFunc(prologs, func(prolog string) bool { return bytes.HasPrefix(content, []byte(prolog)) }) {
+		at = bytes.IndexByte(content, '\n') + 1
+		if at == 0 {
+			content = append(content, '\n')
+			at = len(content)
+		}
+	}
+	if bytes.HasPrefix(content[at:], []byte(header)) {
+		return content
+	}
+
+	result := make([]byte, 0, len(content)+len(header))
+	result = append(result, content[:at]...)
+	result = append(result, header...)
+	return append(result, content[at:]...)
+}
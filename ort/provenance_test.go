@@ -0,0 +1,48 @@
+package ort
+
+import "testing"
+
+func TestProvenanceHeader(t *testing.T) {
+	provenance := Provenance{Patterns: []string{"*.go", "config/*.xml", "index.php"}, Component: "api", Version: "v1.2.0"}
+	for _, test := range []struct {
+		filepath string
+		want     string
+	}{
+		{filepath: "main.go", want: "// Managed by gravel from api v1.2.0, local changes may be overwritten by updates\n"},
+		{filepath: "cmd/server/main.go", want: "// Managed by gravel from api v1.2.0, local changes may be overwritten by updates\n"},
+		{filepath: "config/app.xml", want: "<!-- Managed by gravel from api v1.2.0, local changes may be overwritten by updates -->\n"},
+		{filepath: "public/config/app.xml"},
+		{filepath: "public/index.php", want: "// Managed by gravel from api v1.2.0, local changes may be overwritten by updates\n"},
+		{filepath: "README.md"},
+	} {
+		got, ok := provenance.header(test.filepath)
+		if ok != (test.want != "") || got != test.want {
+			t.Errorf("header(%q) = %q, %v, want %q", test.filepath, got, ok, test.want)
+		}
+	}
+
+	if _, ok := (Provenance{Patterns: []string{"*.bin"}}).header("data.bin"); ok {
+		t.Error("header() of a file without a comment syntax succeeded")
+	}
+}
+
+func TestWithHeader(t *testing.T) {
+	const header = "# managed\n"
+	for _, test := range []struct {
+		name, content, want string
+	}{
+		{name: "plain", content: "echo hi\n", want: "# managed\necho hi\n"},
+		{name: "shebang", content: "#!/bin/sh\necho hi\n", want: "#!/bin/sh\n# managed\necho hi\n"},
+		{name: "shebang only", content: "#!/bin/sh", want: "#!/bin/sh\n# managed\n"},
+		{name: "php", content: "<?php\necho 1;\n", want: "<?php\n# managed\necho 1;\n"},
+		{name: "xml", content: "<?xml version=\"1.0\"?>\n<app/>\n", want: "<?xml version=\"1.0\"?>\n# managed\n<app/>\n"},
+		{name: "present", content: "#!/bin/sh\n# managed\necho hi\n", want: "#!/bin/sh\n# managed\necho hi\n"},
+		{name: "empty", want: "# managed\n"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := string(withHeader([]byte(test.content), header)); got != test.want {
+				t.Errorf("withHeader() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
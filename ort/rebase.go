@@ -0,0 +1,571 @@
+package ort
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"gravel/i18n"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/utils/merkletrie"
+)
+
+const (
+	// REBASE_HEAD is the commit a rebase stopped on because it conflicts
+	REBASE_HEAD plumbing.ReferenceName = "REBASE_HEAD"
+	// rebaseDir holds the state of a rebase in progress, like git's
+	rebaseDir = "rebase-merge"
+)
+
+var (
+	ErrRebaseInProgress   = errors.New("a rebase is in progress, continue or abort it first")
+	ErrNoRebaseInProgress = errors.New("there is no rebase in progress")
+	// ErrDirtyWorktree is returned when the worktree has changes a rebase or
+	// a revert would lose
+	ErrDirtyWorktree = errors.New("the worktree has uncommitted changes, commit or stash them first")
+	// ErrRebaseUnsupported is returned when a conflict stops a rebase in a
+	// repository without a git directory to keep its state in
+	ErrRebaseUnsupported = errors.New("cannot stop a rebase without a git directory")
+)
+
+// rebaseState is what a stopped rebase needs to continue
+type rebaseState struct {
+	// branch is rebased, HEAD is detached until the rebase is done
+	branch plumbing.ReferenceName
+	onto   plumbing.Hash
+	orig   plumbing.Hash
+	// todo are the commits left to replay, the stopped one excluded
+	todo []plumbing.Hash
+}
+
+// Rebase replays the commits of the branch HEAD missing from upstream on top
+// of it, instead of merging upstream: the history stays linear. Merge
+// commits are dropped and commits already applied upstream are skipped. On
+// a conflict, the rebase stops with HEAD detached on the commits replayed
+// so far: resolve and stage the files then call RebaseContinue, or
+// RebaseAbort. Of opts, the options of MergeTrees and Progress apply
+func Rebase(r *git.Repository, upstream plumbing.Reference, opts MergeOptions) (*MergeResult, error) {
+	result := &MergeResult{}
+	return result, rebase(r, upstream, opts, result)
+}
+
+func rebase(r *git.Repository, upstream plumbing.Reference, opts MergeOptions, result *MergeResult) error {
+	if _, err := loadRebase(r); err == nil {
+		return ErrRebaseInProgress
+	} else if !errors.Is(err, ErrNoRebaseInProgress) {
+		return err
+	}
+	if _, err := mergeHead(r); err == nil {
+		return ErrMergeInProgress
+	} else if !errors.Is(err, ErrNoMergeInProgress) {
+		return err
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		return err
+	}
+	if !head.Name().IsBranch() {
+		return fmt.Errorf("cannot rebase %s: HEAD is not a branch", head.Name())
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+	if err = checkClean(w); err != nil {
+		return err
+	}
+
+	ourCommit, err := r.CommitObject(head.Hash())
+	if err != nil {
+		return err
+	}
+	if upstream, err = Peel(r, upstream); err != nil {
+		return err
+	}
+	ontoCommit, err := r.CommitObject(upstream.Hash())
+	if err != nil {
+		return err
+	}
+
+	history := newAncestry(r)
+	defer func() { _ = history.close() }()
+
+	upToDate, err := history.isAncestor(ontoCommit.Hash, ourCommit.Hash)
+	if err != nil {
+		return err
+	}
+	if upToDate {
+		if opts.Progress != nil {
+			_, _ = fmt.Fprintln(opts.Progress, i18n.Tf("Current branch %s is up to date.", head.Name().Short()))
+		}
+		result.Commit = head.Hash()
+		return nil
+	}
+
+	todo, err := rebaseTodo(history, ourCommit, ontoCommit)
+	if err != nil {
+		return err
+	}
+
+	// Nothing of ours to replay, upstream contains HEAD
+	if len(todo) == 0 {
+		if err = setOrigHead(r, head); err != nil {
+			return err
+		}
+		if err = r.Storer.SetReference(plumbing.NewHashReference(head.Name(), ontoCommit.Hash)); err != nil {
+			return err
+		}
+		if err = w.Reset(&git.ResetOptions{Commit: ontoCommit.Hash, Mode: git.HardReset}); err != nil {
+			return err
+		}
+		result.FastForward = true
+		opts.events().CommitCreated(ontoCommit.Hash)
+		return describe(result, ourCommit, ontoCommit)
+	}
+
+	if err = setOrigHead(r, head); err != nil {
+		return err
+	}
+	state := &rebaseState{branch: head.Name(), onto: ontoCommit.Hash, orig: ourCommit.Hash, todo: todo}
+	return replay(r, w, state, ontoCommit.Hash, opts, result)
+}
+
+// rebaseTodo lists the commits of ours missing from onto, parents before
+// their children, like git rebase: the merge commits are dropped but the
+// commits they brought in are replayed
+func rebaseTodo(history *ancestry, ours, onto *object.Commit) ([]plumbing.Hash, error) {
+	var todo []plumbing.Hash
+	visited := make(map[plumbing.Hash]bool)
+
+	var visit func(commit *object.Commit) error
+	visit = func(commit *object.Commit) error {
+		if visited[commit.Hash] {
+			return nil
+		}
+		visited[commit.Hash] = true
+
+		contained, err := history.isAncestor(commit.Hash, onto.Hash)
+		if err != nil || contained {
+			return err
+		}
+		// The first parent first, its commits come before those merged in
+		err = commit.Parents().ForEach(func(parent *object.Commit) error {
+			return visit(parent)
+		})
+		if err != nil {
+			return err
+		}
+		if commit.NumParents() < 2 {
+			todo = append(todo, commit.Hash)
+		}
+		return nil
+	}
+	return todo, visit(ours)
+}
+
+// replay picks the commits of state.todo on top of tip, then moves the
+// rebased branch to the result. It stops on the first conflict
+func replay(r *git.Repository, w *git.Worktree, state *rebaseState, tip plumbing.Hash, opts MergeOptions, result *MergeResult) error {
+	for len(state.todo) > 0 {
+		commit, err := r.CommitObject(state.todo[0])
+		if err != nil {
+			return err
+		}
+		state.todo = state.todo[1:]
+
+		var picked plumbing.Hash
+		picked, err = pick(r, w, state, tip, commit, opts, result)
+		if err != nil {
+			return err
+		}
+		if !picked.IsZero() {
+			opts.events().CommitCreated(picked)
+			tip = picked
+		}
+	}
+
+	if err := r.Storer.SetReference(plumbing.NewHashReference(state.branch, tip)); err != nil {
+		return err
+	}
+	if err := r.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, state.branch)); err != nil {
+		return err
+	}
+	if err := w.Reset(&git.ResetOptions{Commit: tip, Mode: git.HardReset}); err != nil {
+		return err
+	}
+	if err := removeRebase(r); err != nil {
+		return err
+	}
+
+	ourCommit, err := r.CommitObject(state.orig)
+	if err != nil {
+		return err
+	}
+	tipCommit, err := r.CommitObject(tip)
+	if err != nil {
+		return err
+	}
+	if opts.Progress != nil {
+		_, _ = fmt.Fprintln(opts.Progress, i18n.Tf("Successfully rebased and updated %s.", state.branch))
+	}
+	return describe(result, ourCommit, tipCommit)
+}
+
+// pick applies the changes of commit on top of tip and commits them with
+// its author and message. The returned hash is zero when commit brings no
+// change anymore. A conflict stops the rebase with ErrMergeConflict
+func pick(r *git.Repository, w *git.Worktree, state *rebaseState, tip plumbing.Hash, commit *object.Commit, opts MergeOptions, result *MergeResult) (plumbing.Hash, error) {
+	tipCommit, err := r.CommitObject(tip)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	trees := make([]*object.Tree, 3)
+	sides := []*object.Commit{nil, tipCommit, commit}
+	if commit.NumParents() > 0 {
+		if sides[0], err = commit.Parent(0); err != nil {
+			return plumbing.ZeroHash, err
+		}
+	}
+	for index, side := range sides {
+		if side == nil {
+			continue
+		}
+		if trees[index], err = side.Tree(); err != nil {
+			return plumbing.ZeroHash, err
+		}
+	}
+
+	subject, _, _ := strings.Cut(commit.Message, "\n")
+	opts.Labels = Labels{
+		Ours:   "HEAD",
+		Base:   "parent of " + commit.Hash.String()[:7],
+		Theirs: commit.Hash.String()[:7] + " (" + subject + ")",
+	}
+	merged, err := MergeTrees(r.Storer, trees[0], trees[1], trees[2], opts)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	if len(merged.Conflicts) > 0 {
+		return plumbing.ZeroHash, stopRebase(r, w, state, tipCommit, commit, merged, opts, result)
+	}
+
+	// Already applied upstream
+	if merged.Tree == tipCommit.TreeHash {
+		if opts.Progress != nil {
+			_, _ = fmt.Fprintln(opts.Progress, i18n.Tf("Skipped %s (%s), already applied.", commit.Hash.String()[:7], subject))
+		}
+		return plumbing.ZeroHash, nil
+	}
+
+	rebased := &object.Commit{
+		Author:       commit.Author,
+		Committer:    commit.Committer,
+		Message:      commit.Message,
+		TreeHash:     merged.Tree,
+		ParentHashes: []plumbing.Hash{tip},
+	}
+	return storeCommit(r, rebased, opts.SignKey)
+}
+
+// stopRebase checks out the conflicted merge of commit on a detached HEAD,
+// with the conflict stages in the index, and saves the rebase state
+func stopRebase(r *git.Repository, w *git.Worktree, state *rebaseState, tip, commit *object.Commit, merged *TreeResult, opts MergeOptions, result *MergeResult) error {
+	fs := gitDir(r)
+	if fs == nil {
+		if err := restoreBranch(r, w, state); err != nil {
+			return err
+		}
+		return ErrRebaseUnsupported
+	}
+
+	if err := r.Storer.SetReference(plumbing.NewHashReference(plumbing.HEAD, tip.Hash)); err != nil {
+		return err
+	}
+	conflicts, err := checkoutConflicted(r, w, tip, merged)
+	if err != nil {
+		return err
+	}
+
+	if err = saveRebase(r, state); err != nil {
+		return err
+	}
+	if err = r.Storer.SetReference(plumbing.NewHashReference(REBASE_HEAD, commit.Hash)); err != nil {
+		return err
+	}
+
+	result.Conflicts = conflictPaths(conflicts)
+	if opts.Progress != nil {
+		subject, _, _ := strings.Cut(commit.Message, "\n")
+		_, _ = fmt.Fprintln(opts.Progress, i18n.Tf("Could not apply %s... %s", commit.Hash.String()[:7], subject))
+	}
+	return ErrMergeConflict
+}
+
+// checkoutConflicted resets the worktree to tip then writes the conflicted
+// merge onto it, staging the merged files and the conflict stages
+func checkoutConflicted(r *git.Repository, w *git.Worktree, tip *object.Commit, merged *TreeResult) ([]conflictEntry, error) {
+	if err := w.Reset(&git.ResetOptions{Commit: tip.Hash, Mode: git.HardReset}); err != nil {
+		return nil, err
+	}
+
+	tipTree, err := tip.Tree()
+	if err != nil {
+		return nil, err
+	}
+	mergedTree, err := r.TreeObject(merged.Tree)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := object.DiffTree(tipTree, mergedTree)
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range changes {
+		var action merkletrie.Action
+		if action, err = change.Action(); err != nil {
+			return nil, err
+		}
+		if action == merkletrie.Delete {
+			if _, err = w.Remove(change.From.Name); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		var file *object.File
+		if _, file, err = change.Files(); err != nil {
+			return nil, err
+		}
+		if err = writeFile(w, change.To.Name, file); err != nil {
+			return nil, err
+		}
+	}
+
+	conflicts := make([]conflictEntry, 0, len(merged.Conflicts))
+	for _, conflict := range merged.Conflicts {
+		entry := conflictEntry{path: conflict.Path, reason: conflict.Reason}
+		if entry.base, err = entryFileOf(r, conflict.Path, conflict.Base); err != nil {
+			return nil, err
+		}
+		if entry.ours, err = entryFileOf(r, conflict.Path, conflict.Ours); err != nil {
+			return nil, err
+		}
+		if entry.theirs, err = entryFileOf(r, conflict.Path, conflict.Theirs); err != nil {
+			return nil, err
+		}
+		conflicts = append(conflicts, entry)
+	}
+	return conflicts, writeConflictStages(r, conflicts)
+}
+
+// checkClean returns ErrDirtyWorktree when w has uncommitted changes
+func checkClean(w *git.Worktree) error {
+	status, err := w.Status()
+	if err != nil {
+		return err
+	}
+	if !status.IsClean() {
+		return ErrDirtyWorktree
+	}
+	return nil
+}
+
+// entryFileOf returns the file of a tree entry, nil for a missing side
+func entryFileOf(r *git.Repository, filepath string, entry *object.TreeEntry) (*object.File, error) {
+	if entry == nil {
+		return nil, nil
+	}
+	blob, err := r.BlobObject(entry.Hash)
+	if err != nil {
+		return nil, err
+	}
+	return object.NewFile(filepath, entry.Mode, blob), nil
+}
+
+// RebaseContinue commits the resolution of the commit a rebase stopped on,
+// with its author and message, and replays the remaining commits
+func RebaseContinue(r *git.Repository, opts MergeOptions) (*MergeResult, error) {
+	result := &MergeResult{}
+	return result, rebaseContinue(r, opts, result)
+}
+
+func rebaseContinue(r *git.Repository, opts MergeOptions, result *MergeResult) error {
+	state, err := loadRebase(r)
+	if err != nil {
+		return err
+	}
+
+	idx, err := r.Storer.Index()
+	if err != nil {
+		return err
+	}
+	if unresolved := unmergedPaths(idx); len(unresolved) > 0 {
+		return fmt.Errorf("%w: %s", ErrUnresolvedConflicts, strings.Join(unresolved, ", "))
+	}
+
+	stopped, err := r.Reference(REBASE_HEAD, false)
+	if err != nil {
+		return err
+	}
+	commit, err := r.CommitObject(stopped.Hash())
+	if err != nil {
+		return err
+	}
+	head, err := r.Head()
+	if err != nil {
+		return err
+	}
+	tip := head.Hash()
+
+	w, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+	resolved, err := w.Commit(commit.Message, &git.CommitOptions{
+		Author:            &commit.Author,
+		Committer:         &commit.Committer,
+		AllowEmptyCommits: true,
+		Signer:            opts.SignKey,
+	})
+	if err != nil {
+		return err
+	}
+
+	// A resolution keeping HEAD as is drops the commit, like git rebase --skip
+	resolvedCommit, err := r.CommitObject(resolved)
+	if err != nil {
+		return err
+	}
+	headCommit, err := r.CommitObject(tip)
+	if err != nil {
+		return err
+	}
+	if resolvedCommit.TreeHash != headCommit.TreeHash {
+		opts.events().CommitCreated(resolved)
+		tip = resolved
+	}
+
+	if err = r.Storer.RemoveReference(REBASE_HEAD); err != nil {
+		return err
+	}
+	return replay(r, w, state, tip, opts, result)
+}
+
+// RebaseAbort restores the rebased branch and the worktree as they were
+// before the rebase started
+func RebaseAbort(r *git.Repository) error {
+	state, err := loadRebase(r)
+	if err != nil {
+		return err
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+	return restoreBranch(r, w, state)
+}
+
+// restoreBranch puts back the rebased branch where it was
+func restoreBranch(r *git.Repository, w *git.Worktree, state *rebaseState) error {
+	if err := r.Storer.SetReference(plumbing.NewHashReference(state.branch, state.orig)); err != nil {
+		return err
+	}
+	if err := r.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, state.branch)); err != nil {
+		return err
+	}
+	if err := w.Reset(&git.ResetOptions{Commit: state.orig, Mode: git.HardReset}); err != nil {
+		return err
+	}
+	return removeRebase(r)
+}
+
+// loadRebase reads the state of the rebase in progress
+func loadRebase(r *git.Repository) (*rebaseState, error) {
+	fs := gitDir(r)
+	if fs == nil {
+		return nil, ErrNoRebaseInProgress
+	}
+
+	files := make(map[string]string)
+	for _, name := range []string{"head-name", "onto", "orig-head", "git-rebase-todo"} {
+		content, err := readIfExists(fs, path.Join(rebaseDir, name))
+		if err != nil {
+			return nil, err
+		}
+		if content == nil && name == "head-name" {
+			return nil, ErrNoRebaseInProgress
+		}
+		files[name] = strings.TrimSpace(string(content))
+	}
+
+	state := &rebaseState{
+		branch: plumbing.ReferenceName(files["head-name"]),
+		onto:   plumbing.NewHash(files["onto"]),
+		orig:   plumbing.NewHash(files["orig-head"]),
+	}
+	for _, line := range strings.Split(files["git-rebase-todo"], "\n") {
+		if hash, ok := strings.CutPrefix(line, "pick "); ok {
+			state.todo = append(state.todo, plumbing.NewHash(hash))
+		}
+	}
+	return state, nil
+}
+
+// saveRebase writes the state of a stopped rebase under the git directory
+func saveRebase(r *git.Repository, state *rebaseState) error {
+	fs := gitDir(r)
+	if fs == nil {
+		return ErrRebaseUnsupported
+	}
+
+	todo := make([]string, 0, len(state.todo))
+	for _, hash := range state.todo {
+		todo = append(todo, "pick "+hash.String())
+	}
+	for name, content := range map[string]string{
+		"head-name":       state.branch.String(),
+		"onto":            state.onto.String(),
+		"orig-head":       state.orig.String(),
+		"git-rebase-todo": strings.Join(todo, "\n"),
+	} {
+		if err := createFile(fs, path.Join(rebaseDir, name), []byte(content+"\n")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeRebase deletes the state of the rebase, a missing one is not an error
+func removeRebase(r *git.Repository) error {
+	err := r.Storer.RemoveReference(REBASE_HEAD)
+	if err != nil && !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return err
+	}
+
+	fs := gitDir(r)
+	if fs == nil {
+		return nil
+	}
+	entries, err := fs.ReadDir(rebaseDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err = fs.Remove(path.Join(rebaseDir, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return fs.Remove(rebaseDir)
+}
@@ -0,0 +1,84 @@
+package ort
+
+import (
+	"maps"
+	"testing"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+func TestRebaseReplaysMergedCommits(t *testing.T) {
+	r := newTestRepository(t)
+
+	base := commitFiles(t, r, "base", map[string]string{"README": "base\n"})
+	ours := commitFiles(t, r, "ours", map[string]string{"README": "base\n", "ours": "ours\n"}, base)
+	side := commitFiles(t, r, "side", map[string]string{"README": "base\n", "side": "side\n"}, base)
+	merge := commitFiles(t, r, "merge side", map[string]string{"README": "base\n", "ours": "ours\n", "side": "side\n"}, ours, side)
+	upstream := commitFiles(t, r, "upstream", map[string]string{"README": "base\n", "upstream": "upstream\n"}, base)
+	checkoutBranch(t, r, "main", merge)
+
+	_, err := Rebase(r, branchRef("upstream", upstream), MergeOptions{Author: &testSignature, Committer: &testSignature})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"README": "base\n", "ours": "ours\n", "side": "side\n", "upstream": "upstream\n"}
+	if got := headFiles(t, r); !maps.Equal(got, want) {
+		t.Fatalf("rebased files = %v, want %v", got, want)
+	}
+
+	// The merge is dropped, ours and side are replayed on a linear history
+	head, err := r.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commits, err := r.Log(&git.LogOptions{From: head.Hash()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var messages []string
+	err = commits.ForEach(func(commit *object.Commit) error {
+		if commit.NumParents() > 1 {
+			t.Errorf("merge commit %s left in the rebased branch", commit.Hash)
+		}
+		messages = append(messages, commit.Message)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 4 || messages[2] != "upstream" || messages[3] != "base" {
+		t.Fatalf("rebased history = %q, want ours and side on upstream", messages)
+	}
+}
+
+func TestRebaseTodoOrder(t *testing.T) {
+	r := newTestRepository(t)
+
+	base := commitFiles(t, r, "base", map[string]string{"README": "base\n"})
+	first := commitFiles(t, r, "first", map[string]string{"first": "first\n"}, base)
+	side := commitFiles(t, r, "side", map[string]string{"side": "side\n"}, base)
+	merge := commitFiles(t, r, "merge", map[string]string{"first": "first\n", "side": "side\n"}, first, side)
+	last := commitFiles(t, r, "last", map[string]string{"last": "last\n"}, merge)
+
+	ours, err := r.CommitObject(last)
+	if err != nil {
+		t.Fatal(err)
+	}
+	onto, err := r.CommitObject(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	history := newAncestry(r)
+	defer func() { _ = history.close() }()
+	todo, err := rebaseTodo(history, ours, onto)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(todo) != 3 || todo[0] != first || todo[1] != side || todo[2] != last {
+		t.Fatalf("todo = %v, want first, side then last", todo)
+	}
+}
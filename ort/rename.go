@@ -0,0 +1,62 @@
+package ort
+
+import (
+	"gravel/i18n"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+// mergeRenames handles the renames of basePath a content merge cannot
+// resolve: both sides renaming it to different paths (rename/rename) and a
+// side renaming it while the other deletes it (rename/delete). Both renamed
+// copies stay in the worktree, each path is conflicted with the base and the
+// side that renamed it. handled is false for the other changes
+func mergeRenames(r *git.Repository, w *git.Worktree, basePath string, ours, theirs *object.Change, labels Labels) (conflicts []conflictEntry, handled bool, err error) {
+	if ours == nil || theirs == nil {
+		return nil, false, nil
+	}
+
+	base := entryFile(ours.From)
+	ourFile, theirFile := entryFile(ours.To), entryFile(theirs.To)
+
+	switch {
+	case isRename(ours) && isRename(theirs) && ours.To.Name != theirs.To.Name:
+		// Our copy is already checked out
+		if err = takeEntry(r, w, theirs.To.Name, theirFile); err != nil {
+			return
+		}
+		return []conflictEntry{
+			{
+				path: ours.To.Name, base: base, ours: ourFile,
+				reason: i18n.Tf(
+					"CONFLICT (rename/rename): %s renamed to %s in %s and to %s in %s",
+					basePath, ours.To.Name, labels.Ours, theirs.To.Name, labels.Theirs,
+				),
+			},
+			{path: theirs.To.Name, base: base, theirs: theirFile},
+		}, true, nil
+
+	case isRename(ours) && theirFile == nil:
+		return []conflictEntry{{
+			path: ours.To.Name, base: base, ours: ourFile,
+			reason: i18n.Tf(
+				"CONFLICT (rename/delete): %s renamed to %s in %s, but deleted in %s",
+				basePath, ours.To.Name, labels.Ours, labels.Theirs,
+			),
+		}}, true, nil
+
+	case isRename(theirs) && ourFile == nil:
+		if err = takeEntry(r, w, theirs.To.Name, theirFile); err != nil {
+			return
+		}
+		return []conflictEntry{{
+			path: theirs.To.Name, base: base, theirs: theirFile,
+			reason: i18n.Tf(
+				"CONFLICT (rename/delete): %s renamed to %s in %s, but deleted in %s",
+				basePath, theirs.To.Name, labels.Theirs, labels.Ours,
+			),
+		}}, true, nil
+	}
+	return nil, false, nil
+}
@@ -0,0 +1,226 @@
+package ort
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"gravel/ort/diff3"
+
+	"github.com/go-git/go-billy/v6"
+	"github.com/go-git/go-git/v6"
+)
+
+const (
+	// MERGE_RR maps the conflicts of the merge in progress to their
+	// recorded resolution, like git's
+	MERGE_RR = "MERGE_RR"
+	// rrCache holds a directory per conflict, with the conflicted file as
+	// preimage and its resolution as postimage
+	rrCache = "rr-cache"
+)
+
+// conflictID fingerprints the conflict hunks of a file with the markers
+// normalized, so that a conflict recurring with other labels matches.
+// markerSize is the length of its markers
+func conflictID(normalized []byte, markerSize int) string {
+	hash := sha1.New()
+	inConflict := false
+	for _, line := range strings.SplitAfter(string(normalized), "\n") {
+		switch {
+		case diff3.IsMarker(line, diff3.ConflictOurMarker, markerSize):
+			inConflict = true
+		case inConflict && (diff3.IsMarker(line, diff3.ConflictSplitMarker, markerSize) || diff3.IsMarker(line, diff3.ConflictTheirMarker, markerSize)):
+			inConflict = !diff3.IsMarker(line, diff3.ConflictTheirMarker, markerSize)
+			hash.Write([]byte{0})
+		case inConflict:
+			hash.Write([]byte(line))
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// normalizeConflicts strips the labels and the base lines from the
+// conflict markers of content, markerSize long
+func normalizeConflicts(content []byte, markerSize int) []byte {
+	var normalized bytes.Buffer
+	inBase := false
+	for _, line := range strings.SplitAfter(string(content), "\n") {
+		switch {
+		case diff3.IsMarker(line, diff3.ConflictOurMarker, markerSize):
+			normalized.WriteString(diff3.Marker(diff3.ConflictOurMarker, markerSize) + "\n")
+		case diff3.IsMarker(line, diff3.ConflictBaseMarker, markerSize):
+			inBase = true
+		case diff3.IsMarker(line, diff3.ConflictSplitMarker, markerSize):
+			inBase = false
+			normalized.WriteString(diff3.Marker(diff3.ConflictSplitMarker, markerSize) + "\n")
+		case diff3.IsMarker(line, diff3.ConflictTheirMarker, markerSize):
+			normalized.WriteString(diff3.Marker(diff3.ConflictTheirMarker, markerSize) + "\n")
+		case !inBase:
+			normalized.WriteString(line)
+		}
+	}
+	return normalized.Bytes()
+}
+
+// replayResolution applies the recorded resolution of the conflicts of
+// content like `git rerere`: the changes from the recorded conflicted file
+// to its resolution are merged into content. ok is false when no
+// resolution applies cleanly, id identifies the conflict for recording.
+// markerSize is the length of the markers of content
+func replayResolution(fs billy.Filesystem, content []byte, markerSize int) (resolved []byte, id string, ok bool, err error) {
+	normalized := normalizeConflicts(content, markerSize)
+	id = conflictID(normalized, markerSize)
+
+	preimage, err := readIfExists(fs, path.Join(rrCache, id, "preimage"))
+	if err != nil || preimage == nil {
+		return nil, id, false, err
+	}
+	postimage, err := readIfExists(fs, path.Join(rrCache, id, "postimage"))
+	if err != nil || postimage == nil {
+		return nil, id, false, err
+	}
+
+	merged, err := diff3.MergeWithOptions(
+		bytes.NewReader(normalized),
+		bytes.NewReader(preimage),
+		bytes.NewReader(postimage),
+		diff3.Options{Detailed: true, MarkerSize: markerSize},
+	)
+	if err != nil || merged.Conflicts {
+		return nil, id, false, err
+	}
+	if resolved, err = io.ReadAll(merged.Result); err != nil {
+		return nil, id, false, err
+	}
+	return resolved, id, true, nil
+}
+
+// rerereConflict is a conflicted file left to the user, with the length of
+// its markers
+type rerereConflict struct {
+	content    []byte
+	markerSize int
+}
+
+// recordConflicts stores the preimages of the conflicts left by a merge and
+// writes MERGE_RR so that Continue records their resolution
+func recordConflicts(r *git.Repository, conflicted map[string]rerereConflict) error {
+	fs := gitDir(r)
+	if fs == nil || len(conflicted) == 0 {
+		return nil
+	}
+
+	var mergeRR strings.Builder
+	for filepath, conflict := range conflicted {
+		normalized := normalizeConflicts(conflict.content, conflict.markerSize)
+		id := conflictID(normalized, conflict.markerSize)
+		if err := createFile(fs, path.Join(rrCache, id, "preimage"), normalized); err != nil {
+			return err
+		}
+		mergeRR.WriteString(id + "\t" + filepath + "\x00")
+	}
+	return createFile(fs, MERGE_RR, []byte(mergeRR.String()))
+}
+
+// recordResolutions stores the worktree files listed in MERGE_RR as the
+// resolution of their conflict, then deletes MERGE_RR. Files still holding
+// conflict markers, markerSize long unless their attributes tell
+// otherwise, are not recorded
+func recordResolutions(r *git.Repository, w *git.Worktree, markerSize int) error {
+	fs := gitDir(r)
+	if fs == nil {
+		return nil
+	}
+
+	mergeRR, err := readIfExists(fs, MERGE_RR)
+	if err != nil || mergeRR == nil {
+		return err
+	}
+
+	attributes, err := attributeStrategies(w.Filesystem)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range strings.Split(string(mergeRR), "\x00") {
+		id, filepath, ok := strings.Cut(entry, "\t")
+		if !ok {
+			continue
+		}
+
+		var resolution []byte
+		resolution, err = readIfExists(w.Filesystem, filepath)
+		if err != nil {
+			return err
+		}
+		if resolution == nil || hasConflictMarkers(resolution, attributeMarkerSize(attributes, filepath, markerSize)) {
+			continue
+		}
+		if err = createFile(fs, path.Join(rrCache, id, "postimage"), resolution); err != nil {
+			return err
+		}
+	}
+	return removeMergeRR(r)
+}
+
+// removeMergeRR deletes MERGE_RR, a missing file is not an error
+func removeMergeRR(r *git.Repository) error {
+	fs := gitDir(r)
+	if fs == nil {
+		return nil
+	}
+
+	err := fs.Remove(MERGE_RR)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// hasConflictMarkers tells whether a line of content starts a conflict
+// with a marker of markerSize
+func hasConflictMarkers(content []byte, markerSize int) bool {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		if diff3.IsMarker(scanner.Text(), diff3.ConflictOurMarker, markerSize) {
+			return true
+		}
+	}
+	return false
+}
+
+// readIfExists returns the content of name in fs, nil when it does not exist
+func readIfExists(fs billy.Filesystem, name string) ([]byte, error) {
+	file, err := fs.Open(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+	return io.ReadAll(file)
+}
+
+// createFile creates name in fs with its parent directories
+func createFile(fs billy.Filesystem, name string, content []byte) error {
+	if err := fs.MkdirAll(path.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	file, err := fs.Create(name)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	_, err = file.Write(content)
+	return err
+}
@@ -0,0 +1,73 @@
+package ort
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing/filemode"
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+// ConflictHandler resolves a conflicting file during the merge, base reads
+// as empty when both sides added the file. An error aborts the merge
+type ConflictHandler func(path string, base, ours, theirs io.Reader) (Resolution, error)
+
+// ResolutionAction tells Merge what to write for a conflicting file
+type ResolutionAction int
+
+const (
+	// Unresolved keeps the conflict markers and stages, like without a handler
+	Unresolved ResolutionAction = iota
+	// ResolveOurs keeps our version of the file
+	ResolveOurs
+	// ResolveTheirs takes their version of the file
+	ResolveTheirs
+	// ResolveContent writes Resolution.Content
+	ResolveContent
+)
+
+// Resolution is the answer of a ConflictHandler
+type Resolution struct {
+	Action ResolutionAction
+	// Content is the resolved file, used by ResolveContent
+	Content []byte
+}
+
+// resolveConflict asks handler for the content of a conflicting file and
+// stages it, resolved is false when the conflict is left to the user
+func resolveConflict(w *git.Worktree, filepath string, baseFile, ourFile, theirFile *object.File, mode filemode.FileMode, handler ConflictHandler) (resolved bool, err error) {
+	sides, err := contents(baseFile, ourFile, theirFile)
+	if err != nil {
+		return
+	}
+
+	resolution, err := handler(filepath,
+		strings.NewReader(sides[0]),
+		strings.NewReader(sides[1]),
+		strings.NewReader(sides[2]),
+	)
+	if err != nil {
+		return
+	}
+
+	var content []byte
+	switch resolution.Action {
+	case Unresolved:
+		return false, nil
+	case ResolveOurs:
+		content = []byte(sides[1])
+	case ResolveTheirs:
+		content = []byte(sides[2])
+	case ResolveContent:
+		content = resolution.Content
+	default:
+		return false, fmt.Errorf("unknown resolution %d of %s", resolution.Action, filepath)
+	}
+
+	if err = writeContent(w, filepath, content, mode); err != nil {
+		return
+	}
+	return true, nil
+}
@@ -0,0 +1,289 @@
+package ort
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gravel/i18n"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+// REVERT_HEAD is the commit a conflicted revert backs out
+const REVERT_HEAD plumbing.ReferenceName = "REVERT_HEAD"
+
+var (
+	ErrRevertInProgress   = errors.New("a revert is in progress, continue or abort it first")
+	ErrNoRevertInProgress = errors.New("there is no revert in progress (REVERT_HEAD missing)")
+)
+
+// Revert commits the inverse of commit on top of HEAD, three-way merged so
+// that later changes to the same files are kept. The mainline of a merge
+// commit is its ours parent in opts.ParentOrder, so that reverting the merge
+// of a component backs out the component. On a conflict, REVERT_HEAD and
+// MERGE_MSG are written and the conflicts staged: resolve them then call
+// RevertContinue, or RevertAbort. Of opts, the options of MergeTrees,
+// ParentOrder and Progress apply
+func Revert(r *git.Repository, commit plumbing.Hash, opts MergeOptions) (*MergeResult, error) {
+	result := &MergeResult{}
+	return result, revert(r, commit, opts, result)
+}
+
+func revert(r *git.Repository, hash plumbing.Hash, opts MergeOptions, result *MergeResult) error {
+	if _, err := revertHead(r); err == nil {
+		return ErrRevertInProgress
+	} else if !errors.Is(err, ErrNoRevertInProgress) {
+		return err
+	}
+	if _, err := mergeHead(r); err == nil {
+		return ErrMergeInProgress
+	} else if !errors.Is(err, ErrNoMergeInProgress) {
+		return err
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+	if err = checkClean(w); err != nil {
+		return err
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		return err
+	}
+	ourCommit, err := r.CommitObject(head.Hash())
+	if err != nil {
+		return err
+	}
+	commit, err := r.CommitObject(hash)
+	if err != nil {
+		return err
+	}
+
+	mainline, err := revertMainline(commit, opts.ParentOrder)
+	if err != nil {
+		return err
+	}
+
+	trees := make([]*object.Tree, 3)
+	for index, side := range []*object.Commit{commit, ourCommit, mainline} {
+		if side == nil {
+			continue
+		}
+		if trees[index], err = side.Tree(); err != nil {
+			return err
+		}
+	}
+	// A root commit reverts to nothing
+	if trees[2] == nil {
+		trees[2] = &object.Tree{}
+	}
+
+	subject, _, _ := strings.Cut(commit.Message, "\n")
+	message := revertMessage(commit, mainline)
+
+	opts.Labels = Labels{
+		Ours:   "HEAD",
+		Base:   commit.Hash.String()[:7] + " (" + subject + ")",
+		Theirs: "parent of " + commit.Hash.String()[:7] + " (" + subject + ")",
+	}
+	merged, err := MergeTrees(r.Storer, trees[0], trees[1], trees[2], opts)
+	if err != nil {
+		return err
+	}
+
+	if len(merged.Conflicts) > 0 {
+		var conflicts []conflictEntry
+		if conflicts, err = checkoutConflicted(r, w, ourCommit, merged); err != nil {
+			return err
+		}
+		if err = writeMergeMsg(r, conflictMessage(message, conflicts)); err != nil {
+			return err
+		}
+		if err = r.Storer.SetReference(plumbing.NewHashReference(REVERT_HEAD, commit.Hash)); err != nil {
+			return err
+		}
+
+		result.Conflicts = conflictPaths(conflicts)
+		if opts.Progress != nil {
+			_, _ = fmt.Fprintln(opts.Progress, i18n.Tf("could not revert %s... %s", commit.Hash.String()[:7], subject))
+		}
+		return ErrMergeConflict
+	}
+
+	if merged.Tree == ourCommit.TreeHash {
+		return fmt.Errorf("nothing to revert, the changes of %s are not in HEAD", commit.Hash.String()[:7])
+	}
+
+	author, committer := opts.signatures(r, ourCommit)
+	reverted := &object.Commit{
+		Author:       *author,
+		Committer:    *committer,
+		Message:      message,
+		TreeHash:     merged.Tree,
+		ParentHashes: []plumbing.Hash{ourCommit.Hash},
+	}
+	newHash, err := storeCommit(r, reverted, opts.SignKey)
+	if err != nil {
+		return err
+	}
+
+	if err = setOrigHead(r, head); err != nil {
+		return err
+	}
+	if err = r.Storer.SetReference(plumbing.NewHashReference(head.Name(), newHash)); err != nil {
+		return err
+	}
+	if err = w.Reset(&git.ResetOptions{Commit: newHash, Mode: git.HardReset}); err != nil {
+		return err
+	}
+	opts.events().CommitCreated(newHash)
+
+	newCommit, err := r.CommitObject(newHash)
+	if err != nil {
+		return err
+	}
+	if err = describe(result, ourCommit, newCommit); err != nil {
+		return err
+	}
+	if opts.Progress != nil {
+		_, _ = fmt.Fprintf(opts.Progress, "%s\n%s", i18n.Tf("Reverted %s (%s).", commit.Hash.String()[:7], subject), result.Stats)
+	}
+	return nil
+}
+
+// revertMainline returns the parent the commit is reverted to, nil for a root
+// commit
+func revertMainline(commit *object.Commit, order ParentOrder) (*object.Commit, error) {
+	switch {
+	case commit.NumParents() == 0:
+		return nil, nil
+	case commit.NumParents() > 1 && order == TheirsFirst:
+		return commit.Parent(commit.NumParents() - 1)
+	default:
+		return commit.Parent(0)
+	}
+}
+
+// revertMessage is the message git gives to the revert of commit
+func revertMessage(commit, mainline *object.Commit) string {
+	subject, _, _ := strings.Cut(commit.Message, "\n")
+	message := fmt.Sprintf("Revert \"%s\"\n\nThis reverts commit %s", subject, commit.Hash)
+	if commit.NumParents() > 1 {
+		message += fmt.Sprintf(", reversing\nchanges made to %s", mainline.Hash)
+	}
+	return message + ".\n"
+}
+
+// revertHead returns the commit recorded in REVERT_HEAD
+func revertHead(r *git.Repository) (*plumbing.Reference, error) {
+	ref, err := r.Reference(REVERT_HEAD, false)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, ErrNoRevertInProgress
+	}
+	return ref, err
+}
+
+// RevertContinue commits the resolved revert with the message of MERGE_MSG
+func RevertContinue(r *git.Repository, opts MergeOptions) (*MergeResult, error) {
+	result := &MergeResult{}
+	return result, revertContinue(r, opts, result)
+}
+
+func revertContinue(r *git.Repository, opts MergeOptions, result *MergeResult) error {
+	reverting, err := revertHead(r)
+	if err != nil {
+		return err
+	}
+
+	idx, err := r.Storer.Index()
+	if err != nil {
+		return err
+	}
+	if unresolved := unmergedPaths(idx); len(unresolved) > 0 {
+		return fmt.Errorf("%w: %s", ErrUnresolvedConflicts, strings.Join(unresolved, ", "))
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		return err
+	}
+	ourCommit, err := r.CommitObject(head.Hash())
+	if err != nil {
+		return err
+	}
+	// Prefer the message prepared by Revert, it may have been edited since
+	message, err := readMergeMsg(r)
+	if err != nil {
+		return err
+	}
+	if message == "" {
+		var commit, mainline *object.Commit
+		if commit, err = r.CommitObject(reverting.Hash()); err != nil {
+			return err
+		}
+		if mainline, err = revertMainline(commit, opts.ParentOrder); err != nil {
+			return err
+		}
+		message = revertMessage(commit, mainline)
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+	author, committer := opts.signatures(r, ourCommit)
+	newHash, err := w.Commit(message, &git.CommitOptions{
+		Author:    author,
+		Committer: committer,
+		Signer:    opts.SignKey,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = removeMergeMsg(r); err != nil {
+		return err
+	}
+	if err = r.Storer.RemoveReference(REVERT_HEAD); err != nil {
+		return err
+	}
+	opts.events().CommitCreated(newHash)
+
+	newCommit, err := r.CommitObject(newHash)
+	if err != nil {
+		return err
+	}
+	if err = describe(result, ourCommit, newCommit); err != nil {
+		return err
+	}
+	if opts.Progress != nil {
+		_, _ = fmt.Fprintf(opts.Progress, "%s\n%s", i18n.T("Revert concluded."), result.Stats)
+	}
+	return nil
+}
+
+// RevertAbort backs out of a conflicted revert, the index and worktree are
+// reset to HEAD
+func RevertAbort(r *git.Repository) error {
+	if _, err := revertHead(r); err != nil {
+		return err
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+	if err = w.Reset(&git.ResetOptions{Mode: git.HardReset}); err != nil {
+		return err
+	}
+	if err = removeMergeMsg(r); err != nil {
+		return err
+	}
+	return r.Storer.RemoveReference(REVERT_HEAD)
+}
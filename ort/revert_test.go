@@ -0,0 +1,66 @@
+package ort
+
+import (
+	"errors"
+	"maps"
+	"strings"
+	"testing"
+)
+
+func TestRevert(t *testing.T) {
+	r := newTestRepository(t)
+	base := commitFiles(t, r, "base", map[string]string{"README": "one\ntwo\nthree\n"})
+	change := commitFiles(t, r, "change one", map[string]string{"README": "ONE\ntwo\nthree\n", "added": "added\n"}, base)
+	later := commitFiles(t, r, "change three", map[string]string{"README": "ONE\ntwo\nTHREE\n", "added": "added\n"}, change)
+	checkoutBranch(t, r, "main", later)
+
+	result, err := Revert(r, change, MergeOptions{Author: &testSignature, Committer: &testSignature})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The later change to the same file is kept
+	want := map[string]string{"README": "one\ntwo\nTHREE\n"}
+	if got := headFiles(t, r); !maps.Equal(got, want) {
+		t.Fatalf("reverted files = %v, want %v", got, want)
+	}
+	commit, err := r.CommitObject(result.Commit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(commit.Message, "Revert \"change one\"\n\nThis reverts commit "+change.String()) {
+		t.Errorf("message = %q", commit.Message)
+	}
+	if len(commit.ParentHashes) != 1 || commit.ParentHashes[0] != later {
+		t.Errorf("parents = %v, want %s", commit.ParentHashes, later)
+	}
+}
+
+func TestRevertConflict(t *testing.T) {
+	r := newTestRepository(t)
+	base := commitFiles(t, r, "base", map[string]string{"README": "base\n"})
+	change := commitFiles(t, r, "change", map[string]string{"README": "change\n"}, base)
+	later := commitFiles(t, r, "later", map[string]string{"README": "later\n"}, change)
+	checkoutBranch(t, r, "main", later)
+
+	result, err := Revert(r, change, MergeOptions{Author: &testSignature, Committer: &testSignature})
+	if !errors.Is(err, ErrMergeConflict) {
+		t.Fatalf("err = %v, want %v", err, ErrMergeConflict)
+	}
+	if len(result.Conflicts) != 1 || result.Conflicts[0] != "README" {
+		t.Fatalf("conflicts = %v, want README", result.Conflicts)
+	}
+	if _, err = Revert(r, change, MergeOptions{}); !errors.Is(err, ErrRevertInProgress) {
+		t.Fatalf("second Revert() = %v, want %v", err, ErrRevertInProgress)
+	}
+
+	if err = RevertAbort(r); err != nil {
+		t.Fatal(err)
+	}
+	if got := readWorktree(t, r, "README"); got != "later\n" {
+		t.Errorf("README = %q, want it reset to HEAD", got)
+	}
+	if _, err = revertHead(r); !errors.Is(err, ErrNoRevertInProgress) {
+		t.Errorf("REVERT_HEAD after the abort: %v", err)
+	}
+}
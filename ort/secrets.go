@@ -0,0 +1,204 @@
+package ort
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"gravel/i18n"
+
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/utils/merkletrie"
+)
+
+// SecretMode selects what a merge does with secrets found in the incoming content
+type SecretMode int
+
+const (
+	// SecretsIgnore does not scan the incoming content
+	SecretsIgnore SecretMode = iota
+	// SecretsWarn reports the findings on Progress and merges anyway
+	SecretsWarn
+	// SecretsBlock fails with ErrSecretsDetected before changing the repository
+	SecretsBlock
+)
+
+// ErrSecretsDetected is returned by merges blocking on secrets
+var ErrSecretsDetected = errors.New("secrets detected in the merged content")
+
+// maxScannedSize skips large files, secrets are found in small text files
+const maxScannedSize = 1 << 20
+
+// SecretFinding is a likely secret of the incoming content
+type SecretFinding struct {
+	Path string
+	Line int
+	Kind string
+}
+
+func (finding SecretFinding) String() string {
+	return fmt.Sprintf("%s:%d: %s", finding.Path, finding.Line, finding.Kind)
+}
+
+var secretPatterns = []struct {
+	kind    string
+	pattern *regexp.Regexp
+}{
+	{"private key", regexp.MustCompile(`-----BEGIN ([A-Z0-9]+ )*PRIVATE KEY-----`)},
+	{"AWS access key", regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"AWS secret key", regexp.MustCompile(`(?i)aws_secret_access_key\s*[=:]\s*["']?[A-Za-z0-9/+=]{40}`)},
+	{"GitHub token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36}\b`)},
+}
+
+// envSecret matches the assignments of .env files whose name suggests a secret
+var envSecret = regexp.MustCompile(`(?i)^\s*(export\s+)?[A-Z0-9_]*(SECRET|PASSWORD|PASSWD|TOKEN|API_KEY|PRIVATE_KEY|CREDENTIAL)[A-Z0-9_]*\s*=\s*(.*)$`)
+
+// isEnvFile tells whether name holds environment values, examples excluded
+func isEnvFile(name string) bool {
+	base := path.Base(name)
+	if base != ".env" && !strings.HasPrefix(base, ".env.") {
+		return false
+	}
+	switch path.Ext(base) {
+	case ".example", ".sample", ".template", ".dist":
+		return false
+	}
+	return true
+}
+
+// isPlaceholder tells whether an .env value is left for the user to fill
+func isPlaceholder(value string) bool {
+	value = strings.Trim(strings.TrimSpace(value), `"'`)
+	return value == "" ||
+		strings.HasPrefix(value, "[[") ||
+		strings.HasPrefix(value, "${") ||
+		strings.HasPrefix(value, "<") ||
+		strings.EqualFold(value, "changeme")
+}
+
+// scanContent returns the likely secrets of content
+func scanContent(filepath, content string) (findings []SecretFinding) {
+	env := isEnvFile(filepath)
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	scanner.Buffer(nil, maxScannedSize)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		for _, secret := range secretPatterns {
+			if secret.pattern.MatchString(text) {
+				findings = append(findings, SecretFinding{Path: filepath, Line: line, Kind: secret.kind})
+			}
+		}
+		if match := envSecret.FindStringSubmatch(text); env && match != nil && !isPlaceholder(match[3]) {
+			findings = append(findings, SecretFinding{Path: filepath, Line: line, Kind: "environment secret"})
+		}
+	}
+	return
+}
+
+// scanSecrets scans the files theirs adds or changes since from, nil when
+// the histories are unrelated. Secrets already present in from are not
+// reported again
+func scanSecrets(from, theirs *object.Commit) ([]SecretFinding, error) {
+	var fromTree *object.Tree
+	if from != nil {
+		var err error
+		if fromTree, err = from.Tree(); err != nil {
+			return nil, err
+		}
+	}
+	theirTree, err := theirs.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := object.DiffTreeWithOptions(context.Background(), fromTree, theirTree, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []SecretFinding
+	for _, change := range changes {
+		action, err := change.Action()
+		if err != nil {
+			return nil, err
+		}
+		if action == merkletrie.Delete || isGitlink(change) {
+			continue
+		}
+
+		before, after, err := change.Files()
+		if err != nil {
+			return nil, err
+		}
+		if after.Size > maxScannedSize {
+			continue
+		}
+		if binary, err := after.IsBinary(); err != nil || binary {
+			continue
+		}
+
+		content, err := after.Contents()
+		if err != nil {
+			return nil, err
+		}
+
+		var existing string
+		if before != nil {
+			if existing, err = before.Contents(); err != nil {
+				return nil, err
+			}
+		}
+
+		lines := strings.Split(content, "\n")
+		for _, finding := range scanContent(after.Name, content) {
+			if !strings.Contains(existing, lines[finding.Line-1]) {
+				findings = append(findings, finding)
+			}
+		}
+	}
+	return findings, nil
+}
+
+// checkSecrets scans the content theirs brings into ours, nil for an unborn
+// HEAD, warning on Progress or failing depending on opts.Secrets
+func checkSecrets(ours, theirs *object.Commit, opts MergeOptions) error {
+	if opts.Secrets == SecretsIgnore {
+		return nil
+	}
+
+	// Everything is theirs to scan without ours or a common ancestor
+	var from *object.Commit
+	if ours != nil {
+		bases, err := ours.MergeBase(theirs)
+		if err != nil {
+			return err
+		}
+		if len(bases) > 0 {
+			from = bases[0]
+		}
+	}
+
+	findings, err := scanSecrets(from, theirs)
+	if err != nil || len(findings) == 0 {
+		return err
+	}
+
+	if opts.Secrets == SecretsBlock {
+		list := make([]string, len(findings))
+		for index, finding := range findings {
+			list[index] = finding.String()
+		}
+		return fmt.Errorf("%w:\n  %s", ErrSecretsDetected, strings.Join(list, "\n  "))
+	}
+
+	if opts.Progress != nil {
+		for _, finding := range findings {
+			_, _ = fmt.Fprintln(opts.Progress, i18n.Tf("warning: possible %s in %s:%d", finding.Kind, finding.Path, finding.Line))
+		}
+	}
+	return nil
+}
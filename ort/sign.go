@@ -0,0 +1,152 @@
+package ort
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"golang.org/x/crypto/ssh"
+)
+
+// ErrSignKey is returned by ReadSignKey for keys which are neither OpenPGP
+// nor SSH private keys
+var ErrSignKey = errors.New("unsupported signing key, expected an OpenPGP or SSH private key")
+
+// ReadSignKey parses an armored OpenPGP private key or an OpenSSH private
+// key into a signer, passphrase decrypts it when it is encrypted
+func ReadSignKey(content, passphrase []byte) (git.Signer, error) {
+	if bytes.Contains(content, []byte("BEGIN PGP PRIVATE KEY BLOCK")) {
+		entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(content))
+		if err != nil {
+			return nil, err
+		}
+		for _, entity := range entities {
+			if entity.PrivateKey == nil {
+				continue
+			}
+			if entity.PrivateKey.Encrypted {
+				if err = entity.DecryptPrivateKeys(passphrase); err != nil {
+					return nil, err
+				}
+			}
+			return OpenPGPSigner(entity), nil
+		}
+		return nil, ErrSignKey
+	}
+
+	key, err := ssh.ParsePrivateKey(content)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		key, err = ssh.ParsePrivateKeyWithPassphrase(content, passphrase)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSignKey, err)
+	}
+	return SSHSigner(key), nil
+}
+
+// OpenPGPSigner signs commits with key like `git commit -S`, its private
+// key must be decrypted
+func OpenPGPSigner(key *openpgp.Entity) git.Signer {
+	return openPGPSigner{key: key}
+}
+
+type openPGPSigner struct {
+	key *openpgp.Entity
+}
+
+func (signer openPGPSigner) Sign(message io.Reader) ([]byte, error) {
+	var signature bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&signature, signer.key, message, nil); err != nil {
+		return nil, err
+	}
+	return signature.Bytes(), nil
+}
+
+// The SSHSIG format of the signatures git makes with gpg.format=ssh
+const (
+	sshsigMagic     = "SSHSIG"
+	sshsigVersion   = 1
+	sshsigNamespace = "git"
+	sshsigHash      = "sha512"
+	sshsigArmor     = "SSH SIGNATURE"
+)
+
+// SSHSigner signs commits with key like git with gpg.format=ssh, the
+// signatures are checked by `ssh-keygen -Y verify`
+func SSHSigner(key ssh.Signer) git.Signer {
+	return sshSigner{key: key}
+}
+
+type sshSigner struct {
+	key ssh.Signer
+}
+
+func (signer sshSigner) Sign(message io.Reader) ([]byte, error) {
+	hash := sha512.New()
+	if _, err := io.Copy(hash, message); err != nil {
+		return nil, err
+	}
+
+	signed := append([]byte(sshsigMagic), ssh.Marshal(struct {
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Hash          []byte
+	}{sshsigNamespace, "", sshsigHash, hash.Sum(nil)})...)
+
+	var signature *ssh.Signature
+	var err error
+	// RSA keys sign with SHA-1 by default, which ssh-keygen rejects
+	if algorithmSigner, ok := signer.key.(ssh.AlgorithmSigner); ok && signer.key.PublicKey().Type() == ssh.KeyAlgoRSA {
+		signature, err = algorithmSigner.SignWithAlgorithm(rand.Reader, signed, ssh.KeyAlgoRSASHA512)
+	} else {
+		signature, err = signer.key.Sign(rand.Reader, signed)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	blob := append([]byte(sshsigMagic), ssh.Marshal(struct {
+		Version       uint32
+		PublicKey     []byte
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Signature     []byte
+	}{sshsigVersion, signer.key.PublicKey().Marshal(), sshsigNamespace, "", sshsigHash, ssh.Marshal(signature)})...)
+	return pem.EncodeToMemory(&pem.Block{Type: sshsigArmor, Bytes: blob}), nil
+}
+
+// storeCommit signs commit with signer, when not nil, and stores it
+func storeCommit(r *git.Repository, commit *object.Commit, signer git.Signer) (plumbing.Hash, error) {
+	if signer != nil {
+		unsigned := &plumbing.MemoryObject{}
+		if err := commit.EncodeWithoutSignature(unsigned); err != nil {
+			return plumbing.ZeroHash, err
+		}
+		reader, err := unsigned.Reader()
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		signature, err := signer.Sign(reader)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		commit.Signature = string(signature)
+	}
+
+	encoded := r.Storer.NewEncodedObject()
+	if err := commit.Encode(encoded); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return r.Storer.SetEncodedObject(encoded)
+}
@@ -0,0 +1,225 @@
+package ort
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"gravel/i18n"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/format/index"
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+var (
+	// ErrNoMergeInProgress is returned when MERGE_HEAD does not exist
+	ErrNoMergeInProgress = errors.New("there is no merge in progress (MERGE_HEAD missing)")
+	// ErrUnresolvedConflicts is returned when the index still holds conflict stages
+	ErrUnresolvedConflicts = errors.New("unresolved conflicts, stage the resolved files first")
+)
+
+// mergeHead returns the commit recorded in MERGE_HEAD
+func mergeHead(r *git.Repository) (*plumbing.Reference, error) {
+	ref, err := r.Reference(MERGE_HEAD, false)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, ErrNoMergeInProgress
+	}
+	return ref, err
+}
+
+// unmergedPaths lists the paths of idx holding conflict stages
+func unmergedPaths(idx *index.Index) (paths []string) {
+	for _, entry := range idx.Entries {
+		if entry.Stage != 0 && !slices.Contains(paths, entry.Name) {
+			paths = append(paths, entry.Name)
+		}
+	}
+	return
+}
+
+// MergeState summarizes the last merge of a repository, for diagnostics
+type MergeState struct {
+	// InProgress is true while MERGE_HEAD exists
+	InProgress bool
+	MergeHead  plumbing.Hash
+	// OrigHead is HEAD before the last merge moved it
+	OrigHead plumbing.Hash
+	// Message is MERGE_MSG without its comments
+	Message string
+	// Conflicts lists the paths still holding conflict stages
+	Conflicts []string
+}
+
+// State reads the state the last merge left in the repository
+func State(r *git.Repository) (*MergeState, error) {
+	state := new(MergeState)
+
+	merge, err := mergeHead(r)
+	switch {
+	case err == nil:
+		state.InProgress = true
+		state.MergeHead = merge.Hash()
+	case !errors.Is(err, ErrNoMergeInProgress):
+		return nil, err
+	}
+
+	orig, err := r.Reference(ORIG_HEAD, false)
+	switch {
+	case err == nil:
+		state.OrigHead = orig.Hash()
+	case !errors.Is(err, plumbing.ErrReferenceNotFound):
+		return nil, err
+	}
+
+	if state.Message, err = readMergeMsg(r); err != nil {
+		return nil, err
+	}
+
+	idx, err := r.Storer.Index()
+	if err != nil {
+		return nil, err
+	}
+	state.Conflicts = unmergedPaths(idx)
+	return state, nil
+}
+
+// Abort backs out of a conflicted merge: the index is reset to HEAD and the
+// files the merge wrote, those differing between HEAD and MERGE_HEAD, are
+// restored in the worktree. MERGE_HEAD, MERGE_MSG and MERGE_RR are deleted.
+// Uncommitted changes to the other files are kept, as with git merge --abort
+func Abort(r *git.Repository) error {
+	theirs, err := mergeHead(r)
+	if err != nil {
+		return err
+	}
+
+	// A conflicted merge leaves HEAD where it was, at ORIG_HEAD
+	head, err := r.Head()
+	if err != nil {
+		return err
+	}
+	ours, err := r.CommitObject(head.Hash())
+	if err != nil {
+		return err
+	}
+
+	err = resetMerged(r, ours, theirs.Hash())
+	if err != nil {
+		return err
+	}
+
+	if err = removeMergeMsg(r); err != nil {
+		return err
+	}
+	if err = removeMergeRR(r); err != nil {
+		return err
+	}
+	return r.Storer.RemoveReference(MERGE_HEAD)
+}
+
+// Continue concludes a merge once every conflict has been resolved and
+// staged: the merge commit is created with HEAD and MERGE_HEAD as parents,
+// in opts.ParentOrder, and MERGE_HEAD is deleted, the message is taken from MERGE_MSG when present.
+// The merge hooks run around the commit like in Merge
+func Continue(r *git.Repository, opts MergeOptions) error {
+	theirs, err := mergeHead(r)
+	if err != nil {
+		return err
+	}
+
+	idx, err := r.Storer.Index()
+	if err != nil {
+		return err
+	}
+
+	unresolved := unmergedPaths(idx)
+	if len(unresolved) > 0 {
+		return fmt.Errorf("%w: %s", ErrUnresolvedConflicts, strings.Join(unresolved, ", "))
+	}
+
+	head, err := r.Head()
+	if err != nil {
+		return err
+	}
+
+	ourCommit, err := r.CommitObject(head.Hash())
+	if err != nil {
+		return err
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+
+	// Prefer the message prepared by Merge, it may have been edited since
+	message, err := readMergeMsg(r)
+	if err != nil {
+		return err
+	}
+	if message == "" {
+		message = fmt.Sprintf(
+			"Merge commit '%s' into %s",
+			theirs.Hash().String()[:7],
+			head.Name().Short(),
+		)
+	}
+
+	// Like in Merge, a commit rejected by the hooks leaves the merge to
+	// continue again
+	ctx := context.Background()
+	hooks := newMergeHooks(r, opts)
+	if message, err = hooks.verify(ctx, r, message); err != nil {
+		return err
+	}
+
+	// The resolutions are recorded before committing drops their conflicts
+	if opts.Rerere {
+		if err = recordResolutions(r, w, opts.MarkerSize); err != nil {
+			return err
+		}
+	}
+
+	author, committer := opts.signatures(r, ourCommit)
+	newHash, err := w.Commit(
+		message,
+		&git.CommitOptions{
+			Author:    author,
+			Committer: committer,
+			Parents:   opts.ParentOrder.parents(ourCommit.Hash, theirs.Hash()),
+			Signer:    opts.SignKey,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	if err = removeMergeMsg(r); err != nil {
+		return err
+	}
+	if err = r.Storer.RemoveReference(MERGE_HEAD); err != nil {
+		return err
+	}
+	opts.events().CommitCreated(newHash)
+	hooks.merged(ctx, false)
+
+	if opts.Progress != nil {
+		var newCommit *object.Commit
+		newCommit, err = r.CommitObject(newHash)
+		if err != nil {
+			return err
+		}
+
+		var patch *object.Patch
+		patch, err = ourCommit.Patch(newCommit)
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(opts.Progress, "%s\n%s", i18n.T("Merge concluded."), patch.Stats())
+	}
+	return nil
+}
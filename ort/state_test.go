@@ -0,0 +1,92 @@
+package ort
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestAbort(t *testing.T) {
+	r := newTestRepository(t)
+	base := commitFiles(t, r, "base", map[string]string{"README": "base\n"})
+	ours := commitFiles(t, r, "ours", map[string]string{"README": "ours\n"}, base)
+	theirs := commitFiles(t, r, "theirs", map[string]string{"README": "theirs\n"}, base)
+	checkoutBranch(t, r, "main", ours)
+
+	result, err := Merge(r, branchRef("theirs", theirs), MergeOptions{Author: &testSignature, Committer: &testSignature})
+	if !errors.Is(err, ErrMergeConflict) {
+		t.Fatalf("err = %v, want %v", err, ErrMergeConflict)
+	}
+	if !slices.Equal(result.Conflicts, []string{"README"}) {
+		t.Fatalf("conflicts = %v, want README", result.Conflicts)
+	}
+
+	state, err := State(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !state.InProgress || state.MergeHead != theirs || !slices.Equal(state.Conflicts, []string{"README"}) {
+		t.Fatalf("state = %+v, want the merge of theirs in progress", state)
+	}
+
+	if err = Abort(r); err != nil {
+		t.Fatal(err)
+	}
+	if got := readWorktree(t, r, "README"); got != "ours\n" {
+		t.Errorf("README = %q, want it reset to ours", got)
+	}
+	if state, err = State(r); err != nil {
+		t.Fatal(err)
+	}
+	if state.InProgress || len(state.Conflicts) > 0 {
+		t.Errorf("state = %+v, want no merge in progress", state)
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, err := w.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.IsClean() {
+		t.Errorf("worktree is dirty after the abort: %v", status)
+	}
+
+	if err = Abort(r); !errors.Is(err, ErrNoMergeInProgress) {
+		t.Fatalf("second Abort() = %v, want %v", err, ErrNoMergeInProgress)
+	}
+}
+
+func TestAbortKeepsLocalChanges(t *testing.T) {
+	r := newTestRepository(t)
+	base := commitFiles(t, r, "base", map[string]string{"README": "base\n", "NOTES": "notes\n"})
+	ours := commitFiles(t, r, "ours", map[string]string{"README": "ours\n", "NOTES": "notes\n"}, base)
+	theirs := commitFiles(t, r, "theirs", map[string]string{"README": "theirs\n", "NOTES": "notes\n", "LICENSE": "MIT\n"}, base)
+	checkoutBranch(t, r, "main", ours)
+
+	writeWorktree(t, r, "NOTES", "edited\n")
+
+	_, err := Merge(r, branchRef("theirs", theirs), MergeOptions{Author: &testSignature, Committer: &testSignature})
+	if !errors.Is(err, ErrMergeConflict) {
+		t.Fatalf("err = %v, want %v", err, ErrMergeConflict)
+	}
+
+	if err = Abort(r); err != nil {
+		t.Fatal(err)
+	}
+	if got := readWorktree(t, r, "README"); got != "ours\n" {
+		t.Errorf("README = %q, want it reset to ours", got)
+	}
+	if got := readWorktree(t, r, "NOTES"); got != "edited\n" {
+		t.Errorf("NOTES = %q, want the local edit kept", got)
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.Filesystem.Stat("LICENSE"); err == nil {
+		t.Error("LICENSE added by the merge is still in the worktree")
+	}
+}
@@ -0,0 +1,275 @@
+package ort
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"path"
+	"slices"
+
+	"gravel/ort/diff3"
+)
+
+// ConflictStrategy names how a path modified on both sides gets resolved
+type ConflictStrategy string
+
+const (
+	// StrategyOurs keeps our whole file
+	StrategyOurs ConflictStrategy = "ours"
+	// StrategyTheirs takes their whole file
+	StrategyTheirs ConflictStrategy = "theirs"
+	// StrategyUnion keeps the lines of both sides without conflict markers
+	StrategyUnion ConflictStrategy = "union"
+	// StrategyJSONMerge merges JSON documents key by key
+	StrategyJSONMerge ConflictStrategy = "json-merge"
+)
+
+// PathStrategy applies a ConflictStrategy to the paths matching Pattern
+type PathStrategy struct {
+	Pattern  string
+	Strategy ConflictStrategy
+}
+
+// strategyFor returns the strategy of the first pattern matching filepath
+func strategyFor(strategies []PathStrategy, filepath string) ConflictStrategy {
+	for _, strategy := range strategies {
+		if matched, _ := path.Match(strategy.Pattern, filepath); matched {
+			return strategy.Strategy
+		}
+	}
+	return ""
+}
+
+// absent marks a key missing from one of the merged JSON objects
+type absent struct{}
+
+// jsonObject is a decoded JSON object keeping the order of its keys, so
+// that a merged document reads like the documents it merges
+type jsonObject struct {
+	keys   []string
+	values map[string]any
+}
+
+func (object *jsonObject) MarshalJSON() ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteByte('{')
+	for index, key := range object.keys {
+		if index > 0 {
+			buffer.WriteByte(',')
+		}
+		encodedKey, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(object.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(encodedKey)
+		buffer.WriteByte(':')
+		buffer.Write(value)
+	}
+	buffer.WriteByte('}')
+	return buffer.Bytes(), nil
+}
+
+// decodeJSON decodes a document into jsonObject, []any and the scalars of
+// json.Decoder, numbers are kept as json.Number so large integers keep
+// their precision
+func decodeJSON(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	value, err := decodeJSONValue(decoder)
+	if errors.Is(err, io.EOF) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err = decoder.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("invalid character after top-level value")
+	}
+	return value, nil
+}
+
+func decodeJSONValue(decoder *json.Decoder) (any, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		object := &jsonObject{values: make(map[string]any)}
+		for decoder.More() {
+			if token, err = decoder.Token(); err != nil {
+				return nil, err
+			}
+			key := token.(string)
+			value, err := decodeJSONValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			if _, seen := object.values[key]; !seen {
+				object.keys = append(object.keys, key)
+			}
+			object.values[key] = value
+		}
+		_, err = decoder.Token()
+		return object, err
+	case json.Delim('['):
+		array := make([]any, 0)
+		for decoder.More() {
+			value, err := decodeJSONValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		_, err = decoder.Token()
+		return array, err
+	default:
+		return token, nil
+	}
+}
+
+// equalJSON compares decoded values, objects regardless of the order of
+// their keys
+func equalJSON(a, b any) bool {
+	aObject, aIsObject := a.(*jsonObject)
+	bObject, bIsObject := b.(*jsonObject)
+	if aIsObject || bIsObject {
+		if !aIsObject || !bIsObject || len(aObject.keys) != len(bObject.keys) {
+			return false
+		}
+		for key, value := range aObject.values {
+			other, ok := bObject.values[key]
+			if !ok || !equalJSON(value, other) {
+				return false
+			}
+		}
+		return true
+	}
+
+	aArray, aIsArray := a.([]any)
+	bArray, bIsArray := b.([]any)
+	if aIsArray || bIsArray {
+		return aIsArray && bIsArray && slices.EqualFunc(aArray, bArray, equalJSON)
+	}
+	return a == b
+}
+
+// mergeJSON three-way merges JSON documents, ok is false when both sides
+// changed the same value differently. Merged objects keep our keys in our
+// order followed by the keys they added
+func mergeJSON(base, ours, theirs []byte) (result []byte, ok bool, err error) {
+	var baseValue, ourValue, theirValue any
+	if len(bytes.TrimSpace(base)) > 0 {
+		if baseValue, err = decodeJSON(base); err != nil {
+			return
+		}
+	}
+	if ourValue, err = decodeJSON(ours); err != nil {
+		return
+	}
+	if theirValue, err = decodeJSON(theirs); err != nil {
+		return
+	}
+
+	merged, ok := mergeJSONValue(baseValue, ourValue, theirValue)
+	if !ok {
+		return
+	}
+
+	result, err = json.MarshalIndent(merged, "", "  ")
+	if err != nil {
+		return
+	}
+	result = append(result, '\n')
+	return
+}
+
+func mergeJSONValue(base, ours, theirs any) (any, bool) {
+	switch {
+	case equalJSON(ours, theirs):
+		return ours, true
+	case equalJSON(base, ours):
+		return theirs, true
+	case equalJSON(base, theirs):
+		return ours, true
+	}
+
+	ourObject, ourIsObject := ours.(*jsonObject)
+	theirObject, theirIsObject := theirs.(*jsonObject)
+	if !ourIsObject || !theirIsObject {
+		return nil, false
+	}
+
+	baseObject, _ := base.(*jsonObject)
+	if baseObject == nil {
+		baseObject = &jsonObject{}
+	}
+	merged := &jsonObject{values: make(map[string]any)}
+
+	var keys []string
+	seen := make(map[string]bool)
+	for _, object := range []*jsonObject{ourObject, theirObject, baseObject} {
+		for _, key := range object.keys {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	lookup := func(object *jsonObject, key string) any {
+		if value, ok := object.values[key]; ok {
+			return value
+		}
+		return absent{}
+	}
+
+	for _, key := range keys {
+		value, ok := mergeJSONValue(
+			lookup(baseObject, key),
+			lookup(ourObject, key),
+			lookup(theirObject, key),
+		)
+		if !ok {
+			return nil, false
+		}
+		if _, deleted := value.(absent); !deleted {
+			merged.keys = append(merged.keys, key)
+			merged.values[key] = value
+		}
+	}
+	return merged, true
+}
+
+// StrategyOption selects the side conflicting hunks resolve to, like
+// `git merge -X`. It is not go-git's OrtMergeStrategyOption, whose zero
+// value is TheirsMergeStrategy: the zero value of this one is unset
+type StrategyOption int8
+
+const (
+	// NoStrategyOption leaves conflict markers around conflicting hunks
+	NoStrategyOption StrategyOption = iota
+	// OursMergeStrategy resolves conflicting hunks with our lines, -X ours
+	OursMergeStrategy
+	// TheirsMergeStrategy resolves conflicting hunks with their lines, -X theirs
+	TheirsMergeStrategy
+)
+
+// optionFavor maps the -X ours/theirs option onto the side conflicting hunks resolve to
+func optionFavor(option StrategyOption) diff3.Favor {
+	switch option {
+	case OursMergeStrategy:
+		return diff3.FavorOurs
+	case TheirsMergeStrategy:
+		return diff3.FavorTheirs
+	default:
+		return diff3.FavorNone
+	}
+}
@@ -0,0 +1,75 @@
+package ort
+
+import "testing"
+
+func TestMergeJSON(t *testing.T) {
+	for _, test := range []struct {
+		name               string
+		base, ours, theirs string
+		want               string
+		conflict           bool
+	}{
+		{
+			name:   "keys keep their order",
+			base:   `{"name": "app", "version": "1.0.0"}`,
+			ours:   `{"name": "app", "version": "1.1.0", "private": true}`,
+			theirs: `{"name": "app", "version": "1.0.0", "scripts": {"build": "vite"}}`,
+			want:   "{\n  \"name\": \"app\",\n  \"version\": \"1.1.0\",\n  \"private\": true,\n  \"scripts\": {\n    \"build\": \"vite\"\n  }\n}\n",
+		},
+		{
+			name:   "large integers",
+			base:   `{"id": 9007199254740993}`,
+			ours:   `{"id": 9007199254740993, "ratio": 0.10}`,
+			theirs: `{"id": 12345678901234567890}`,
+			want:   "{\n  \"id\": 12345678901234567890,\n  \"ratio\": 0.10\n}\n",
+		},
+		{
+			name:   "deleted key",
+			base:   `{"a": 1, "b": 2}`,
+			ours:   `{"b": 2, "a": 1}`,
+			theirs: `{"a": 1}`,
+			want:   "{\n  \"a\": 1\n}\n",
+		},
+		{
+			name:   "reordered is unchanged",
+			base:   `{"a": 1, "b": 2}`,
+			ours:   `{"a": 1, "b": 2}`,
+			theirs: `{"b": 2, "a": 1}`,
+			want:   "{\n  \"a\": 1,\n  \"b\": 2\n}\n",
+		},
+		{
+			name:     "both change a value",
+			base:     `{"port": 80}`,
+			ours:     `{"port": 8080}`,
+			theirs:   `{"port": 3000}`,
+			conflict: true,
+		},
+		{
+			name:   "no base",
+			ours:   `{"a": [1, 2]}`,
+			theirs: `{"b": null}`,
+			want:   "{\n  \"a\": [\n    1,\n    2\n  ],\n  \"b\": null\n}\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			result, ok, err := mergeJSON([]byte(test.base), []byte(test.ours), []byte(test.theirs))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok == test.conflict {
+				t.Fatalf("mergeJSON() ok = %v, want %v", ok, !test.conflict)
+			}
+			if string(result) != test.want {
+				t.Fatalf("mergeJSON() = %q, want %q", result, test.want)
+			}
+		})
+	}
+}
+
+func TestMergeJSONInvalid(t *testing.T) {
+	for _, document := range []string{``, `{"a": 1`, `{"a": 1} {}`, `{"a": }`} {
+		if _, _, err := mergeJSON(nil, []byte(document), []byte(`{}`)); err == nil {
+			t.Errorf("mergeJSON() of %q succeeded", document)
+		}
+	}
+}
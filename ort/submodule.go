@@ -0,0 +1,244 @@
+package ort
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing/filemode"
+	format "github.com/go-git/go-git/v6/plumbing/format/config"
+	"github.com/go-git/go-git/v6/plumbing/format/index"
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+// gitmodulesFile declares the submodules of a repository
+const gitmodulesFile = ".gitmodules"
+
+// isGitlink tells whether change involves a submodule entry, whose hash is a
+// commit of another repository and cannot be read as a blob
+func isGitlink(change *object.Change) bool {
+	return change != nil &&
+		(change.From.TreeEntry.Mode == filemode.Submodule || change.To.TreeEntry.Mode == filemode.Submodule)
+}
+
+// entryFile describes a tree entry without reading it, nil when absent
+func entryFile(entry object.ChangeEntry) *object.File {
+	if entry.Name == "" {
+		return nil
+	}
+	return &object.File{
+		Name: entry.Name,
+		Mode: entry.TreeEntry.Mode,
+		Blob: object.Blob{Hash: entry.TreeEntry.Hash},
+	}
+}
+
+func sameEntry(a, b *object.File) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Hash == b.Hash && a.Mode == b.Mode
+}
+
+// mergeGitlink merges a path recorded as a submodule on at least one side:
+// the recorded commit fast-forwards when a single side changed it, a
+// conflict is returned otherwise. The submodule checkout itself is left alone
+func mergeGitlink(r *git.Repository, w *git.Worktree, filepath string, ours, theirs *object.Change) (*conflictEntry, error) {
+	var base *object.File
+	for _, change := range []*object.Change{ours, theirs} {
+		if change != nil {
+			base = entryFile(change.From)
+			break
+		}
+	}
+
+	ourFile, theirFile := base, base
+	if ours != nil {
+		ourFile = entryFile(ours.To)
+	}
+	if theirs != nil {
+		theirFile = entryFile(theirs.To)
+	}
+
+	switch {
+	// Our entry is already in the index and worktree
+	case sameEntry(ourFile, theirFile), sameEntry(base, theirFile):
+		return nil, nil
+	case sameEntry(base, ourFile):
+		return nil, takeEntry(r, w, filepath, theirFile)
+	default:
+		return &conflictEntry{path: filepath, base: base, ours: ourFile, theirs: theirFile}, nil
+	}
+}
+
+// takeEntry replaces our entry at filepath by theirs, nil deletes it
+func takeEntry(r *git.Repository, w *git.Worktree, filepath string, theirs *object.File) error {
+	switch {
+	case theirs == nil:
+		idx, err := r.Storer.Index()
+		if err != nil {
+			return err
+		}
+		if _, err = idx.Remove(filepath); err != nil && !errors.Is(err, index.ErrEntryNotFound) {
+			return err
+		}
+		if err = r.Storer.SetIndex(idx); err != nil {
+			return err
+		}
+		// Only an empty, uninitialized submodule directory goes away
+		_ = w.Filesystem.Remove(filepath)
+		return nil
+
+	case theirs.Mode == filemode.Submodule:
+		// Like an uninitialized submodule, an empty directory stands in the worktree
+		if err := w.Filesystem.MkdirAll(filepath, 0o755); err != nil {
+			return err
+		}
+
+		idx, err := r.Storer.Index()
+		if err != nil {
+			return err
+		}
+		entry, err := idx.Entry(filepath)
+		if err != nil {
+			entry = idx.Add(filepath)
+		}
+		entry.Hash = theirs.Hash
+		entry.Mode = filemode.Submodule
+		return r.Storer.SetIndex(idx)
+
+	default:
+		blob, err := r.BlobObject(theirs.Hash)
+		if err != nil {
+			return err
+		}
+		if err = w.Filesystem.Remove(filepath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return writeFile(w, filepath, object.NewFile(filepath, theirs.Mode, blob))
+	}
+}
+
+// mergeGitmodules three-way merges .gitmodules submodule by submodule and
+// key by key, ok is false when both sides changed the same key differently
+func mergeGitmodules(base, ours, theirs string) (result []byte, ok bool, err error) {
+	configs := make([]*format.Config, 3)
+	for side, content := range []string{base, ours, theirs} {
+		configs[side] = format.New()
+		if err = format.NewDecoder(strings.NewReader(content)).Decode(configs[side]); err != nil {
+			return
+		}
+	}
+	baseSection := configs[0].Section("submodule")
+	ourSection := configs[1].Section("submodule")
+	theirSection := configs[2].Section("submodule")
+
+	// Keep our order, then their additions
+	var names []string
+	for _, subsection := range slices.Concat(ourSection.Subsections, theirSection.Subsections, baseSection.Subsections) {
+		if !slices.Contains(names, subsection.Name) {
+			names = append(names, subsection.Name)
+		}
+	}
+
+	var merged format.Subsections
+	for _, name := range names {
+		subsection, ok := mergeSubsection(
+			lookupSubsection(baseSection, name),
+			lookupSubsection(ourSection, name),
+			lookupSubsection(theirSection, name),
+		)
+		if !ok {
+			return nil, false, nil
+		}
+		if subsection != nil {
+			merged = append(merged, subsection)
+		}
+	}
+	ourSection.Subsections = merged
+
+	var buffer bytes.Buffer
+	if err = format.NewEncoder(&buffer).Encode(configs[1]); err != nil {
+		return
+	}
+	return buffer.Bytes(), true, nil
+}
+
+func lookupSubsection(section *format.Section, name string) *format.Subsection {
+	if !section.HasSubsection(name) {
+		return nil
+	}
+	return section.Subsection(name)
+}
+
+func sameSubsection(a, b *format.Subsection) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return slices.EqualFunc(a.Options, b.Options, func(x, y *format.Option) bool {
+		return x.Key == y.Key && x.Value == y.Value
+	})
+}
+
+// mergeSubsection merges the declaration of a submodule, nil when deleted
+func mergeSubsection(base, ours, theirs *format.Subsection) (*format.Subsection, bool) {
+	switch {
+	case sameSubsection(ours, theirs), sameSubsection(base, theirs):
+		return ours, true
+	case sameSubsection(base, ours):
+		return theirs, true
+	case ours == nil || theirs == nil:
+		// Deleted on one side, changed on the other
+		return nil, false
+	}
+
+	if base == nil {
+		base = &format.Subsection{}
+	}
+
+	var keys []string
+	for _, option := range slices.Concat(ours.Options, theirs.Options, base.Options) {
+		if !slices.Contains(keys, option.Key) {
+			keys = append(keys, option.Key)
+		}
+	}
+
+	merged := &format.Subsection{Name: ours.Name}
+	for _, key := range keys {
+		baseValue, ourValue, theirValue := base.Option(key), ours.Option(key), theirs.Option(key)
+		value := ourValue
+		switch {
+		case ourValue == theirValue, baseValue == theirValue:
+		case baseValue == ourValue:
+			value = theirValue
+		default:
+			return nil, false
+		}
+		if value != "" {
+			merged.AddOption(key, value)
+		}
+	}
+	return merged, true
+}
+
+// mergeGitmodulesFile merges .gitmodules into the worktree, resolved is false
+// when it needs a line based merge
+func mergeGitmodulesFile(w *git.Worktree, baseFile, ourFile, theirFile *object.File, mode filemode.FileMode) (resolved bool, err error) {
+	sides, err := contents(baseFile, ourFile, theirFile)
+	if err != nil {
+		return
+	}
+
+	result, ok, parseErr := mergeGitmodules(sides[0], sides[1], sides[2])
+	if parseErr != nil || !ok {
+		return
+	}
+
+	if err = writeContent(w, gitmodulesFile, result, mode); err != nil {
+		return
+	}
+	return true, nil
+}
@@ -0,0 +1,88 @@
+package ort
+
+import (
+	"slices"
+
+	"gravel/i18n"
+
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/utils/merkletrie"
+)
+
+// MergeSummary counts what a merge did with the files their side changed
+type MergeSummary struct {
+	// Added, Modified and Deleted are the files taken from their side
+	Added    int
+	Modified int
+	Deleted  int
+	// Merged are the files both sides changed, merged without conflict
+	Merged int
+	// Conflicted are the files left conflicting
+	Conflicted int
+	// ResolvedHunks are the conflicting hunks resolved by -X ours or
+	// theirs and the union strategy
+	ResolvedHunks int
+}
+
+// String is the compact summary printed to MergeOptions.Progress
+func (summary MergeSummary) String() string {
+	return i18n.Tf("%d added, %d modified, %d deleted, %d merged, %d conflicted, %d hunks auto-resolved",
+		summary.Added, summary.Modified, summary.Deleted, summary.Merged, summary.Conflicted, summary.ResolvedHunks)
+}
+
+// summarize counts the changes of their side to the paths of changes
+func summarize(changes map[string]changePair, conflicts []conflictEntry, resolvedHunks int) (MergeSummary, error) {
+	summary := MergeSummary{ResolvedHunks: resolvedHunks}
+	conflicted := conflictPaths(conflicts)
+	for basePath, pair := range changes {
+		if pair.theirs == nil {
+			continue
+		}
+		switch {
+		case slices.Contains(conflicted, basePath) ||
+			slices.Contains(conflicted, pair.theirs.To.Name) ||
+			pair.ours != nil && slices.Contains(conflicted, pair.ours.To.Name):
+			summary.Conflicted++
+			continue
+		case pair.ours != nil:
+			summary.Merged++
+			continue
+		}
+
+		action, err := pair.theirs.Action()
+		if err != nil {
+			return summary, err
+		}
+		summary.count(action)
+	}
+	return summary, nil
+}
+
+// summarizePatch counts the files patch adds, modifies and deletes, for
+// the merges taking whole commits such as fast-forwards
+func summarizePatch(patch *object.Patch) MergeSummary {
+	var summary MergeSummary
+	for _, file := range patch.FilePatches() {
+		from, to := file.Files()
+		switch {
+		case from == nil:
+			summary.count(merkletrie.Insert)
+		case to == nil:
+			summary.count(merkletrie.Delete)
+		default:
+			summary.count(merkletrie.Modify)
+		}
+	}
+	return summary
+}
+
+func (summary *MergeSummary) count(action merkletrie.Action) {
+	switch action {
+	case merkletrie.Insert:
+		summary.Added++
+	case merkletrie.Delete:
+		summary.Deleted++
+	case merkletrie.Modify:
+		summary.Modified++
+	}
+}
@@ -0,0 +1,60 @@
+package ort
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+// ErrNotCommit is returned when a merged ref, through its annotated tags,
+// points at an object other than a commit
+var ErrNotCommit = errors.New("the reference does not point to a commit")
+
+// Peel resolves ref to the commit it points at, following symbolic
+// references and annotated tags like git does with the refs it merges. The
+// name is kept, so that labels and messages name the tag
+func Peel(r *git.Repository, ref plumbing.Reference) (plumbing.Reference, error) {
+	if ref.Type() == plumbing.SymbolicReference {
+		resolved, err := r.Reference(ref.Name(), true)
+		if err != nil {
+			return ref, err
+		}
+		ref = *resolved
+	}
+
+	hash := ref.Hash()
+	for {
+		obj, err := r.Storer.EncodedObject(plumbing.AnyObject, hash)
+		if err != nil {
+			return ref, err
+		}
+
+		switch obj.Type() {
+		case plumbing.CommitObject:
+			return *plumbing.NewHashReference(ref.Name(), hash), nil
+		case plumbing.TagObject:
+			tag, err := object.DecodeTag(r.Storer, obj)
+			if err != nil {
+				return ref, err
+			}
+			hash = tag.Target
+		default:
+			return ref, fmt.Errorf("%w: %s is a %s", ErrNotCommit, ref.Name().Short(), obj.Type())
+		}
+	}
+}
+
+// peelAll peels each of refs
+func peelAll(r *git.Repository, refs []plumbing.Reference) ([]plumbing.Reference, error) {
+	peeled := make([]plumbing.Reference, len(refs))
+	for index, ref := range refs {
+		var err error
+		if peeled[index], err = Peel(r, ref); err != nil {
+			return nil, err
+		}
+	}
+	return peeled, nil
+}
@@ -0,0 +1,377 @@
+package ort
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"io"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+
+	"gravel/i18n"
+	"gravel/ort/diff3"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/filemode"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/plumbing/storer"
+	"github.com/go-git/go-git/v6/utils/binary"
+)
+
+// TreeConflict is a path MergeTrees could not merge cleanly
+type TreeConflict struct {
+	Path string
+	// Base, Ours and Theirs are the entries of the sides, nil when the path
+	// does not exist there
+	Base, Ours, Theirs *object.TreeEntry
+	// Reason describes the conflict, like git's CONFLICT lines
+	Reason string
+}
+
+// TreeResult is the outcome of MergeTrees
+type TreeResult struct {
+	// Tree is the merged tree, stored with conflict markers in the
+	// conflicting files and our side of the other conflicts
+	Tree plumbing.Hash
+	// Conflicts are sorted by path
+	Conflicts []TreeConflict
+}
+
+// MergeTrees three-way merges trees from object storage alone, for bare
+// repositories and servers. A nil base merges unrelated trees. Of opts,
+// only Labels, ConflictStyle, MarkerSize, Whitespace, OrtMergeStrategyOption,
+// Union, Exclude, PathSpecs, LargeFileThreshold and Events or Progress
+// apply, renames are not detected
+func MergeTrees(s storer.EncodedObjectStorer, base, ours, theirs *object.Tree, opts MergeOptions) (*TreeResult, error) {
+	labels := Labels{
+		Ours:   cmp.Or(opts.Labels.Ours, "ours"),
+		Base:   cmp.Or(opts.Labels.Base, "base"),
+		Theirs: cmp.Or(opts.Labels.Theirs, "theirs"),
+	}
+
+	sides := make([]map[string]*object.TreeEntry, 3)
+	for index, tree := range []*object.Tree{base, ours, theirs} {
+		entries, err := flattenTree(tree)
+		if err != nil {
+			return nil, err
+		}
+		sides[index] = entries
+	}
+
+	// Their paths outside of the merge are left as in base, so that ours are kept
+	for filepath := range sides[2] {
+		if opts.outside(filepath) {
+			delete(sides[2], filepath)
+		}
+	}
+	for filepath, entry := range sides[0] {
+		if opts.outside(filepath) {
+			sides[2][filepath] = entry
+		}
+	}
+
+	paths := make(map[string]struct{})
+	for _, entries := range sides {
+		for filepath := range entries {
+			paths[filepath] = struct{}{}
+		}
+	}
+
+	events := opts.events()
+	result := &TreeResult{}
+	merged := make(map[string]object.TreeEntry)
+	for filepath := range paths {
+		baseEntry, ourEntry, theirEntry := sides[0][filepath], sides[1][filepath], sides[2][filepath]
+
+		var entry *object.TreeEntry
+		switch {
+		case sameTreeEntry(ourEntry, theirEntry), sameTreeEntry(theirEntry, baseEntry):
+			entry = ourEntry
+		case sameTreeEntry(ourEntry, baseEntry):
+			entry = theirEntry
+		default:
+			events.FileStarted(filepath)
+			var reason string
+			var err error
+			entry, reason, err = mergeTreeEntries(s, filepath, baseEntry, ourEntry, theirEntry, labels, opts)
+			if err != nil {
+				return nil, err
+			}
+			if reason == "" {
+				events.FileMerged(filepath)
+				break
+			}
+			events.ConflictDetected(filepath, reason)
+			result.Conflicts = append(result.Conflicts, TreeConflict{
+				Path:   filepath,
+				Base:   baseEntry,
+				Ours:   ourEntry,
+				Theirs: theirEntry,
+				Reason: reason,
+			})
+		}
+
+		if entry != nil {
+			merged[filepath] = *entry
+		}
+	}
+
+	for _, conflict := range directoryConflicts(merged, sides, labels) {
+		events.ConflictDetected(conflict.Path, conflict.Reason)
+		result.Conflicts = append(result.Conflicts, conflict)
+	}
+	slices.SortFunc(result.Conflicts, func(a, b TreeConflict) int { return strings.Compare(a.Path, b.Path) })
+
+	hash, err := writeTree(s, merged, "")
+	if err != nil {
+		return nil, err
+	}
+	result.Tree = hash
+	return result, nil
+}
+
+// flattenTree maps the paths of the files, symlinks and gitlinks of tree to
+// their entries, a nil tree is empty
+func flattenTree(tree *object.Tree) (map[string]*object.TreeEntry, error) {
+	entries := make(map[string]*object.TreeEntry)
+	if tree == nil {
+		return entries, nil
+	}
+
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+	for {
+		name, entry, err := walker.Next()
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if entry.Mode == filemode.Dir {
+			continue
+		}
+		entries[name] = &entry
+	}
+}
+
+// sameTreeEntry compares entries by content and mode, nil for a missing path
+func sameTreeEntry(a, b *object.TreeEntry) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Hash == b.Hash && a.Mode == b.Mode
+}
+
+// isBlobMode tells whether entries of mode have a line mergeable content
+func isBlobMode(mode filemode.FileMode) bool {
+	return mode == filemode.Regular || mode == filemode.Executable || mode == filemode.Deprecated
+}
+
+// mergeTreeEntries merges a path both sides changed, reason is empty when
+// the merge is clean
+func mergeTreeEntries(s storer.EncodedObjectStorer, filepath string, baseEntry, ourEntry, theirEntry *object.TreeEntry, labels Labels, opts MergeOptions) (entry *object.TreeEntry, reason string, err error) {
+	switch {
+	case ourEntry == nil:
+		return theirEntry, i18n.Tf("CONFLICT (modify/delete): %s deleted in %s and modified in %s", filepath, labels.Ours, labels.Theirs), nil
+	case theirEntry == nil:
+		return ourEntry, i18n.Tf("CONFLICT (modify/delete): %s deleted in %s and modified in %s", filepath, labels.Theirs, labels.Ours), nil
+	case !isBlobMode(ourEntry.Mode) || !isBlobMode(theirEntry.Mode):
+		return ourEntry, i18n.Tf("CONFLICT (content): merge conflict in %s", filepath), nil
+	}
+
+	baseMode := filemode.Empty
+	if baseEntry != nil {
+		baseMode = baseEntry.Mode
+	}
+	mode, modeMerged := mergeMode(baseMode, ourEntry.Mode, theirEntry.Mode)
+
+	if ourEntry.Hash == theirEntry.Hash {
+		entry = &object.TreeEntry{Name: ourEntry.Name, Mode: mode, Hash: ourEntry.Hash}
+		if !modeMerged {
+			reason = i18n.Tf("CONFLICT (mode): %s changed mode on both sides", filepath)
+		}
+		return entry, reason, nil
+	}
+
+	threshold := opts.largeFileThreshold()
+	contents := make([][]byte, 3)
+	for index, side := range []*object.TreeEntry{baseEntry, ourEntry, theirEntry} {
+		if side == nil || !isBlobMode(side.Mode) {
+			continue
+		}
+
+		var size int64
+		if size, err = s.EncodedObjectSize(side.Hash); err != nil {
+			return nil, "", err
+		}
+		if size > threshold {
+			return ourEntry, i18n.Tf("CONFLICT (large file): %s is larger than %d bytes, cannot merge it (%s vs. %s)",
+				filepath, threshold, labels.Ours, labels.Theirs), nil
+		}
+
+		if contents[index], err = readBlob(s, side.Hash); err != nil {
+			return nil, "", err
+		}
+		if isBinary, _ := binary.IsBinary(bytes.NewReader(contents[index])); isBinary {
+			return ourEntry, i18n.Tf("CONFLICT (binary): merge conflict in %s", filepath), nil
+		}
+	}
+
+	favor := optionFavor(opts.OrtMergeStrategyOption)
+	if opts.Union {
+		favor = diff3.FavorUnion
+	}
+	// Without a worktree, the endings of ours are kept
+	ending := lineEndings{}.of(filepath, contents[1])
+	merged, err := diff3.MergeWithOptions(
+		bytes.NewReader(toLF(contents[1])),
+		bytes.NewReader(toLF(contents[0])),
+		bytes.NewReader(toLF(contents[2])),
+		diff3.Options{
+			Detailed:   true,
+			LabelA:     labels.Ours,
+			LabelO:     labels.Base,
+			LabelB:     labels.Theirs,
+			Favor:      favor,
+			Style:      opts.ConflictStyle,
+			Whitespace: opts.Whitespace,
+			MarkerSize: opts.MarkerSize,
+		},
+	)
+	if err != nil {
+		return nil, "", err
+	}
+
+	content, err := io.ReadAll(merged.Result)
+	if err != nil {
+		return nil, "", err
+	}
+	hash, err := writeBlob(s, withEnding(content, ending))
+	if err != nil {
+		return nil, "", err
+	}
+
+	entry = &object.TreeEntry{Name: ourEntry.Name, Mode: mode, Hash: hash}
+	switch {
+	case merged.Conflicts:
+		reason = i18n.Tf("CONFLICT (content): merge conflict in %s", filepath)
+	case !modeMerged:
+		reason = i18n.Tf("CONFLICT (mode): %s changed mode on both sides", filepath)
+	}
+	return entry, reason, nil
+}
+
+// directoryConflicts moves the files of merged that are directories of
+// other paths to <path>~<label>, like git does on file/directory conflicts
+func directoryConflicts(merged map[string]object.TreeEntry, sides []map[string]*object.TreeEntry, labels Labels) []TreeConflict {
+	directories := make(map[string]struct{})
+	for filepath := range merged {
+		for dir := path.Dir(filepath); dir != "."; dir = path.Dir(dir) {
+			directories[dir] = struct{}{}
+		}
+	}
+
+	var conflicts []TreeConflict
+	for _, filepath := range slices.Sorted(maps.Keys(merged)) {
+		if _, collides := directories[filepath]; !collides {
+			continue
+		}
+
+		// The file comes from the side holding it, ours when both do
+		label := labels.Ours
+		if sides[1][filepath] == nil {
+			label = labels.Theirs
+		}
+		moved := filepath + "~" + label
+		entry := merged[filepath]
+		delete(merged, filepath)
+		merged[moved] = entry
+
+		conflicts = append(conflicts, TreeConflict{
+			Path:   filepath,
+			Base:   sides[0][filepath],
+			Ours:   sides[1][filepath],
+			Theirs: sides[2][filepath],
+			Reason: i18n.Tf("CONFLICT (file/directory): directory in the way of %s, adding it as %s instead", filepath, moved),
+		})
+	}
+	return conflicts
+}
+
+// writeTree stores the tree of the entries under dir, recursively
+func writeTree(s storer.EncodedObjectStorer, entries map[string]object.TreeEntry, dir string) (plumbing.Hash, error) {
+	prefix := ""
+	if dir != "" {
+		prefix = dir + "/"
+	}
+
+	tree := &object.Tree{}
+	subtrees := make(map[string]struct{})
+	for filepath, entry := range entries {
+		rest, ok := strings.CutPrefix(filepath, prefix)
+		if !ok {
+			continue
+		}
+		if name, _, nested := strings.Cut(rest, "/"); nested {
+			subtrees[name] = struct{}{}
+			continue
+		}
+		entry.Name = rest
+		tree.Entries = append(tree.Entries, entry)
+	}
+
+	for name := range subtrees {
+		hash, err := writeTree(s, entries, prefix+name)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		tree.Entries = append(tree.Entries, object.TreeEntry{Name: name, Mode: filemode.Dir, Hash: hash})
+	}
+
+	// Git orders directories as if their name ended with a slash
+	sortKey := func(entry object.TreeEntry) string {
+		if entry.Mode == filemode.Dir {
+			return entry.Name + "/"
+		}
+		return entry.Name
+	}
+	slices.SortFunc(tree.Entries, func(a, b object.TreeEntry) int { return strings.Compare(sortKey(a), sortKey(b)) })
+
+	encoded := s.NewEncodedObject()
+	if err := tree.Encode(encoded); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return s.SetEncodedObject(encoded)
+}
+
+func readBlob(s storer.EncodedObjectStorer, hash plumbing.Hash) ([]byte, error) {
+	blob, err := object.GetBlob(s, hash)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := blob.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = reader.Close() }()
+	return io.ReadAll(reader)
+}
+
+func writeBlob(s storer.EncodedObjectStorer, content []byte) (plumbing.Hash, error) {
+	encoded := s.NewEncodedObject()
+	encoded.SetType(plumbing.BlobObject)
+	writer, err := encoded.Writer()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if _, err = writer.Write(content); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if err = writer.Close(); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return s.SetEncodedObject(encoded)
+}
@@ -0,0 +1,113 @@
+package ort
+
+import (
+	"maps"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+// commitTree returns the tree of the commit hash
+func commitTree(t *testing.T, r *git.Repository, hash plumbing.Hash) *object.Tree {
+	t.Helper()
+	commit, err := r.CommitObject(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree
+}
+
+// treeFiles returns the files of the tree hash with their content
+func treeFiles(t *testing.T, r *git.Repository, hash plumbing.Hash) map[string]string {
+	t.Helper()
+	tree, err := r.TreeObject(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents := make(map[string]string)
+	err = tree.Files().ForEach(func(file *object.File) error {
+		content, err := file.Contents()
+		contents[file.Name] = content
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return contents
+}
+
+func TestMergeTrees(t *testing.T) {
+	r := newTestRepository(t)
+	base := commitFiles(t, r, "base", map[string]string{"README": "one\nmid\ntwo\n", "port": "80\n", "removed": "removed\n"})
+	ours := commitFiles(t, r, "ours", map[string]string{"README": "ONE\nmid\ntwo\n", "port": "8080\n", "removed": "removed\n"}, base)
+	theirs := commitFiles(t, r, "theirs", map[string]string{"README": "one\nmid\nTWO\n", "port": "3000\n", "added": "added\n"}, base)
+
+	result, err := MergeTrees(r.Storer, commitTree(t, r, base), commitTree(t, r, ours), commitTree(t, r, theirs), MergeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Conflicts) != 1 || result.Conflicts[0].Path != "port" {
+		t.Fatalf("conflicts = %+v, want port", result.Conflicts)
+	}
+	files := treeFiles(t, r, result.Tree)
+	port := files["port"]
+	if !strings.Contains(port, "<<<<<<< ours\n8080\n") || !strings.Contains(port, "3000\n>>>>>>> theirs\n") {
+		t.Errorf("port = %q, want conflict markers", port)
+	}
+	delete(files, "port")
+	want := map[string]string{"README": "ONE\nmid\nTWO\n", "added": "added\n"}
+	if !maps.Equal(files, want) {
+		t.Errorf("merged files = %v, want %v", files, want)
+	}
+}
+
+func TestMergeTreesUnrelated(t *testing.T) {
+	r := newTestRepository(t)
+	ours := commitFiles(t, r, "ours", map[string]string{"README": "ours\n"})
+	theirs := commitFiles(t, r, "theirs", map[string]string{"LICENSE": "theirs\n"})
+
+	result, err := MergeTrees(r.Storer, nil, commitTree(t, r, ours), commitTree(t, r, theirs), MergeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"README": "ours\n", "LICENSE": "theirs\n"}
+	if got := treeFiles(t, r, result.Tree); len(result.Conflicts) > 0 || !maps.Equal(got, want) {
+		t.Fatalf("merged files = %v, conflicts %+v, want %v", got, result.Conflicts, want)
+	}
+}
+
+func TestMergeTreesStrategyOption(t *testing.T) {
+	r := newTestRepository(t)
+	base := commitFiles(t, r, "base", map[string]string{"port": "80\n"})
+	ours := commitFiles(t, r, "ours", map[string]string{"port": "8080\n"}, base)
+	theirs := commitFiles(t, r, "theirs", map[string]string{"port": "3000\n"}, base)
+
+	for _, test := range []struct {
+		name   string
+		option StrategyOption
+		want   string
+	}{
+		{name: "unset", want: "<<<<<<< ours\n8080\n=======\n3000\n>>>>>>> theirs\n"},
+		{name: "ours", option: OursMergeStrategy, want: "8080\n"},
+		{name: "theirs", option: TheirsMergeStrategy, want: "3000\n"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			opts := MergeOptions{OrtMergeStrategyOption: test.option}
+			result, err := MergeTrees(r.Storer, commitTree(t, r, base), commitTree(t, r, ours), commitTree(t, r, theirs), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := treeFiles(t, r, result.Tree)["port"]; got != test.want {
+				t.Errorf("port = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package ort
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gravel/i18n"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+// ErrUnbornOptions is returned when a merge into an unborn branch is asked
+// to squash, not to commit or not to fast-forward, which git refuses too
+var ErrUnbornOptions = errors.New("a merge into a branch without commits can only fast-forward")
+
+// unbornBranch returns the branch HEAD points to when it has no commit
+// yet, like after `git init`, and an empty name otherwise
+func unbornBranch(r *git.Repository) (plumbing.ReferenceName, error) {
+	head, err := r.Storer.Reference(plumbing.HEAD)
+	if err != nil || head.Type() != plumbing.SymbolicReference {
+		return "", err
+	}
+
+	_, err = r.Storer.Reference(head.Target())
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return head.Target(), nil
+	}
+	return "", err
+}
+
+// mergeUnborn merges ref into the unborn branch of HEAD as if HEAD were the
+// empty tree: the branch is created at their commit, which is checked out.
+// Exclude and PathSpecs do not apply, there is no side of ours to keep
+func mergeUnborn(ctx context.Context, r *git.Repository, branch plumbing.ReferenceName, ref plumbing.Reference, opts MergeOptions, result *MergeResult) (err error) {
+	if opts.Squash || opts.NoCommit || opts.NoFastForward {
+		return ErrUnbornOptions
+	}
+
+	theirCommit, err := r.CommitObject(ref.Hash())
+	if err != nil {
+		return err
+	}
+	if err = checkSecrets(nil, theirCommit, opts); err != nil {
+		return err
+	}
+
+	theirTree, err := theirCommit.Tree()
+	if err != nil {
+		return err
+	}
+	changes, err := object.DiffTreeWithOptions(ctx, nil, theirTree, nil)
+	if errors.Is(err, object.ErrCanceled) {
+		return ctx.Err()
+	}
+	if err != nil {
+		return err
+	}
+	patch, err := changes.PatchContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Bare repositories only need the branch
+	w, err := r.Worktree()
+	if errors.Is(err, git.ErrIsBareRepository) {
+		if err = r.Storer.SetReference(plumbing.NewHashReference(branch, ref.Hash())); err != nil {
+			return err
+		}
+		unbornMerged(ref, patch, opts, result)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// The files of the directory are untracked, their side must not overwrite them
+	if err = checkOverwrite(w, patchPaths(patch)); err != nil {
+		return err
+	}
+
+	lock, err := lockIndex(r)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if releaseErr := lock.release(); err == nil {
+			err = releaseErr
+		}
+	}()
+	r = lock.r
+	if w, err = r.Worktree(); err != nil {
+		return err
+	}
+
+	// Reset moves a branch that exists, it is created first and dropped
+	// again when the checkout fails
+	if err = r.Storer.SetReference(plumbing.NewHashReference(branch, ref.Hash())); err != nil {
+		return err
+	}
+	err = w.Reset(&git.ResetOptions{Commit: ref.Hash(), Mode: git.MergeReset})
+	if err == nil {
+		err = lock.commit()
+	}
+	if err != nil {
+		_ = r.Storer.RemoveReference(branch)
+		return err
+	}
+	unbornMerged(ref, patch, opts, result)
+	newMergeHooks(r, opts).merged(ctx, false)
+	return nil
+}
+
+// unbornMerged reports the checkout of ref into an unborn branch
+func unbornMerged(ref plumbing.Reference, patch *object.Patch, opts MergeOptions, result *MergeResult) {
+	result.FastForward = true
+	result.Commit = ref.Hash()
+	result.Stats = patch.Stats()
+	result.Summary = summarizePatch(patch)
+
+	if opts.Progress != nil {
+		_, _ = fmt.Fprintf(opts.Progress, "%s\n%s%s\n", i18n.T("Fast-forward"), result.Stats, result.Summary)
+	}
+	opts.events().CommitCreated(ref.Hash())
+}
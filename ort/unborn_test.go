@@ -0,0 +1,44 @@
+package ort
+
+import (
+	"errors"
+	"maps"
+	"testing"
+
+	"github.com/go-git/go-git/v6/plumbing"
+)
+
+func TestMergeUnborn(t *testing.T) {
+	r := newTestRepository(t)
+	theirs := commitFiles(t, r, "theirs", map[string]string{"README": "theirs\n"})
+	writeWorktree(t, r, "notes", "mine\n")
+
+	if _, err := Merge(r, branchRef("theirs", theirs), MergeOptions{Squash: true}); !errors.Is(err, ErrUnbornOptions) {
+		t.Fatalf("squashed Merge() = %v, want %v", err, ErrUnbornOptions)
+	}
+
+	result, err := Merge(r, branchRef("theirs", theirs), MergeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.FastForward || result.Commit != theirs {
+		t.Fatalf("result = %+v, want a fast-forward to %s", result, theirs)
+	}
+
+	branch, err := r.Reference(plumbing.NewBranchReferenceName("master"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if branch.Hash() != theirs {
+		t.Fatalf("master = %s, want %s", branch.Hash(), theirs)
+	}
+	if got := headFiles(t, r); !maps.Equal(got, map[string]string{"README": "theirs\n"}) {
+		t.Errorf("HEAD files = %v", got)
+	}
+	if got := readWorktree(t, r, "README"); got != "theirs\n" {
+		t.Errorf("README = %q, want it checked out", got)
+	}
+	if got := readWorktree(t, r, "notes"); got != "mine\n" {
+		t.Errorf("untracked notes = %q, want it kept", got)
+	}
+}
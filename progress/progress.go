@@ -0,0 +1,105 @@
+package progress
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Event is a progress message attached to a node of the operation tree,
+// e.g. plugin:auth → fetch
+type Event struct {
+	Scope   []string `json:"scope"`
+	Message string   `json:"message"`
+	// Transient events are superseded by the next one, like object counters
+	Transient bool      `json:"transient,omitempty"`
+	Time      time.Time `json:"time"`
+	// Kind classifies structured events, like a merged file or a conflict,
+	// empty for plain messages
+	Kind string `json:"kind,omitempty"`
+	// Path is the file a structured event is about
+	Path string `json:"path,omitempty"`
+}
+
+// Key identifies the scope of the event
+func (event Event) Key() string {
+	return strings.Join(event.Scope, "/")
+}
+
+// Sink receives the events of every scope
+type Sink interface {
+	Emit(Event)
+}
+
+// Reporter emits events under a scope, a nil Reporter discards everything
+type Reporter struct {
+	sink  Sink
+	scope []string
+}
+
+// New returns the root reporter of sink
+func New(sink Sink) *Reporter {
+	return &Reporter{sink: sink}
+}
+
+// Scope returns a reporter nested under key
+func (reporter *Reporter) Scope(key string) *Reporter {
+	if reporter == nil {
+		return nil
+	}
+	return &Reporter{sink: reporter.sink, scope: append(slices.Clip(reporter.scope), key)}
+}
+
+// Printf emits a message in the scope of the reporter
+func (reporter *Reporter) Printf(format string, args ...any) {
+	reporter.emit(Event{Message: fmt.Sprintf(format, args...)})
+}
+
+// Report emits a structured event of kind about path, message is what the
+// text output prints
+func (reporter *Reporter) Report(kind, path, message string, transient bool) {
+	reporter.emit(Event{Kind: kind, Path: path, Message: message, Transient: transient})
+}
+
+// emit stamps event with the scope of the reporter and the time
+func (reporter *Reporter) emit(event Event) {
+	if reporter == nil {
+		return
+	}
+	event.Scope = reporter.scope
+	event.Time = time.Now()
+	reporter.sink.Emit(event)
+}
+
+// Writer adapts line oriented output, like go-git sideband progress, into
+// events: lines ending with a carriage return are transient
+func (reporter *Reporter) Writer() io.Writer {
+	if reporter == nil {
+		return io.Discard
+	}
+	return &lineWriter{reporter: reporter}
+}
+
+type lineWriter struct {
+	reporter *Reporter
+	buffer   []byte
+}
+
+func (writer *lineWriter) Write(p []byte) (int, error) {
+	writer.buffer = append(writer.buffer, p...)
+	for {
+		end := bytes.IndexAny(writer.buffer, "\r\n")
+		if end < 0 {
+			return len(p), nil
+		}
+
+		line := strings.TrimSpace(string(writer.buffer[:end]))
+		if line != "" {
+			writer.reporter.emit(Event{Message: line, Transient: writer.buffer[end] == '\r'})
+		}
+		writer.buffer = writer.buffer[end+1:]
+	}
+}
@@ -0,0 +1,78 @@
+package progress
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReporter(t *testing.T) {
+	recorder := new(Recorder)
+	root := New(recorder)
+	plugin := root.Scope("plugin:auth")
+
+	plugin.Scope("fetch").Printf("fetched %d objects", 3)
+	plugin.Scope("merge").Report("conflict", "go.mod", "conflict in go.mod", false)
+	root.Printf("done")
+
+	var got []string
+	for _, event := range recorder.Events() {
+		got = append(got, fmt.Sprintf("%s %s %s", event.Key(), event.Kind, event.Message))
+	}
+	want := []string{
+		"plugin:auth/fetch  fetched 3 objects",
+		"plugin:auth/merge conflict conflict in go.mod",
+		"  done",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("events = %q, want %q", got, want)
+	}
+}
+
+func TestNilReporter(t *testing.T) {
+	var reporter *Reporter
+	reporter.Scope("fetch").Printf("discarded")
+	if _, err := fmt.Fprintln(reporter.Writer(), "discarded"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	recorder := new(Recorder)
+	var transient []string
+	sink := Tee(recorder, sinkFunc(func(event Event) {
+		if event.Transient {
+			transient = append(transient, event.Message)
+		}
+	}), nil)
+
+	writer := New(sink).Scope("fetch").Writer()
+	_, _ = fmt.Fprint(writer, "Counting objects: 1\rCounting obj")
+	_, _ = fmt.Fprint(writer, "ects: 2\r\nDone\n")
+
+	if want := []string{"Counting objects: 1", "Counting objects: 2"}; !reflect.DeepEqual(transient, want) {
+		t.Errorf("transient lines = %q, want %q", transient, want)
+	}
+	if events := recorder.Events(); len(events) != 1 || events[0].Message != "Done" {
+		t.Errorf("recorded events = %+v, want only Done", events)
+	}
+}
+
+func TestTextSink(t *testing.T) {
+	var out strings.Builder
+	reporter := New(NewTextSink(&out)).Scope("plugin:auth")
+
+	reporter.Scope("fetch").Report("", "", "1%", true)
+	reporter.Scope("fetch").Report("", "", "100%", true)
+	reporter.Scope("merge").Printf("merged")
+
+	want := "plugin:auth\n  fetch\n    1%\r    100%\n  merge\n    merged\n"
+	if out.String() != want {
+		t.Fatalf("text = %q, want %q", out.String(), want)
+	}
+}
+
+type sinkFunc func(Event)
+
+func (emit sinkFunc) Emit(event Event) { emit(event) }
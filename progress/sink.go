@@ -0,0 +1,115 @@
+package progress
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// TextSink renders events as an indented tree, printing each scope once
+type TextSink struct {
+	mu      sync.Mutex
+	out     io.Writer
+	scope   []string
+	pending bool
+}
+
+// NewTextSink returns a sink writing the tree to out
+func NewTextSink(out io.Writer) *TextSink {
+	return &TextSink{out: out}
+}
+
+func (sink *TextSink) Emit(event Event) {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	common := 0
+	for common < len(sink.scope) && common < len(event.Scope) && sink.scope[common] == event.Scope[common] {
+		common++
+	}
+
+	// A transient line is rewritten in place by the next line of its scope
+	sameScope := common == len(event.Scope) && common == len(sink.scope)
+	if sink.pending {
+		if sameScope {
+			_, _ = fmt.Fprint(sink.out, "\r")
+		} else {
+			_, _ = fmt.Fprintln(sink.out)
+		}
+		sink.pending = false
+	}
+
+	for depth := common; depth < len(event.Scope); depth++ {
+		_, _ = fmt.Fprintf(sink.out, "%s%s\n", strings.Repeat("  ", depth), event.Scope[depth])
+	}
+	sink.scope = event.Scope
+
+	line := strings.Repeat("  ", len(event.Scope)) + event.Message
+	if event.Transient {
+		_, _ = fmt.Fprint(sink.out, line)
+		sink.pending = true
+		return
+	}
+	_, _ = fmt.Fprintln(sink.out, line)
+}
+
+// JSONSink writes one JSON object per event so consumers can correlate them by scope
+type JSONSink struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+// NewJSONSink returns a sink writing JSON lines to out
+func NewJSONSink(out io.Writer) *JSONSink {
+	return &JSONSink{encoder: json.NewEncoder(out)}
+}
+
+func (sink *JSONSink) Emit(event Event) {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	_ = sink.encoder.Encode(event)
+}
+
+// Recorder keeps the events in memory, transient ones excepted, to write
+// them out later, e.g. into a log once an operation failed
+type Recorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (recorder *Recorder) Emit(event Event) {
+	if event.Transient {
+		return
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	recorder.events = append(recorder.events, event)
+}
+
+// Events returns the recorded events in emission order
+func (recorder *Recorder) Events() []Event {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	return slices.Clone(recorder.events)
+}
+
+// tee forwards events to several sinks
+type tee []Sink
+
+func (sinks tee) Emit(event Event) {
+	for _, sink := range sinks {
+		sink.Emit(event)
+	}
+}
+
+// Tee returns a sink forwarding events to every non nil sink
+func Tee(sinks ...Sink) Sink {
+	return tee(slices.DeleteFunc(sinks, func(sink Sink) bool { return sink == nil }))
+}
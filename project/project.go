@@ -0,0 +1,94 @@
+package project
+
+import (
+	"errors"
+	"slices"
+
+	"gravel/state"
+
+	"gopkg.in/yaml.v3"
+)
+
+// File is the project state of generated apps, under the state directory
+const File = state.Project
+
+// ErrNoProject is returned by Load when the app has no project state, like
+// the apps created before it was recorded
+var ErrNoProject = errors.New(File + " not found")
+
+// Component is the base or a plugin with the commits that merged it
+type Component struct {
+	Name string `yaml:"name"`
+	// Merges are the commits HEAD moved to when the component was merged,
+	// oldest first: merge commits, or the commit fast-forwarded to
+	Merges []string `yaml:"merges,omitempty"`
+}
+
+// Project is what init, add, remove and update know of the app, read by
+// the commands instead of guessing from the remotes and the history
+type Project struct {
+	Base    Component   `yaml:"base"`
+	Plugins []Component `yaml:"plugins,omitempty"`
+	// Variables are the values the templates were rendered with
+	Variables map[string]string `yaml:"variables,omitempty"`
+}
+
+// Load reads the project state of the app
+func Load(store state.Store) (*Project, error) {
+	content, err := store.Read(state.Project)
+	if errors.Is(err, state.ErrNotExist) {
+		return nil, ErrNoProject
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	project := new(Project)
+	if err = yaml.Unmarshal(content, project); err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+// Save writes the project state of the app
+func (project *Project) Save(store state.Store) error {
+	content, err := yaml.Marshal(project)
+	if err != nil {
+		return err
+	}
+	return store.Write(state.Project, content)
+}
+
+// Component returns the base or the plugin named name, nil when unknown
+func (project *Project) Component(name string) *Component {
+	if project.Base.Name == name {
+		return &project.Base
+	}
+	for index := range project.Plugins {
+		if project.Plugins[index].Name == name {
+			return &project.Plugins[index]
+		}
+	}
+	return nil
+}
+
+// Merged records commit as a merge of the component named name, a plugin
+// unknown so far is added. A zero or repeated commit only records the plugin
+func (project *Project) Merged(name, commit string) {
+	component := project.Component(name)
+	if component == nil {
+		project.Plugins = append(project.Plugins, Component{Name: name})
+		component = &project.Plugins[len(project.Plugins)-1]
+	}
+	if commit == "" || slices.Contains(component.Merges, commit) {
+		return
+	}
+	component.Merges = append(component.Merges, commit)
+}
+
+// Remove forgets the plugin named name
+func (project *Project) Remove(name string) {
+	project.Plugins = slices.DeleteFunc(project.Plugins, func(component Component) bool {
+		return component.Name == name
+	})
+}
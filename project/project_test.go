@@ -0,0 +1,59 @@
+package project
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"gravel/state"
+
+	"github.com/go-git/go-billy/v6/memfs"
+)
+
+func TestMerged(t *testing.T) {
+	project := &Project{Base: Component{Name: "web"}}
+
+	project.Merged("web", "1111111")
+	project.Merged("auth", "2222222")
+	project.Merged("auth", "2222222")
+	project.Merged("auth", "3333333")
+	project.Merged("db", "")
+
+	want := &Project{
+		Base: Component{Name: "web", Merges: []string{"1111111"}},
+		Plugins: []Component{
+			{Name: "auth", Merges: []string{"2222222", "3333333"}},
+			{Name: "db"},
+		},
+	}
+	if !reflect.DeepEqual(project, want) {
+		t.Fatalf("project = %+v, want %+v", project, want)
+	}
+
+	project.Remove("auth")
+	if project.Component("auth") != nil || project.Component("db") == nil {
+		t.Fatalf("plugins after Remove() = %+v", project.Plugins)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	store := state.NewWorktree(memfs.New())
+	if _, err := Load(store); !errors.Is(err, ErrNoProject) {
+		t.Fatalf("Load() without a project = %v, want %v", err, ErrNoProject)
+	}
+
+	project := &Project{
+		Base:      Component{Name: "web", Merges: []string{"1111111"}},
+		Variables: map[string]string{"base.web.port": "8080"},
+	}
+	if err := project.Save(store); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, project) {
+		t.Fatalf("Load() = %+v, want %+v", loaded, project)
+	}
+}
@@ -0,0 +1,206 @@
+package render
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"io"
+	"os"
+
+	"gravel/state"
+
+	"github.com/go-git/go-billy/v6"
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+	format "github.com/go-git/go-git/v6/plumbing/format/config"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"gopkg.in/yaml.v3"
+)
+
+// OwnershipFile records, inside the worktree, which files are rendered from
+// templates when the state is kept in the worktree
+const OwnershipFile = state.Dir + "/" + state.Ownership
+
+// Owner describes the template a file is rendered from
+type Owner struct {
+	// Template is the hash of the blob holding the unrendered content
+	Template string `yaml:"template"`
+	// Rendered is the hash of the blob last rendered from the template, the
+	// pristine content of the file
+	Rendered string `yaml:"rendered,omitempty"`
+}
+
+// Ownership maps template owned paths to their template
+type Ownership map[string]Owner
+
+// LoadOwnership reads the ownership map, a missing one yields an empty map
+func LoadOwnership(store state.Store) (Ownership, error) {
+	ownership := make(Ownership)
+
+	content, err := store.Read(state.Ownership)
+	if errors.Is(err, state.ErrNotExist) {
+		return ownership, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = yaml.Unmarshal(content, &ownership)
+	if err != nil {
+		return nil, err
+	}
+	return ownership, nil
+}
+
+// Save writes the ownership map into the store
+func (ownership Ownership) Save(store state.Store) error {
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(ownership); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+	return store.Write(state.Ownership, buffer.Bytes())
+}
+
+// Discover records every file of the HEAD tree referencing a variable as template owned
+func Discover(r *git.Repository, ownership Ownership) error {
+	head, err := r.Head()
+	if err != nil {
+		return err
+	}
+
+	commit, err := r.CommitObject(head.Hash())
+	if err != nil {
+		return err
+	}
+
+	files, err := commit.Files()
+	if err != nil {
+		return err
+	}
+
+	return files.ForEach(func(file *object.File) error {
+		if file.Name == OwnershipFile {
+			return nil
+		}
+		if _, owned := ownership[file.Name]; owned {
+			return nil
+		}
+
+		binary, err := file.IsBinary()
+		if err != nil || binary {
+			return err
+		}
+
+		content, err := file.Contents()
+		if err != nil {
+			return err
+		}
+		if HasPlaceholders([]byte(content)) {
+			ownership[file.Name] = Owner{Template: file.Hash.String()}
+		}
+		return nil
+	})
+}
+
+// Apply renders the templates of owned files into the worktree and stages
+// them. Files edited since their last render, committed or not, are the
+// user's: they are left alone and returned as userOwned. The pristine hash of
+// rendered files is updated, it returns the paths whose content changed
+func Apply(r *git.Repository, ownership Ownership, values map[string]string) (changed, userOwned []string, err error) {
+	w, err := r.Worktree()
+	if err != nil {
+		return
+	}
+
+	status, err := w.Status()
+	if err != nil {
+		return
+	}
+
+	for filepath, owner := range ownership {
+		// Clean files are absent from the status
+		if fileStatus, ok := status[filepath]; ok && fileStatus.Worktree != git.Unmodified {
+			userOwned = append(userOwned, filepath)
+			continue
+		}
+
+		// A committed edit or deletion leaves the file clean but no longer
+		// pristine, the template itself until the first render
+		pristine := cmp.Or(owner.Rendered, owner.Template)
+		var current []byte
+		current, err = readFile(w.Filesystem, filepath)
+		if errors.Is(err, os.ErrNotExist) {
+			userOwned = append(userOwned, filepath)
+			continue
+		}
+		if err != nil {
+			return
+		}
+		if blobHash(current) != pristine {
+			userOwned = append(userOwned, filepath)
+			continue
+		}
+
+		var blob *object.Blob
+		blob, err = r.BlobObject(plumbing.NewHash(owner.Template))
+		if err != nil {
+			return
+		}
+
+		var content string
+		content, err = object.NewFile(filepath, 0, blob).Contents()
+		if err != nil {
+			return
+		}
+
+		rendered := Render([]byte(content), values)
+		owner.Rendered = blobHash(rendered)
+		ownership[filepath] = owner
+
+		if bytes.Equal(current, rendered) {
+			continue
+		}
+
+		if err = writeFile(w.Filesystem, filepath, rendered); err != nil {
+			return
+		}
+		if _, err = w.Add(filepath); err != nil {
+			return
+		}
+		changed = append(changed, filepath)
+	}
+	return changed, userOwned, nil
+}
+
+// blobHash is the hash git gives to a blob holding content
+func blobHash(content []byte) string {
+	hasher := plumbing.NewHasher(format.DefaultObjectFormat, plumbing.BlobObject, int64(len(content)))
+	_, _ = hasher.Write(content)
+	return hasher.Sum().String()
+}
+
+func readFile(fs billy.Filesystem, filepath string) ([]byte, error) {
+	file, err := fs.Open(filepath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	return io.ReadAll(file)
+}
+
+func writeFile(fs billy.Filesystem, filepath string, content []byte) error {
+	file, err := fs.Create(filepath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	_, err = file.Write(content)
+	return err
+}
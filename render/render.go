@@ -0,0 +1,33 @@
+package render
+
+import (
+	"regexp"
+)
+
+// placeholder matches [[ name ]], distinct from the {{ }} used by most
+// template engines scaffolds ship with
+var placeholder = regexp.MustCompile(`\[\[\s*([A-Za-z_][A-Za-z0-9_.-]*)\s*\]\]`)
+
+// HasPlaceholders reports whether content references any variable
+func HasPlaceholders(content []byte) bool {
+	return placeholder.Match(content)
+}
+
+// Placeholders returns the variable names referenced by content
+func Placeholders(content []byte) (names []string) {
+	for _, match := range placeholder.FindAllSubmatch(content, -1) {
+		names = append(names, string(match[1]))
+	}
+	return
+}
+
+// Render substitutes the placeholders of content, unknown variables are left untouched
+func Render(content []byte, values map[string]string) []byte {
+	return placeholder.ReplaceAllFunc(content, func(match []byte) []byte {
+		name := placeholder.FindSubmatch(match)[1]
+		if value, ok := values[string(name)]; ok {
+			return []byte(value)
+		}
+		return match
+	})
+}
@@ -0,0 +1,143 @@
+package render
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v6/memfs"
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/storage/memory"
+)
+
+func TestRender(t *testing.T) {
+	content := []byte("name: [[ name ]]\nport: [[plugin.db.port]]\nkeep: [[ unknown ]] {{ .Go }}\n")
+	values := map[string]string{"name": "demo", "plugin.db.port": "5432"}
+
+	if got := Placeholders(content); !reflect.DeepEqual(got, []string{"name", "plugin.db.port", "unknown"}) {
+		t.Errorf("Placeholders() = %q", got)
+	}
+	want := "name: demo\nport: 5432\nkeep: [[ unknown ]] {{ .Go }}\n"
+	if got := Render(content, values); string(got) != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+	if HasPlaceholders([]byte("{{ .Go }}")) {
+		t.Error("HasPlaceholders() matched a Go template")
+	}
+}
+
+func TestDiscoverApply(t *testing.T) {
+	r, err := git.Init(memory.NewStorage(), git.WithWorkTree(memfs.New()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"README":  "# [[ name ]]\n",
+		"config":  "port: [[ port ]]\n",
+		"main.go": "package main\n",
+		"edited":  "[[ name ]]\n",
+	} {
+		if err = writeFile(w.Filesystem, name, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = w.Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	signature := &object.Signature{Name: "gravel", Email: "test@gravel", When: time.Unix(0, 0)}
+	if _, err = w.Commit("base", &git.CommitOptions{Author: signature, Committer: signature}); err != nil {
+		t.Fatal(err)
+	}
+
+	ownership := make(Ownership)
+	if err = Discover(r, ownership); err != nil {
+		t.Fatal(err)
+	}
+	if owned := slices.Sorted(maps.Keys(ownership)); !reflect.DeepEqual(owned, []string{"README", "config", "edited"}) {
+		t.Fatalf("Discover() owned %v", owned)
+	}
+
+	// Uncommitted changes are the user's, they are not rendered over
+	if err = writeFile(w.Filesystem, "edited", []byte("mine\n")); err != nil {
+		t.Fatal(err)
+	}
+	changed, userOwned, err := Apply(r, ownership, map[string]string{"name": "demo", "port": "8080"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(changed)
+	if !reflect.DeepEqual(changed, []string{"README", "config"}) {
+		t.Fatalf("Apply() changed %v, want README and config", changed)
+	}
+	if !reflect.DeepEqual(userOwned, []string{"edited"}) {
+		t.Fatalf("Apply() user owned %v, want edited", userOwned)
+	}
+
+	readme, err := readFile(w.Filesystem, "README")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(readme) != "# demo\n" || ownership["README"].Rendered != blobHash(readme) {
+		t.Errorf("README = %q rendered as %s", readme, ownership["README"].Rendered)
+	}
+	if ownership["edited"].Rendered != "" {
+		t.Errorf("edited was rendered over the uncommitted change")
+	}
+}
+
+func TestApplyCommittedEdit(t *testing.T) {
+	r, err := git.Init(memory.NewStorage(), git.WithWorkTree(memfs.New()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := &object.Signature{Name: "gravel", Email: "test@gravel", When: time.Unix(0, 0)}
+	commit := func(content string) {
+		t.Helper()
+		if err := writeFile(w.Filesystem, "README", []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Add("README"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Commit("README", &git.CommitOptions{Author: signature, Committer: signature}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	commit("# [[ name ]]\n")
+
+	ownership := make(Ownership)
+	if err = Discover(r, ownership); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = Apply(r, ownership, map[string]string{"name": "demo"}); err != nil {
+		t.Fatal(err)
+	}
+	rendered := ownership["README"].Rendered
+
+	// The edit is committed, the worktree is clean but no longer pristine
+	commit("# my app\n")
+	changed, userOwned, err := Apply(r, ownership, map[string]string{"name": "other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) > 0 || !reflect.DeepEqual(userOwned, []string{"README"}) {
+		t.Fatalf("Apply() changed %v and user owned %v, want README user owned", changed, userOwned)
+	}
+	if readme, _ := readFile(w.Filesystem, "README"); string(readme) != "# my app\n" {
+		t.Errorf("README = %q, want the committed edit kept", readme)
+	}
+	if ownership["README"].Rendered != rendered {
+		t.Errorf("the pristine hash of README changed without a render")
+	}
+}
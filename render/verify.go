@@ -0,0 +1,139 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"gravel/lock"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+// DriftKind classifies how a file differs from the recorded composition
+type DriftKind string
+
+const (
+	// Modified template owned files no longer hold their pristine content
+	Modified DriftKind = "modified"
+	// Deleted template owned files are missing from the worktree
+	Deleted DriftKind = "deleted"
+	// Extraneous files are template owned files no locked component ships,
+	// or tracked files referencing variables without being template owned
+	Extraneous DriftKind = "extraneous"
+)
+
+// Drift is a worktree file differing from the recorded composition
+type Drift struct {
+	Path string    `json:"path"`
+	Kind DriftKind `json:"kind"`
+}
+
+// Verify compares the worktree with the composition recorded in the
+// lockfile: owned files must be shipped by one of the locked components and
+// hold their pristine content, the last render of their template or the
+// template itself when it was never rendered
+func Verify(r *git.Repository, locked *lock.Lock, ownership Ownership) (drifts []Drift, err error) {
+	w, err := r.Worktree()
+	if err != nil {
+		return
+	}
+
+	shipped, err := shippedPaths(r, locked)
+	if err != nil {
+		return
+	}
+
+	for filepath, owner := range ownership {
+		if !shipped[filepath] {
+			drifts = append(drifts, Drift{Path: filepath, Kind: Extraneous})
+			continue
+		}
+
+		var content []byte
+		content, err = readFile(w.Filesystem, filepath)
+		if errors.Is(err, os.ErrNotExist) {
+			drifts = append(drifts, Drift{Path: filepath, Kind: Deleted})
+			continue
+		}
+		if err != nil {
+			return
+		}
+
+		pristine := owner.Rendered
+		if pristine == "" {
+			pristine = owner.Template
+		}
+		if blobHash(content) != pristine {
+			drifts = append(drifts, Drift{Path: filepath, Kind: Modified})
+		}
+	}
+
+	// Untracked files are the user's own, only tracked ones are inspected
+	idx, err := r.Storer.Index()
+	if err != nil {
+		return
+	}
+
+	for _, entry := range idx.Entries {
+		if _, owned := ownership[entry.Name]; owned || entry.Name == OwnershipFile || entry.Stage != 0 {
+			continue
+		}
+
+		var content []byte
+		content, err = readFile(w.Filesystem, entry.Name)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return
+		}
+
+		if HasPlaceholders(content) {
+			drifts = append(drifts, Drift{Path: entry.Name, Kind: Extraneous})
+		}
+	}
+	return drifts, nil
+}
+
+// shippedPaths returns the paths of the files of the locked commits of the
+// components, less those they exclude
+func shippedPaths(r *git.Repository, locked *lock.Lock) (map[string]bool, error) {
+	shipped := make(map[string]bool)
+	for _, component := range locked.Components() {
+		commit, err := r.CommitObject(plumbing.NewHash(component.Commit))
+		if err != nil {
+			return nil, fmt.Errorf("%s: component %q at %s: %w", lock.File, component.Name, component.Commit, err)
+		}
+		files, err := commit.Files()
+		if err != nil {
+			return nil, err
+		}
+		err = files.ForEach(func(file *object.File) error {
+			if !excluded(component.Exclude, file.Name) {
+				shipped[file.Name] = true
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return shipped, nil
+}
+
+// excluded tells whether filepath or one of its directories matches patterns
+func excluded(patterns []string, filepath string) bool {
+	for name := filepath; name != "." && name != "/"; name = path.Dir(name) {
+		for _, pattern := range patterns {
+			if matched, _ := path.Match(strings.TrimSuffix(pattern, "/"), name); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package render
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"gravel/lock"
+
+	"github.com/go-git/go-billy/v6/memfs"
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/storage/memory"
+)
+
+func TestVerify(t *testing.T) {
+	r, err := git.Init(memory.NewStorage(), git.WithWorkTree(memfs.New()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(name, content string) {
+		t.Helper()
+		if err := writeFile(w.Filesystem, name, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("README", "# [[ name ]]\n")
+	write("config", "port: [[ port ]]\n")
+	write("ci.yml", "image: [[ image ]]\n")
+	write("docs/notes", "see [[ url ]]\n")
+	for _, name := range []string{"README", "config", "ci.yml", "docs/notes"} {
+		if _, err = w.Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	signature := &object.Signature{Name: "gravel", Email: "test@gravel", When: time.Unix(0, 0)}
+	commit, err := w.Commit("base", &git.CommitOptions{Author: signature, Committer: signature})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The app renders README, leaves config as shipped then edits it
+	write("README", "# app\n")
+	write("config", "port: 8080\n")
+	ownership := Ownership{
+		"README":  {Template: blobHash([]byte("# [[ name ]]\n")), Rendered: blobHash([]byte("# app\n"))},
+		"config":  {Template: blobHash([]byte("port: [[ port ]]\n"))},
+		"removed": {Template: blobHash([]byte("[[ name ]]\n"))},
+		"ci.yml":  {Template: blobHash([]byte("image: [[ image ]]\n"))},
+	}
+	locked := &lock.Lock{Base: lock.Component{Name: "web", Commit: commit.String(), Exclude: []string{"ci.yml"}}}
+
+	drifts, err := Verify(r, locked, ownership)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.SortFunc(drifts, func(a, b Drift) int { return strings.Compare(a.Path, b.Path) })
+
+	want := []Drift{
+		{Path: "ci.yml", Kind: Extraneous},
+		{Path: "config", Kind: Modified},
+		{Path: "docs/notes", Kind: Extraneous},
+		{Path: "removed", Kind: Extraneous},
+	}
+	if !reflect.DeepEqual(drifts, want) {
+		t.Fatalf("Verify() = %v, want %v", drifts, want)
+	}
+}
@@ -0,0 +1,92 @@
+package resume
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"gravel/state"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrNoOperation is returned by Load when no command was interrupted
+var ErrNoOperation = errors.New("no interrupted operation to continue")
+
+// ErrInProgress is returned by Start while an interrupted operation remains
+var ErrInProgress = errors.New("an interrupted operation is in progress, continue or abort it first")
+
+// Operation records a command merging the components of an app one at a
+// time, so that an interrupted run continues from the first pending one
+// instead of merging everything again
+type Operation struct {
+	// Command is the interrupted command, e.g. update
+	Command string `yaml:"command"`
+	// Pending are the components left in order, the first one failed or
+	// was interrupted
+	Pending []string `yaml:"pending"`
+	// Done are the components already merged, recorded in the lockfile too
+	Done []string `yaml:"done,omitempty"`
+}
+
+// Start records a new operation over the components, failing while
+// another one remains
+func Start(store state.Store, command string, components []string) (*Operation, error) {
+	existing, err := Load(store)
+	if err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrInProgress, existing.Command)
+	}
+	if !errors.Is(err, ErrNoOperation) {
+		return nil, err
+	}
+
+	operation := &Operation{Command: command, Pending: slices.Clone(components)}
+	return operation, operation.save(store)
+}
+
+// Load reads the interrupted operation of the app
+func Load(store state.Store) (*Operation, error) {
+	content, err := store.Read(state.Resume)
+	if errors.Is(err, state.ErrNotExist) {
+		return nil, ErrNoOperation
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	operation := new(Operation)
+	if err = yaml.Unmarshal(content, operation); err != nil {
+		return nil, err
+	}
+	return operation, nil
+}
+
+// Complete marks the component done, the operation is removed once
+// nothing is pending
+func (operation *Operation) Complete(store state.Store, component string) error {
+	index := slices.Index(operation.Pending, component)
+	if index < 0 {
+		return fmt.Errorf("%s: %q is not pending", state.Resume, component)
+	}
+	operation.Pending = slices.Delete(operation.Pending, index, index+1)
+	operation.Done = append(operation.Done, component)
+
+	if len(operation.Pending) == 0 {
+		return store.Remove(state.Resume)
+	}
+	return operation.save(store)
+}
+
+// Abort forgets the interrupted operation, the components already done
+// stay merged
+func Abort(store state.Store) error {
+	return store.Remove(state.Resume)
+}
+
+func (operation *Operation) save(store state.Store) error {
+	content, err := yaml.Marshal(operation)
+	if err != nil {
+		return err
+	}
+	return store.Write(state.Resume, content)
+}
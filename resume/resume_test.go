@@ -0,0 +1,62 @@
+package resume
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"gravel/state"
+
+	"github.com/go-git/go-billy/v6/memfs"
+)
+
+func TestOperation(t *testing.T) {
+	store := state.NewWorktree(memfs.New())
+
+	operation, err := Start(store, "update", []string{"web", "auth", "db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Start(store, "init", []string{"web"}); !errors.Is(err, ErrInProgress) {
+		t.Fatalf("Start() during an operation = %v, want %v", err, ErrInProgress)
+	}
+
+	if err = operation.Complete(store, "web"); err != nil {
+		t.Fatal(err)
+	}
+	if err = operation.Complete(store, "web"); err == nil {
+		t.Fatal("Complete() of a done component succeeded")
+	}
+
+	// An interrupted command continues from what was saved
+	loaded, err := Load(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Operation{Command: "update", Pending: []string{"auth", "db"}, Done: []string{"web"}}
+	if !reflect.DeepEqual(loaded, want) {
+		t.Fatalf("Load() = %+v, want %+v", loaded, want)
+	}
+
+	for _, component := range []string{"auth", "db"} {
+		if err = loaded.Complete(store, component); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = Load(store); !errors.Is(err, ErrNoOperation) {
+		t.Fatalf("Load() once nothing is pending = %v, want %v", err, ErrNoOperation)
+	}
+}
+
+func TestAbort(t *testing.T) {
+	store := state.NewWorktree(memfs.New())
+	if _, err := Start(store, "update", []string{"web"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Abort(store); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Start(store, "update", []string{"web"}); err != nil {
+		t.Fatalf("Start() after Abort() = %v", err)
+	}
+}
@@ -0,0 +1,170 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// sharedAddressSpace is the carrier-grade NAT range, not covered by netip.Addr.IsPrivate
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// Policy restricts the schemes, hosts and addresses the resolver and the git
+// fetcher may contact, required whenever URLs come from untrusted callers
+type Policy struct {
+	// Schemes allowed to be resolved or fetched, empty allows http and https.
+	// ssh and git are dialed by git outside of HTTPClient, so they are only
+	// allowed when listed, their host names then being checked once resolved
+	Schemes []Source `yaml:"schemes"`
+	// Hosts allowed to be contacted, "*.example.com" matches subdomains, empty allows every host
+	Hosts []string `yaml:"hosts"`
+	// AllowPrivate permits loopback, private, link-local and metadata addresses
+	AllowPrivate bool `yaml:"allowPrivate"`
+}
+
+// Check validates a raw URL against the policy before any connection happens
+func (policy *Policy) Check(raw string) error {
+	scheme, host, err := schemeHost(raw)
+	if err != nil {
+		return err
+	}
+
+	if !policy.allowsScheme(scheme) {
+		return fmt.Errorf("%s: scheme %q is not allowed", raw, scheme)
+	}
+	if scheme == File {
+		return nil
+	}
+
+	if !policy.allowsHost(host) {
+		return fmt.Errorf("%s: host %q is not allowed", raw, host)
+	}
+
+	// Literal addresses are rejected early, names are checked once resolved at dial time
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !policy.allowsAddr(addr) {
+			return fmt.Errorf("%s: address %s is not allowed", raw, addr)
+		}
+		return nil
+	}
+
+	// The ssh and git transports dial on their own, their names are
+	// resolved here instead
+	if scheme == SSH || scheme == Git {
+		addrs, err := net.DefaultResolver.LookupNetIP(context.Background(), "ip", host)
+		if err != nil {
+			return err
+		}
+		for _, addr := range addrs {
+			if !policy.allowsAddr(addr) {
+				return fmt.Errorf("%s: host %q resolves to %s which is not allowed", raw, host, addr)
+			}
+		}
+	}
+	return nil
+}
+
+// schemeHost returns the scheme and host of raw, scp like remotes
+// (git@host:path) being ssh ones
+func schemeHost(raw string) (Source, string, error) {
+	if !strings.Contains(raw, "://") {
+		if match := scpLike.FindStringSubmatch(raw); match != nil {
+			return SSH, match[1], nil
+		}
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return "", "", err
+	}
+	return Source(parsed.Scheme), parsed.Hostname(), nil
+}
+
+func (policy *Policy) allowsScheme(scheme Source) bool {
+	if len(policy.Schemes) > 0 {
+		return slices.Contains(policy.Schemes, scheme)
+	}
+	return scheme == HTTP || scheme == HTTPS
+}
+
+func (policy *Policy) allowsHost(host string) bool {
+	if len(policy.Hosts) == 0 {
+		return true
+	}
+
+	host = strings.ToLower(host)
+	for _, allowed := range policy.Hosts {
+		allowed = strings.ToLower(allowed)
+		if suffix, ok := strings.CutPrefix(allowed, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+			continue
+		}
+		if host == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+func (policy *Policy) allowsAddr(addr netip.Addr) bool {
+	if policy.AllowPrivate {
+		return true
+	}
+
+	addr = addr.Unmap()
+	return !addr.IsLoopback() &&
+		!addr.IsPrivate() &&
+		!addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() &&
+		!addr.IsInterfaceLocalMulticast() &&
+		!addr.IsUnspecified() &&
+		!sharedAddressSpace.Contains(addr)
+}
+
+// control rejects connections to denied addresses after DNS resolution,
+// which also defeats DNS rebinding
+func (policy *Policy) control(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !policy.allowsAddr(addrPort.Addr()) {
+		return fmt.Errorf("address %s is not allowed", addrPort.Addr())
+	}
+	return nil
+}
+
+// HTTPClient returns a client enforcing the policy on every dial and redirect
+func (policy *Policy) HTTPClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   policy.control,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// Proxies would hide the real destination from the dialer
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, address)
+	}
+
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after 10 redirects")
+			}
+			return policy.Check(req.URL.String())
+		},
+	}
+}
@@ -0,0 +1,112 @@
+package source
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	policy := &Policy{Hosts: []string{"github.com", "*.example.com"}}
+	for raw, allowed := range map[string]bool{
+		"https://github.com/org/repo":      true,
+		"https://GitHub.com/org/repo":      true,
+		"https://git.example.com/repo":     true,
+		"https://example.com/repo":         false,
+		"https://github.com.evil.org/repo": false,
+		"file:///etc/passwd":               false,
+	} {
+		if err := policy.Check(raw); (err == nil) != allowed {
+			t.Errorf("Check(%q) = %v, want allowed %v", raw, err, allowed)
+		}
+	}
+
+	// Any host, literal addresses are checked before dialing
+	policy = &Policy{}
+	for raw, allowed := range map[string]bool{
+		"https://140.82.112.3/org/repo":     true,
+		"https://127.0.0.1/repo":            false,
+		"https://[::ffff:10.0.0.1]/repo":    false,
+		"https://169.254.169.254/meta-data": false,
+	} {
+		if err := policy.Check(raw); (err == nil) != allowed {
+			t.Errorf("Check(%q) = %v, want allowed %v", raw, err, allowed)
+		}
+	}
+}
+
+func TestCheckSchemes(t *testing.T) {
+	policy := &Policy{Schemes: []Source{File}}
+	if err := policy.Check("file:///srv/manifest.yaml"); err != nil {
+		t.Errorf("Check() of an allowed file = %v", err)
+	}
+	if err := policy.Check("https://github.com/org/repo"); err == nil {
+		t.Error("Check() of a scheme outside the allowed ones succeeded")
+	}
+}
+
+func TestAllowsAddr(t *testing.T) {
+	policy := &Policy{}
+	for addr, allowed := range map[string]bool{
+		"140.82.112.3": true,
+		"10.1.2.3":     false,
+		"100.64.0.1":   false,
+		"::1":          false,
+		"fe80::1":      false,
+		"0.0.0.0":      false,
+	} {
+		if got := policy.allowsAddr(netip.MustParseAddr(addr)); got != allowed {
+			t.Errorf("allowsAddr(%s) = %v, want %v", addr, got, allowed)
+		}
+	}
+
+	if !(&Policy{AllowPrivate: true}).allowsAddr(netip.MustParseAddr("10.1.2.3")) {
+		t.Error("AllowPrivate must permit private addresses")
+	}
+}
+
+func TestCheckGitTransports(t *testing.T) {
+	// ssh and git dial outside of HTTPClient, they must be listed
+	policy := &Policy{}
+	for _, raw := range []string{"ssh://git@github.com/org/repo", "git@github.com:org/repo.git", "git://github.com/org/repo"} {
+		if err := policy.Check(raw); err == nil {
+			t.Errorf("Check(%q) of an unlisted scheme succeeded", raw)
+		}
+	}
+
+	// Listed, their names are checked once resolved, localhost resolving to a loopback address
+	policy = &Policy{Schemes: []Source{SSH, Git}}
+	for _, raw := range []string{"ssh://git@localhost/org/repo", "git@localhost:org/repo.git", "git://localhost/org/repo"} {
+		err := policy.Check(raw)
+		if err == nil || !strings.Contains(err.Error(), "resolves to") {
+			t.Errorf("Check(%q) = %v, want the resolved address refused", raw, err)
+		}
+	}
+
+	policy.AllowPrivate = true
+	if err := policy.Check("ssh://git@localhost/org/repo"); err != nil {
+		t.Errorf("Check() with AllowPrivate = %v", err)
+	}
+}
+
+func TestHTTPClientResolvedName(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// The name passes Check, the dialer refuses the loopback address it resolves to
+	target := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	policy := &Policy{}
+	if err := policy.Check(target); err != nil {
+		t.Fatalf("Check(%q) = %v", target, err)
+	}
+	res, err := policy.HTTPClient().Get(target)
+	if err == nil {
+		_ = res.Body.Close()
+		t.Fatalf("GET %s succeeded, want the resolved address refused", target)
+	}
+	if !strings.Contains(err.Error(), "is not allowed") {
+		t.Errorf("GET %s = %v, want the address refused", target, err)
+	}
+}
@@ -0,0 +1,141 @@
+package source
+
+import (
+	"cmp"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// defaultPorts are the ports of the schemes when the URL has none
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+	"ssh":   "22",
+	"git":   "9418",
+}
+
+// scpLike matches the user@host:path form of ssh remotes
+var scpLike = regexp.MustCompile(`^(?:[^@/]+@)?([^:/]+):`)
+
+// Endpoint is a network destination contacted by a command
+type Endpoint struct {
+	Scheme string `json:"scheme"`
+	Host   string `json:"host"`
+	Port   string `json:"port"`
+	// Purposes are what the endpoint was contacted for, e.g. manifest or a
+	// component, empty for requests only seen on the wire such as redirects
+	Purposes []string `json:"purposes,omitempty"`
+}
+
+// Recorder collects the endpoints contacted, so that egress allowlists can
+// be built from a run. A nil Recorder records nothing
+type Recorder struct {
+	mu        sync.Mutex
+	endpoints map[address][]string
+}
+
+// address identifies an endpoint whatever its purposes
+type address struct {
+	scheme, host, port string
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{endpoints: make(map[address][]string)}
+}
+
+// Record adds the endpoint of a raw URL, local files are ignored
+func (recorder *Recorder) Record(purpose, raw string) {
+	if recorder == nil {
+		return
+	}
+
+	endpoint, ok := parseEndpoint(raw)
+	if !ok {
+		return
+	}
+
+	key := address{endpoint.Scheme, endpoint.Host, endpoint.Port}
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	purposes := recorder.endpoints[key]
+	if purpose != "" && !slices.Contains(purposes, purpose) {
+		purposes = append(purposes, purpose)
+	}
+	recorder.endpoints[key] = purposes
+}
+
+// Endpoints returns the recorded endpoints sorted by host
+func (recorder *Recorder) Endpoints() []Endpoint {
+	if recorder == nil {
+		return nil
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	endpoints := make([]Endpoint, 0, len(recorder.endpoints))
+	for key, purposes := range recorder.endpoints {
+		endpoints = append(endpoints, Endpoint{
+			Scheme:   key.scheme,
+			Host:     key.host,
+			Port:     key.port,
+			Purposes: slices.Clone(purposes),
+		})
+	}
+	slices.SortFunc(endpoints, func(a, b Endpoint) int {
+		return cmp.Or(
+			strings.Compare(a.Host, b.Host),
+			strings.Compare(a.Port, b.Port),
+			strings.Compare(a.Scheme, b.Scheme),
+		)
+	})
+	return endpoints
+}
+
+// Client returns a copy of client recording every request, redirects
+// included. A nil Recorder returns client
+func (recorder *Recorder) Client(client *http.Client) *http.Client {
+	if recorder == nil {
+		return client
+	}
+
+	recorded := *client
+	next := cmp.Or[http.RoundTripper](client.Transport, http.DefaultTransport)
+	recorded.Transport = roundTripper(func(request *http.Request) (*http.Response, error) {
+		recorder.Record("", request.URL.String())
+		return next.RoundTrip(request)
+	})
+	return &recorded
+}
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (fn roundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	return fn(request)
+}
+
+// parseEndpoint returns the endpoint of URLs and scp-like ssh remotes
+func parseEndpoint(raw string) (Endpoint, bool) {
+	if !strings.Contains(raw, "://") {
+		match := scpLike.FindStringSubmatch(raw)
+		if match == nil {
+			return Endpoint{}, false
+		}
+		return Endpoint{Scheme: "ssh", Host: strings.ToLower(match[1]), Port: defaultPorts["ssh"]}, true
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Scheme == string(File) || parsed.Hostname() == "" {
+		return Endpoint{}, false
+	}
+
+	scheme := strings.ToLower(parsed.Scheme)
+	return Endpoint{
+		Scheme: scheme,
+		Host:   strings.ToLower(parsed.Hostname()),
+		Port:   cmp.Or(parsed.Port(), defaultPorts[scheme]),
+	}, true
+}
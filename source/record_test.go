@@ -0,0 +1,35 @@
+package source
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	recorder := NewRecorder()
+	recorder.Record("manifest", "https://raw.githubusercontent.com/org/catalog/main/manifest.yaml")
+	recorder.Record("base", "git@GitHub.com:org/web.git")
+	recorder.Record("auth", "https://github.com/org/auth.git")
+	recorder.Record("db", "https://github.com/org/db.git")
+	recorder.Record("", "https://github.com:8443/org/mirror.git")
+	recorder.Record("local", "file:///srv/manifest.yaml")
+	recorder.Record("local", "./manifest.yaml")
+
+	want := []Endpoint{
+		{Scheme: "ssh", Host: "github.com", Port: "22", Purposes: []string{"base"}},
+		{Scheme: "https", Host: "github.com", Port: "443", Purposes: []string{"auth", "db"}},
+		{Scheme: "https", Host: "github.com", Port: "8443"},
+		{Scheme: "https", Host: "raw.githubusercontent.com", Port: "443", Purposes: []string{"manifest"}},
+	}
+	if got := recorder.Endpoints(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Endpoints() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestNilRecorder(t *testing.T) {
+	var recorder *Recorder
+	recorder.Record("manifest", "https://example.com/manifest.yaml")
+	if endpoints := recorder.Endpoints(); endpoints != nil {
+		t.Fatalf("Endpoints() of a nil recorder = %v", endpoints)
+	}
+}
@@ -17,8 +17,16 @@ const (
 	HTTPS Source = "https"
 	// File source driver identifying local files
 	File Source = "file"
+	// SSH identifies the ssh remotes of git, fetched but never resolved
+	SSH Source = "ssh"
+	// Git identifies the git:// remotes of git, fetched but never resolved
+	Git Source = "git"
 )
 
+// Stdin is the raw source reading the standard input, so that manifests can
+// be piped
+const Stdin = "-"
+
 // Driver splits a raw string with source://path format separating the source from the path
 type Driver struct {
 	Raw    string
@@ -49,16 +57,43 @@ func Extract(raw string) (*Driver, error) {
 
 // Resolve resolves a raw string into a  Reader by parsing it into a source.Driver
 func Resolve(source string) (reader io.ReadCloser, err error) {
+	return ResolveWithPolicy(source, nil)
+}
+
+// ResolveWithPolicy resolves a raw string like Resolve, refusing what the policy denies.
+// A nil policy allows everything
+func ResolveWithPolicy(source string, policy *Policy) (reader io.ReadCloser, err error) {
+	return ResolveRecorded(source, policy, nil)
+}
+
+// ResolveRecorded resolves a raw string like ResolveWithPolicy, recording the
+// contacted endpoints. A nil recorder records nothing
+func ResolveRecorded(source string, policy *Policy, recorder *Recorder) (reader io.ReadCloser, err error) {
+	if source == Stdin {
+		return io.NopCloser(os.Stdin), nil
+	}
+
 	var driver *Driver
 	driver, err = Extract(source)
 	if err != nil {
 		return
 	}
 
+	client := http.DefaultClient
+	if policy != nil {
+		err = policy.Check(driver.Raw)
+		if err != nil {
+			return
+		}
+		client = policy.HTTPClient()
+	}
+	client = recorder.Client(client)
+	recorder.Record("manifest", driver.Raw)
+
 	switch driver.Source {
 	case HTTP, HTTPS:
 		var response *http.Response
-		response, err = http.Get(driver.Raw)
+		response, err = client.Get(driver.Raw)
 		if err != nil {
 			return
 		}
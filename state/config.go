@@ -0,0 +1,57 @@
+package state
+
+import (
+	"encoding/base64"
+
+	"github.com/go-git/go-git/v6"
+)
+
+// Config keeps the state documents base64 encoded in the git config of the
+// repository, [gravel "ownership.yaml"] content = ...
+type Config struct {
+	r *git.Repository
+}
+
+func NewConfig(r *git.Repository) *Config {
+	return &Config{r: r}
+}
+
+func (store *Config) Path(string) (string, bool) { return "", false }
+
+func (store *Config) Read(name string) ([]byte, error) {
+	cfg, err := store.r.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	section := cfg.Raw.Section(configSection)
+	if !section.HasSubsection(name) || !section.Subsection(name).HasOption("content") {
+		return nil, ErrNotExist
+	}
+	return base64.StdEncoding.DecodeString(section.Subsection(name).Option("content"))
+}
+
+func (store *Config) Write(name string, content []byte) error {
+	cfg, err := store.r.Config()
+	if err != nil {
+		return err
+	}
+
+	cfg.Raw.Section(configSection).Subsection(name).
+		SetOption("content", base64.StdEncoding.EncodeToString(content))
+	return store.r.SetConfig(cfg)
+}
+
+func (store *Config) Remove(name string) error {
+	cfg, err := store.r.Config()
+	if err != nil {
+		return err
+	}
+
+	section := cfg.Raw.Section(configSection)
+	if !section.HasSubsection(name) {
+		return nil
+	}
+	section.RemoveSubsection(name)
+	return store.r.SetConfig(cfg)
+}
@@ -0,0 +1,167 @@
+package state
+
+import (
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/filemode"
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+// NotesRef holds the state documents of the notes backend, fetch and push
+// it with refs/notes/gravel:refs/notes/gravel to share the state
+const NotesRef plumbing.ReferenceName = "refs/notes/gravel"
+
+// Notes keeps the state documents as blobs of a tree committed on NotesRef,
+// every change adds a commit so that the history of the state is kept
+type Notes struct {
+	r *git.Repository
+}
+
+func NewNotes(r *git.Repository) *Notes {
+	return &Notes{r: r}
+}
+
+func (store *Notes) Path(string) (string, bool) { return "", false }
+
+// tip returns the last commit of NotesRef, nil before the first write
+func (store *Notes) tip() (*object.Commit, error) {
+	ref, err := store.r.Reference(NotesRef, true)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return store.r.CommitObject(ref.Hash())
+}
+
+func (store *Notes) Read(name string) ([]byte, error) {
+	tip, err := store.tip()
+	if err != nil {
+		return nil, err
+	}
+	if tip == nil {
+		return nil, ErrNotExist
+	}
+
+	file, err := tip.File(name)
+	if errors.Is(err, object.ErrFileNotFound) {
+		return nil, ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := file.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = reader.Close() }()
+
+	return io.ReadAll(reader)
+}
+
+func (store *Notes) Write(name string, content []byte) error {
+	blob := store.r.Storer.NewEncodedObject()
+	blob.SetType(plumbing.BlobObject)
+	writer, err := blob.Writer()
+	if err != nil {
+		return err
+	}
+	if _, err = writer.Write(content); err != nil {
+		return err
+	}
+	if err = writer.Close(); err != nil {
+		return err
+	}
+
+	hash, err := store.r.Storer.SetEncodedObject(blob)
+	if err != nil {
+		return err
+	}
+
+	return store.commit("Update "+name, func(entries []object.TreeEntry) []object.TreeEntry {
+		entries = slices.DeleteFunc(entries, func(entry object.TreeEntry) bool { return entry.Name == name })
+		return append(entries, object.TreeEntry{Name: name, Mode: filemode.Regular, Hash: hash})
+	})
+}
+
+func (store *Notes) Remove(name string) error {
+	tip, err := store.tip()
+	if err != nil || tip == nil {
+		return err
+	}
+	if _, err = tip.File(name); errors.Is(err, object.ErrFileNotFound) {
+		return nil
+	}
+
+	return store.commit("Remove "+name, func(entries []object.TreeEntry) []object.TreeEntry {
+		return slices.DeleteFunc(entries, func(entry object.TreeEntry) bool { return entry.Name == name })
+	})
+}
+
+// commit records on NotesRef the tree of the tip changed by edit
+func (store *Notes) commit(message string, edit func([]object.TreeEntry) []object.TreeEntry) error {
+	tip, err := store.tip()
+	if err != nil {
+		return err
+	}
+
+	var entries []object.TreeEntry
+	var parents []plumbing.Hash
+	if tip != nil {
+		tree, err := tip.Tree()
+		if err != nil {
+			return err
+		}
+		entries = slices.Clone(tree.Entries)
+		parents = []plumbing.Hash{tip.Hash}
+	}
+
+	entries = edit(entries)
+	// Documents are flat files, sorting by name is the git tree order
+	slices.SortFunc(entries, func(a, b object.TreeEntry) int { return strings.Compare(a.Name, b.Name) })
+
+	treeObject := store.r.Storer.NewEncodedObject()
+	if err = (&object.Tree{Entries: entries}).Encode(treeObject); err != nil {
+		return err
+	}
+	treeHash, err := store.r.Storer.SetEncodedObject(treeObject)
+	if err != nil {
+		return err
+	}
+
+	signature := store.signature()
+	commitObject := store.r.Storer.NewEncodedObject()
+	err = (&object.Commit{
+		Author:       signature,
+		Committer:    signature,
+		Message:      message,
+		TreeHash:     treeHash,
+		ParentHashes: parents,
+	}).Encode(commitObject)
+	if err != nil {
+		return err
+	}
+	commitHash, err := store.r.Storer.SetEncodedObject(commitObject)
+	if err != nil {
+		return err
+	}
+
+	return store.r.Storer.SetReference(plumbing.NewHashReference(NotesRef, commitHash))
+}
+
+// signature is the user of the repository, gravel when unset
+func (store *Notes) signature() object.Signature {
+	signature := object.Signature{Name: "gravel", Email: "gravel@localhost", When: time.Now()}
+	if cfg, err := store.r.Config(); err == nil && cfg.User.Name != "" {
+		signature.Name, signature.Email = cfg.User.Name, cfg.User.Email
+	}
+	return signature
+}
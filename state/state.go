@@ -0,0 +1,100 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v6"
+)
+
+// Names of the state documents gravel keeps for an app
+const (
+	// Lockfile records the composition of the app, see the lock package
+	Lockfile = "gravel.lock"
+	// Ownership maps template owned files to their template, see the render package
+	Ownership = "ownership.yaml"
+	// Resume records the progress of an interrupted command, see the resume package
+	Resume = "resume.yaml"
+	// Audit is the log of the commands changing the app
+	Audit = "audit.log"
+	// Project records the components, merges and variables of the app, see
+	// the project package
+	Project = "state.yaml"
+)
+
+// Backends of a Store
+const (
+	// WorktreeBackend keeps the documents as files of the worktree, committed with the app
+	WorktreeBackend = "worktree"
+	// ConfigBackend keeps the documents in the git config of the repository, local to the clone
+	ConfigBackend = "config"
+	// NotesBackend keeps the documents on a notes ref, pushed and fetched
+	// along the branches without adding files to the worktree
+	NotesBackend = "notes"
+)
+
+// Backends lists the accepted backend names
+var Backends = []string{WorktreeBackend, ConfigBackend, NotesBackend}
+
+// ErrNotExist is returned when reading a document the store does not hold
+var ErrNotExist = errors.New("state does not exist")
+
+// configSection is the git config section recording the backend of a
+// repository, gravel.state = notes
+const configSection = "gravel"
+
+// Store reads and writes the state documents of an app
+type Store interface {
+	// Read returns the content of the document, ErrNotExist when missing
+	Read(name string) ([]byte, error)
+	Write(name string, content []byte) error
+	// Remove deletes the document, a missing one is not an error
+	Remove(name string) error
+	// Path returns the worktree path of the document, false when the store
+	// keeps it outside of the worktree
+	Path(name string) (string, bool)
+}
+
+// Open returns the store of the repository. The backend recorded in the
+// repository by Select wins over backend, the worktree is the default
+func Open(r *git.Repository, backend string) (Store, error) {
+	cfg, err := r.Config()
+	if err != nil {
+		return nil, err
+	}
+	if recorded := cfg.Raw.Section(configSection).Option("state"); recorded != "" {
+		backend = recorded
+	}
+
+	switch backend {
+	case "", WorktreeBackend:
+		w, err := r.Worktree()
+		if err != nil {
+			return nil, err
+		}
+		return NewWorktree(w.Filesystem), nil
+	case ConfigBackend:
+		return NewConfig(r), nil
+	case NotesBackend:
+		return NewNotes(r), nil
+	default:
+		return nil, fmt.Errorf("unknown state backend %q, expected one of %s", backend, strings.Join(Backends, ", "))
+	}
+}
+
+// Select records the backend in the repository, later Open calls use it
+// whatever the configuration of the user
+func Select(r *git.Repository, backend string) error {
+	if !slices.Contains(Backends, backend) {
+		return fmt.Errorf("unknown state backend %q, expected one of %s", backend, strings.Join(Backends, ", "))
+	}
+
+	cfg, err := r.Config()
+	if err != nil {
+		return err
+	}
+	cfg.Raw.Section(configSection).SetOption("state", backend)
+	return r.SetConfig(cfg)
+}
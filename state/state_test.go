@@ -0,0 +1,86 @@
+package state
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-git/go-billy/v6/memfs"
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/storage/memory"
+)
+
+func newRepository(t *testing.T) *git.Repository {
+	t.Helper()
+	r, err := git.Init(memory.NewStorage(), git.WithWorkTree(memfs.New()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestStores(t *testing.T) {
+	for _, backend := range Backends {
+		t.Run(backend, func(t *testing.T) {
+			store, err := Open(newRepository(t), backend)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err = store.Read(Ownership); !errors.Is(err, ErrNotExist) {
+				t.Fatalf("Read() of a missing document = %v, want %v", err, ErrNotExist)
+			}
+			for _, content := range []string{"first\n", "second\n"} {
+				if err = store.Write(Ownership, []byte(content)); err != nil {
+					t.Fatal(err)
+				}
+				got, err := store.Read(Ownership)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(got) != content {
+					t.Fatalf("Read() = %q, want %q", got, content)
+				}
+			}
+
+			if err = store.Remove(Ownership); err != nil {
+				t.Fatal(err)
+			}
+			if _, err = store.Read(Ownership); !errors.Is(err, ErrNotExist) {
+				t.Fatalf("Read() of a removed document = %v, want %v", err, ErrNotExist)
+			}
+			if err = store.Remove(Ownership); err != nil {
+				t.Fatalf("Remove() of a missing document = %v", err)
+			}
+		})
+	}
+}
+
+func TestWorktreePath(t *testing.T) {
+	store := NewWorktree(memfs.New())
+	if path, tracked := store.Path(Lockfile); !tracked || path != Lockfile {
+		t.Errorf("Path(%q) = %q, %v, want it at the root", Lockfile, path, tracked)
+	}
+	if path, tracked := store.Path(Ownership); !tracked || path != Dir+"/"+Ownership {
+		t.Errorf("Path(%q) = %q, %v, want it under %s", Ownership, path, tracked, Dir)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	r := newRepository(t)
+	if err := Select(r, NotesBackend); err != nil {
+		t.Fatal(err)
+	}
+
+	// The recorded backend wins over the configured one
+	store, err := Open(r, WorktreeBackend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, notes := store.(*Notes); !notes {
+		t.Fatalf("Open() = %T, want the selected notes backend", store)
+	}
+
+	if err = Select(r, "database"); err == nil {
+		t.Fatal("Select() of an unknown backend succeeded")
+	}
+}
@@ -0,0 +1,69 @@
+package state
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path"
+
+	"github.com/go-git/go-billy/v6"
+)
+
+// Dir holds the state documents of the worktree backend but the lockfile,
+// which stays at the root where users look for it
+const Dir = ".gravel"
+
+// Worktree keeps the state documents as files of the worktree
+type Worktree struct {
+	fs billy.Filesystem
+}
+
+func NewWorktree(fs billy.Filesystem) *Worktree {
+	return &Worktree{fs: fs}
+}
+
+func (store *Worktree) Path(name string) (string, bool) {
+	if name == Lockfile {
+		return name, true
+	}
+	return path.Join(Dir, name), true
+}
+
+func (store *Worktree) Read(name string) ([]byte, error) {
+	filepath, _ := store.Path(name)
+	file, err := store.fs.Open(filepath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	return io.ReadAll(file)
+}
+
+func (store *Worktree) Write(name string, content []byte) error {
+	filepath, _ := store.Path(name)
+	if err := store.fs.MkdirAll(path.Dir(filepath), 0o755); err != nil {
+		return err
+	}
+
+	file, err := store.fs.Create(filepath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	_, err = file.Write(content)
+	return err
+}
+
+func (store *Worktree) Remove(name string) error {
+	filepath, _ := store.Path(name)
+	err := store.fs.Remove(filepath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
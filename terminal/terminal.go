@@ -0,0 +1,181 @@
+package terminal
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+const (
+	// EnvColor overrides the detected color support, see Colors
+	EnvColor = "GRAVEL_COLOR"
+	// EnvGlyphs overrides the detected unicode support, see GlyphSets
+	EnvGlyphs = "GRAVEL_GLYPHS"
+)
+
+// Auto keeps the detected capability
+const Auto = "auto"
+
+// Colors lists the accepted color overrides, from the richest to none
+var Colors = []string{Auto, "truecolor", "256", "16", "none"}
+
+// GlyphSets lists the accepted glyph overrides
+var GlyphSets = []string{Auto, "unicode", "ascii"}
+
+var profiles = map[string]termenv.Profile{
+	"truecolor": termenv.TrueColor,
+	"256":       termenv.ANSI256,
+	"16":        termenv.ANSI,
+	"none":      termenv.Ascii,
+}
+
+// Glyphs are the symbols drawn by the selectors
+type Glyphs struct {
+	Selected   string
+	Unselected string
+	Cursor     string
+}
+
+// Unicode and ASCII are the glyph sets, ASCII fits minimal terminals
+var (
+	Unicode = Glyphs{Selected: "●", Unselected: "○", Cursor: ">"}
+	ASCII   = Glyphs{Selected: "[x]", Unselected: "[ ]", Cursor: ">"}
+)
+
+// Palette holds the colors of the messages the components draw
+type Palette struct {
+	Error  lipgloss.TerminalColor
+	Accent lipgloss.TerminalColor
+}
+
+// Light and Dark are the palettes of the light and dark themes: the normal
+// ANSI colors read on light backgrounds, their bright variants on dark ones.
+// Adaptive picks either from the detected background
+var (
+	Light    = Palette{Error: lipgloss.Color("1"), Accent: lipgloss.Color("6")}
+	Dark     = Palette{Error: lipgloss.Color("9"), Accent: lipgloss.Color("14")}
+	Adaptive = Palette{
+		Error:  lipgloss.AdaptiveColor{Light: "1", Dark: "9"},
+		Accent: lipgloss.AdaptiveColor{Light: "6", Dark: "14"},
+	}
+)
+
+// ThemePalette returns the palette of theme, Adaptive for auto and none,
+// which drops the colors with the color support instead
+func ThemePalette(theme string) Palette {
+	switch theme {
+	case "light":
+		return Light
+	case "dark":
+		return Dark
+	default:
+		return Adaptive
+	}
+}
+
+var palette = Adaptive
+
+// CurrentPalette returns the palette last set
+func CurrentPalette() Palette { return palette }
+
+// SetPalette makes the components draw with p
+func SetPalette(p Palette) { palette = p }
+
+// Capabilities is what the terminal is able to render
+type Capabilities struct {
+	Profile termenv.Profile
+	Unicode bool
+}
+
+// Glyphs returns the glyph set the terminal renders
+func (c Capabilities) Glyphs() Glyphs {
+	if c.Unicode {
+		return Unicode
+	}
+	return ASCII
+}
+
+// Color returns the name of the color support, one of Colors
+func (c Capabilities) Color() string {
+	for name, profile := range profiles {
+		if profile == c.Profile {
+			return name
+		}
+	}
+	return Auto
+}
+
+var current = Capabilities{Profile: termenv.TrueColor, Unicode: true}
+
+// Current returns the capabilities last applied
+func Current() Capabilities { return current }
+
+// Detect guesses the capabilities of the terminal attached to stdout from
+// the environment, NO_COLOR and CLICOLOR_FORCE are honored
+func Detect() Capabilities {
+	return Capabilities{
+		Profile: termenv.EnvColorProfile(),
+		Unicode: unicode(),
+	}
+}
+
+// Override replaces the detected capabilities by color and glyphs, empty or
+// auto values keep the detected ones
+func (c Capabilities) Override(color, glyphs string) (Capabilities, error) {
+	switch color {
+	case "", Auto:
+	default:
+		profile, ok := profiles[color]
+		if !ok {
+			return c, fmt.Errorf("unknown color %q, expected one of %s", color, strings.Join(Colors, ", "))
+		}
+		c.Profile = profile
+	}
+
+	switch glyphs {
+	case "", Auto:
+	case "unicode":
+		c.Unicode = true
+	case "ascii":
+		c.Unicode = false
+	default:
+		return c, fmt.Errorf("unknown glyphs %q, expected one of %s", glyphs, strings.Join(GlyphSets, ", "))
+	}
+	return c, nil
+}
+
+// Apply makes the styles and selectors degrade to c
+func Apply(c Capabilities) {
+	current = c
+	lipgloss.SetColorProfile(c.Profile)
+}
+
+// asciiTerms are terminals whose fonts commonly lack the unicode glyphs,
+// serial consoles and the Linux virtual console
+var asciiTerms = []string{"dumb", "linux", "vt100", "vt102", "vt220", "vt320", "ansi"}
+
+// unicode tells whether the terminal likely renders unicode glyphs
+func unicode() bool {
+	if slices.Contains(asciiTerms, os.Getenv("TERM")) {
+		return false
+	}
+
+	// The legacy Windows console lacks most glyphs, unlike Windows Terminal
+	// and the terminals of editors
+	if runtime.GOOS == "windows" {
+		return os.Getenv("WT_SESSION") != "" || os.Getenv("TERM_PROGRAM") != ""
+	}
+
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if locale := os.Getenv(name); locale != "" {
+			locale = strings.ToLower(locale)
+			return strings.Contains(locale, "utf-8") || strings.Contains(locale, "utf8")
+		}
+	}
+	return true
+}
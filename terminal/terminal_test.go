@@ -0,0 +1,88 @@
+package terminal
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/muesli/termenv"
+)
+
+func TestOverride(t *testing.T) {
+	detected := Capabilities{Profile: termenv.TrueColor, Unicode: true}
+
+	for _, test := range []struct {
+		color, glyphs string
+		want          Capabilities
+	}{
+		{want: detected},
+		{color: Auto, glyphs: Auto, want: detected},
+		{color: "16", want: Capabilities{Profile: termenv.ANSI, Unicode: true}},
+		{color: "none", glyphs: "ascii", want: Capabilities{Profile: termenv.Ascii}},
+	} {
+		got, err := detected.Override(test.color, test.glyphs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("Override(%q, %q) = %+v, want %+v", test.color, test.glyphs, got, test.want)
+		}
+		if test.color != "" && test.color != Auto && got.Color() != test.color {
+			t.Errorf("Color() = %q, want %q", got.Color(), test.color)
+		}
+	}
+
+	if _, err := detected.Override("rainbow", ""); err == nil {
+		t.Error("Override() of an unknown color succeeded")
+	}
+	if _, err := detected.Override("", "emoji"); err == nil {
+		t.Error("Override() of unknown glyphs succeeded")
+	}
+}
+
+func TestGlyphs(t *testing.T) {
+	if got := (Capabilities{Unicode: true}).Glyphs(); got != Unicode {
+		t.Errorf("Glyphs() = %+v, want the unicode set", got)
+	}
+	if got := (Capabilities{}).Glyphs(); got != ASCII {
+		t.Errorf("Glyphs() = %+v, want the ascii set", got)
+	}
+}
+
+func TestUnicode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the console decides on windows")
+	}
+
+	for _, test := range []struct {
+		term, all, ctype, lang string
+		want                   bool
+	}{
+		{term: "xterm-256color", lang: "en_US.UTF-8", want: true},
+		{term: "linux", lang: "en_US.UTF-8", want: false},
+		{term: "xterm", all: "C", lang: "en_US.UTF-8", want: false},
+		{term: "xterm", ctype: "fr_FR.utf8", want: true},
+		{term: "xterm", want: true},
+	} {
+		t.Setenv("TERM", test.term)
+		t.Setenv("LC_ALL", test.all)
+		t.Setenv("LC_CTYPE", test.ctype)
+		t.Setenv("LANG", test.lang)
+		if got := unicode(); got != test.want {
+			t.Errorf("unicode() with TERM=%q LC_ALL=%q LC_CTYPE=%q LANG=%q = %v, want %v", test.term, test.all, test.ctype, test.lang, got, test.want)
+		}
+	}
+}
+
+func TestThemePalette(t *testing.T) {
+	for theme, want := range map[string]Palette{
+		"":      Adaptive,
+		Auto:    Adaptive,
+		"none":  Adaptive,
+		"light": Light,
+		"dark":  Dark,
+	} {
+		if got := ThemePalette(theme); got != want {
+			t.Errorf("ThemePalette(%q) = %+v, want %+v", theme, got, want)
+		}
+	}
+}
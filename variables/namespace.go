@@ -0,0 +1,169 @@
+package variables
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Namespaces of the components declaring variables
+const (
+	BaseNamespace   = "base"
+	PluginNamespace = "plugin"
+)
+
+// Qualify returns the namespaced name of a variable declared by a
+// component, plugin.auth.port for the port of the auth plugin
+func Qualify(namespace, component, name string) string {
+	return strings.Join([]string{namespace, slug(component), name}, ".")
+}
+
+// slug lowercases a component name and replaces the characters placeholders
+// do not accept, GORM SQLite becomes gorm-sqlite
+func slug(component string) string {
+	var builder strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(component) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' {
+			builder.WriteRune(r)
+			dash = false
+		} else if !dash && builder.Len() > 0 {
+			builder.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(builder.String(), "-")
+}
+
+// Declaration is a variable declared by a component
+type Declaration struct {
+	// Name is the qualified name of the variable
+	Name string
+	// Short is the name as declared by the component
+	Short       string
+	Description string
+	Default     string
+}
+
+// Namespace resolves the short names and the aliases of declared variables
+// to their qualified names
+type Namespace struct {
+	declarations []Declaration
+	// owners maps short names to the qualified names declaring them
+	owners  map[string][]string
+	aliases map[string]string
+}
+
+// NewNamespace ignores the aliases of variables no declaration provides,
+// they belong to components that were not selected
+func NewNamespace(declarations []Declaration, aliases map[string]string) *Namespace {
+	namespace := &Namespace{
+		declarations: declarations,
+		owners:       make(map[string][]string),
+		aliases:      make(map[string]string),
+	}
+	declared := make(map[string]bool)
+	for _, declaration := range declarations {
+		namespace.owners[declaration.Short] = append(namespace.owners[declaration.Short], declaration.Name)
+		declared[declaration.Name] = true
+	}
+
+	for alias, target := range aliases {
+		if declared[target] {
+			namespace.aliases[alias] = target
+		}
+	}
+	return namespace
+}
+
+// Ambiguous tells whether several components declare the short name
+func (namespace *Namespace) Ambiguous(short string) bool {
+	return len(namespace.owners[short]) > 1
+}
+
+// Label is the name a declared variable is prompted with, qualified when
+// its short name is ambiguous
+func (namespace *Namespace) Label(declaration Declaration) string {
+	if namespace.Ambiguous(declaration.Short) {
+		return declaration.Name
+	}
+	return declaration.Short
+}
+
+// Declarations returns the declared variables in declaration order
+func (namespace *Namespace) Declarations() []Declaration {
+	return namespace.declarations
+}
+
+// lookup returns the value of a declared variable, set by its qualified
+// name, an alias or its unambiguous short name, in that order
+func (namespace *Namespace) lookup(declaration Declaration, values map[string]string) (string, bool) {
+	if value, ok := values[declaration.Name]; ok {
+		return value, true
+	}
+	for _, alias := range slices.Sorted(maps.Keys(namespace.aliases)) {
+		if namespace.aliases[alias] != declaration.Name {
+			continue
+		}
+		if value, ok := values[alias]; ok {
+			return value, true
+		}
+	}
+	if !namespace.Ambiguous(declaration.Short) {
+		value, ok := values[declaration.Short]
+		return value, ok
+	}
+	return "", false
+}
+
+// Missing returns the declared variables values leave unset
+func (namespace *Namespace) Missing(values map[string]string) (missing []Declaration) {
+	for _, declaration := range namespace.declarations {
+		if _, ok := namespace.lookup(declaration, values); !ok {
+			missing = append(missing, declaration)
+		}
+	}
+	return
+}
+
+// Resolve returns the values templates are rendered with. Declared
+// variables fall back to their default and are available under their
+// qualified name, their aliases and their short name when unambiguous.
+// Undeclared values are kept as is. Setting an ambiguous short name fails
+// instead of picking one of the components
+func (namespace *Namespace) Resolve(values map[string]string) (map[string]string, error) {
+	resolved := maps.Clone(values)
+	if resolved == nil {
+		resolved = make(map[string]string)
+	}
+
+	for _, short := range slices.Sorted(maps.Keys(namespace.owners)) {
+		if _, set := values[short]; !set || !namespace.Ambiguous(short) {
+			continue
+		}
+		if _, alias := namespace.aliases[short]; alias {
+			continue
+		}
+		return nil, fmt.Errorf(
+			"variable %q is declared by %s, set one of them or declare an alias in the manifest",
+			short, strings.Join(namespace.owners[short], ", "),
+		)
+	}
+
+	for _, declaration := range namespace.declarations {
+		value, ok := namespace.lookup(declaration, values)
+		if !ok {
+			value = declaration.Default
+		}
+		resolved[declaration.Name] = value
+		if !namespace.Ambiguous(declaration.Short) {
+			resolved[declaration.Short] = value
+		}
+	}
+
+	for alias, target := range namespace.aliases {
+		resolved[alias] = resolved[target]
+	}
+	return resolved, nil
+}
@@ -0,0 +1,59 @@
+package variables
+
+import (
+	"maps"
+	"strings"
+	"testing"
+)
+
+func TestQualify(t *testing.T) {
+	if got := Qualify(PluginNamespace, "GORM SQLite!", "port"); got != "plugin.gorm-sqlite.port" {
+		t.Fatalf("Qualify() = %q", got)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	namespace := NewNamespace([]Declaration{
+		{Name: "base.web.name", Short: "name", Default: "app"},
+		{Name: "base.web.port", Short: "port", Default: "80"},
+		{Name: "plugin.db.port", Short: "port", Default: "5432"},
+	}, map[string]string{
+		"db_port": "plugin.db.port",
+		"unused":  "plugin.cache.size",
+	})
+
+	if !namespace.Ambiguous("port") || namespace.Ambiguous("name") {
+		t.Fatal("port is declared twice, name once")
+	}
+	if missing := namespace.Missing(map[string]string{"name": "demo", "db_port": "5433"}); len(missing) != 1 || missing[0].Name != "base.web.port" {
+		t.Fatalf("Missing() = %v, want base.web.port", missing)
+	}
+
+	got, err := namespace.Resolve(map[string]string{"name": "demo", "db_port": "5433", "extra": "kept"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"name":           "demo",
+		"base.web.name":  "demo",
+		"base.web.port":  "80",
+		"plugin.db.port": "5433",
+		"db_port":        "5433",
+		"extra":          "kept",
+	}
+	if !maps.Equal(got, want) {
+		t.Fatalf("Resolve() = %v, want %v", got, want)
+	}
+}
+
+func TestResolveAmbiguous(t *testing.T) {
+	namespace := NewNamespace([]Declaration{
+		{Name: "base.web.port", Short: "port"},
+		{Name: "plugin.db.port", Short: "port"},
+	}, nil)
+
+	_, err := namespace.Resolve(map[string]string{"port": "8080"})
+	if err == nil || !strings.Contains(err.Error(), "base.web.port, plugin.db.port") {
+		t.Fatalf("Resolve() = %v, want the ambiguous port refused", err)
+	}
+}
@@ -0,0 +1,97 @@
+package variables
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// EnvPrefix marks the environment variables holding template variables,
+// GRAVEL_VAR_project_name=demo sets project_name
+const EnvPrefix = "GRAVEL_VAR_"
+
+// Origin tells where the value of a variable comes from
+type Origin string
+
+const (
+	// Project values are those the project state of the app records, from
+	// before the lockfile recorded them
+	Project Origin = "project"
+	// Lockfile values are those the app was last rendered with
+	Lockfile    Origin = "lockfile"
+	Config      Origin = "config"
+	Profile     Origin = "profile"
+	Environment Origin = "environment"
+	Flag        Origin = "flag"
+	Prompt      Origin = "prompt"
+)
+
+// Variable is a template variable with the origin of its effective value
+type Variable struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Origin Origin `json:"origin"`
+}
+
+// Set resolves variables from layered origins, later layers win
+type Set struct {
+	variables map[string]Variable
+}
+
+func New() *Set {
+	return &Set{variables: make(map[string]Variable)}
+}
+
+// Layer applies values from origin over the current ones
+func (set *Set) Layer(origin Origin, values map[string]string) {
+	for name, value := range values {
+		set.variables[name] = Variable{Name: name, Value: value, Origin: origin}
+	}
+}
+
+// List returns the effective variables sorted by name
+func (set *Set) List() []Variable {
+	list := make([]Variable, 0, len(set.variables))
+	for _, name := range slices.Sorted(maps.Keys(set.variables)) {
+		list = append(list, set.variables[name])
+	}
+	return list
+}
+
+// Values returns the effective value of every variable
+func (set *Set) Values() map[string]string {
+	values := make(map[string]string, len(set.variables))
+	for name, variable := range set.variables {
+		values[name] = variable.Value
+	}
+	return values
+}
+
+// FromEnviron extracts the variables prefixed by EnvPrefix from os.Environ like entries
+func FromEnviron(environ []string) map[string]string {
+	values := make(map[string]string)
+	for _, entry := range environ {
+		name, value, found := strings.Cut(entry, "=")
+		if !found {
+			continue
+		}
+		if name, found = strings.CutPrefix(name, EnvPrefix); found && name != "" {
+			values[name] = value
+		}
+	}
+	return values
+}
+
+// ParseAssignments parses key=value pairs such as the ones given to --set
+func ParseAssignments(assignments []string) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, assignment := range assignments {
+		name, value, found := strings.Cut(assignment, "=")
+		if !found || name == "" {
+			return nil, fmt.Errorf("invalid assignment %q, expected key=value", assignment)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
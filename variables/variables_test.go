@@ -0,0 +1,56 @@
+package variables
+
+import (
+	"maps"
+	"reflect"
+	"testing"
+)
+
+func TestSetLayers(t *testing.T) {
+	set := New()
+	set.Layer(Config, map[string]string{"name": "config", "port": "80"})
+	set.Layer(Environment, map[string]string{"port": "8080"})
+	set.Layer(Flag, map[string]string{"host": "localhost"})
+
+	want := []Variable{
+		{Name: "host", Value: "localhost", Origin: Flag},
+		{Name: "name", Value: "config", Origin: Config},
+		{Name: "port", Value: "8080", Origin: Environment},
+	}
+	if got := set.List(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("List() = %+v, want %+v", got, want)
+	}
+	if got := set.Values(); !maps.Equal(got, map[string]string{"host": "localhost", "name": "config", "port": "8080"}) {
+		t.Fatalf("Values() = %v", got)
+	}
+}
+
+func TestFromEnviron(t *testing.T) {
+	got := FromEnviron([]string{
+		"GRAVEL_VAR_project_name=demo",
+		"GRAVEL_VAR_url=https://example.com/?a=b",
+		"GRAVEL_VAR_=ignored",
+		"HOME=/root",
+		"malformed",
+	})
+	want := map[string]string{"project_name": "demo", "url": "https://example.com/?a=b"}
+	if !maps.Equal(got, want) {
+		t.Fatalf("FromEnviron() = %v, want %v", got, want)
+	}
+}
+
+func TestParseAssignments(t *testing.T) {
+	got, err := ParseAssignments([]string{"name=demo", "empty=", "query=a=b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"name": "demo", "empty": "", "query": "a=b"}; !maps.Equal(got, want) {
+		t.Fatalf("ParseAssignments() = %v, want %v", got, want)
+	}
+
+	for _, assignment := range []string{"name", "=demo"} {
+		if _, err = ParseAssignments([]string{assignment}); err == nil {
+			t.Errorf("ParseAssignments(%q) succeeded", assignment)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package version
+
+import (
+	"cmp"
+	"regexp"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// Version of the running binary, set at build time with
+// -ldflags "-X gravel/version.Version=v1.2.3". Otherwise it is the version
+// go build stamps from the tags of the checkout, a pseudo-version after the
+// last one, and "dev" when the binary has none, like in go run, tests and
+// checkouts without tags
+var Version = "dev"
+
+// untagged matches the pseudo-versions of the checkouts without tags
+var untagged = regexp.MustCompile(`^v\d+\.0\.0-\d{14}-[0-9a-f]{12}(\+.*)?$`)
+
+func init() {
+	if Version != "dev" {
+		return
+	}
+	info, ok := debug.ReadBuildInfo()
+	if ok && info.Main.Version != "" && info.Main.Version != "(devel)" && !untagged.MatchString(info.Main.Version) {
+		Version = info.Main.Version
+	}
+}
+
+// Compare compares two semantic versions, the leading "v" and build
+// suffixes are ignored. A pre-release comes before its release. The result
+// is -1, 0 or +1
+func Compare(a, b string) int {
+	av, ap := parse(a)
+	bv, bp := parse(b)
+	for index := range av {
+		if c := cmp.Compare(av[index], bv[index]); c != 0 {
+			return c
+		}
+	}
+	return comparePreRelease(ap, bp)
+}
+
+// Satisfies reports whether current is at least minimum. Development builds
+// without a version satisfy every requirement. A pre-release does not
+// satisfy its release, a pseudo-version between two tags only the older one
+func Satisfies(current, minimum string) bool {
+	if minimum == "" || current == "dev" {
+		return true
+	}
+	return Compare(current, minimum) >= 0
+}
+
+// pseudo matches the pre-release of the pseudo-versions go stamps between
+// two tags, such as v1.2.4-0.20260102150405-0123456789ab
+var pseudo = regexp.MustCompile(`(^|\.)\d{14}-[0-9a-f]{12}$`)
+
+// Released reports whether version names a tagged release, not a
+// development build, a pseudo-version or a modified checkout
+func Released(version string) bool {
+	if version == "dev" || strings.Contains(version, "+") {
+		return false
+	}
+	_, preRelease := parse(version)
+	return !pseudo.MatchString(preRelease)
+}
+
+// parse returns the numbers of raw and its pre-release
+func parse(raw string) (parts [3]int, preRelease string) {
+	raw = strings.TrimPrefix(raw, "v")
+	raw, _, _ = strings.Cut(raw, "+")
+	raw, preRelease, _ = strings.Cut(raw, "-")
+
+	for index, field := range strings.SplitN(raw, ".", 3) {
+		parts[index], _ = strconv.Atoi(field)
+	}
+	return
+}
+
+// comparePreRelease orders pre-releases like semver: none comes last, then
+// field by field, numbers before words
+func comparePreRelease(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for index := range min(len(as), len(bs)) {
+		an, aErr := strconv.Atoi(as[index])
+		bn, bErr := strconv.Atoi(bs[index])
+		var c int
+		switch {
+		case aErr == nil && bErr == nil:
+			c = cmp.Compare(an, bn)
+		case aErr == nil:
+			c = -1
+		case bErr == nil:
+			c = 1
+		default:
+			c = strings.Compare(as[index], bs[index])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(as), len(bs))
+}
@@ -0,0 +1,64 @@
+package version
+
+import "testing"
+
+func TestCompare(t *testing.T) {
+	for _, test := range []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"v1.2.3", "1.2.3", 0},
+		{"1.10.0", "1.9.9", 1},
+		{"1.2", "1.2.1", -1},
+		{"2.0.0-rc.1", "2.0.0", -1},
+		{"2.0.0-rc.1", "1.9.9", 1},
+		{"2.0.0-rc.2", "2.0.0-rc.10", -1},
+		{"2.0.0-alpha", "2.0.0-alpha.1", -1},
+		{"2.0.0-1", "2.0.0-alpha", -1},
+		{"1.2.3+build.5", "1.2.4", -1},
+		{"1.2.3+build.5", "1.2.3", 0},
+	} {
+		if got := Compare(test.a, test.b); got != test.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestSatisfies(t *testing.T) {
+	for _, test := range []struct {
+		current, minimum string
+		want             bool
+	}{
+		{"1.2.0", "", true},
+		{"dev", "9.0.0", true},
+		{"dev", "", true},
+		{"1.2.0", "1.2.0", true},
+		{"1.2.0", "1.3.0", false},
+		{"v2.0.0", "1.9.0", true},
+		{"v2.0.0-rc.1", "2.0.0", false},
+		{"v2.0.0-rc.1", "2.0.0-rc.1", true},
+		{"v2.0.0", "2.0.0-rc.1", true},
+		{"v1.2.4-0.20260102150405-0123456789ab", "1.2.3", true},
+		{"v1.2.4-0.20260102150405-0123456789ab", "1.2.4", false},
+	} {
+		if got := Satisfies(test.current, test.minimum); got != test.want {
+			t.Errorf("Satisfies(%q, %q) = %v, want %v", test.current, test.minimum, got, test.want)
+		}
+	}
+}
+
+func TestReleased(t *testing.T) {
+	for version, want := range map[string]bool{
+		"v1.2.3":                               true,
+		"v2.0.0-rc.1":                          true,
+		"dev":                                  false,
+		"v1.2.3+dirty":                         false,
+		"v1.2.4-0.20260102150405-0123456789ab": false,
+		"v2.0.0-rc.1.0.20260102150405-0123456789ab": false,
+	} {
+		if got := Released(version); got != want {
+			t.Errorf("Released(%q) = %v, want %v", version, got, want)
+		}
+	}
+}
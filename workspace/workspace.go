@@ -0,0 +1,198 @@
+package workspace
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gravel/manifest"
+
+	"github.com/go-git/go-billy/v6"
+)
+
+// Paths of the editor files written by Emit
+const (
+	VSCodeExtensions = ".vscode/extensions.json"
+	VSCodeSettings   = ".vscode/settings.json"
+	// RunConfigurations holds a JetBrains run configuration per file
+	RunConfigurations = ".idea/runConfigurations"
+)
+
+// Emit writes the editor files aggregated from the workspaces into fs and
+// returns their paths. Files shipped by the components are merged, their
+// settings win, and left alone when they are not plain JSON
+func Emit(fs billy.Filesystem, workspaces ...manifest.Workspace) (written []string, err error) {
+	var extensions []string
+	settings := make(map[string]any)
+	var runs []manifest.RunConfiguration
+	for _, workspace := range workspaces {
+		for _, extension := range workspace.Extensions {
+			if !slices.Contains(extensions, extension) {
+				extensions = append(extensions, extension)
+			}
+		}
+		for key, value := range workspace.Settings {
+			settings[key] = value
+		}
+		runs = append(runs, workspace.Run...)
+	}
+
+	if len(extensions) > 0 {
+		ok, err := mergeJSON(fs, VSCodeExtensions, func(document map[string]any) {
+			recommendations, _ := document["recommendations"].([]any)
+			for _, extension := range extensions {
+				if !slices.Contains(recommendations, any(extension)) {
+					recommendations = append(recommendations, extension)
+				}
+			}
+			document["recommendations"] = recommendations
+		})
+		if err != nil {
+			return written, err
+		}
+		if ok {
+			written = append(written, VSCodeExtensions)
+		}
+	}
+
+	if len(settings) > 0 {
+		ok, err := mergeJSON(fs, VSCodeSettings, func(document map[string]any) {
+			for key, value := range settings {
+				if _, shipped := document[key]; !shipped {
+					document[key] = value
+				}
+			}
+		})
+		if err != nil {
+			return written, err
+		}
+		if ok {
+			written = append(written, VSCodeSettings)
+		}
+	}
+
+	for _, run := range runs {
+		filepath := path.Join(RunConfigurations, fileName(run.Name)+".xml")
+		if _, err = fs.Stat(filepath); err == nil {
+			continue
+		}
+
+		var content []byte
+		content, err = runConfiguration(run)
+		if err != nil {
+			return written, err
+		}
+		if err = writeFile(fs, filepath, content); err != nil {
+			return written, err
+		}
+		written = append(written, filepath)
+	}
+	return written, nil
+}
+
+// mergeJSON edits the JSON object at filepath, created when missing. ok is
+// false when the existing file is not a plain JSON object
+func mergeJSON(fs billy.Filesystem, filepath string, edit func(map[string]any)) (ok bool, err error) {
+	document := make(map[string]any)
+
+	file, err := fs.Open(filepath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return false, err
+	default:
+		content, err := io.ReadAll(file)
+		_ = file.Close()
+		if err != nil {
+			return false, err
+		}
+		if json.Unmarshal(content, &document) != nil {
+			return false, nil
+		}
+	}
+
+	edit(document)
+
+	content, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return false, err
+	}
+	return true, writeFile(fs, filepath, append(content, '\n'))
+}
+
+var unsafeName = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// fileName turns a run configuration name into a file name
+func fileName(name string) string {
+	return strings.Trim(unsafeName.ReplaceAllString(name, "_"), "_")
+}
+
+type option struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type configuration struct {
+	Default string   `xml:"default,attr"`
+	Name    string   `xml:"name,attr"`
+	Type    string   `xml:"type,attr"`
+	Options []option `xml:"option"`
+}
+
+type component struct {
+	XMLName       xml.Name      `xml:"component"`
+	Name          string        `xml:"name,attr"`
+	Configuration configuration `xml:"configuration"`
+}
+
+// runConfiguration renders a JetBrains shell script run configuration
+func runConfiguration(run manifest.RunConfiguration) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := xml.NewEncoder(&buffer)
+	encoder.Indent("", "  ")
+	err := encoder.Encode(component{
+		Name: "ProjectRunConfigurationManager",
+		Configuration: configuration{
+			Default: "false",
+			Name:    run.Name,
+			Type:    "ShConfigurationType",
+			Options: []option{
+				{"SCRIPT_TEXT", run.Command},
+				{"INDEPENDENT_SCRIPT_PATH", "true"},
+				{"SCRIPT_PATH", ""},
+				{"SCRIPT_OPTIONS", ""},
+				{"INDEPENDENT_SCRIPT_WORKING_DIRECTORY", "true"},
+				{"SCRIPT_WORKING_DIRECTORY", "$PROJECT_DIR$"},
+				{"EXECUTE_IN_TERMINAL", "true"},
+				{"EXECUTE_SCRIPT_FILE", "false"},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	buffer.WriteByte('\n')
+	return buffer.Bytes(), nil
+}
+
+func writeFile(fs billy.Filesystem, filepath string, content []byte) error {
+	if err := fs.MkdirAll(path.Dir(filepath), 0o755); err != nil {
+		return err
+	}
+	file, err := fs.Create(filepath)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,87 @@
+package workspace
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gravel/manifest"
+
+	"github.com/go-git/go-billy/v6"
+	"github.com/go-git/go-billy/v6/memfs"
+)
+
+func readFile(t *testing.T, fs billy.Filesystem, filepath string) string {
+	t.Helper()
+	file, err := fs.Open(filepath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = file.Close() }()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestEmit(t *testing.T) {
+	fs := memfs.New()
+	// The settings shipped by a component win over the manifest ones
+	if err := writeFile(fs, VSCodeSettings, []byte(`{"editor.tabSize": 4}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	written, err := Emit(fs,
+		manifest.Workspace{
+			Extensions: []string{"golang.go"},
+			Settings:   map[string]any{"editor.tabSize": 2, "go.lintTool": "staticcheck"},
+			Run:        []manifest.RunConfiguration{{Name: "Run tests", Command: "go test ./..."}},
+		},
+		manifest.Workspace{Extensions: []string{"golang.go", "redhat.vscode-yaml"}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{VSCodeExtensions, VSCodeSettings, RunConfigurations + "/Run_tests.xml"}
+	if !reflect.DeepEqual(written, want) {
+		t.Fatalf("Emit() = %v, want %v", written, want)
+	}
+	if got := readFile(t, fs, VSCodeExtensions); got != "{\n  \"recommendations\": [\n    \"golang.go\",\n    \"redhat.vscode-yaml\"\n  ]\n}\n" {
+		t.Errorf("%s = %s", VSCodeExtensions, got)
+	}
+	if got := readFile(t, fs, VSCodeSettings); got != "{\n  \"editor.tabSize\": 4,\n  \"go.lintTool\": \"staticcheck\"\n}\n" {
+		t.Errorf("%s = %s", VSCodeSettings, got)
+	}
+	if got := readFile(t, fs, want[2]); !strings.Contains(got, `<option name="SCRIPT_TEXT" value="go test ./..."></option>`) {
+		t.Errorf("run configuration = %s", got)
+	}
+}
+
+func TestEmitLeavesOtherFiles(t *testing.T) {
+	fs := memfs.New()
+	// JSON with comments, as VS Code accepts, is not merged
+	shipped := "{\n  // tabs\n  \"editor.insertSpaces\": false\n}\n"
+	if err := writeFile(fs, VSCodeSettings, []byte(shipped)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFile(fs, RunConfigurations+"/Build.xml", []byte("shipped")); err != nil {
+		t.Fatal(err)
+	}
+
+	written, err := Emit(fs, manifest.Workspace{
+		Settings: map[string]any{"editor.tabSize": 2},
+		Run:      []manifest.RunConfiguration{{Name: "Build", Command: "make"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(written) != 0 {
+		t.Fatalf("Emit() = %v, want the shipped files left alone", written)
+	}
+	if got := readFile(t, fs, VSCodeSettings); got != shipped {
+		t.Errorf("%s = %s", VSCodeSettings, got)
+	}
+}